@@ -1,25 +1,24 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
-	"github.com/rsdenck/nux/internal/core"
+	"github.com/rsdenck/nux/internal/agent"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
 )
 
-var agentExecutor core.Executor = &core.RealExecutor{}
-
 var agentCmd = &cobra.Command{
 	Use:   "agent",
 	Short: "AI agent for system analysis and automation",
-	Long:  `Interact with AI providers (Ollama, OpenAI, Claude) for intelligent system management.`,
+	Long:  `Interact with AI providers (Ollama, OpenAI, Claude, NVIDIA Build) for intelligent system management.`,
 }
 
 var agentStatusCmd = &cobra.Command{
@@ -29,7 +28,8 @@ var agentStatusCmd = &cobra.Command{
 		// Load vault for configuration
 		v, err := vault.Load()
 		if err != nil {
-			v = vault.NewVault()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
 		}
 
 		// Get configuration
@@ -69,7 +69,8 @@ var agentStatusCmd = &cobra.Command{
 		if provider == "ollama" {
 			start := time.Now()
 			url := fmt.Sprintf("%s/api/tags", host)
-			resp, err := http.Get(url)
+			client := &http.Client{Timeout: time.Duration(flagTimeout) * time.Second}
+			resp, err := client.Get(url)
 			if err == nil {
 				defer resp.Body.Close()
 				if resp.StatusCode == 200 {
@@ -107,79 +108,113 @@ var agentQueryCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		question := strings.Join(args, " ")
 
-		// Load vault for API keys
-		v, _ := vault.Load()
-		
-		// Determine provider
-		provider := "ollama"
-		if v != nil {
-			if p, ok := v.Config["agent_provider"].(string); ok {
-				provider = p
-			}
-		}
-
-		switch provider {
-		case "ollama":
-			agentQueryOllama(question, v, provider)
-		case "openai":
-			agentQueryOpenAI(question, v)
-		default:
-			output.NewError(fmt.Sprintf("unknown provider: %s", provider), "AGENT_INVALID_PROVIDER").Print()
+		// Flags override the configured provider and model
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		p, model, save, err := openAgentProvider(provider, model)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
 		}
+		defer save()
+		printAnswer(p, userMessage(model, question))
 	},
 }
 
-func agentQueryOllama(question string, v *vault.Vault, provider string) {
-	host := "http://localhost:11434"
-	model := "qwen3-coder"
+// agentSystemPrompt tells the model how to use the tool catalog
+const agentSystemPrompt = `You are nux, an assistant operating a Linux host through tools. Call the
+tools to inspect the host before answering, rather than guessing. Tools that
+change the host run only once the user approves them; when one is refused,
+do not retry it, explain what you would have done instead. Answer concisely
+with what you found and what to do next.`
+
+var agentRunCmd = &cobra.Command{
+	Use:   "run <task>",
+	Short: "Let the AI inspect and operate the host through nux tools",
+	Long: `Let the AI work on a task by calling nux tools: health checks, service
+status and logs, the process tree, disk usage and so on. Every call is shown
+on stderr with its arguments and output, or as NDJSON {"type":"tool_call"}
+and {"type":"tool_result"} events with --json, and logged to
+~/.nux/agent.jsonl.
+
+Read-only tools run on their own. Tools that change the host, such as
+restart_service or kill_process, run only once confirmed on the terminal or
+named with --allow; without a terminal the others are refused. With
+--dry-run they are approved but only record what they would run.`,
+	Example: `  nux agent run "why is nginx failing?"
+  nux agent run "free some space on /var" --context doctor
+  nux agent run "restart the failed services" --allow restart_service --host web01`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		task := strings.Join(args, " ")
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		allow, _ := cmd.Flags().GetStringSlice("allow")
+		maxSteps, _ := cmd.Flags().GetInt("max-steps")
 
-	if v != nil {
-		if h, ok := v.Config["ollama_host"].(string); ok {
-			host = h
+		tools, err := agentTools()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeUnsupported).WithCause(err).Print()
+			return
 		}
-		if m, ok := v.Config["agent_model"].(string); ok {
-			model = m
+		if err := checkAllowed(tools, allow); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
+		}
+		preamble, err := hostContext(cmd)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
 		}
-	}
-
-	url := fmt.Sprintf("%s/api/generate", host)
-
-	payload := map[string]interface{}{
-		"model":  model,
-		"prompt": question,
-		"stream": false,
-	}
-
-	data, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(data)))
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to connect to Ollama: %s", err.Error()), "AGENT_OLLAMA_ERROR").Print()
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		output.NewError("failed to parse Ollama response", "AGENT_PARSE_ERROR").Print()
-		return
-	}
+		p, model, save, err := openAgentProvider(provider, model)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		defer save()
 
-	if response, ok := result["response"].(string); ok {
-		output.NewSuccess(map[string]interface{}{
-			"provider": provider,
-			"model":    model,
-			"response": response,
-		}).WithMessage("Ollama Response").Print()
-	} else {
-		output.NewError("no response from Ollama", "AGENT_NO_RESPONSE").Print()
-	}
-}
+		log, err := agent.OpenLog(flagHost)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ %s, tool calls are not logged\n", err.Error())
+		}
+		onToken, done := tokenPrinter()
+		steps := 0
+		a := &agent.Agent{
+			Provider: p,
+			Model:    model,
+			Tools:    tools,
+			MaxSteps: maxSteps,
+			Approve:  approver(allow),
+			OnCall: func(step agent.Step) {
+				// End the text the model wrote before calling tools
+				done()
+				showCall(step)
+			},
+			OnResult: func(step agent.Step) {
+				steps = step.N
+				showResult(step)
+				log.Record(step)
+			},
+		}
 
-func agentQueryOpenAI(question string, v *vault.Vault) {
-	// Similar to ask.go OpenAI implementation
-	output.NewInfo("OpenAI provider not yet fully implemented").Print()
+		messages := withContext(preamble, []ports.ChatMessage{
+			{Role: ports.RoleSystem, Content: agentSystemPrompt},
+			{Role: ports.RoleUser, Content: task},
+		})
+		if preamble != "" {
+			showContext(preamble)
+		}
+		resp, _, err := a.Run(commandContext(), messages, onToken)
+		done()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+			return
+		}
+		if onToken == nil || flagJSON {
+			printResponse(resp, map[string]interface{}{"steps": steps, "run": log.Run()})
+		}
+	},
 }
 
 var agentConfigCmd = &cobra.Command{
@@ -193,7 +228,8 @@ var agentConfigCmd = &cobra.Command{
 
 		v, err := vault.Load()
 		if err != nil {
-			v = vault.NewVault()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
 		}
 
 		if provider != "" {
@@ -222,7 +258,7 @@ var agentConfigCmd = &cobra.Command{
 		}
 
 		if err := vault.Save(v); err != nil {
-			output.NewError(fmt.Sprintf("failed to save config: %s", err.Error()), "AGENT_CONFIG_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to save config: %s", err.Error()), nuxerrors.ErrCodeConfig).WithCause(err).Print()
 			return
 		}
 
@@ -237,9 +273,16 @@ func init() {
 	agentStatusCmd.Flags().Bool("json", false, "Output in JSON format")
 	agentCmd.AddCommand(agentStatusCmd)
 	
-	agentQueryCmd.Flags().String("provider", "", "AI provider (ollama, openai, claude)")
+	agentQueryCmd.Flags().String("provider", "", "AI provider ("+llmProviders+", or an endpoint)")
 	agentQueryCmd.Flags().String("model", "", "Model name")
 	agentCmd.AddCommand(agentQueryCmd)
+
+	agentRunCmd.Flags().String("provider", "", "AI provider ("+llmProviders+", or an endpoint)")
+	agentRunCmd.Flags().String("model", "", "Model name")
+	agentRunCmd.Flags().StringSlice("allow", nil, "Tools that change the host allowed to run without confirmation")
+	agentRunCmd.Flags().Int("max-steps", agent.DefaultMaxSteps, "Maximum rounds of tool calls")
+	addContextFlags(agentRunCmd)
+	agentCmd.AddCommand(agentRunCmd)
 	
 	agentConfigCmd.Flags().String("provider", "", "Provider to configure")
 	agentConfigCmd.Flags().String("api-key", "", "API key for OpenAI")
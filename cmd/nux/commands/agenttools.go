@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rsdenck/nux/internal/agent"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/disk"
+	"github.com/rsdenck/nux/internal/modules/doctor"
+	"github.com/rsdenck/nux/internal/modules/process"
+	"github.com/rsdenck/nux/internal/modules/service"
+	"github.com/rsdenck/nux/internal/output"
+)
+
+// shownOutputLines bounds the lines of a tool output shown on the terminal;
+// the model and the agent log get more
+const shownOutputLines = 12
+
+// agentTools returns the tool catalog operating the target host. With
+// --dry-run the mutating tools record their commands instead of running them.
+func agentTools() ([]agent.Tool, error) {
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
+	if err != nil {
+		return nil, err
+	}
+	tools := agent.Catalog(agent.Managers{
+		Doctor:    doctor.NewUniversalDoctorManager(executor, profile),
+		Services:  service.NewUniversalServiceManager(executor, profile).WithJournal(newJournal()),
+		Processes: process.NewUniversalProcessManager(executor, profile),
+		Disks:     disk.NewUniversalDiskManager(executor, profile),
+	})
+	if flagDryRun {
+		for i := range tools {
+			if !tools[i].Mutating {
+				continue
+			}
+			run := tools[i].Run
+			tools[i].Run = func(args json.RawMessage) (string, error) {
+				if _, err := run(args); err != nil {
+					return "", err
+				}
+				return "planned: the change is recorded by --dry-run, not made", nil
+			}
+		}
+	}
+	return tools, nil
+}
+
+// checkAllowed checks that --allow names mutating tools of the catalog
+func checkAllowed(tools []agent.Tool, allow []string) error {
+	mutating := map[string]bool{}
+	var names []string
+	for _, t := range tools {
+		if t.Mutating {
+			mutating[t.Name] = true
+			names = append(names, t.Name)
+		}
+	}
+	for _, name := range allow {
+		if !mutating[name] {
+			return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("--allow %s: not a tool that changes the host (tools: %s)", name, strings.Join(names, ", ")))
+		}
+	}
+	return nil
+}
+
+// approver returns the approval of mutating calls: the tools in allow run,
+// and the others once confirmed on the terminal. Without a terminal, and with
+// --dry-run where nothing changes, they are refused or approved outright.
+func approver(allow []string) func(call ports.ToolCall) bool {
+	allowed := map[string]bool{}
+	for _, name := range allow {
+		allowed[name] = true
+	}
+	return func(call ports.ToolCall) bool {
+		if allowed[call.Name] || flagDryRun {
+			return true
+		}
+		if !isTerminal(os.Stdin) {
+			fmt.Fprintf(os.Stderr, "⚠ %s was not run: allow it with --allow %s\n", call.Name, call.Name)
+			return false
+		}
+		return confirmCall(call)
+	}
+}
+
+// confirmCall asks on the controlling terminal whether a call may run
+func confirmCall(call ports.ToolCall) bool {
+	return confirm(fmt.Sprintf("Run %s %s on %s?", call.Name, string(call.Arguments), targetName()))
+}
+
+// targetName names the host commands run on, for prompts
+func targetName() string {
+	if flagHost != "" {
+		return flagHost
+	}
+	return "this host"
+}
+
+// confirm asks a y/N question on the controlling terminal, so it works while
+// stdin is piped. No terminal means no.
+func confirm(question string) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// toolEvent is the NDJSON event of a tool call, and of its result
+type toolEvent struct {
+	Type      string          `json:"type"`
+	Step      int             `json:"step"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Mutating  bool            `json:"mutating,omitempty"`
+	Approved  *bool           `json:"approved,omitempty"`
+	Output    string          `json:"output,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// showCall shows a tool call about to run: an NDJSON event with --json, or a
+// line on stderr, leaving stdout to the answer
+func showCall(step agent.Step) {
+	if flagJSON {
+		output.PrintEvent(toolEvent{Type: "tool_call", Step: step.N, Tool: step.Call.Name, Arguments: step.Call.Arguments, Mutating: step.Mutating})
+		return
+	}
+	mark := "⚙"
+	if step.Mutating {
+		mark = "⚠"
+	}
+	fmt.Fprintf(os.Stderr, "%s [%d] %s %s\n", mark, step.N, step.Call.Name, string(step.Call.Arguments))
+}
+
+// showResult shows what a tool call returned, its first lines on stderr
+func showResult(step agent.Step) {
+	if flagJSON {
+		approved := step.Approved
+		event := toolEvent{Type: "tool_result", Step: step.N, Tool: step.Call.Name, Approved: &approved, Output: step.Output}
+		if step.Err != nil {
+			event.Error = step.Err.Error()
+		}
+		output.PrintEvent(event)
+		return
+	}
+	switch {
+	case !step.Approved:
+		fmt.Fprintln(os.Stderr, "  ✖ not approved, not run")
+		return
+	case step.Err != nil:
+		fmt.Fprintf(os.Stderr, "  ✖ %s\n", step.Err.Error())
+		return
+	}
+	lines := strings.Split(strings.TrimRight(step.Output, "\n"), "\n")
+	for i, line := range lines {
+		if i == shownOutputLines {
+			fmt.Fprintf(os.Stderr, "  … %d more lines\n", len(lines)-i)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+}
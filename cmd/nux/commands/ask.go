@@ -1,14 +1,16 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
 	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/rag"
+	"github.com/rsdenck/nux/internal/redact"
+	"github.com/rsdenck/nux/internal/usage"
 	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
 )
@@ -22,351 +24,350 @@ import (
 var askQueryCmd = &cobra.Command{
 	Use:   "query <question>",
 	Short: "Ask a question to AI",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Ask a question to an AI provider. The answer is printed as it is generated;
+with --json each piece is an NDJSON {"type":"token"} event, followed by the
+result. --timeout bounds the wait for the answer to start and every pause
+while it streams.
+
+--context sends data gathered from the host with the question, such as
+health checks or the journal of a unit, shown on stderr before it is sent.
+With --dry-run the context is printed and nothing is sent.
+
+--rag sends the passages of the nux docs and skill catalog closest to the
+question, from the index built by 'nux ask index', and lists them after the
+answer, which cites them as [n].`,
+	Example: `  nux ask query "why is this box slow?" --context doctor,metrics
+  nux ask query "why does nginx keep restarting?" --context services,journal:nginx
+  nux ask query "what would you check?" --context profile,doctor --dry-run
+  nux ask query "how do I list listening ports with nux?" --rag`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		question := strings.Join(args, " ")
 
 		provider, _ := cmd.Flags().GetString("provider")
 		model, _ := cmd.Flags().GetString("model")
 
-		if provider == "" {
-			provider = "ollama"
+		useRAG, _ := cmd.Flags().GetBool("rag")
+		top, _ := cmd.Flags().GetInt("rag-top")
+
+		preamble, err := hostContext(cmd)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
 		}
-		if model == "" {
-			model = "qwen3-coder"
+		var hits []rag.Hit
+		if useRAG {
+			if hits, err = retrievePassages(question, top); err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+				return
+			}
+		}
+		if flagDryRun && (preamble != "" || useRAG) {
+			printContext(preamble + rag.Preamble(hits))
+			return
 		}
 
-		switch provider {
-		case "ollama":
-			askOllamaFunc(question, model, provider)
-		case "openai":
-			askOpenAI(question, model, provider)
-		case "nvidia":
-			askNvidiaBuild(question, model, provider)
-		case "claude":
-			askClaude(question, model, provider)
-		default:
-			output.NewError(fmt.Sprintf("unknown provider: %s", provider), "ASK_INVALID_PROVIDER").Print()
+		p, save, err := openLLMProvider(provider)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		defer save()
+		req := userMessage(model, question)
+		if preamble != "" {
+			showContext(preamble)
+			req.Messages = withContext(preamble, req.Messages)
 		}
+		if useRAG {
+			req.Messages = withContext(rag.Preamble(hits), req.Messages)
+			printCitedAnswer(p, req, hits)
+			return
+		}
+		printAnswer(p, req)
 	},
 }
 
-func askOllamaFunc(question, model, provider string) {
-	v, err := vault.Load()
-	if err != nil {
-		output.NewError("failed to load vault, using defaults", "VAULT_ERROR").Print()
-	}
+var askModelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List the models of a provider",
+	Run: func(cmd *cobra.Command, args []string) {
+		provider, _ := cmd.Flags().GetString("provider")
 
-	host := "http://localhost:11434"
-	if v != nil && v.Config != nil {
-		if h, ok := v.Config["ollama_host"].(string); ok {
-			host = h
+		p, save, err := openLLMProvider(provider)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
 		}
-	}
-
-	url := fmt.Sprintf("%s/api/generate", host)
-
-	payload := map[string]interface{}{
-		"model":  model,
-		"prompt": question,
-		"stream": false,
-	}
-
-	data, _ := json.Marshal(payload)
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(data)))
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to connect to Ollama: %s", err.Error()), "ASK_OLLAMA_ERROR").Print()
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		output.NewError("failed to parse Ollama response", "ASK_PARSE_ERROR").Print()
-		return
-	}
-
-	if response, ok := result["response"].(string); ok {
-		output.NewSuccess(map[string]interface{}{
-			"provider": provider,
-			"model":    model,
-			"response": response,
-		}).WithMessage("Ollama Response").Print()
-	} else {
-		output.NewError("no response from Ollama", "ASK_NO_RESPONSE").Print()
-	}
-}
-
-func askOpenAI(question, model, provider string) {
-	v, err := vault.Load()
-	if err != nil {
-		output.NewError("failed to load vault", "VAULT_ERROR").Print()
-		return
-	}
-
-	apiKey, ok := v.GetAPIKey("openai")
-	if !ok {
-		output.NewError("OpenAI API key not found. Use: nux vault set-key openai <key>", "ASK_NO_API_KEY").Print()
-		return
-	}
-
-	url := "https://api.openai.com/v1/chat/completions"
-
-	payload := map[string]interface{}{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "user", "content": question},
-		},
-	}
-
-	data, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, strings.NewReader(string(data)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to connect to OpenAI: %s", err.Error()), "ASK_OPENAI_ERROR").Print()
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		output.NewError("failed to parse OpenAI response", "ASK_PARSE_ERROR").Print()
-		return
-	}
-
-	if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			if message, ok := choice["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					output.NewSuccess(map[string]interface{}{
-						"provider": "openai",
-						"model":    model,
-						"response": content,
-					}).WithMessage("OpenAI Response").Print()
-					return
-				}
-			}
+		defer save()
+		lister, ok := p.(ports.ModelLister)
+		if !ok {
+			output.NewError(fmt.Sprintf("provider %s cannot list its models", p.Name()), nuxerrors.ErrCodeUnsupported).Print()
+			return
 		}
-	}
-
-	output.NewError("no response from OpenAI", "ASK_NO_RESPONSE").Print()
-}
-
-func askClaude(question, model, provider string) {
-	v, err := vault.Load()
-	if err != nil {
-		output.NewError("failed to load vault", "VAULT_ERROR").Print()
-		return
-	}
-
-	apiKey, ok := v.GetAPIKey("claude")
-	if !ok {
-		output.NewError("Claude API key not found. Use: nux ask config --provider claude --api-key <key>", "ASK_NO_API_KEY").Print()
-		return
-	}
-
-	url := "https://api.anthropic.com/v1/messages"
-
-	if model == "" {
-		model = "claude-3-5-sonnet-20241022"
-	}
-
-	payload := map[string]interface{}{
-		"model":      model,
-		"max_tokens": 8192,
-		"messages": []map[string]string{
-			{"role": "user", "content": question},
-		},
-	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to marshal payload: %s", err.Error()), "ASK_MARSHAL_ERROR").Print()
-		return
-	}
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(data)))
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to create request: %s", err.Error()), "ASK_REQUEST_ERROR").Print()
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to connect to Claude: %s", err.Error()), "ASK_CLAUDE_ERROR").Print()
-		return
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to read response: %s", err.Error()), "ASK_READ_ERROR").Print()
-		return
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		output.NewError("failed to parse Claude response", "ASK_PARSE_ERROR").Print()
-		return
-	}
-
-	if content, ok := result["content"].([]interface{}); ok && len(content) > 0 {
-		if block, ok := content[0].(map[string]interface{}); ok {
-			if text, ok := block["text"].(string); ok {
-				output.NewSuccess(map[string]interface{}{
-					"provider": "claude",
-					"model":    model,
-					"response": text,
-				}).WithMessage("Claude Response").Print()
-				return
-			}
+		models, err := lister.ListModels(commandContext())
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+			return
 		}
-	}
 
-	output.NewError("no response from Claude", "ASK_NO_RESPONSE").Print()
+		items := make([]map[string]interface{}, 0, len(models))
+		for _, m := range models {
+			items = append(items, map[string]interface{}{
+				"model":   m.ID,
+				"owner":   m.Owner,
+				"default": m.ID == p.DefaultModel(),
+			})
+		}
+		output.NewList(items, len(items)).WithColumns("model", "owner", "default").
+			WithMessage(fmt.Sprintf("Models of %s", p.Name())).Print()
+	},
 }
 
-func askNvidiaBuild(question, model, provider string) {
-	v, err := vault.Load()
-	if err != nil {
-		output.NewError("failed to load vault", "VAULT_ERROR").Print()
-		return
-	}
-
-	apiKey, ok := v.GetAPIKey("nvidia")
-	if !ok {
-		output.NewError("NVIDIA API key not found. Use: nux ask config --provider nvidia --api-key <key>", "ASK_NO_API_KEY").Print()
-		return
-	}
-
-	url := "https://integrate.api.nvidia.com/v1/chat/completions"
-
-	if model == "" {
-		model = "minimaxai/minimax-m2.7"
-	}
-
-	payload := map[string]interface{}{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "user", "content": question},
-		},
-		"temperature": 1,
-		"top_p": 0.95,
-		"max_tokens": 8192,
-	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to marshal payload: %s", err.Error()), "ASK_MARSHAL_ERROR").Print()
-		return
-	}
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(data)))
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to create request: %s", err.Error()), "ASK_REQUEST_ERROR").Print()
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to connect to NVIDIA Build: %s", err.Error()), "ASK_NVIDIA_ERROR").Print()
-		return
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		output.NewError(fmt.Sprintf("failed to read response: %s", err.Error()), "ASK_READ_ERROR").Print()
-		return
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		output.NewError("failed to parse NVIDIA Build response", "ASK_PARSE_ERROR").Print()
-		return
-	}
-
-	if choices, ok := result["choices"].([]interface{}); ok && len(choices) > 0 {
-		if choice, ok := choices[0].(map[string]interface{}); ok {
-			if message, ok := choice["message"].(map[string]interface{}); ok {
-				if content, ok := message["content"].(string); ok {
-					output.NewSuccess(map[string]interface{}{
-						"provider": "nvidia-build",
-						"model":    model,
-						"response": content,
-					}).WithMessage("NVIDIA Build Response").Print()
-					return
-				}
-			}
+var askEndpointsCmd = &cobra.Command{
+	Use:   "endpoints",
+	Short: "List the OpenAI-compatible endpoints configured by name",
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := vault.Load()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
 		}
-	}
 
-	output.NewError("no response from NVIDIA Build", "ASK_NO_RESPONSE").Print()
+		items := []map[string]interface{}{}
+		for _, name := range v.EndpointNames() {
+			e, _ := v.GetEndpoint(name)
+			items = append(items, map[string]interface{}{
+				"name":        name,
+				"type":        e.Type,
+				"base_url":    e.BaseURL,
+				"model":       e.Model,
+				"api_key_ref": e.APIKeyRef,
+			})
+		}
+		output.NewList(items, len(items)).WithColumns("name", "type", "base_url", "model", "api_key_ref").
+			WithMessage("AI Endpoints").Print()
+	},
 }
 
 var askConfigCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configure AI providers",
+	Long: `Configure AI providers. Built-in providers take an API key, and Ollama its
+host. Any other name configures an endpoint speaking the OpenAI API, such as
+llama.cpp or vLLM, which is then selected with --provider <name>.
+
+--redact sets what is masked before anything reaches the provider: all, the
+default except for Ollama, off, the default for Ollama, or a list of
+categories. 'nux ask redact' shows the result.
+
+--chain sets the providers asked when --provider is not given, each in turn
+until one answers, as provider or provider:model; "off" goes back to Ollama
+alone. Every provider retries on 429 and 5xx, waiting as long as its
+Retry-After asks, before the next one is tried.
+
+--price sets what a model (model=input/output) or the --provider
+(input/output) charges, in US dollars per million tokens, for 'nux ask
+usage'. Common OpenAI and Claude models are priced already.`,
+	Example: `  nux ask config --provider nvidia --api-key nvapi-...
+  nux ask config --provider ollama --host http://192.168.130.25:11434
+  nux ask config --provider vllm --base-url http://gpu01:8000 --api-key-ref vault://vllm --model qwen2.5-72b
+  nux ask config --provider vllm --remove
+  nux ask config --provider openai --redact ip,hostname,secret,private-key
+  nux ask config --chain ollama:qwen3:8b,nvidia,openai
+  nux ask config --price meta/llama-3.1-70b-instruct=0.35/0.40`,
 	Run: func(cmd *cobra.Command, args []string) {
 		provider, _ := cmd.Flags().GetString("provider")
 		apiKey, _ := cmd.Flags().GetString("api-key")
 		host, _ := cmd.Flags().GetString("host")
+		baseURL, _ := cmd.Flags().GetString("base-url")
+		keyRef, _ := cmd.Flags().GetString("api-key-ref")
+		model, _ := cmd.Flags().GetString("model")
+		remove, _ := cmd.Flags().GetBool("remove")
+		redactRules, _ := cmd.Flags().GetString("redact")
+		chain, _ := cmd.Flags().GetString("chain")
+		price, _ := cmd.Flags().GetString("price")
+
+		priced, priceSpec, perModel := strings.Cut(price, "=")
+		if !perModel {
+			priced, priceSpec = canonicalProvider(provider), price
+		}
+		if provider == "" && chain == "" && !perModel {
+			output.NewError("--provider is required", nuxerrors.ErrCodeInvalidInput).Print()
+			return
+		}
 
 		v, err := vault.Load()
 		if err != nil {
-			v = vault.NewVault()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
 		}
 
-		if provider == "openai" && apiKey != "" {
-			v.SetAPIKey("openai", apiKey)
+		if chain != "" {
+			if err := configureChain(v, chain); err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+				return
+			}
+		}
+		if price != "" {
+			p, err := usage.ParsePrice(priceSpec)
+			if err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+				return
+			}
+			priced = strings.TrimSpace(priced)
+			v.SetPrice(priced, p.String())
+		}
+		if provider == "" {
+			if err := vault.Save(v); err != nil {
+				output.NewError(fmt.Sprintf("failed to save config: %s", err.Error()), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+				return
+			}
+			result := map[string]interface{}{
+				"chain":  configuredChain(v),
+				"status": "configured",
+			}
+			if price != "" {
+				result["price"] = priced + "=" + v.GetPrices()[priced]
+			}
+			output.NewSuccess(result).Print()
+			return
 		}
 
-		if provider == "ollama" && host != "" {
-			if v.Config == nil {
-				v.Config = make(map[string]interface{})
+		if redactRules != "" {
+			if _, err := redact.ParseRules(redactRules); err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+				return
+			}
+			v.SetRedaction(canonicalProvider(provider), redactRules)
+		}
+
+		status := "configured"
+		if _, isEndpoint := v.GetEndpoint(provider); isEndpoint || baseURL != "" || keyRef != "" || model != "" || remove {
+			if isBuiltinProvider(provider) {
+				output.NewError(fmt.Sprintf("%s is a built-in provider, --base-url, --api-key-ref, --model and --remove only apply to endpoints", provider), nuxerrors.ErrCodeInvalidInput).
+					WithHint("give the endpoint another name").Print()
+				return
+			}
+			if remove {
+				if !v.RemoveEndpoint(provider) {
+					output.NewError(fmt.Sprintf("no endpoint named %s", provider), nuxerrors.ErrCodeNotFound).Print()
+					return
+				}
+				status = "removed"
+			} else if err := configureEndpoint(v, provider, baseURL, apiKey, keyRef, model); err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+				return
+			}
+		} else {
+			if apiKey != "" {
+				switch provider {
+				case "openai", "claude", "nvidia":
+					v.SetAPIKey(provider, apiKey)
+				default:
+					output.NewError(fmt.Sprintf("provider %q does not take an API key (available: openai, claude, nvidia)", provider), nuxerrors.ErrCodeInvalidInput).
+						WithHint("configure an OpenAI-compatible endpoint with --base-url").Print()
+					return
+				}
+			}
+
+			if provider == "ollama" && host != "" {
+				if v.Config == nil {
+					v.Config = make(map[string]interface{})
+				}
+				v.Config["ollama_host"] = host
 			}
-			v.Config["ollama_host"] = host
 		}
 
 		if err := vault.Save(v); err != nil {
-			output.NewError(fmt.Sprintf("failed to save config: %s", err.Error()), "ASK_CONFIG_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to save config: %s", err.Error()), nuxerrors.ErrCodeConfig).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"provider": provider,
-			"status":   "configured",
+			"status":   status,
 		}).Print()
 	},
 }
 
+// configureChain stores the providers asked when none is given, checking
+// they are known; "off" removes the chain
+func configureChain(v *vault.Vault, chain string) error {
+	if v.Config == nil {
+		v.Config = make(map[string]interface{})
+	}
+	if chain == "off" {
+		delete(v.Config, "provider_chain")
+		return nil
+	}
+	names, _, err := parseChain(chain)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, ok := v.GetEndpoint(name); !ok && !isBuiltinProvider(name) {
+			return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("unknown provider in chain: %s (available: %s)", name, llmProviders)).
+				WithHint("add an OpenAI-compatible endpoint with 'nux ask config --provider " + name + " --base-url URL'")
+		}
+	}
+	v.Config["provider_chain"] = chain
+	return nil
+}
+
+// configureEndpoint creates or updates an OpenAI-compatible endpoint. A key
+// given with --api-key is stored as a secret named after the endpoint.
+func configureEndpoint(v *vault.Vault, name, baseURL, apiKey, keyRef, model string) error {
+	e, exists := v.GetEndpoint(name)
+	if !exists && baseURL == "" {
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("endpoint %s needs --base-url", name))
+	}
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid base URL %q, expected http(s)://host[:port][/path]", baseURL))
+		}
+		e.BaseURL = baseURL
+	}
+	if apiKey != "" && keyRef != "" {
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "--api-key and --api-key-ref cannot be used together")
+	}
+	if apiKey != "" {
+		v.SetAPIKey(name, apiKey)
+		keyRef = vault.RefScheme + name
+	}
+	if keyRef != "" {
+		e.APIKeyRef = keyRef
+	}
+	if model != "" {
+		e.Model = model
+	}
+	e.Type = vault.EndpointOpenAICompatible
+	return v.SetEndpoint(name, e)
+}
+
 func init() {
-	askQueryCmd.Flags().String("provider", "", "AI provider (ollama, openai, claude)")
-	askQueryCmd.Flags().String("model", "", "Model name (default: qwen3-coder for ollama)")
+	askQueryCmd.Flags().String("provider", "", "AI provider ("+llmProviders+", or an endpoint)")
+	askQueryCmd.Flags().String("model", "", "Model name (default: the provider's, qwen3-coder for ollama)")
+	addContextFlags(askQueryCmd)
+	askQueryCmd.Flags().Bool("rag", false, "Send the passages of the nux docs and skills closest to the question, built by 'nux ask index'")
+	askQueryCmd.Flags().Int("rag-top", defaultRAGPassages, "Number of passages sent with --rag")
 	askCmd.AddCommand(askQueryCmd)
 
-	askConfigCmd.Flags().String("provider", "", "Provider to configure")
+	askModelsCmd.Flags().String("provider", "", "AI provider ("+llmProviders+", or an endpoint)")
+	askCmd.AddCommand(askModelsCmd)
+
+	askCmd.AddCommand(askEndpointsCmd)
+
+	askConfigCmd.Flags().String("provider", "", "Provider or endpoint name to configure")
 	askConfigCmd.Flags().String("api-key", "", "API key for the provider")
 	askConfigCmd.Flags().String("host", "", "Host for Ollama")
+	askConfigCmd.Flags().String("base-url", "", "Base URL of an OpenAI-compatible endpoint")
+	askConfigCmd.Flags().String("api-key-ref", "", "Secret holding the endpoint API key, as vault://name")
+	askConfigCmd.Flags().String("model", "", "Default model of the endpoint")
+	askConfigCmd.Flags().Bool("remove", false, "Remove the endpoint")
+	askConfigCmd.Flags().String("redact", "", "What is masked before it is sent: all, off, or a list of "+strings.Join(redact.Categories, ","))
+	askConfigCmd.Flags().String("chain", "", "Providers asked in turn when --provider is not given, such as ollama,nvidia,openai (off to remove)")
+	askConfigCmd.Flags().String("price", "", "Price in USD per million tokens, as input/output for --provider or model=input/output")
 	askCmd.AddCommand(askConfigCmd)
 
 	rootCmd.AddCommand(askCmd)
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rsdenck/nux/internal/chat"
+	"github.com/rsdenck/nux/internal/collectors/system"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/doctor"
+	"github.com/rsdenck/nux/internal/modules/service"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// journalContextLines is how many lines of a unit's journal are gathered,
+// before the budget shortens them
+const journalContextLines = 50
+
+// defaultContextBudget bounds the host context sent with a question, in tokens
+const defaultContextBudget = 1500
+
+// addContextFlags adds the flags that send host context with the questions
+func addContextFlags(cmd *cobra.Command) {
+	cmd.Flags().String("context", "", "Send host context with the questions ("+chat.ContextSources+")")
+	cmd.Flags().Int("context-budget", defaultContextBudget, "Maximum size of the host context, in tokens")
+}
+
+// hostContext gathers the host context asked for with --context and renders
+// it as a preamble within --context-budget. It is empty without --context.
+func hostContext(cmd *cobra.Command) (string, error) {
+	spec, _ := cmd.Flags().GetString("context")
+	budget, _ := cmd.Flags().GetInt("context-budget")
+	if spec == "" {
+		return "", nil
+	}
+	sources, err := chat.ParseSources(spec)
+	if err != nil {
+		return "", err
+	}
+
+	sections := gatherContext(sources)
+	host := strings.TrimSpace(getHostname())
+	header := fmt.Sprintf("Context gathered by nux from host %s at %s, to help answer the questions:",
+		host, time.Now().Format(time.RFC3339))
+	preamble, trimmed := chat.RenderPreamble(header, sections, budget)
+	if len(trimmed) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠ context shortened to fit %d tokens: %s\n", budget, strings.Join(trimmed, ", "))
+	}
+	return preamble, nil
+}
+
+// gatherContext collects each source. A source that fails is sent as
+// unavailable rather than failing the question.
+func gatherContext(sources []chat.ContextSource) []chat.Section {
+	executor := newExecutor()
+	profile, profileErr := detectProfile(executor)
+
+	sections := make([]chat.Section, 0, len(sources))
+	for _, src := range sources {
+		s := chat.Section{Name: src.Name()}
+		var err error
+		switch src.Kind {
+		case chat.SourceProfile:
+			if err = profileErr; err == nil {
+				s.Body = chat.FormatProfile(profile)
+			}
+		case chat.SourceDoctor:
+			if err = profileErr; err == nil {
+				var checks []ports.HealthCheck
+				if checks, err = doctor.NewUniversalDoctorManager(executor, profile).RunChecks(); err == nil {
+					s.Body = chat.FormatChecks(checks)
+				}
+			}
+		case chat.SourceMetrics:
+			var m ports.SystemMetrics
+			if m, err = sampleMetrics(); err == nil {
+				s.Body = chat.FormatMetrics(m)
+			}
+		case chat.SourceServices, chat.SourceJournal:
+			if err = profileErr; err != nil {
+				break
+			}
+			manager := service.NewUniversalServiceManager(executor, profile)
+			if src.Kind == chat.SourceServices {
+				var units []ports.ServiceUnit
+				if units, err = manager.ListServices(); err == nil {
+					s.Body = chat.FormatServices(units)
+				}
+			} else {
+				s.Body, err = manager.GetServiceLogs(src.Unit, journalContextLines)
+				s.Tail = true
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ context %s unavailable: %s\n", s.Name, err.Error())
+			s.Body = "unavailable: " + err.Error()
+		}
+		sections = append(sections, s)
+	}
+	return sections
+}
+
+// sampleMetrics reads the metrics twice, since CPU usage and network rates
+// are measured between two readings. They come from the local /proc, so they
+// cannot describe another --host.
+func sampleMetrics() (ports.SystemMetrics, error) {
+	if flagHost != "" {
+		return ports.SystemMetrics{}, fmt.Errorf("metrics are only read from the local host, not %s", flagHost)
+	}
+	monitor := system.NewSystemMonitor()
+	if _, err := monitor.GetMetrics(); err != nil {
+		return ports.SystemMetrics{}, err
+	}
+	time.Sleep(500 * time.Millisecond)
+	return monitor.GetMetrics()
+}
+
+// showContext shows on stderr the context about to be sent, leaving stdout
+// to the answer
+func showContext(preamble string) {
+	tokens := chat.EstimateTokens(ports.ChatMessage{Content: preamble})
+	fmt.Fprintf(os.Stderr, "Sending this host context (about %d tokens):\n%s\n", tokens, preamble)
+}
+
+// printContext prints the context that would be sent, for --dry-run
+func printContext(preamble string) {
+	if plainText() {
+		fmt.Print(preamble)
+		return
+	}
+	output.NewSuccess(map[string]interface{}{
+		"context": preamble,
+		"tokens":  chat.EstimateTokens(ports.ChatMessage{Content: preamble}),
+	}).WithMessage("Host context (dry run, nothing sent)").Print()
+}
+
+// withContext prepends the host context to a conversation as a system message
+func withContext(preamble string, messages []ports.ChatMessage) []ports.ChatMessage {
+	if preamble == "" {
+		return messages
+	}
+	return append([]ports.ChatMessage{{Role: ports.RoleSystem, Content: preamble}}, messages...)
+}
@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rsdenck/nux/internal/agent"
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/bash"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var askDoCmd = &cobra.Command{
+	Use:   "do <request>",
+	Short: "Turn a request into a plan of commands, run once confirmed",
+	Long: `Ask an AI provider for a plan of nux or shell commands that carries out a
+request. Every command is checked before the plan is shown: shell operators,
+quotes and substitutions are refused, and so are destructive patterns such as
+deleting system directories, formatting disks, taking the host down or
+stopping sshd. A plan with a refused command does not run.
+
+The plan is shown on stderr with its explanation and runs only once
+confirmed on the terminal, or with --yes; commands run one by one through
+bash, elevated when they need root, and the plan stops at the first that
+fails. With --dry-run the commands are recorded instead of run.
+
+The request, the plan and the output of every command are saved to
+~/.nux/transcripts/<id>.json.`,
+	Example: `  nux ask do "free up space in /var"
+  nux ask do "restart the services that failed" --context services
+  nux ask do "clean the package cache" --host web01 --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		request := strings.Join(args, " ")
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		dir, err := agent.TranscriptDir()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+			return
+		}
+		preamble, err := hostContext(cmd)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
+		}
+		p, save, err := openLLMProvider(provider)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		defer save()
+
+		messages := withContext(preamble, []ports.ChatMessage{{Role: ports.RoleUser, Content: request}})
+		messages = append([]ports.ChatMessage{{Role: ports.RoleSystem, Content: agent.PlanPrompt}}, messages...)
+		if preamble != "" {
+			showContext(preamble)
+		}
+		resp, err := p.Chat(commandContext(), ports.ChatRequest{Model: model, Messages: messages}, nil)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+			return
+		}
+
+		t := agent.NewTranscript(dir, request)
+		t.Host, t.Provider, t.Model, t.DryRun = flagHost, resp.Provider, resp.Model, flagDryRun
+		plan, err := agent.ParsePlan(resp.Content)
+		if err != nil {
+			t.SetPlan(resp.Content, nil)
+			saveTranscript(t)
+			output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+			return
+		}
+		valid := plan.Validate()
+		t.SetPlan(resp.Content, plan)
+		showPlan(plan)
+
+		switch {
+		case len(plan.Steps) == 0:
+			saveTranscript(t)
+			output.NewSuccess(map[string]interface{}{
+				"request":     request,
+				"explanation": plan.Explanation,
+				"transcript":  t.ID,
+			}).WithMessage("Nothing to run").Print()
+			return
+		case !valid:
+			saveTranscript(t)
+			output.NewError("the plan holds commands nux refuses to run", nuxerrors.ErrCodeForbidden).
+				WithHint("ask again with a narrower request, or run the commands you trust yourself").Print()
+			return
+		case !flagDryRun && !yes:
+			if !isTerminal(os.Stdin) {
+				saveTranscript(t)
+				output.NewError("the plan needs confirmation and there is no terminal", nuxerrors.ErrCodeCanceled).
+					WithHint("run it again with --yes to accept the plan").Print()
+				return
+			}
+			if !confirm(fmt.Sprintf("Run these %d commands on %s?", len(plan.Steps), targetName())) {
+				saveTranscript(t)
+				output.NewError("plan not confirmed, nothing was run", nuxerrors.ErrCodeCanceled).Print()
+				return
+			}
+		}
+
+		t.Confirmed = true
+		failed := runPlan(t)
+		saveTranscript(t)
+		if failed != nil {
+			output.NewError(fmt.Sprintf("step %d failed: %s", failed.index, failed.err.Error()), nuxerrors.ErrCodeCommandFailed).
+				WithCause(failed.err).WithHint("the steps after it were not run, the transcript is " + t.Path()).Print()
+			return
+		}
+
+		items := make([]map[string]interface{}, 0, len(t.Steps))
+		for i, s := range t.Steps {
+			items = append(items, map[string]interface{}{
+				"step":     i + 1,
+				"command":  s.Command,
+				"status":   actionStatus("executed"),
+				"duration": (time.Duration(s.Duration) * time.Millisecond).String(),
+			})
+		}
+		output.NewList(items, len(items)).WithColumns("step", "command", "status", "duration").
+			WithMessage(fmt.Sprintf("Plan %s", t.ID)).Print()
+	},
+}
+
+// showPlan shows a plan on stderr, leaving stdout to the result
+func showPlan(plan *agent.Plan) {
+	if plan.Explanation != "" {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plan.Explanation)
+	}
+	for i, s := range plan.Steps {
+		fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, s.Command)
+		if s.Explanation != "" {
+			fmt.Fprintf(os.Stderr, "     %s\n", s.Explanation)
+		}
+		if s.Rejected != "" {
+			fmt.Fprintf(os.Stderr, "     ✖ refused: %s\n", s.Rejected)
+		}
+	}
+	if len(plan.Steps) > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// stepFailure is the first step of a plan that failed
+type stepFailure struct {
+	index int
+	err   error
+}
+
+// runPlan runs the steps of a confirmed plan in order, recording their
+// output, and stops at the first that fails
+func runPlan(t *agent.Transcript) *stepFailure {
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
+	if err != nil {
+		return &stepFailure{index: 1, err: err}
+	}
+	self, _ := os.Executable()
+
+	for i := range t.Steps {
+		step := &t.Steps[i]
+		fields := strings.Fields(step.Command)
+		command := step.Command
+		// nux commands run with this binary, which may not be on the PATH
+		if fields[0] == "nux" && flagHost == "" && self != "" {
+			command = adapter.ShellJoin(self, fields[1:]...)
+		}
+		ctx := commandContext()
+		switch {
+		case adapter.NeedsRoot(fields[0], fields[1:]...):
+			ctx = adapter.WithPrivileged(ctx)
+		case adapter.IsReadOnly(fields[0], fields[1:]...):
+			ctx = adapter.WithReadOnly(ctx)
+		}
+
+		fmt.Fprintf(os.Stderr, "▶ [%d/%d] %s\n", i+1, len(t.Steps), step.Command)
+		start := time.Now()
+		out, err := bash.NewUniversalBashManager(executor, profile).WithContext(ctx).RunCommand(command)
+		step.Ran = true
+		step.Output = out
+		step.Duration = time.Since(start).Milliseconds()
+		for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+			if line != "" {
+				fmt.Fprintf(os.Stderr, "  %s\n", line)
+			}
+		}
+		if err != nil {
+			step.Error = err.Error()
+			return &stepFailure{index: i + 1, err: err}
+		}
+	}
+	return nil
+}
+
+// saveTranscript saves a transcript, only warning when it cannot be written
+// so the outcome of the plan is still reported
+func saveTranscript(t *agent.Transcript) {
+	if err := t.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ %s\n", err.Error())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Transcript saved to %s\n", t.Path())
+}
+
+func init() {
+	askDoCmd.Flags().String("provider", "", "AI provider ("+llmProviders+", or an endpoint)")
+	askDoCmd.Flags().String("model", "", "Model name")
+	askDoCmd.Flags().BoolP("yes", "y", false, "Run the plan without asking for confirmation, once it passed validation")
+	addContextFlags(askDoCmd)
+	askCmd.AddCommand(askDoCmd)
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/llm"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/rag"
+	"github.com/spf13/cobra"
+)
+
+// defaultRAGPassages is how many passages --rag sends with a question
+const defaultRAGPassages = 5
+
+var askIndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Index the nux docs and skill catalog for --rag",
+	Long: `Split the Markdown files of commands/, skills/ and docs/ (or of the --dir
+given) into passages, embed them with the local Ollama and store them in
+~/.nux/rag/index.json. The directories are read from the share/nux directory
+next to the binary, else /usr/share/nux, else the working directory. 'nux ask query --rag' then sends the passages closest
+to a question with it, and the answer cites them.
+
+Only files that changed since the last run are embedded again, and files
+gone are dropped; --rebuild starts over. Nothing leaves the Ollama host.`,
+	Example: `  nux ask index
+  nux ask index --dir ./docs --dir ./skills
+  nux ask index --embed-model mxbai-embed-large --rebuild`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dirs, _ := cmd.Flags().GetStringArray("dir")
+		model, _ := cmd.Flags().GetString("embed-model")
+		rebuild, _ := cmd.Flags().GetBool("rebuild")
+		if len(dirs) == 0 {
+			dirs = rag.DefaultDirs()
+		}
+
+		path, err := rag.Path()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		files, err := rag.Collect(dirs)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeNotFound).WithCause(err).Print()
+			return
+		}
+		e, err := openEmbedder()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+
+		ix := &rag.Index{}
+		if !rebuild {
+			if loaded, err := rag.Load(path); err == nil {
+				ix = loaded
+			} else if nuxerrors.CodeOf(err) != nuxerrors.ErrCodeNotFound {
+				fmt.Fprintf(os.Stderr, "⚠ %s, rebuilding it\n", err.Error())
+			}
+		}
+
+		handler, done := streamHandler()
+		start := time.Now()
+		embedded, removed, err := ix.Update(commandContext(), e, model, files, func(n, total int) {
+			handler(adapter.Event{Type: adapter.EventProgress, Current: n, Total: total, Percent: float64(n) * 100 / float64(total)})
+		})
+		done()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).
+				WithHint(fmt.Sprintf("pull the embedding model with 'ollama pull %s'", model)).Print()
+			return
+		}
+		if err := ix.Save(path); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+
+		output.NewSuccess(map[string]interface{}{
+			"index":    path,
+			"model":    ix.Model,
+			"files":    len(ix.Files),
+			"passages": len(ix.Chunks),
+			"embedded": embedded,
+			"removed":  removed,
+			"duration": time.Since(start).Round(time.Millisecond).String(),
+		}).WithMessage("Documentation index").Print()
+	},
+}
+
+// openEmbedder returns the Ollama server configured in the vault, which
+// embeds the passages and the questions
+func openEmbedder() (ports.Embedder, error) {
+	v, err := llmVault("ollama")
+	if err != nil {
+		return nil, err
+	}
+	p, err := newLLMProvider("ollama", v)
+	if err != nil {
+		return nil, err
+	}
+	return ports.AsEmbedder(p)
+}
+
+// retrievePassages returns the passages of the index closest to question
+func retrievePassages(question string, k int) ([]rag.Hit, error) {
+	path, err := rag.Path()
+	if err != nil {
+		return nil, err
+	}
+	ix, err := rag.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := openEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	return ix.Search(commandContext(), e, question, k)
+}
+
+// printCitedAnswer sends a conversation to p and prints the answer followed
+// by the passages it may cite, numbered as in the question
+func printCitedAnswer(p ports.LLMProvider, req ports.ChatRequest, hits []rag.Hit) {
+	resp, shown, err := streamAnswer(commandContext(), p, req)
+	if err != nil {
+		output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+		return
+	}
+	sources := make([]map[string]interface{}, 0, len(hits))
+	for i, h := range hits {
+		sources = append(sources, map[string]interface{}{
+			"n":       i + 1,
+			"source":  h.Source,
+			"heading": h.Heading,
+			"score":   fmt.Sprintf("%.3f", h.Score),
+		})
+	}
+	if !shown {
+		printResponse(resp, map[string]interface{}{"sources": sources})
+		return
+	}
+	fmt.Println("\nSources:")
+	for i, h := range hits {
+		fmt.Printf("  [%d] %s\n", i+1, h.Cite())
+	}
+}
+
+func init() {
+	askIndexCmd.Flags().StringArray("dir", nil, "Directory of Markdown files to index (default: commands, skills and docs; can repeat)")
+	askIndexCmd.Flags().String("embed-model", llm.DefaultEmbedModel, "Ollama model the passages are embedded with")
+	askIndexCmd.Flags().Bool("rebuild", false, "Embed every file again, discarding the index")
+	askCmd.AddCommand(askIndexCmd)
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/usage"
+	"github.com/rsdenck/nux/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var askUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Sum up the tokens, latency and cost of the AI requests",
+	Long: `Sum up the requests made to AI providers, recorded in ~/.nux/usage.jsonl,
+by provider and model: how many were answered or failed, the tokens they
+took, the average time to a whole answer and what they cost.
+
+Tokens are those the provider counted, or estimated from the length of the
+text when it does not say, as the estimated column tells. Costs use the
+prices set with 'nux ask config --price', then the list prices of common
+OpenAI and Claude models; Ollama is free, and models without a price show
+"-".`,
+	Example: `  nux ask usage
+  nux ask usage --since 7d
+  nux ask usage --since 2026-10-01 --provider openai --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		provider, _ := cmd.Flags().GetString("provider")
+
+		var since time.Time
+		if sinceFlag != "" {
+			var err error
+			if since, err = parseSince(sinceFlag, time.Now()); err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).Print()
+				return
+			}
+		}
+		path, err := usage.Path()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		records, err := usage.Read(path, since)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		if provider != "" {
+			provider = canonicalProvider(provider)
+			kept := records[:0]
+			for _, r := range records {
+				if r.Provider == provider {
+					kept = append(kept, r)
+				}
+			}
+			records = kept
+		}
+
+		// Prices are optional, a vault that cannot be opened only loses them
+		prices := map[string]string{}
+		if v, err := vault.Load(); err == nil {
+			prices = v.GetPrices()
+		}
+		totals := usage.Summarize(records, func(provider, model string) (usage.Price, bool) {
+			return usage.Lookup(prices, provider, model)
+		})
+
+		var cost float64
+		requests := 0
+		items := make([]map[string]interface{}, 0, len(totals))
+		for _, t := range totals {
+			cost += t.Cost
+			requests += t.Requests
+			items = append(items, map[string]interface{}{
+				"provider":          t.Provider,
+				"model":             t.Model,
+				"requests":          t.Requests,
+				"failed":            t.Failed,
+				"prompt_tokens":     t.PromptTokens,
+				"completion_tokens": t.CompletionTokens,
+				"estimated":         t.Estimated,
+				"avg_latency":       t.Latency.Round(time.Millisecond).String(),
+				"cost_usd":          costText(t.Cost, t.Priced),
+			})
+		}
+		period := "all time"
+		if !since.IsZero() {
+			period = "since " + since.Local().Format("2006-01-02 15:04")
+		}
+		output.NewList(items, len(items)).
+			WithColumns("provider", "model", "requests", "failed", "prompt_tokens", "completion_tokens", "estimated", "avg_latency", "cost_usd").
+			WithMessage(fmt.Sprintf("AI usage, %s: %d requests, $%.4f", period, requests, cost)).Print()
+	},
+}
+
+// costText shows a cost in US dollars, or "-" when the model has no price
+func costText(cost float64, priced bool) string {
+	if !priced {
+		return "-"
+	}
+	return fmt.Sprintf("%.6f", cost)
+}
+
+// parseSince reads the start of a period given as a number of days ("7d"),
+// a duration ("12h") back from now, or a date ("2026-10-01", or RFC 3339)
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q, expected days (7d), a duration (12h) or a date (2026-10-01)", s)
+}
+
+func init() {
+	askUsageCmd.Flags().String("since", "", "Only count requests since then: days (7d), a duration (12h) or a date")
+	askUsageCmd.Flags().String("provider", "", "Only count the requests to this provider")
+	askCmd.AddCommand(askUsageCmd)
+}
@@ -5,8 +5,7 @@ import (
 	"os"
 	"time"
 
-	"github.com/rsdenck/nux/internal/core/adapter"
-	"github.com/rsdenck/nux/internal/core/services"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/modules/audit"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
@@ -27,15 +26,13 @@ var auditLoginsCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		manager, err := getAuditManager()
 		if err != nil {
-			output.NewError(err.Error(), "ERR_AUDIT").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		// Default lookback 24h
 		events, err := manager.AnalyzeLogins(24 * time.Hour)
 		if err != nil {
-			output.NewError(err.Error(), "ERR_LOGINS").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		items := make([]map[string]interface{}, 0)
@@ -46,7 +43,7 @@ var auditLoginsCmd = &cobra.Command{
 				"message": e.Message,
 			})
 		}
-		output.NewList(items, len(items)).WithMessage("Login history (24h)").Print()
+		output.NewList(items, len(items)).WithColumns("user", "ip", "message").WithMessage("Login history (24h)").Print()
 	},
 }
 
@@ -56,14 +53,12 @@ var auditPrivilegedCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		manager, err := getAuditManager()
 		if err != nil {
-			output.NewError(err.Error(), "ERR_AUDIT").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		users, err := manager.CheckPrivilegedGroups()
 		if err != nil {
-			output.NewError(err.Error(), "ERR_PRIVILEGED").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		items := make([]map[string]interface{}, 0)
@@ -82,15 +77,13 @@ var auditChangesCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		manager, err := getAuditManager()
 		if err != nil {
-			output.NewError(err.Error(), "ERR_AUDIT").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		paths := []string{"/etc", "/var"}
 		changes, err := manager.AnalyzeFileChanges(paths, 24*time.Hour)
 		if err != nil {
-			output.NewError(err.Error(), "ERR_CHANGES").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		items := make([]map[string]interface{}, 0)
@@ -111,14 +104,12 @@ var auditCommandsCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		manager, err := getAuditManager()
 		if err != nil {
-			output.NewError(err.Error(), "ERR_AUDIT").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		commands, err := manager.AnalyzeSudo(24 * time.Hour)
 		if err != nil {
-			output.NewError(err.Error(), "ERR_COMMANDS").Print()
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeAudit).WithCause(err))
 		}
 
 		items := make([]map[string]interface{}, 0)
@@ -134,11 +125,10 @@ var auditCommandsCmd = &cobra.Command{
 }
 
 func getAuditManager() (*audit.UniversalAuditManager, error) {
-	executor := adapter.NewExecutor()
-	profileEngine := services.NewProfileEngine(executor)
-	profile, err := profileEngine.DetectProfile()
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to detect system profile: %w", err)
+		return nil, err
 	}
 	return audit.NewUniversalAuditManager(executor, profile), nil
 }
@@ -2,14 +2,15 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var bashExecutor core.Executor = &core.RealExecutor{}
-
 var bashCmd = &cobra.Command{
 	Use:   "bash",
 	Short: "Execute Bash commands",
@@ -23,28 +24,18 @@ var bashExecCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		command := core.SanitizeInput(args[0])
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"command": command,
-				"dry_run": true,
-				"shell":   "bash",
-			}).Print()
-			return
-		}
 
-		out, err := bashExecutor.CombinedOutput("bash", "-c", command)
+		res, err := runStream("", "bash", "-c", command)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("command failed: %s", err.Error()), "BASH_EXEC_ERROR").Print()
+			output.NewError(fmt.Sprintf("command failed: %s", err.Error()), nuxerrors.ErrCodeCommandFailed).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
-			"command": command,
-			"output":  out,
-			"status":  "executed",
+			"command":  command,
+			"duration": res.Duration.Round(time.Millisecond).String(),
+			"status":   actionStatus("executed"),
 		}).Print()
 	},
 }
@@ -56,35 +47,30 @@ var bashScriptCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		scriptFile := core.SanitizeInput(args[0])
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"script":  scriptFile,
-				"dry_run": true,
-				"command": fmt.Sprintf("bash %s", scriptFile),
-			}).Print()
+		// The script always lives on this machine, so with --host it is
+		// streamed to the remote bash instead of being referenced by path
+		script, err := os.ReadFile(scriptFile)
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to read script: %s", err.Error()), nuxerrors.ErrCodeCommandFailed).WithCause(err).Print()
 			return
 		}
-
-		out, err := bashExecutor.CombinedOutput("bash", scriptFile)
+		res, err := runStream(string(script), "bash", "-s")
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("script failed: %s", err.Error()), "BASH_SCRIPT_ERROR").Print()
+			output.NewError(fmt.Sprintf("script failed: %s", err.Error()), nuxerrors.ErrCodeCommandFailed).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
-			"script": scriptFile,
-			"output": out,
-			"status": "executed",
+			"script":   scriptFile,
+			"duration": res.Duration.Round(time.Millisecond).String(),
+			"status":   actionStatus("executed"),
 		}).Print()
 	},
 }
 
 func init() {
-	bashExecCmd.Flags().Bool("dry-run", false, "Simulate command")
-	bashScriptCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	bashCmd.AddCommand(bashExecCmd)
 	bashCmd.AddCommand(bashScriptCmd)
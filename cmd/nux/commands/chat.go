@@ -0,0 +1,411 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rsdenck/nux/internal/chat"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// maxChatLine bounds one line of input, large enough for pasted logs
+const maxChatLine = 1024 * 1024
+
+const chatHelp = `/model [name]      show or switch the model
+/provider [name]   show or switch the provider, with its default model
+/save [title]      save the session, and every exchange after it
+/clear             forget the conversation, keeping the system prompt
+/exit              leave (or Ctrl-D); Ctrl-C stops an answer`
+
+var askChatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Chat with an AI provider, keeping the conversation",
+	Long: `Chat with an AI provider. Every question is sent with the conversation so
+far, trimmed to the context window of the model by leaving out the earliest
+messages.
+
+Sessions are kept in memory until /save, which writes them to
+~/.nux/sessions/<id>.json; from then on, and in sessions resumed with
+--resume, every exchange is saved.
+
+--context gathers data from the host once, shown on stderr, and sends it
+with every question without saving it in the session.
+
+` + chatHelp,
+	Example: `  nux ask chat
+  nux ask chat --provider claude --system "Answer as a senior Linux admin"
+  nux ask chat --resume 3f2a`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		provider, _ := cmd.Flags().GetString("provider")
+		model, _ := cmd.Flags().GetString("model")
+		resume, _ := cmd.Flags().GetString("resume")
+		system, _ := cmd.Flags().GetString("system")
+
+		dir, err := chat.Dir()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+			return
+		}
+		var s *chat.Session
+		if resume != "" {
+			if s, err = chat.Load(dir, resume); err != nil {
+				output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+				return
+			}
+			// The session goes on with its model unless the provider changes
+			if provider == "" {
+				provider = s.Provider
+				if model == "" {
+					model = s.Model
+				}
+			}
+		} else if s, err = chat.New(dir, provider, model); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+			return
+		}
+
+		preamble, err := hostContext(cmd)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
+		}
+		if preamble != "" && flagDryRun {
+			printContext(preamble)
+			return
+		}
+
+		v, err := llmVault(provider)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		p, err := newLLMProvider(provider, v)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+		if system != "" {
+			s.SetSystem(system)
+		}
+
+		r := &chatREPL{
+			session:     s,
+			vault:       v,
+			interactive: isTerminal(os.Stdin),
+			saved:       resume != "",
+			preamble:    preamble,
+		}
+		if preamble != "" {
+			showContext(preamble)
+		}
+		r.use(p, model)
+		r.run()
+	},
+}
+
+// chatREPL reads questions and slash-commands until the input ends
+type chatREPL struct {
+	session  *chat.Session
+	provider ports.LLMProvider
+	model    string
+	vault    *vault.Vault
+	// preamble is the host context sent with every question, not saved
+	preamble string
+
+	interactive bool
+	// saved is set once the session is on disk, so every exchange is saved
+	saved bool
+	// usedKey is set once an API key was read, whose use is then recorded
+	usedKey bool
+	// dropped is how many messages the context window last left out
+	dropped int
+
+	interrupts chan os.Signal
+}
+
+// use makes p and model answer the next questions
+func (r *chatREPL) use(p ports.LLMProvider, model string) {
+	r.provider = p
+	r.model = model
+	r.session.Provider = p.Name()
+	r.session.Model = model
+	r.dropped = 0
+	if p.Name() != "ollama" {
+		r.usedKey = true
+	}
+}
+
+// currentModel returns the model answering, the provider default if unset
+func (r *chatREPL) currentModel() string {
+	if r.model != "" {
+		return r.model
+	}
+	return r.provider.DefaultModel()
+}
+
+func (r *chatREPL) run() {
+	// Ctrl-C stops the answer being generated rather than the chat, and
+	// SIGTERM ends the chat once the session is saved
+	r.interrupts = make(chan os.Signal, 1)
+	signal.Notify(r.interrupts, os.Interrupt)
+	defer signal.Stop(r.interrupts)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxChatLine)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	if r.interactive {
+		r.info("Chatting with %s (%s)%s. /help lists the commands.", r.provider.Name(), r.currentModel(), r.resumed())
+	}
+	for {
+		if r.interactive {
+			fmt.Fprint(os.Stderr, "› ")
+		}
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-r.interrupts:
+			fmt.Fprintln(os.Stderr)
+			r.info("Type /exit or press Ctrl-D to leave.")
+			continue
+		case <-ctx.Done():
+		}
+		if !ok {
+			if r.interactive {
+				fmt.Fprintln(os.Stderr)
+			}
+			break
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "/"):
+			if !r.command(line) {
+				r.finish()
+				return
+			}
+		default:
+			r.ask(ctx, line)
+		}
+	}
+	r.finish()
+}
+
+// resumed describes the history of a resumed session for the banner
+func (r *chatREPL) resumed() string {
+	if !r.saved {
+		return ""
+	}
+	return fmt.Sprintf(", resuming session %s with %d messages", r.session.ID, len(r.session.Messages))
+}
+
+// ask sends a question with the conversation and records the answer. A
+// question that gets no answer is forgotten.
+func (r *chatREPL) ask(ctx context.Context, question string) {
+	s := r.session
+	s.Add(ports.RoleUser, question)
+	model := r.currentModel()
+	window := chat.ContextWindow(model)
+	if r.preamble != "" {
+		window -= chat.EstimateTokens(ports.ChatMessage{Content: r.preamble})
+	}
+	messages, dropped := s.Context(window)
+	if dropped > r.dropped {
+		r.warn("%d earlier messages no longer fit the context window of %s and are left out", dropped, model)
+	}
+	r.dropped = dropped
+
+	answerCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-r.interrupts:
+			cancel()
+		case <-done:
+		}
+	}()
+	resp, shown, err := streamAnswer(answerCtx, r.provider, ports.ChatRequest{Model: r.model, Messages: withContext(r.preamble, messages)})
+	close(done)
+	interrupted := answerCtx.Err() != nil && ctx.Err() == nil
+	cancel()
+
+	if err != nil {
+		s.Messages = s.Messages[:len(s.Messages)-1]
+		if interrupted {
+			r.warn("answer interrupted")
+		} else {
+			printChatError(err)
+		}
+		return
+	}
+	s.Add(ports.RoleAssistant, resp.Content)
+	if !shown {
+		printResponse(resp, map[string]interface{}{"session": s.ID})
+	}
+	if r.saved {
+		if err := s.Save(); err != nil {
+			printChatError(err)
+		}
+	}
+}
+
+// command runs a slash-command, returning false when the chat should end
+func (r *chatREPL) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "/exit", "/quit":
+		return false
+	case "/help":
+		fmt.Fprintln(os.Stderr, chatHelp)
+	case "/model":
+		if arg != "" {
+			r.use(r.provider, arg)
+		}
+		r.info("Model: %s (context window of %d tokens)", r.currentModel(), chat.ContextWindow(r.currentModel()))
+	case "/provider":
+		if arg != "" {
+			p, err := newLLMProvider(arg, r.vault)
+			if err != nil {
+				printChatError(err)
+				return true
+			}
+			r.use(p, "")
+		}
+		r.info("Provider: %s (%s)", r.provider.Name(), r.currentModel())
+	case "/save":
+		if arg != "" {
+			r.session.Title = arg
+		}
+		if err := r.session.Save(); err != nil {
+			printChatError(err)
+			return true
+		}
+		r.saved = true
+		r.info("Saved session %s, resume it with 'nux ask chat --resume %s'", r.session.ID, r.session.ID)
+	case "/clear":
+		r.session.Clear()
+		r.dropped = 0
+		r.info("Conversation cleared")
+	default:
+		r.warn("unknown command %s, /help lists them", name)
+	}
+	return true
+}
+
+// finish saves the session and the use of the API keys
+func (r *chatREPL) finish() {
+	if r.saved {
+		if err := r.session.Save(); err != nil {
+			printChatError(err)
+		} else if r.interactive {
+			r.info("Session %s saved", r.session.ID)
+		}
+	}
+	if r.usedKey {
+		saveSecretUse(r.vault)
+	}
+}
+
+// info and warn talk to the user on stderr, leaving stdout to the answers
+func (r *chatREPL) info(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (r *chatREPL) warn(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "⚠ "+format+"\n", args...)
+}
+
+// printChatError reports a failed turn without ending the chat or setting
+// the exit status
+func printChatError(err error) {
+	fmt.Fprintf(os.Stderr, "✖ %s\n", err.Error())
+	if hint := nuxerrors.HintOf(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "  hint: %s\n", hint)
+	}
+}
+
+var askSessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List saved chat sessions",
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := chat.Dir()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+			return
+		}
+		sessions, err := chat.List(dir)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+			return
+		}
+
+		items := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			items = append(items, map[string]interface{}{
+				"id":       s.ID,
+				"title":    s.Title,
+				"provider": s.Provider,
+				"model":    s.Model,
+				"messages": len(s.Messages),
+				"updated":  s.Updated.Local().Format(time.DateTime),
+			})
+		}
+		output.NewList(items, len(items)).WithColumns("id", "updated", "provider", "model", "messages", "title").
+			WithMessage("Chat sessions").Print()
+	},
+}
+
+var askSessionsRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Delete a saved chat session",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := chat.Dir()
+		if err == nil {
+			err = chat.Delete(dir, args[0])
+		}
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeSession).WithCause(err).Print()
+			return
+		}
+		output.NewSuccess(map[string]interface{}{
+			"session": args[0],
+			"status":  "deleted",
+		}).Print()
+	},
+}
+
+func init() {
+	askChatCmd.Flags().String("provider", "", "AI provider ("+llmProviders+", or an endpoint)")
+	askChatCmd.Flags().String("model", "", "Model name (default: the provider's)")
+	askChatCmd.Flags().String("resume", "", "Resume a saved session by id")
+	askChatCmd.Flags().String("system", "", "System prompt guiding every answer")
+	addContextFlags(askChatCmd)
+	askCmd.AddCommand(askChatCmd)
+
+	askSessionsCmd.AddCommand(askSessionsRmCmd)
+	askCmd.AddCommand(askSessionsCmd)
+}
@@ -3,16 +3,14 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var containerExecutor core.Executor = &core.RealExecutor{}
-
 var containerCmd = &cobra.Command{
 	Use:   "container",
 	Short: "Container management",
@@ -29,7 +27,7 @@ func detectContainerRuntime() string {
 	}
 
 	for _, r := range runtimes {
-		if _, err := exec.LookPath(r.command); err == nil {
+		if commandAvailable(r.command) {
 			return r.name
 		}
 	}
@@ -44,16 +42,16 @@ var containerListCmd = &cobra.Command{
 		runtime := detectContainerRuntime()
 
 		if runtime == "none" {
-			output.NewError("no container runtime found (docker/podman)", "CONTAINER_RUNTIME_MISSING").Print()
+			output.NewError("no container runtime found (docker/podman)", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
 		// Try JSON output first
-		out, err := containerExecutor.CombinedOutput(runtime, "ps", "-a", "--format", "{{json .}}")
+		out, err := combinedOutput(runtime, "ps", "-a", "--format", "{{json .}}")
 
 		if err != nil {
 			// Fallback to text output
-			textOut, _ := containerExecutor.CombinedOutput(runtime, "ps", "-a")
+			textOut, _ := combinedOutput(runtime, "ps", "-a")
 			output.NewSuccess(map[string]interface{}{
 				"runtime": runtime,
 				"output":  textOut,
@@ -89,7 +87,7 @@ var containerRunCmd = &cobra.Command{
 		runtime := detectContainerRuntime()
 
 		if runtime == "none" {
-			output.NewError("no container runtime found (docker/podman)", "CONTAINER_RUNTIME_MISSING").Print()
+			output.NewError("no container runtime found (docker/podman)", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
@@ -97,7 +95,6 @@ var containerRunCmd = &cobra.Command{
 		ports, _ := cmd.Flags().GetString("ports")
 		detach, _ := cmd.Flags().GetBool("detach")
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 		cmdArgs := []string{"run"}
 		if detach {
@@ -111,27 +108,17 @@ var containerRunCmd = &cobra.Command{
 		}
 		cmdArgs = append(cmdArgs, image)
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"runtime": runtime,
-				"image":   image,
-				"dry_run": true,
-				"command": fmt.Sprintf("%s %s", runtime, strings.Join(cmdArgs, " ")),
-			}).Print()
-			return
-		}
-
-		_, err := containerExecutor.CombinedOutput(runtime, cmdArgs...)
+		_, err := newExecutor().Exec(commandContext(), runtime, cmdArgs...)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to run container: %s", err.Error()), "CONTAINER_RUN_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to run container: %s", err.Error()), nuxerrors.ErrCodeContainerStart).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"runtime": runtime,
 			"image":   image,
-			"status":  "running",
+			"status":  actionStatus("running"),
 		}).Print()
 	},
 }
@@ -145,33 +132,22 @@ var containerStopCmd = &cobra.Command{
 		runtime := detectContainerRuntime()
 
 		if runtime == "none" {
-			output.NewError("no container runtime found (docker/podman)", "CONTAINER_RUNTIME_MISSING").Print()
+			output.NewError("no container runtime found (docker/podman)", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"runtime":   runtime,
-				"container": container,
-				"dry_run":   true,
-				"command":   fmt.Sprintf("%s stop %s", runtime, container),
-			}).Print()
-			return
-		}
 
-		_, err := containerExecutor.CombinedOutput(runtime, "stop", container)
+		_, err := newExecutor().Exec(commandContext(), runtime, "stop", container)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to stop container: %s", err.Error()), "CONTAINER_STOP_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to stop container: %s", err.Error()), nuxerrors.ErrCodeContainerStop).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"runtime":   runtime,
 			"container": container,
-			"status":    "stopped",
+			"status":    actionStatus("stopped"),
 		}).Print()
 	},
 }
@@ -185,12 +161,11 @@ var containerRemoveCmd = &cobra.Command{
 		runtime := detectContainerRuntime()
 
 		if runtime == "none" {
-			output.NewError("no container runtime found (docker/podman)", "CONTAINER_RUNTIME_MISSING").Print()
+			output.NewError("no container runtime found (docker/podman)", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
 		force, _ := cmd.Flags().GetBool("force")
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 		cmdArgs := []string{"rm"}
 		if force {
@@ -198,27 +173,17 @@ var containerRemoveCmd = &cobra.Command{
 		}
 		cmdArgs = append(cmdArgs, container)
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"runtime":   runtime,
-				"container": container,
-				"dry_run":   true,
-				"command":   fmt.Sprintf("%s %s", runtime, strings.Join(cmdArgs, " ")),
-			}).Print()
-			return
-		}
-
-		_, err := containerExecutor.CombinedOutput(runtime, cmdArgs...)
+		_, err := newExecutor().Exec(commandContext(), runtime, cmdArgs...)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to remove container: %s", err.Error()), "CONTAINER_REMOVE_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to remove container: %s", err.Error()), nuxerrors.ErrCodeContainerRemove).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"runtime":   runtime,
 			"container": container,
-			"status":    "removed",
+			"status":    actionStatus("removed"),
 		}).Print()
 	},
 }
@@ -227,12 +192,9 @@ func init() {
 	containerRunCmd.Flags().String("name", "", "Container name")
 	containerRunCmd.Flags().String("ports", "", "Port mappings (e.g., 8080:80)")
 	containerRunCmd.Flags().Bool("detach", true, "Run in background")
-	containerRunCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
-	containerStopCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	containerRemoveCmd.Flags().Bool("force", false, "Force removal")
-	containerRemoveCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	containerCmd.AddCommand(containerListCmd)
 	containerCmd.AddCommand(containerRunCmd)
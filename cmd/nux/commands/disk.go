@@ -6,12 +6,11 @@ import (
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var diskExecutor core.Executor = &core.RealExecutor{}
-
 var diskCmd = &cobra.Command{
 	Use:   "disk",
 	Short: "Disk management",
@@ -22,9 +21,9 @@ var diskListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List disk devices",
 	Run: func(cmd *cobra.Command, args []string) {
-		out, err := diskExecutor.CombinedOutput("lsblk", "-J")
+		out, err := combinedOutput("lsblk", "-J")
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list disks: %s", err.Error()), "DISK_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to list disks: %s", err.Error()), nuxerrors.ErrCodeDiskRead).WithCause(err).Print()
 			return
 		}
 
@@ -32,13 +31,13 @@ var diskListCmd = &cobra.Command{
 
 		var result map[string]interface{}
 		if err := json.Unmarshal([]byte(out), &result); err != nil {
-			output.NewError("lsblk JSON parsing failed", "DISK_LIST_PARSE_ERROR").Print()
+			output.NewError("lsblk JSON parsing failed", nuxerrors.ErrCodeDiskRead).Print()
 			return
 		}
 
 		blockDevices, ok := result["blockdevices"].([]interface{})
 		if !ok {
-			output.NewError("no block devices found", "DISK_LIST_NO_DEVICES").Print()
+			output.NewError("no block devices found", nuxerrors.ErrCodeNotFound).Print()
 			return
 		}
 
@@ -88,20 +87,20 @@ var diskUsageCmd = &cobra.Command{
 		if len(args) > 0 {
 			path = core.SanitizeInput(args[0])
 			if !core.ValidatePath(path) {
-				output.NewError(fmt.Sprintf("invalid path: %s", path), "DISK_USAGE_INVALID_PATH").Print()
+				output.NewError(fmt.Sprintf("invalid path: %s", path), nuxerrors.ErrCodeInvalidInput).Print()
 				return
 			}
 		}
 
-		out, err := diskExecutor.CombinedOutput("df", "-h", path)
+		out, err := combinedOutput("df", "-h", path)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to get disk usage: %s", err.Error()), "DISK_USAGE_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to get disk usage: %s", err.Error()), nuxerrors.ErrCodeDiskRead).WithCause(err).Print()
 			return
 		}
 
 		lines := strings.Split(out, "\n")
 		if len(lines) < 2 {
-			output.NewError("no disk usage data", "DISK_USAGE_NO_DATA").Print()
+			output.NewError("no disk usage data", nuxerrors.ErrCodeNotFound).Print()
 			return
 		}
 
@@ -126,13 +125,13 @@ var diskRescanCmd = &cobra.Command{
 	Use:   "rescan",
 	Short: "Rescan SCSI bus for new disks",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := diskExecutor.RunSilent("bash", "-c", "echo '- - -' | tee /sys/class/scsi_host/host*/scan")
+		_, err := newExecutor().Exec(commandContext(), "bash", "-c", "echo '- - -' | tee /sys/class/scsi_host/host*/scan")
 		if err != nil {
-			output.NewError("failed to rescan SCSI bus", "DISK_RESCAN_ERROR").Print()
+			output.NewError("failed to rescan SCSI bus", nuxerrors.ErrCodeDiskWrite).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
-			"status":  "SCSI rescan initiated",
+			"status":  actionStatus("SCSI rescan initiated"),
 			"command": "echo '- - -' | sudo tee /sys/class/scsi_host/host*/scan",
 		}).Print()
 	},
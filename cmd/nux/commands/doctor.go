@@ -1,6 +1,11 @@
 package commands
 
 import (
+	"fmt"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/doctor"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
@@ -10,10 +15,37 @@ var doctorCmd = &cobra.Command{
 	Short: "System health check",
 	Long:  `Run system health checks (Load, Swap, Disk, TCP, etc).`,
 	Run: func(cmd *cobra.Command, args []string) {
-		output.NewSuccess(map[string]interface{}{
-			"status": "ok",
-			"checks": 4,
-		}).WithMessage("System health check completed").Print()
+		executor := newExecutor()
+		profile, err := detectProfile(executor)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
+			return
+		}
+
+		checks, err := doctor.NewUniversalDoctorManager(executor, profile).RunChecks()
+		if err != nil {
+			output.NewError(fmt.Sprintf("health check failed: %s", err.Error()), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
+			return
+		}
+
+		items := make([]map[string]interface{}, 0, len(checks))
+		worst := ports.StatusOk
+		for _, c := range checks {
+			items = append(items, map[string]interface{}{
+				"check":   c.Name,
+				"status":  string(c.Status),
+				"value":   c.Value,
+				"message": c.Message,
+			})
+			if c.Status == ports.StatusCritical || (c.Status == ports.StatusWarning && worst == ports.StatusOk) {
+				worst = c.Status
+			}
+		}
+
+		output.NewList(items, len(items)).
+			WithColumns("check", "status", "value", "message").
+			WithMessage(fmt.Sprintf("System health check completed: %s", worst)).
+			Print()
 	},
 }
 
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/core/domain"
+	"github.com/rsdenck/nux/internal/core/logger"
+	"github.com/rsdenck/nux/internal/core/services"
+	"github.com/rsdenck/nux/internal/journal"
+	"github.com/rsdenck/nux/internal/output"
+)
+
+// dryRunExecutor collects the plan for the whole invocation when --dry-run is set
+var dryRunExecutor *adapter.DryRunExecutor
+
+// recorder captures every command of the invocation when --record is set
+var recorder *adapter.RecordingExecutor
+
+// broker elevates the commands that need root for the whole invocation, so
+// the password is asked for at most once
+var broker *adapter.PrivilegeBroker
+
+// sessionCtx is cancelled on Ctrl-C/SIGTERM so running commands are killed
+var sessionCtx = context.Background()
+
+// commandContext returns the context every command execution should use
+func commandContext() context.Context {
+	return sessionCtx
+}
+
+// commandTimeout bounds every executed command. It is only set when
+// --timeout is given, so scripts, plugins, transfers and upgrades keep the
+// limits their modules set.
+var commandTimeout time.Duration
+
+// newExecutor returns the executor every module should use. Each command is
+// bounded by --timeout when it is given and elevated through --elevate when it needs root;
+// with --dry-run, mutating commands are recorded into the session plan
+// instead of being run.
+func newExecutor() adapter.Executor {
+	base := adapter.NewSessionExecutor(sessionCtx, commandTimeout)
+	if flagHost != "" {
+		remote := adapter.NewSSHExecutor(base, flagHost)
+		remote.ControlPath = sshControlPath()
+		base = remote
+	}
+	if broker == nil {
+		broker = adapter.NewPrivilegeBroker(base)
+		broker.Tool = flagElevate
+		broker.Interactive = isTerminal(os.Stdin)
+	}
+	base = broker
+	if flagRecord != "" {
+		if recorder == nil {
+			recorder = adapter.NewRecordingExecutor(base)
+		}
+		base = recorder
+	}
+	if !flagDryRun {
+		return base
+	}
+	if dryRunExecutor == nil {
+		dryRunExecutor = adapter.NewDryRunExecutor(base)
+	}
+	return dryRunExecutor
+}
+
+// validateElevate checks the --elevate flag
+func validateElevate() error {
+	switch flagElevate {
+	case adapter.ElevateAuto, adapter.ElevateSudo, adapter.ElevateDoas, adapter.ElevatePkexec, adapter.ElevateNone:
+		return nil
+	}
+	return fmt.Errorf("invalid --elevate %q: use auto, sudo, doas, pkexec or none", flagElevate)
+}
+
+// newJournal returns the journal mutating commands record into. With
+// --dry-run nothing changes, so nothing is journaled.
+func newJournal() *journal.Journal {
+	if flagDryRun {
+		return nil
+	}
+	j, err := journal.Open(flagHost)
+	if err != nil {
+		logger.Warn("journal disabled: %v", err)
+		return nil
+	}
+	return j
+}
+
+// combinedOutput runs a command and returns stdout and stderr joined. The
+// output is returned even on failure so callers can show what went wrong.
+func combinedOutput(command string, args ...string) (string, error) {
+	res, err := newExecutor().Exec(commandContext(), command, args...)
+	return res.Combined(), err
+}
+
+// commandAvailable reports whether command is installed on the target host
+func commandAvailable(command string) bool {
+	ctx := adapter.WithReadOnly(commandContext())
+	_, err := newExecutor().Exec(ctx, "sh", "-c", `command -v "$1" >/dev/null`, "sh", command)
+	return err == nil
+}
+
+// detectProfile runs the ProfileEngine through the given executor
+func detectProfile(executor adapter.Executor) (*domain.SystemProfile, error) {
+	profile, err := services.NewProfileEngine(executor).DetectProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect system profile: %w", err)
+	}
+	if recorder != nil {
+		recorder.SetProfile(profile)
+	}
+	return profile, nil
+}
+
+// actionStatus returns the status reported for a mutating action, which is
+// "planned" while --dry-run records instead of executing.
+func actionStatus(done string) string {
+	if flagDryRun {
+		return "planned"
+	}
+	return done
+}
+
+// saveRecording appends the commands captured with --record to the fixture
+// file, so several invocations can build one transcript per host
+func saveRecording() {
+	if recorder == nil {
+		return
+	}
+	captured := recorder.Fixture()
+
+	fixture := &adapter.Fixture{Name: flagHost}
+	if existing, err := adapter.LoadFixture(flagRecord); err == nil {
+		fixture = existing
+	}
+	if captured.Profile != nil {
+		fixture.Profile = captured.Profile
+	}
+	fixture.Interactions = append(fixture.Interactions, captured.Interactions...)
+
+	if err := fixture.Save(flagRecord); err != nil {
+		output.PrintWarningMessage(fmt.Sprintf("failed to save recording: %s", err.Error()))
+	}
+}
+
+// printDryRunPlan prints the commands recorded during a --dry-run invocation
+func printDryRunPlan() {
+	if dryRunExecutor == nil {
+		return
+	}
+	plan := dryRunExecutor.Plan()
+	target := ""
+	if flagHost != "" {
+		target = " on " + flagHost
+	}
+
+	if flagJSON {
+		output.NewList(plan, len(plan)).WithMessage("Dry-run plan" + target).Print()
+		return
+	}
+
+	if len(plan) == 0 {
+		output.PrintInfoMessage("Dry-run: no changes would be made" + target)
+		return
+	}
+
+	rows := make([][]string, 0, len(plan))
+	for i, step := range plan {
+		stdin := "-"
+		if step.Stdin != "" {
+			stdin = fmt.Sprintf("%d bytes", len(step.Stdin))
+		}
+		rows = append(rows, []string{fmt.Sprintf("%d", i+1), step.String(), stdin})
+	}
+
+	fmt.Printf("\nDry-run plan%s (nothing was executed)\n", target)
+	output.PrintTable([]string{"STEP", "COMMAND", "STDIN"}, rows)
+}
@@ -0,0 +1,345 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/inventory"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// fanOutFlags are consumed by the parent process and never forwarded to the
+// per-host invocations. The list view, query and report flags apply to the
+// merged output.
+var fanOutFlags = map[string]bool{
+	"--on": true, "--parallel": true, "--host-timeout": true,
+	"--columns": true, "--sort-by": true, "--filter": true,
+	"--query": true, "--template": true, "--format": true, "--out": true,
+}
+
+// hostResult is the outcome of one per-host invocation of a --on fan-out
+type hostResult struct {
+	Host     string         `json:"-"`
+	Status   string         `json:"status"`
+	Duration string         `json:"duration"`
+	Error    string         `json:"error,omitempty"`
+	Output   *output.Output `json:"output,omitempty"`
+	Plan     *output.Output `json:"plan,omitempty"`
+	// Lines holds the output streamed by long-running commands
+	Lines []string `json:"lines,omitempty"`
+}
+
+// runFanOut runs the current invocation once per inventory host matched by
+// --on, through "nux --host <profile> --json ...", and prints the aggregated
+// result. It returns the process exit code.
+func runFanOut(cmd *cobra.Command) int {
+	if err := validateFanOut(cmd); err != nil {
+		output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).Print()
+		return output.ExitStatus()
+	}
+
+	inv, err := inventory.Load()
+	if err != nil {
+		output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+		return output.ExitStatus()
+	}
+	hosts, err := inv.Resolve(flagOn)
+	if err != nil {
+		output.NewError(fmt.Sprintf("%s (see 'nux inventory list')", err.Error()), nuxerrors.ErrCodeNotFound).WithCause(err).Print()
+		return output.ExitStatus()
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		output.NewError(fmt.Sprintf("failed to locate nux binary: %s", err.Error()), nuxerrors.ErrCodeInternal).WithCause(err).Print()
+		return output.ExitStatus()
+	}
+	args := stripFanOutFlags(os.Args[1:])
+
+	parallel := flagParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	executor := adapter.NewSessionExecutor(sessionCtx, time.Duration(flagHostTimeout)*time.Second)
+
+	results := make([]hostResult, len(hosts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *inventory.Host) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = runOnHost(executor, self, args, h)
+		}(i, h)
+	}
+	wg.Wait()
+
+	printFanOut(results)
+	return fanOutExitCode(results)
+}
+
+// fanOutExitCode is the exit code the failed hosts agree on, or ExitGeneral
+// when they failed for different reasons
+func fanOutExitCode(results []hostResult) int {
+	code := nuxerrors.ExitOK
+	for _, r := range results {
+		if r.Status == "success" {
+			continue
+		}
+		hostCode := nuxerrors.ExitGeneral
+		if r.Output != nil && r.Output.ExitCode != 0 {
+			hostCode = r.Output.ExitCode
+		}
+		if code != nuxerrors.ExitOK && code != hostCode {
+			return nuxerrors.ExitGeneral
+		}
+		code = hostCode
+	}
+	return code
+}
+
+func validateFanOut(cmd *cobra.Command) error {
+	if flagHost != "" {
+		return fmt.Errorf("--on and --host cannot be used together")
+	}
+	top := topLevelCommand(cmd)
+	if !remoteCapableCommands[top.Name()] {
+		return fmt.Errorf("--on is not supported by 'nux %s'", top.Name())
+	}
+	return nil
+}
+
+func runOnHost(executor adapter.Executor, self string, args []string, h *inventory.Host) hostResult {
+	result := hostResult{Host: h.Name, Status: "error"}
+
+	hostArgs, err := renderHostArgs(args, h)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = "0s"
+		return result
+	}
+
+	full := append([]string{"--host", h.SSHProfile(), "--json"}, hostArgs...)
+	res, err := executor.Exec(commandContext(), self, full...)
+	result.Duration = res.Duration.Round(time.Millisecond).String()
+
+	// The child prints the streamed events of long commands, then the
+	// command envelope, then the dry-run plan if any
+	dec := json.NewDecoder(strings.NewReader(res.Stdout))
+	var docs []*output.Output
+	for {
+		var raw json.RawMessage
+		if decErr := dec.Decode(&raw); decErr != nil {
+			if decErr != io.EOF && len(docs) == 0 {
+				result.Error = strings.TrimSpace(res.Combined())
+			}
+			break
+		}
+		var ev adapter.Event
+		if json.Unmarshal(raw, &ev) == nil && ev.Type != "" {
+			if ev.Type == adapter.EventLine {
+				result.Lines = append(result.Lines, ev.Line)
+			}
+			continue
+		}
+		var o output.Output
+		if err := json.Unmarshal(raw, &o); err != nil {
+			continue
+		}
+		docs = append(docs, &o)
+	}
+
+	if len(docs) > 0 {
+		result.Output = docs[0]
+		if len(docs) > 1 {
+			result.Plan = docs[1]
+		}
+		if docs[0].Status == "error" {
+			result.Error = docs[0].Error
+		} else {
+			result.Status = "success"
+		}
+	}
+
+	if result.Error == "" && result.Status == "error" && err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// hostVarPattern matches a host variable in a fan-out argument, %{role}. Go
+// templates are left alone, they belong to the command being fanned out, as
+// in docker ps --format '{{.Names}}'.
+var hostVarPattern = regexp.MustCompile(`%\{([A-Za-z0-9_.-]+)\}`)
+
+// renderHostArgs replaces %{var} in the arguments with the host variables
+// plus %{host}
+func renderHostArgs(args []string, h *inventory.Host) ([]string, error) {
+	data := map[string]string{"host": h.Name}
+	for k, v := range h.Vars {
+		data[k] = v
+	}
+
+	out := make([]string, len(args))
+	for i, a := range args {
+		var missing string
+		out[i] = hostVarPattern.ReplaceAllStringFunc(a, func(m string) string {
+			name := hostVarPattern.FindStringSubmatch(m)[1]
+			v, ok := data[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("host %s has no variable %q used in %q", h.Name, missing, a)
+		}
+	}
+	return out, nil
+}
+
+// stripFanOutFlags removes the fan-out and output format flags from args
+func stripFanOutFlags(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			out = append(out, args[i:]...)
+			break
+		}
+		name := a
+		if eq := strings.Index(a, "="); eq > 0 {
+			name = a[:eq]
+		}
+		switch {
+		case fanOutFlags[name]:
+			if name == a && i+1 < len(args) {
+				i++ // skip the separate value
+			}
+		case name == "--json" || name == "--yaml" || name == "--no-headers":
+			// children always answer in JSON
+		default:
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func printFanOut(results []hostResult) {
+	failed := 0
+	for _, r := range results {
+		if r.Status != "success" {
+			failed++
+		}
+	}
+	message := fmt.Sprintf("%d/%d host(s) succeeded", len(results)-failed, len(results))
+
+	if flagJSON {
+		byHost := make(map[string]hostResult, len(results))
+		for _, r := range results {
+			byHost[r.Host] = r
+		}
+		output.NewSuccess(byHost).WithMessage(message).Print()
+		return
+	}
+
+	if items, ok := mergeHostItems(results); ok {
+		output.NewList(items, len(items)).WithColumns("host").WithMessage(message).Print()
+	} else {
+		rows := make([]map[string]interface{}, 0, len(results))
+		for _, r := range results {
+			rows = append(rows, map[string]interface{}{
+				"host":     r.Host,
+				"status":   r.Status,
+				"duration": r.Duration,
+				"result":   summarizeHostResult(r),
+			})
+		}
+		output.NewList(rows, len(rows)).WithColumns("host", "status", "duration", "result").WithMessage(message).Print()
+	}
+
+	for _, r := range results {
+		if r.Status != "success" {
+			output.PrintErrorMessage(fmt.Sprintf("%s: %s", r.Host, r.Error))
+		}
+	}
+}
+
+// mergeHostItems flattens list outputs from every host into one table with a
+// leading host column. It reports false when the results are not lists.
+func mergeHostItems(results []hostResult) ([]map[string]interface{}, bool) {
+	var merged []map[string]interface{}
+	for _, r := range results {
+		if r.Status != "success" {
+			continue
+		}
+		list, ok := r.Output.Items.([]interface{})
+		if !ok {
+			return nil, false
+		}
+		for _, it := range list {
+			row, ok := it.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			row["host"] = r.Host
+			merged = append(merged, row)
+		}
+	}
+	return merged, merged != nil
+}
+
+func summarizeHostResult(r hostResult) string {
+	if r.Status != "success" {
+		return firstLine(r.Error)
+	}
+	if r.Plan != nil {
+		if steps, ok := r.Plan.Items.([]interface{}); ok {
+			return fmt.Sprintf("%d planned step(s)", len(steps))
+		}
+	}
+	if r.Output.Message != "" {
+		return r.Output.Message
+	}
+	if len(r.Lines) > 0 {
+		return firstLine(strings.Join(r.Lines, "\n"))
+	}
+
+	data, ok := r.Output.Data.(map[string]interface{})
+	if !ok {
+		return firstLine(fmt.Sprintf("%v", r.Output.Data))
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, data[k]))
+	}
+	return firstLine(strings.Join(pairs, " "))
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + " …"
+	}
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/inventory"
+	"github.com/rsdenck/nux/internal/output"
+)
+
+func TestStripFanOutFlags(t *testing.T) {
+	args := []string{"--on", "web", "--parallel=5", "--yaml", "service", "status", "--host-timeout", "30", "--sort-by", "host", "--no-headers", "--query=.items", "nginx", "--", "--on"}
+	want := []string{"service", "status", "nginx", "--", "--on"}
+	if got := stripFanOutFlags(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("stripFanOutFlags() = %v, want %v", got, want)
+	}
+}
+
+func TestRenderHostArgs(t *testing.T) {
+	h := &inventory.Host{Name: "web01", Vars: map[string]string{"role": "frontend"}}
+
+	got, err := renderHostArgs([]string{"bash", "exec", "echo %{role} on %{host}"}, h)
+	if err != nil {
+		t.Fatalf("renderHostArgs failed: %v", err)
+	}
+	if got[2] != "echo frontend on web01" {
+		t.Errorf("unexpected rendered arg: %q", got[2])
+	}
+
+	if _, err := renderHostArgs([]string{"%{missing}"}, h); err == nil {
+		t.Error("expected error for missing host variable")
+	}
+
+	// Templates of the command itself are not host variables
+	got, err = renderHostArgs([]string{"bash", "exec", "docker ps --format '{{.Names}}'"}, h)
+	if err != nil || got[2] != "docker ps --format '{{.Names}}'" {
+		t.Errorf("expected the template to be left alone, got %q, %v", got, err)
+	}
+}
+
+func TestRunOnHostSkipsStreamedEvents(t *testing.T) {
+	f := &adapter.Fixture{Interactions: []adapter.Interaction{{
+		Command: "nux",
+		Args:    []string{"--host", "web01", "--json", "bash", "exec", "uptime"},
+		Stdout: `{"type":"line","stream":"stdout","line":"10:00 up 3 days"}
+{"type":"progress","percent":50}
+{"status":"success","data":{"command":"uptime","status":"executed"}}`,
+	}}}
+
+	r := runOnHost(adapter.NewReplayExecutor(f), "nux", []string{"bash", "exec", "uptime"}, &inventory.Host{Name: "web01"})
+	if r.Status != "success" || r.Output == nil {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	if !reflect.DeepEqual(r.Lines, []string{"10:00 up 3 days"}) {
+		t.Errorf("unexpected lines: %v", r.Lines)
+	}
+	if got := summarizeHostResult(r); got != "10:00 up 3 days" {
+		t.Errorf("unexpected summary: %q", got)
+	}
+}
+
+func TestFanOutExitCode(t *testing.T) {
+	notFound := &output.Output{ExitCode: nuxerrors.ExitNotFound}
+	tests := []struct {
+		results []hostResult
+		want    int
+	}{
+		{[]hostResult{{Status: "success"}}, nuxerrors.ExitOK},
+		{[]hostResult{{Status: "success"}, {Status: "error", Output: notFound}, {Status: "error", Output: notFound}}, nuxerrors.ExitNotFound},
+		{[]hostResult{{Status: "error", Output: notFound}, {Status: "error"}}, nuxerrors.ExitGeneral},
+	}
+	for i, tt := range tests {
+		if got := fanOutExitCode(tt.results); got != tt.want {
+			t.Errorf("case %d: fanOutExitCode() = %d, want %d", i, got, tt.want)
+		}
+	}
+}
@@ -2,16 +2,13 @@ package commands
 
 import (
 	"fmt"
-	"os/exec"
-	"strings"
 
-	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/modules/firewall"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var firewallExecutor core.Executor = &core.RealExecutor{}
-
 var firewallCmd = &cobra.Command{
 	Use:   "firewall",
 	Short: "Firewall management",
@@ -30,7 +27,7 @@ func detectFirewall() string {
 	}
 
 	for _, t := range tools {
-		if _, err := exec.LookPath(t.command); err == nil {
+		if commandAvailable(t.command) {
 			return t.name
 		}
 	}
@@ -61,14 +58,14 @@ var firewallListCmd = &cobra.Command{
 			command = "ufw"
 			cmdArgs = []string{"status", "verbose"}
 		default:
-			output.NewError("no supported firewall found", "FIREWALL_UNSUPPORTED").Print()
+			output.NewError("no supported firewall found", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		out, err := firewallExecutor.CombinedOutput(command, cmdArgs...)
+		out, err := combinedOutput(command, cmdArgs...)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list rules: %s", err.Error()), "FIREWALL_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to list rules: %s", err.Error()), nuxerrors.ErrCodeFirewall).WithCause(err).Print()
 			return
 		}
 
@@ -88,52 +85,33 @@ var firewallAddCmd = &cobra.Command{
 		action, _ := cmd.Flags().GetString("action")
 
 		if port == "" {
-			output.NewError("port is required", "FIREWALL_PORT_REQUIRED").Print()
+			output.NewError("port is required", nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
-		fw := detectFirewall()
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		var command string
-		var cmdArgs []string
-
-		switch fw {
-		case "nft":
-			command = "nft"
-			cmdArgs = []string{"add", "rule", "inet", "filter", "input", "tcp", "dport", port, action}
-		case "iptables":
-			command = "iptables"
-			if action == "allow" {
-				action = "ACCEPT"
-			} else {
-				action = "DROP"
-			}
-			cmdArgs = []string{"-A", "INPUT", "-p", protocol, "--dport", port, "-j", action}
-		case "firewalld":
-			command = "firewall-cmd"
-			cmdArgs = []string{"--add-port=" + port + "/" + protocol}
-		case "ufw":
-			command = "ufw"
-			cmdArgs = []string{action, port + "/" + protocol}
-		default:
-			output.NewError("no supported firewall found", "FIREWALL_UNSUPPORTED").Print()
+		manager, err := newFirewallManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
 			return
 		}
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"firewall": fw,
-				"dry_run":  true,
-				"command":  fmt.Sprintf("%s %s", command, strings.Join(cmdArgs, " ")),
-			}).Print()
+		fw, err := manager.DetectFirewall()
+		if err != nil {
+			output.NewError("no supported firewall found", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		_, err := firewallExecutor.CombinedOutput(command, cmdArgs...)
+		switch action {
+		case "allow":
+			err = manager.AllowPort(port, protocol)
+		case "deny":
+			err = manager.BlockPort(port, protocol)
+		default:
+			output.NewError(fmt.Sprintf("invalid action %q (allow/deny)", action), nuxerrors.ErrCodeInvalidInput).Print()
+			return
+		}
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to add rule: %s", err.Error()), "FIREWALL_ADD_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to add rule: %s", err.Error()), nuxerrors.ErrCodeFirewall).WithCause(err).Print()
 			return
 		}
 
@@ -142,7 +120,7 @@ var firewallAddCmd = &cobra.Command{
 			"port":     port,
 			"protocol": protocol,
 			"action":   action,
-			"status":   "added",
+			"status":   actionStatus("added"),
 		}).Print()
 	},
 }
@@ -155,12 +133,11 @@ var firewallRemoveCmd = &cobra.Command{
 		protocol, _ := cmd.Flags().GetString("protocol")
 
 		if port == "" {
-			output.NewError("port is required", "FIREWALL_PORT_REQUIRED").Print()
+			output.NewError("port is required", nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
 		fw := detectFirewall()
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 		var command string
 		var cmdArgs []string
@@ -176,23 +153,14 @@ var firewallRemoveCmd = &cobra.Command{
 			command = "ufw"
 			cmdArgs = []string{"delete", "allow", port + "/" + protocol}
 		default:
-			output.NewError("firewall does not support rule removal or is unsupported", "FIREWALL_REMOVE_UNSUPPORTED").Print()
-			return
-		}
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"firewall": fw,
-				"dry_run":  true,
-				"command":  fmt.Sprintf("%s %s", command, strings.Join(cmdArgs, " ")),
-			}).Print()
+			output.NewError("firewall does not support rule removal or is unsupported", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		_, err := firewallExecutor.CombinedOutput(command, cmdArgs...)
+		_, err := newExecutor().Exec(commandContext(), command, cmdArgs...)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to remove rule: %s", err.Error()), "FIREWALL_REMOVE_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to remove rule: %s", err.Error()), nuxerrors.ErrCodeFirewall).WithCause(err).Print()
 			return
 		}
 
@@ -200,20 +168,29 @@ var firewallRemoveCmd = &cobra.Command{
 			"firewall": fw,
 			"port":     port,
 			"protocol": protocol,
-			"status":   "removed",
+			"status":   actionStatus("removed"),
 		}).Print()
 	},
 }
 
+// newFirewallManager returns a firewall manager for the target host that
+// records allowed/blocked ports into the journal
+func newFirewallManager() (*firewall.UniversalFirewallManager, error) {
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
+	if err != nil {
+		return nil, err
+	}
+	return firewall.NewUniversalFirewallManager(executor, profile).WithJournal(newJournal()), nil
+}
+
 func init() {
 	firewallAddCmd.Flags().String("port", "", "Port number")
 	firewallAddCmd.Flags().String("protocol", "tcp", "Protocol (tcp/udp)")
 	firewallAddCmd.Flags().String("action", "allow", "Action (allow/deny)")
-	firewallAddCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	firewallRemoveCmd.Flags().String("port", "", "Port number")
 	firewallRemoveCmd.Flags().String("protocol", "tcp", "Protocol (tcp/udp)")
-	firewallRemoveCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	firewallCmd.AddCommand(firewallListCmd)
 	firewallCmd.AddCommand(firewallAddCmd)
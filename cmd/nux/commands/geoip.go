@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/oschwald/geoip2-golang/v2"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
@@ -25,27 +26,27 @@ var geoipLookupCmd = &cobra.Command{
 
 		ip, err := netip.ParseAddr(ipStr)
 		if err != nil {
-			output.NewError(fmt.Sprintf("invalid IP address: %s", ipStr), "GEOIP_INVALID_IP").Print()
+			output.NewError(fmt.Sprintf("invalid IP address: %s", ipStr), nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
 		dbPath := "/opt/nux/geoip/GeoLite2-City.mmdb"
 		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-			output.NewError("GeoIP database not found. Please install GeoLite2-City.mmdb at /opt/nux/geoip/", "GEOIP_DB_NOT_FOUND").Print()
-			output.NewInfo("You can download it from: https://www.maxmind.com/en/geolite2/signup").Print()
+			output.NewError("GeoIP database not found. Please install GeoLite2-City.mmdb at /opt/nux/geoip/", nuxerrors.ErrCodeDependency).
+				WithHint("download it from https://www.maxmind.com/en/geolite2/signup").Print()
 			return
 		}
 
 		db, err := geoip2.Open(dbPath)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to open GeoIP database: %s", err.Error()), "GEOIP_DB_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to open GeoIP database: %s", err.Error()), nuxerrors.ErrCodeGeoIP).WithCause(err).Print()
 			return
 		}
 		defer db.Close()
 
 		record, err := db.City(ip)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to lookup IP: %s", err.Error()), "GEOIP_LOOKUP_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to lookup IP: %s", err.Error()), nuxerrors.ErrCodeGeoIP).WithCause(err).Print()
 			return
 		}
 
@@ -71,26 +72,26 @@ var geoipWhoisCmd = &cobra.Command{
 
 		ip, err := netip.ParseAddr(ipStr)
 		if err != nil {
-			output.NewError(fmt.Sprintf("invalid IP address: %s", ipStr), "GEOIP_INVALID_IP").Print()
+			output.NewError(fmt.Sprintf("invalid IP address: %s", ipStr), nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
 		asnDbPath := "/opt/nux/geoip/GeoLite2-ASN.mmdb"
 		if _, err := os.Stat(asnDbPath); os.IsNotExist(err) {
-			output.NewError("ASN database not found. Please install GeoLite2-ASN.mmdb at /opt/nux/geoip/", "GEOIP_ASN_NOT_FOUND").Print()
+			output.NewError("ASN database not found. Please install GeoLite2-ASN.mmdb at /opt/nux/geoip/", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
 		db, err := geoip2.Open(asnDbPath)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to open ASN database: %s", err.Error()), "GEOIP_DB_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to open ASN database: %s", err.Error()), nuxerrors.ErrCodeGeoIP).WithCause(err).Print()
 			return
 		}
 		defer db.Close()
 
 		record, err := db.ASN(ip)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to lookup ASN: %s", err.Error()), "GEOIP_WHOIS_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to lookup ASN: %s", err.Error()), nuxerrors.ErrCodeGeoIP).WithCause(err).Print()
 			return
 		}
 
@@ -112,7 +113,7 @@ var geoipSetupCmd = &cobra.Command{
 		geoipDir := "/opt/nux/geoip"
 
 		if err := os.MkdirAll(geoipDir, 0755); err != nil {
-			output.NewError(fmt.Sprintf("failed to create directory: %s", err.Error()), "GEOIP_SETUP_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to create directory: %s", err.Error()), nuxerrors.ErrCodeGeoIP).WithCause(err).Print()
 			return
 		}
 
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"fmt"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/journal"
+	"github.com/rsdenck/nux/internal/modules/scheduler"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recent state-changing operations",
+	Long: `List the operations recorded in ~/.nux/journal.jsonl, newest first.
+
+Use the id with 'nux undo <id>' to revert an operation.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		j, err := journal.Open("")
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeJournal).WithCause(err).Print()
+			return
+		}
+		entries, err := j.Entries()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeJournal).WithCause(err).Print()
+			return
+		}
+		undone := journal.UndoneBy(entries)
+
+		items := make([]map[string]interface{}, 0, len(entries))
+		for i := len(entries) - 1; i >= 0; i-- {
+			if limit > 0 && len(items) >= limit {
+				break
+			}
+			e := entries[i]
+			host := e.Host
+			if host == "" {
+				host = "local"
+			}
+			state := ""
+			if by, ok := undone[e.ID]; ok {
+				state = "undone by " + by
+			} else if e.Undoes != "" {
+				state = "undo of " + e.Undoes
+			}
+			items = append(items, map[string]interface{}{
+				"id":        e.ID,
+				"time":      e.Time.Local().Format("2006-01-02 15:04:05"),
+				"host":      host,
+				"operation": e.Operation,
+				"target":    formatVars(e.Params),
+				"state":     state,
+			})
+		}
+
+		output.NewList(items, len(items)).
+			WithColumns("id", "time", "host", "operation", "target", "state").
+			WithMessage("Operation history").Print()
+	},
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo <id>",
+	Short: "Revert an operation from the history",
+	Long: `Revert a journaled operation, for example putting back the rules a port
+had before it was allowed or disabling a service that was enabled. The undo
+runs on the host the operation was made on.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		j, err := journal.Open("")
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeJournal).WithCause(err).Print()
+			return
+		}
+		entries, err := j.Entries()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeJournal).WithCause(err).Print()
+			return
+		}
+
+		var entry *journal.Entry
+		for _, e := range entries {
+			if e.ID == args[0] {
+				entry = e
+			}
+		}
+		if entry == nil {
+			output.NewError(fmt.Sprintf("no operation with id %s (see 'nux history')", args[0]), nuxerrors.ErrCodeNotFound).Print()
+			return
+		}
+		if by, ok := journal.UndoneBy(entries)[entry.ID]; ok {
+			output.NewError(fmt.Sprintf("operation %s was already undone by %s", entry.ID, by), nuxerrors.ErrCodeAlreadyExists).Print()
+			return
+		}
+		inverse, ok := journal.Inverse(entry.Operation)
+		if !ok {
+			output.NewError(fmt.Sprintf("%s cannot be undone", entry.Operation), nuxerrors.ErrCodeUnsupported).Print()
+			return
+		}
+
+		// The undo must run where the operation was made. --host is refused
+		// for undo, so the host always comes from the journal.
+		flagHost = entry.Host
+
+		if err := applyOperation(inverse, entry.Params, entry.ID); err != nil {
+			output.NewError(fmt.Sprintf("failed to undo %s: %s", entry.ID, err.Error()), nuxerrors.ErrCodeJournal).WithCause(err).Print()
+			return
+		}
+
+		output.NewSuccess(map[string]interface{}{
+			"id":        entry.ID,
+			"operation": inverse,
+			"target":    formatVars(entry.Params),
+			"status":    actionStatus("undone"),
+		}).Print()
+	},
+}
+
+// applyOperation runs a journaled operation through its module manager. The
+// run is journaled as reverting the entry undoes.
+func applyOperation(op string, params map[string]string, undoes string) error {
+	switch op {
+	case journal.OpFirewallAllow, journal.OpFirewallBlock:
+		manager, err := newFirewallManager()
+		if err != nil {
+			return err
+		}
+		manager.WithJournal(newJournal().Undoing(undoes))
+		// Entries journaled with the state the port was in are undone by
+		// going back to it; older ones only by the opposite operation
+		if previous, ok := params["previous"]; ok {
+			return manager.RestorePort(params["port"], params["protocol"], previous)
+		}
+		if op == journal.OpFirewallAllow {
+			return manager.AllowPort(params["port"], params["protocol"])
+		}
+		return manager.BlockPort(params["port"], params["protocol"])
+
+	case journal.OpServiceEnable, journal.OpServiceDisable:
+		manager, err := newServiceManager()
+		if err != nil {
+			return err
+		}
+		manager.WithJournal(newJournal().Undoing(undoes))
+		if op == journal.OpServiceEnable {
+			return manager.EnableService(params["service"])
+		}
+		return manager.DisableService(params["service"])
+
+	case journal.OpCronAdd, journal.OpCronRemove:
+		manager := scheduler.NewLinuxSchedulerManager(newExecutor()).WithJournal(newJournal().Undoing(undoes))
+		job := ports.CronJob{Schedule: params["schedule"], Command: params["command"]}
+		if op == journal.OpCronAdd {
+			return manager.AddCronJob(job)
+		}
+		return manager.RemoveCronJob(job)
+
+	case journal.OpUserDelete:
+		manager, err := newUserManager()
+		if err != nil {
+			return err
+		}
+		manager.WithJournal(newJournal().Undoing(undoes))
+		return manager.DeleteUser(params["username"], params["remove_home"] == "true")
+
+	case journal.OpPackageInstall, journal.OpPackageRemove:
+		manager, _, err := newPackageManager()
+		if err != nil {
+			return err
+		}
+		manager.WithJournal(newJournal().Undoing(undoes))
+		if op == journal.OpPackageInstall {
+			return manager.Install(params["package"])
+		}
+		return manager.Remove(params["package"])
+	}
+	return fmt.Errorf("unsupported operation %s", op)
+}
+
+func init() {
+	historyCmd.Flags().Int("limit", 20, "Number of operations to show (0 shows all)")
+
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(undoCmd)
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rsdenck/nux/internal/modules/ssh"
+	"github.com/spf13/cobra"
+)
+
+// remoteCapableCommands are the top-level commands that only touch the system
+// through the executor and therefore work unchanged with --host
+var remoteCapableCommands = map[string]bool{
+	"system": true, "doctor": true, "audit": true, "disk": true, "network": true,
+	"service": true, "status": true, "start": true, "stop": true, "restart": true,
+	"process": true, "users": true, "firewall": true, "container": true, "bash": true,
+	"install": true, "remove": true, "update": true, "upgrade": true, "clean": true,
+}
+
+// validateHost rejects --host for commands that cannot run remotely and for
+// profiles missing from ~/.ssh/config
+func validateHost(cmd *cobra.Command) error {
+	if flagHost == "" {
+		return nil
+	}
+
+	top := topLevelCommand(cmd)
+	if !remoteCapableCommands[top.Name()] {
+		return fmt.Errorf("--host is not supported by 'nux %s'", top.Name())
+	}
+
+	profiles, err := sshProfiles()
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if p == flagHost {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown ssh profile %q (see 'nux remote list')", flagHost)
+}
+
+// topLevelCommand returns the direct child of the root that cmd belongs to
+func topLevelCommand(cmd *cobra.Command) *cobra.Command {
+	top := cmd
+	for top.HasParent() && top.Parent().HasParent() {
+		top = top.Parent()
+	}
+	return top
+}
+
+// sshProfiles lists the host aliases defined in ~/.ssh/config
+func sshProfiles() ([]string, error) {
+	client, err := ssh.NewNativeSSHClient(nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListProfiles()
+}
+
+// sshControlPath returns the ControlPath used to share one ssh connection
+// across the commands of an invocation, or "" if it cannot be created
+func sshControlPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(home, ".nux", "ssh")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "%C")
+}
+
+func completeHost(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	profiles, err := sshProfiles()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
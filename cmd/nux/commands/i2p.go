@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
@@ -37,15 +39,14 @@ var i2pStartCmd = &cobra.Command{
 			return
 		}
 
-cmdStart := exec.Command(i2pRouter)
-	cmdStart.Dir = i2pDir
-	if err := cmdStart.Start(); err != nil {
-		output.NewError(fmt.Sprintf("Failed to start I2P: %s", err.Error()), "I2P_START_ERR").Print()
-		return
-	}
+		pid, err := adapter.RunDetached(newExecutor(), i2pDir, i2pRouter)
+		if err != nil {
+			output.NewError(fmt.Sprintf("Failed to start I2P: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
+			return
+		}
 		pidFile := filepath.Join(i2pDir, ".pid")
-		os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", cmdStart.Process.Pid)), 0644)
-		output.NewInfo(fmt.Sprintf("I2P router started (PID: %d)", cmdStart.Process.Pid)).Print()
+		os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", pid)), 0644)
+		output.NewInfo(fmt.Sprintf("I2P router started (PID: %d)", pid)).Print()
 		output.NewInfo("Web console: http://127.0.0.1:7657").Print()
 	},
 }
@@ -63,12 +64,12 @@ var i2pStopCmd = &cobra.Command{
 		pidBytes, err := os.ReadFile(pidFile)
 		if err == nil {
 			pid := strings.TrimSpace(string(pidBytes))
-			exec.Command("kill", pid).Run()
+			newExecutor().Exec(commandContext(), "kill", pid)
 			os.Remove(pidFile)
 			output.NewInfo(fmt.Sprintf("I2P router (PID %s) stopped", pid)).Print()
 			return
 		}
-		exec.Command("pkill", "-f", "i2p|java.net.router").Run()
+		newExecutor().Exec(commandContext(), "pkill", "-f", "i2p|java.net.router")
 		output.NewInfo("I2P router stopped").Print()
 	},
 }
@@ -275,7 +276,7 @@ var i2pConsoleCmd = &cobra.Command{
 	Short: "Open I2P web admin console",
 	Run: func(cmd *cobra.Command, args []string) {
 		output.NewInfo("Opening I2P web console...").Print()
-		exec.Command("xdg-open", "http://127.0.0.1:7657").Start()
+		adapter.RunDetached(newExecutor(), "", "xdg-open", "http://127.0.0.1:7657")
 	},
 }
 
@@ -298,7 +299,7 @@ func findI2PRouter() string {
 }
 
 func isI2PRunning() bool {
-	out, err := exec.Command("pgrep", "-f", "i2prouter|I2P.*router").CombinedOutput()
+	out, err := combinedOutput("pgrep", "-f", "i2prouter|I2P.*router")
 	if err != nil {
 		return false
 	}
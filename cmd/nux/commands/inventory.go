@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/inventory"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:     "inventory",
+	Short:   "Manage the host inventory used by --on",
+	Aliases: []string{"inv"},
+	Long: `Hosts, groups and per-host variables stored in ~/.nux/inventory.yaml.
+
+Every host is reached through an ssh profile from ~/.ssh/config. Run any
+remote-capable command on a group with --on:
+
+  nux --on web service status nginx
+  nux --on web,db02 --parallel 5 doctor
+  nux --on all bash exec "echo %{role}"     # per-host vars as %{var}`,
+}
+
+var inventoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List inventory hosts",
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := inventory.Load()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		items := make([]map[string]interface{}, 0, len(inv.Hosts))
+		groups := inv.GroupMembers()
+		for _, name := range inv.HostNames() {
+			h := inv.Hosts[name]
+			items = append(items, map[string]interface{}{
+				"host":    name,
+				"profile": h.SSHProfile(),
+				"groups":  strings.Join(hostGroups(groups, name), ","),
+				"vars":    formatVars(h.Vars),
+			})
+		}
+
+		output.NewList(items, len(items)).
+			WithColumns("host", "profile", "groups", "vars").
+			WithMessage("Inventory hosts").Print()
+	},
+}
+
+var inventoryGroupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "List inventory groups",
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := inventory.Load()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		groups := inv.GroupMembers()
+		names := make([]string, 0, len(groups))
+		for g := range groups {
+			names = append(names, g)
+		}
+		sort.Strings(names)
+
+		items := make([]map[string]interface{}, 0, len(names))
+		for _, g := range names {
+			items = append(items, map[string]interface{}{
+				"group": g,
+				"count": len(groups[g]),
+				"hosts": strings.Join(groups[g], ","),
+			})
+		}
+
+		output.NewList(items, len(items)).
+			WithColumns("group", "count", "hosts").
+			WithMessage("Inventory groups").Print()
+	},
+}
+
+var inventorySeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Add every ~/.ssh/config profile to the inventory",
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := inventory.Load()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		profiles, err := sshProfiles()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to read ssh profiles: %s", err.Error()), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+
+		added := inv.Seed(profiles)
+		if err := inventory.Save(inv); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		path, _ := inventory.Path()
+		output.NewSuccess(map[string]interface{}{
+			"added": added,
+			"total": len(inv.Hosts),
+			"file":  path,
+		}).WithMessage(fmt.Sprintf("%d host(s) added from ~/.ssh/config", len(added))).Print()
+	},
+}
+
+var inventoryAddCmd = &cobra.Command{
+	Use:   "add <host>",
+	Short: "Add or update a host",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		profile, _ := cmd.Flags().GetString("profile")
+		groups, _ := cmd.Flags().GetStringSlice("group")
+		vars, _ := cmd.Flags().GetStringToString("var")
+
+		inv, err := inventory.Load()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		h := &inventory.Host{Name: args[0], Profile: profile, Groups: groups, Vars: vars}
+		if existing, ok := inv.Hosts[h.Name]; ok {
+			// Flags only override what was given
+			if profile == "" {
+				h.Profile = existing.Profile
+			}
+			if !cmd.Flags().Changed("group") {
+				h.Groups = existing.Groups
+			}
+			if existing.Vars != nil {
+				merged := existing.Vars
+				for k, v := range vars {
+					merged[k] = v
+				}
+				h.Vars = merged
+			}
+		}
+		inv.AddHost(h)
+
+		if err := inventory.Save(inv); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		output.NewSuccess(map[string]interface{}{
+			"host":    h.Name,
+			"profile": h.SSHProfile(),
+			"groups":  strings.Join(h.Groups, ","),
+			"vars":    formatVars(h.Vars),
+		}).WithMessage("Host saved").Print()
+	},
+}
+
+var inventoryRemoveCmd = &cobra.Command{
+	Use:   "remove <host>",
+	Short: "Remove a host",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := inventory.Load()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		if !inv.RemoveHost(args[0]) {
+			output.NewError(fmt.Sprintf("host %s is not in the inventory", args[0]), nuxerrors.ErrCodeNotFound).Print()
+			return
+		}
+
+		if err := inventory.Save(inv); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInventory).WithCause(err).Print()
+			return
+		}
+
+		output.NewSuccess(map[string]interface{}{
+			"host":   args[0],
+			"status": "removed",
+		}).Print()
+	},
+}
+
+func hostGroups(groups map[string][]string, host string) []string {
+	var in []string
+	for g, members := range groups {
+		for _, m := range members {
+			if m == host {
+				in = append(in, g)
+				break
+			}
+		}
+	}
+	sort.Strings(in)
+	return in
+}
+
+func formatVars(vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+vars[k])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// completeInventoryTarget completes --on with "all", group and host names
+func completeInventoryTarget(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	inv, err := inventory.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	targets := []string{inventory.AllGroup}
+	for g := range inv.GroupMembers() {
+		targets = append(targets, g)
+	}
+	sort.Strings(targets[1:])
+	targets = append(targets, inv.HostNames()...)
+	return targets, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	inventoryAddCmd.Flags().String("profile", "", "ssh profile used to reach the host (default: host name)")
+	inventoryAddCmd.Flags().StringSlice("group", nil, "Group membership (repeatable)")
+	inventoryAddCmd.Flags().StringToString("var", nil, "Host variable key=value (repeatable)")
+
+	inventoryCmd.AddCommand(inventoryListCmd)
+	inventoryCmd.AddCommand(inventoryGroupsCmd)
+	inventoryCmd.AddCommand(inventorySeedCmd)
+	inventoryCmd.AddCommand(inventoryAddCmd)
+	inventoryCmd.AddCommand(inventoryRemoveCmd)
+	rootCmd.AddCommand(inventoryCmd)
+}
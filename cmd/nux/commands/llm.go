@@ -0,0 +1,334 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/llm"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/redact"
+	"github.com/rsdenck/nux/internal/usage"
+	"github.com/rsdenck/nux/internal/vault"
+)
+
+// llmProviders lists the built-in providers accepted by --provider, besides
+// the endpoints configured by name
+const llmProviders = "ollama, openai, claude, nvidia"
+
+// isBuiltinProvider reports whether name is a built-in provider rather than
+// a configured endpoint
+func isBuiltinProvider(name string) bool {
+	switch name {
+	case "ollama", "openai", "claude", "anthropic", "nvidia":
+		return true
+	}
+	return false
+}
+
+// openLLMProvider loads the vault and returns the named provider configured
+// from it. save records the use of the API key in the vault.
+func openLLMProvider(name string) (p ports.LLMProvider, save func(), err error) {
+	v, err := llmVault(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return vaultLLMProvider(name, v)
+}
+
+// openAgentProvider is openLLMProvider for the agent commands, whose provider
+// and model, when not given, are the ones set with 'nux agent config'
+func openAgentProvider(name, model string) (p ports.LLMProvider, agentModel string, save func(), err error) {
+	v, err := llmVault(name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if v != nil {
+		if c, ok := v.Config["agent_provider"].(string); ok && name == "" {
+			name = c
+		}
+		if c, ok := v.Config["agent_model"].(string); ok && model == "" {
+			model = c
+		}
+	}
+	p, save, err = vaultLLMProvider(name, v)
+	return p, model, save, err
+}
+
+// vaultLLMProvider returns the named provider configured from v, and how to
+// record the use of its API key
+func vaultLLMProvider(name string, v *vault.Vault) (p ports.LLMProvider, save func(), err error) {
+	p, err = newLLMProvider(name, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, func() {
+		if p.Name() != "ollama" {
+			saveSecretUse(v)
+		}
+	}, nil
+}
+
+// llmVault loads the vault providers are configured from. Ollama works
+// without it, so for Ollama a failure is only reported and the vault is nil.
+func llmVault(provider string) (*vault.Vault, error) {
+	v, err := vault.Load()
+	if err != nil {
+		if provider != "" && provider != "ollama" {
+			return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeVault, "failed to load vault")
+		}
+		fmt.Fprintf(os.Stderr, "⚠ failed to load vault, using defaults\n")
+		return nil, nil
+	}
+	return v, nil
+}
+
+// newLLMProvider returns the named AI provider, configured from v, behind
+// the redaction rules of the provider and recording its usage. The API keys
+// are read from v, recording their use; v may be nil for Ollama, which then
+// runs on the local host.
+//
+// A comma-separated list of providers, such as "ollama,nvidia,openai", is a
+// chain asking each in turn until one answers. Without a name, the chain set
+// with 'nux ask config --chain' is used, or Ollama.
+func newLLMProvider(name string, v *vault.Vault) (ports.LLMProvider, error) {
+	if name == "" {
+		name = configuredChain(v)
+	}
+	if strings.Contains(name, ",") {
+		return newProviderChain(name, v)
+	}
+	p, err := newLLMBackend(name, v)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRedactor(p.Name(), v)
+	if err != nil {
+		return nil, err
+	}
+	return usage.NewProvider(redact.NewProvider(p, r), usageLog()), nil
+}
+
+// configuredChain returns the providers asked when none is given, empty for
+// Ollama alone
+func configuredChain(v *vault.Vault) string {
+	if v == nil {
+		return ""
+	}
+	chain, _ := v.Config["provider_chain"].(string)
+	return chain
+}
+
+// parseChain returns the providers of a chain, as "provider" or
+// "provider:model" separated by commas. Models such as "qwen3:8b" keep
+// their own colons.
+func parseChain(spec string) (names, models []string, err error) {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		name, model, _ := strings.Cut(entry, ":")
+		if name == "" {
+			return nil, nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid provider chain %q, expected providers such as ollama,nvidia,openai", spec))
+		}
+		names = append(names, name)
+		models = append(models, model)
+	}
+	return names, models, nil
+}
+
+// newProviderChain returns the providers of a chain behind one another.
+// Each keeps its own redaction rules, so a local Ollama still sees the
+// real values while a cloud fallback does not.
+func newProviderChain(spec string, v *vault.Vault) (ports.LLMProvider, error) {
+	names, models, err := parseChain(spec)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]llm.Link, 0, len(names))
+	for i, name := range names {
+		p, err := newLLMProvider(name, v)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, llm.Link{Provider: p, Model: models[i]})
+	}
+	chain := llm.NewChain(spec, links)
+	chain.OnFallback = func(failed, next string, err error) {
+		fmt.Fprintf(os.Stderr, "⚠ %s failed (%s), trying %s\n", failed, err.Error(), next)
+	}
+	return chain, nil
+}
+
+var (
+	usageOnce      sync.Once
+	sharedUsageLog *usage.Log
+)
+
+// usageLog returns the log the requests to providers are recorded in, nil
+// when it has no location
+func usageLog() *usage.Log {
+	usageOnce.Do(func() {
+		if l, err := usage.Open(); err == nil {
+			sharedUsageLog = l
+		}
+	})
+	return sharedUsageLog
+}
+
+// ollamaHost returns the Ollama server configured in the vault, the local
+// one by default
+func ollamaHost(v *vault.Vault) string {
+	if v != nil {
+		if h, ok := v.Config["ollama_host"].(string); ok && h != "" {
+			return h
+		}
+	}
+	return llm.DefaultOllamaHost
+}
+
+// newLLMBackend returns the named AI provider as is
+func newLLMBackend(name string, v *vault.Vault) (ports.LLMProvider, error) {
+	timeout := time.Duration(flagTimeout) * time.Second
+	switch name {
+	case "", "ollama":
+		return llm.NewOllamaProvider(ollamaHost(v), timeout), nil
+	case "openai":
+		key, err := providerKey(v, "openai", "OpenAI")
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewOpenAIProvider(key, timeout), nil
+	case "claude", "anthropic":
+		key, err := providerKey(v, "claude", "Claude")
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewAnthropicProvider(key, timeout), nil
+	case "nvidia":
+		key, err := providerKey(v, "nvidia", "NVIDIA")
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewNvidiaProvider(key, timeout), nil
+	}
+
+	available := llmProviders
+	if v != nil {
+		if e, ok := v.GetEndpoint(name); ok {
+			key, err := v.EndpointKey(e)
+			if err != nil {
+				return nil, err
+			}
+			if ref, ok := strings.CutPrefix(e.APIKeyRef, vault.RefScheme); ok {
+				warnExpiring(v, ref)
+			}
+			return llm.NewOpenAICompatibleProvider(name, e.BaseURL, key, e.Model, timeout), nil
+		}
+		if names := v.EndpointNames(); len(names) > 0 {
+			available += ", " + strings.Join(names, ", ")
+		}
+	}
+	return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("unknown provider: %s (available: %s)", name, available)).
+		WithHint("add an OpenAI-compatible endpoint with 'nux ask config --provider " + name + " --base-url URL'")
+}
+
+// providerKey returns the API key of a provider from the vault
+func providerKey(v *vault.Vault, service, name string) (string, error) {
+	if v == nil {
+		return "", nuxerrors.New(nuxerrors.ErrCodeVault, fmt.Sprintf("%s needs an API key from the vault", name))
+	}
+	key, ok := v.UseSecret(service)
+	if !ok {
+		return "", nuxerrors.New(nuxerrors.ErrCodeConfig, fmt.Sprintf("%s API key not found", name)).
+			WithHint(fmt.Sprintf("store it with 'nux vault set-key %s'", service))
+	}
+	warnExpiring(v, service)
+	return key, nil
+}
+
+// tokenEvent is the NDJSON event of a piece of a streamed answer
+type tokenEvent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// tokenPrinter returns the handler that shows an answer while it streams:
+// printed as it arrives in text output, or one {"type":"token"} NDJSON event
+// per piece with --json. Formats that can only render the final result get a
+// nil handler. done ends the streamed text, and may be called again once
+// more of it was printed.
+func tokenPrinter() (onToken ports.TokenHandler, done func()) {
+	switch {
+	case flagJSON:
+		output.SetStreaming(true)
+		return func(token string) {
+			output.PrintEvent(tokenEvent{Type: "token", Text: token})
+		}, func() {}
+	case !plainText():
+		return nil, func() {}
+	}
+	printed := false
+	return func(token string) {
+			printed = true
+			fmt.Print(token)
+		}, func() {
+			if printed {
+				fmt.Println()
+				printed = false
+			}
+		}
+}
+
+// plainText reports whether results are shown as text on the terminal,
+// rather than rendered in a format or written to a file
+func plainText() bool {
+	return !flagJSON && !flagYAML && flagQuery == "" && flagTemplate == "" && flagOut == "" && !isReportFormat()
+}
+
+// printAnswer sends a conversation to p and prints the answer as it streams.
+// The result is printed only when the answer was not already shown as text.
+func printAnswer(p ports.LLMProvider, req ports.ChatRequest) {
+	resp, shown, err := streamAnswer(commandContext(), p, req)
+	if err != nil {
+		output.NewError(err.Error(), nuxerrors.ErrCodeProvider).WithCause(err).Print()
+		return
+	}
+	if !shown {
+		printResponse(resp, nil)
+	}
+}
+
+// streamAnswer sends a conversation to p, showing the answer while it
+// streams. shown reports whether it was printed as text, leaving nothing else
+// to print.
+func streamAnswer(ctx context.Context, p ports.LLMProvider, req ports.ChatRequest) (resp *ports.ChatResponse, shown bool, err error) {
+	onToken, done := tokenPrinter()
+	resp, err = p.Chat(ctx, req, onToken)
+	done()
+	return resp, onToken != nil && !flagJSON, err
+}
+
+// printResponse prints an answer as a result, with extra fields
+func printResponse(resp *ports.ChatResponse, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"provider": resp.Provider,
+		"model":    resp.Model,
+		"response": resp.Content,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	output.NewSuccess(data).WithMessage("AI Response").Print()
+}
+
+// userMessage returns a request with a single question
+func userMessage(model, question string) ports.ChatRequest {
+	return ports.ChatRequest{
+		Model:    model,
+		Messages: []ports.ChatMessage{{Role: ports.RoleUser, Content: question}},
+	}
+}
@@ -6,12 +6,11 @@ import (
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var networkExecutor core.Executor = &core.RealExecutor{}
-
 var networkCmd = &cobra.Command{
 	Use:   "network",
 	Short: "Network management",
@@ -32,7 +31,7 @@ var networkListCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		interfaces, err := getNetworkInterfaces()
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list interfaces: %s", err.Error()), "NETWORK_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to list interfaces: %s", err.Error()), nuxerrors.ErrCodeNetworkConfig).WithCause(err).Print()
 			return
 		}
 
@@ -106,12 +105,12 @@ func padRight(s string, length int) string {
 }
 
 func getNetworkInterfaces() ([]NetworkInterface, error) {
-	linkOut, err := networkExecutor.CombinedOutput("ip", "-j", "link", "show")
+	linkOut, err := combinedOutput("ip", "-j", "link", "show")
 	if err != nil {
 		return nil, fmt.Errorf("ip link show failed: %s", err.Error())
 	}
 
-	addrOut, err := networkExecutor.CombinedOutput("ip", "-j", "addr", "show")
+	addrOut, err := combinedOutput("ip", "-j", "addr", "show")
 	if err != nil {
 		return nil, fmt.Errorf("ip addr show failed: %s", err.Error())
 	}
@@ -184,7 +183,7 @@ func getNetworkInterfaces() ([]NetworkInterface, error) {
 
 			speed := "-"
 			if state == "up" && name != "lo" && !strings.HasPrefix(name, "veth") && !strings.HasPrefix(name, "br-") {
-				speedOut, _ := networkExecutor.CombinedOutput("ethtool", name)
+				speedOut, _ := combinedOutput("ethtool", name)
 				if strings.Contains(speedOut, "Speed:") {
 					lines := strings.Split(speedOut, "\n")
 					for _, line := range lines {
@@ -223,9 +222,9 @@ var networkShowCmd = &cobra.Command{
 			cmdArgs = append(cmdArgs, iface)
 		}
 
-		out, err := networkExecutor.CombinedOutput("ip", cmdArgs...)
+		out, err := combinedOutput("ip", cmdArgs...)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to show interface: %s", strings.TrimSpace(out)), "NETWORK_SHOW_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to show interface: %s", strings.TrimSpace(out)), nuxerrors.ErrCodeNetworkConfig).WithCause(err).Print()
 			return
 		}
 
@@ -254,7 +253,6 @@ var networkSetCmd = &cobra.Command{
 		up, _ := cmd.Flags().GetBool("up")
 		down, _ := cmd.Flags().GetBool("down")
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 		commands := []string{}
 
@@ -277,27 +275,18 @@ var networkSetCmd = &cobra.Command{
 			commands = append(commands, fmt.Sprintf("ip route add default via %s", gateway))
 		}
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"interface": iface,
-				"dry_run":   true,
-				"commands":  commands,
-			}).Print()
-			return
-		}
-
 		for _, command := range commands {
 			parts := strings.Fields(command)
-			_, err := networkExecutor.Run(parts[0], parts[1:]...)
+			_, err := newExecutor().Exec(commandContext(), parts[0], parts[1:]...)
 			if err != nil {
-				output.NewError(fmt.Sprintf("command failed: %s", command), "NETWORK_SET_ERROR").Print()
+				output.NewError(fmt.Sprintf("command failed: %s", command), nuxerrors.ErrCodeNetworkConfig).WithCause(err).Print()
 				return
 			}
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"interface": iface,
-			"status":    "configured",
+			"status":    actionStatus("configured"),
 			"commands":  commands,
 		}).Print()
 	},
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
@@ -45,7 +46,8 @@ func runOnboardFlow() {
 
 	v, err := vault.Load()
 	if err != nil {
-		v = vault.NewVault()
+		output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+		return
 	}
 
 	if v.Config == nil {
@@ -55,7 +57,7 @@ func runOnboardFlow() {
 	v.Config["onboard_date"] = "2026-04-30"
 
 	if err := vault.Save(v); err != nil {
-		output.NewError(fmt.Sprintf("failed to save: %s", err.Error()), "ONBOARD_SAVE_ERROR").Print()
+		output.NewError(fmt.Sprintf("failed to save: %s", err.Error()), nuxerrors.ErrCodeConfig).WithCause(err).Print()
 		return
 	}
 
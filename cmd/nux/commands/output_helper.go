@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"fmt"
+	"os"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 )
 
@@ -8,10 +12,28 @@ func printSuccess(data interface{}, message string) {
 	output.NewSuccess(data).WithMessage(message).Print()
 }
 
-func printError(err string, code string) {
+func printError(err string, code nuxerrors.ErrorCode) {
 	output.NewError(err, code).Print()
 }
 
 func printList(items interface{}, total int, message string) {
 	output.NewList(items, total).WithMessage(message).Print()
 }
+
+// exitWithError prints out and ends the process with its exit code
+func exitWithError(out *output.Output) {
+	out.Print()
+	os.Exit(output.ExitStatus())
+}
+
+// setOutputRendering installs --query or --template, checking them before the
+// command runs
+func setOutputRendering() error {
+	if flagQuery != "" && flagTemplate != "" {
+		return fmt.Errorf("--query and --template cannot be used together")
+	}
+	if err := output.SetQuery(flagQuery); err != nil {
+		return err
+	}
+	return output.SetTemplate(flagTemplate)
+}
@@ -2,35 +2,76 @@ package commands
 
 import (
 	"fmt"
-	"os/exec"
 
-	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/modules/pkg"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var pkgExecutor core.Executor = &core.RealExecutor{}
-
+// detectPackageManager returns the first supported package manager installed on the target host
 func detectPackageManager() string {
-	if _, err := exec.LookPath("apt"); err == nil {
-		return "apt"
-	}
-	if _, err := exec.LookPath("dnf"); err == nil {
-		return "dnf"
-	}
-	if _, err := exec.LookPath("yum"); err == nil {
-		return "yum"
-	}
-	if _, err := exec.LookPath("pacman"); err == nil {
-		return "pacman"
+	for _, pm := range []string{"apt", "dnf", "yum", "pacman", "zypper", "apk"} {
+		if commandAvailable(pm) {
+			return pm
+		}
 	}
-	if _, err := exec.LookPath("zypper"); err == nil {
-		return "zypper"
+	return ""
+}
+
+// pkgActions maps each action to the arguments of every supported package manager
+var pkgActions = map[string]map[string][]string{
+	"update": {
+		"apt":    {"update"},
+		"dnf":    {"check-update"},
+		"yum":    {"check-update"},
+		"pacman": {"-Sy"},
+		"zypper": {"refresh"},
+		"apk":    {"update"},
+	},
+	"upgrade": {
+		"apt":    {"upgrade", "-y"},
+		"dnf":    {"upgrade", "-y"},
+		"yum":    {"upgrade", "-y"},
+		"pacman": {"-Syu"},
+		"zypper": {"update", "-y"},
+		"apk":    {"upgrade"},
+	},
+	"clean": {
+		"apt":    {"clean"},
+		"dnf":    {"clean", "all"},
+		"yum":    {"clean", "all"},
+		"pacman": {"-Sc"},
+		"zypper": {"clean"},
+		"apk":    {"cache", "clean"},
+	},
+}
+
+// runPkgAction runs a package action through the shared executor, so it is
+// elevated when needed and --dry-run records it into the plan instead of
+// touching the system. The package manager output is streamed while it runs.
+func runPkgAction(pm string, action string, packages []string) error {
+	base, ok := pkgActions[action][pm]
+	if !ok {
+		return fmt.Errorf("gerenciador não suportado: %s", pm)
 	}
-	if _, err := exec.LookPath("apk"); err == nil {
-		return "apk"
+
+	cmdArgs := append(append([]string{}, base...), packages...)
+
+	_, err := runStream("", pm, cmdArgs...)
+	return err
+}
+
+// newPackageManager returns a package manager for the target host that
+// records installed/removed packages into the journal
+func newPackageManager() (*pkg.UniversalPackageManager, string, error) {
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
+	if err != nil {
+		return nil, "", err
 	}
-	return ""
+	manager := pkg.NewUniversalPackageManager(executor, profile).WithJournal(newJournal())
+	return manager, profile.PackageManager, nil
 }
 
 var installCmd = &cobra.Command{
@@ -39,56 +80,25 @@ var installCmd = &cobra.Command{
 	Aliases: []string{"i"},
 	Args:    cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		pm := detectPackageManager()
+		manager, pm, err := newPackageManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
+			return
+		}
 		if pm == "" {
-			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", "PKG_NO_PM").Print()
+			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		switch pm {
-		case "apt":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "install", "packages": args, "dry_run": true}).Print()
+		// One call per package so each one is journaled and can be undone
+		for _, p := range args {
+			if err := manager.Install(p); err != nil {
+				output.NewError(fmt.Sprintf("falha ao instalar %s: %s", p, err.Error()), nuxerrors.ErrCodePkgInstall).WithCause(err).Print()
 				return
 			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"apt", "install", "-y"}, args...)...)
-		case "dnf":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "install", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"dnf", "install", "-y"}, args...)...)
-		case "yum":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "install", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"yum", "install", "-y"}, args...)...)
-		case "pacman":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "install", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"pacman", "-S", "--noconfirm"}, args...)...)
-		case "zypper":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "install", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"zypper", "install", "-y"}, args...)...)
-		case "apk":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "install", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"apk", "add"}, args...)...)
-		default:
-			output.NewError(fmt.Sprintf("Gerenciador não suportado: %s", pm), "PKG_UNSUPPORTED").Print()
 		}
 
-		output.NewSuccess(map[string]interface{}{"packages": args, "status": "installed", "manager": pm}).Print()
+		output.NewSuccess(map[string]interface{}{"packages": args, "status": actionStatus("installed"), "manager": pm}).Print()
 	},
 }
 
@@ -98,54 +108,24 @@ var removeCmd = &cobra.Command{
 	Aliases: []string{"rm"},
 	Args:    cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		pm := detectPackageManager()
+		manager, pm, err := newPackageManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
+			return
+		}
 		if pm == "" {
-			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", "PKG_NO_PM").Print()
+			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		switch pm {
-		case "apt":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "remove", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"apt", "remove", "-y"}, args...)...)
-		case "dnf":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "remove", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"dnf", "remove", "-y"}, args...)...)
-		case "yum":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "remove", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"yum", "remove", "-y"}, args...)...)
-		case "pacman":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "remove", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"pacman", "-R", "--noconfirm"}, args...)...)
-		case "zypper":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "remove", "packages": args, "dry_run": true}).Print()
+		for _, p := range args {
+			if err := manager.Remove(p); err != nil {
+				output.NewError(fmt.Sprintf("falha ao remover %s: %s", p, err.Error()), nuxerrors.ErrCodePkgRemove).WithCause(err).Print()
 				return
 			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"zypper", "remove", "-y"}, args...)...)
-		case "apk":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "remove", "packages": args, "dry_run": true}).Print()
-				return
-			}
-			pkgExecutor.CombinedOutput("sudo", append([]string{"apk", "del"}, args...)...)
 		}
 
-		output.NewSuccess(map[string]interface{}{"packages": args, "status": "removed", "manager": pm}).Print()
+		output.NewSuccess(map[string]interface{}{"packages": args, "status": actionStatus("removed"), "manager": pm}).Print()
 	},
 }
 
@@ -155,52 +135,15 @@ var updateCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		pm := detectPackageManager()
 		if pm == "" {
-			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", "PKG_NO_PM").Print()
+			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		switch pm {
-		case "apt":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "update", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "apt", "update").Run()
-		case "dnf":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "update", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "dnf", "check-update").Run()
-		case "yum":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "update", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "yum", "check-update").Run()
-		case "pacman":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "update", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "pacman", "-Sy").Run()
-		case "zypper":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "update", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "zypper", "refresh").Run()
-		case "apk":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "update", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "apk", "update").Run()
-		}
+		// dnf/yum check-update exits 100 when updates are available, so the
+		// result is reported regardless of the exit code as before.
+		_ = runPkgAction(pm, "update", nil)
 
-		output.NewSuccess(map[string]interface{}{"action": "update", "manager": pm, "status": "updated"}).Print()
+		output.NewSuccess(map[string]interface{}{"action": "update", "manager": pm, "status": actionStatus("updated")}).Print()
 	},
 }
 
@@ -210,52 +153,16 @@ var upgradeCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		pm := detectPackageManager()
 		if pm == "" {
-			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", "PKG_NO_PM").Print()
+			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		switch pm {
-		case "apt":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "upgrade", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "apt", "upgrade", "-y").Run()
-		case "dnf":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "upgrade", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "dnf", "upgrade", "-y").Run()
-		case "yum":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "upgrade", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "yum", "upgrade", "-y").Run()
-		case "pacman":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "upgrade", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "pacman", "-Syu").Run()
-		case "zypper":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "upgrade", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "zypper", "update", "-y").Run()
-		case "apk":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "upgrade", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "apk", "upgrade").Run()
+		if err := runPkgAction(pm, "upgrade", nil); err != nil {
+			output.NewError(fmt.Sprintf("falha ao atualizar pacotes: %s", err.Error()), nuxerrors.ErrCodePkgUpdate).WithCause(err).Print()
+			return
 		}
 
-		output.NewSuccess(map[string]interface{}{"action": "upgrade", "manager": pm, "status": "upgraded"}).Print()
+		output.NewSuccess(map[string]interface{}{"action": "upgrade", "manager": pm, "status": actionStatus("upgraded")}).Print()
 	},
 }
 
@@ -265,52 +172,16 @@ var cleanCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		pm := detectPackageManager()
 		if pm == "" {
-			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", "PKG_NO_PM").Print()
+			output.NewError("Nenhum gerenciador de pacotes suportado encontrado", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		switch pm {
-		case "apt":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "clean", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "apt", "clean").Run()
-		case "dnf":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "clean", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "dnf", "clean", "all").Run()
-		case "yum":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "clean", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "yum", "clean", "all").Run()
-		case "pacman":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "clean", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "pacman", "-Sc").Run()
-		case "zypper":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "clean", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "zypper", "clean").Run()
-		case "apk":
-			if dryRun {
-				output.NewInfo(map[string]interface{}{"action": "clean", "manager": pm, "dry_run": true}).Print()
-				return
-			}
-			exec.Command("sudo", "apk", "cache", "clean").Run()
+		if err := runPkgAction(pm, "clean", nil); err != nil {
+			output.NewError(fmt.Sprintf("falha ao limpar cache: %s", err.Error()), nuxerrors.ErrCodePkgClean).WithCause(err).Print()
+			return
 		}
 
-		output.NewSuccess(map[string]interface{}{"action": "clean", "manager": pm, "status": "cleaned"}).Print()
+		output.NewSuccess(map[string]interface{}{"action": "clean", "manager": pm, "status": actionStatus("cleaned")}).Print()
 	},
 }
 
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/vault"
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	vault.SetPassphraseFunc(promptPassphrase)
+}
+
+// promptPassphrase asks for the vault or bundle passphrase on the
+// controlling terminal, so it works even when stdin and stdout are redirected
+func promptPassphrase(what string, confirm bool) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		env := vault.PassphraseEnv
+		if what == "bundle" {
+			env = vault.BundlePassphraseEnv
+		}
+		return nil, nuxerrors.New(nuxerrors.ErrCodeVault, fmt.Sprintf("no terminal to ask for the %s passphrase", what)).
+			WithHint("set " + env)
+	}
+	defer tty.Close()
+
+	prompt := fmt.Sprintf("%s passphrase: ", strings.ToUpper(what[:1])+what[1:])
+	if confirm {
+		prompt = fmt.Sprintf("New %s passphrase: ", what)
+	}
+	pass, err := readHidden(tty, prompt)
+	if err != nil || !confirm {
+		return pass, err
+	}
+	again, err := readHidden(tty, "Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pass, again) {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "passphrases do not match")
+	}
+	return pass, nil
+}
+
+// readHidden reads a line from tty with echo turned off
+func readHidden(tty *os.File, prompt string) ([]byte, error) {
+	fmt.Fprint(tty, prompt)
+	defer fmt.Fprintln(tty)
+
+	fd := int(tty.Fd())
+	if state, err := unix.IoctlGetTermios(fd, unix.TCGETS); err == nil {
+		hidden := *state
+		hidden.Lflag &^= unix.ECHO
+		hidden.Lflag |= unix.ICANON | unix.ISIG
+		if err := unix.IoctlSetTermios(fd, unix.TCSETS, &hidden); err == nil {
+			defer unix.IoctlSetTermios(fd, unix.TCSETS, state)
+		}
+	}
+
+	line, err := bufio.NewReader(tty).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// readSecret reads the value of a secret from stdin when it is piped, or else
+// asks for it on the terminal, so it never lands in the shell history
+func readSecret(name string) (string, error) {
+	if !isTerminal(os.Stdin) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read %s from stdin: %w", name, err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to open terminal: %w", err)
+	}
+	defer tty.Close()
+	value, err := readHidden(tty, fmt.Sprintf("Value for %s: ", name))
+	return string(value), err
+}
@@ -1,13 +1,16 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/skill"
+	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -30,7 +33,13 @@ var pluginListCmd = &cobra.Command{
 
 		files, err := os.ReadDir(skillsDir)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to read skills directory: %s", err.Error()), "PLUGIN_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to read skills directory: %s", err.Error()), nuxerrors.ErrCodePlugin).WithCause(err).Print()
+			return
+		}
+
+		v, err := vault.Load()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
 			return
 		}
 
@@ -45,7 +54,7 @@ var pluginListCmd = &cobra.Command{
 				"name":      skillName,
 				"type":      "skill",
 				"file":      file.Name(),
-				"installed": checkSkillInstalled(skillName),
+				"installed": v.IsInstalled(skillName),
 			}
 			items = append(items, item)
 		}
@@ -69,7 +78,7 @@ var pluginInfoCmd = &cobra.Command{
 
 		content, err := os.ReadFile(skillFile)
 		if err != nil {
-			output.NewError(fmt.Sprintf("plugin not found: %s", pluginName), "PLUGIN_NOT_FOUND").Print()
+			output.NewError(fmt.Sprintf("plugin not found: %s", pluginName), nuxerrors.ErrCodeNotFound).Print()
 			return
 		}
 
@@ -80,39 +89,43 @@ var pluginInfoCmd = &cobra.Command{
 	},
 }
 
-func checkSkillInstalled(skillName string) bool {
-	// Check if skill is in vault as installed
-	vaultPath := os.Getenv("HOME") + "/.skills/.nux.json"
-	if _, err := os.Stat(vaultPath); err != nil {
-		return false
-	}
-
-	data, err := os.ReadFile(vaultPath)
-	if err != nil {
-		return false
-	}
-
-	var vault map[string]interface{}
-	if err := json.Unmarshal(data, &vault); err != nil {
-		return false
-	}
-
-	installed, ok := vault["installed_skills"].([]interface{})
-	if !ok {
-		return false
-	}
-
-	for _, s := range installed {
-		if s == skillName {
-			return true
+var pluginRunCmd = &cobra.Command{
+	Use:   "run <plugin> [-- args...]",
+	Short: "Run the tool of a plugin",
+	Long: `Run the command of an installed plugin with the terminal attached. Secrets
+are handed to it as environment variables with --env NAME=vault://secret, so
+they never appear on the command line or in the shell history.`,
+	Example: `  nux plugin run gh --env GH_TOKEN=vault://github -- repo list`,
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := skill.LoadSkillFromMD(args[0])
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeNotFound).WithCause(err).Print()
+			return
+		}
+		fields := strings.Fields(s.Commands)
+		if len(fields) == 0 {
+			output.NewError(fmt.Sprintf("plugin %s has no command to run", s.Name), nuxerrors.ErrCodePlugin).Print()
+			return
 		}
-	}
 
-	return false
+		env, _ := cmd.Flags().GetStringArray("env")
+		ctx, err := secretEnv(commandContext(), nil, env)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		if err := adapter.RunInteractive(ctx, newExecutor(), fields[0], args[1:]...); err != nil {
+			output.NewError(fmt.Sprintf("%s failed: %s", fields[0], err.Error()), nuxerrors.ErrCodePlugin).WithCause(err).Print()
+		}
+	},
 }
 
 func init() {
 	pluginCmd.AddCommand(pluginListCmd)
 	pluginCmd.AddCommand(pluginInfoCmd)
+
+	pluginRunCmd.Flags().StringArray("env", nil, "Set NAME=value for the plugin; vault://name is read from the vault")
+	pluginCmd.AddCommand(pluginRunCmd)
 	rootCmd.AddCommand(pluginCmd)
 }
@@ -6,12 +6,11 @@ import (
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var processExecutor core.Executor = &core.RealExecutor{}
-
 var processCmd = &cobra.Command{
 	Use:   "process",
 	Short: "Process management",
@@ -22,10 +21,10 @@ var processListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List processes",
 	Run: func(cmd *cobra.Command, args []string) {
-		out, err := processExecutor.CombinedOutput("ps", "aux")
+		out, err := combinedOutput("ps", "aux")
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list processes: %s", err.Error()), "PROCESS_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to list processes: %s", err.Error()), nuxerrors.ErrCodeProcess).WithCause(err).Print()
 			return
 		}
 
@@ -59,7 +58,9 @@ var processListCmd = &cobra.Command{
 			items = append(items, item)
 		}
 
-		output.NewList(items, len(items)).WithMessage("Process list").Print()
+		output.NewList(items, len(items)).
+			WithColumns("pid", "user", "cpu", "mem", "vsz", "rss", "tty", "stat", "start", "time", "command").
+			WithMessage("Process list").Print()
 	},
 }
 
@@ -75,29 +76,18 @@ var processKillCmd = &cobra.Command{
 			signal = core.SanitizeInput(args[1])
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"pid":     pid,
-				"signal":  signal,
-				"dry_run": true,
-				"command": fmt.Sprintf("kill -%s %s", signal, pid),
-			}).Print()
-			return
-		}
 
-		_, err := processExecutor.CombinedOutput("kill", "-"+signal, pid)
+		_, err := newExecutor().Exec(commandContext(), "kill", "-"+signal, pid)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to kill process: %s", err.Error()), "PROCESS_KILL_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to kill process: %s", err.Error()), nuxerrors.ErrCodeProcess).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"pid":    pid,
 			"signal": signal,
-			"status": "killed",
+			"status": actionStatus("killed"),
 		}).Print()
 	},
 }
@@ -111,20 +101,20 @@ var processInfoCmd = &cobra.Command{
 
 		// Validate PID is numeric
 		if _, err := strconv.Atoi(pid); err != nil {
-			output.NewError("invalid PID: must be numeric", "PROCESS_INVALID_PID").Print()
+			output.NewError("invalid PID: must be numeric", nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
 		// Get process info using ps
-		out, err := processExecutor.CombinedOutput("ps", "-p", pid, "-o", "pid,user,%cpu,%mem,vsz,rss,tty,stat,start,time,comm")
+		out, err := combinedOutput("ps", "-p", pid, "-o", "pid,user,%cpu,%mem,vsz,rss,tty,stat,start,time,comm")
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to get process info: %s", err.Error()), "PROCESS_INFO_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to get process info: %s", err.Error()), nuxerrors.ErrCodeProcess).WithCause(err).Print()
 			return
 		}
 
 		// Also get full command line
-		cmdlineOut, _ := processExecutor.CombinedOutput("cat", fmt.Sprintf("/proc/%s/cmdline", pid))
+		cmdlineOut, _ := combinedOutput("cat", fmt.Sprintf("/proc/%s/cmdline", pid))
 
 		output.NewSuccess(map[string]interface{}{
 			"pid":     pid,
@@ -135,7 +125,6 @@ var processInfoCmd = &cobra.Command{
 }
 
 func init() {
-	processKillCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	processCmd.AddCommand(processListCmd)
 	processCmd.AddCommand(processKillCmd)
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"strings"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
@@ -36,7 +37,7 @@ var protonStatusCmd = &cobra.Command{
 		}
 
 		// Get status from protonvpn-cli
-		out, err := exec.Command("protonvpn", "status").CombinedOutput()
+		out, err := combinedOutput("protonvpn", "status")
 		if err != nil {
 			output.NewInfo(map[string]interface{}{
 				"status":   "disconnected",
@@ -68,32 +69,32 @@ var protonVpnInstallCmd = &cobra.Command{
 		output.NewInfo("Installing ProtonVPN CLI...").Print()
 
 		// Detect distro
-		out, err := exec.Command("cat", "/etc/os-release").CombinedOutput()
+		out, err := combinedOutput("cat", "/etc/os-release")
 		if err != nil {
-			output.NewError("Failed to detect Linux distribution", "PROTON_INSTALL_ERROR").Print()
+			output.NewError("Failed to detect Linux distribution", nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 
 		osRelease := strings.ToLower(string(out))
-		var installCmd *exec.Cmd
+		var installScript string
 
 		switch {
 		case strings.Contains(osRelease, "ubuntu") || strings.Contains(osRelease, "debian"):
-			installCmd = exec.Command("sh", "-c", "wget -q https://protonvpn.com/download/protonvpn-stable-release/protonvpn-stable-release_1.0.0-1_all.deb && sudo dpkg -i protonvpn-stable-release_1.0.0-1_all.deb && sudo apt-get update && sudo apt-get install -y protonvpn-cli")
+			installScript = "wget -q https://protonvpn.com/download/protonvpn-stable-release/protonvpn-stable-release_1.0.0-1_all.deb && sudo dpkg -i protonvpn-stable-release_1.0.0-1_all.deb && sudo apt-get update && sudo apt-get install -y protonvpn-cli"
 		case strings.Contains(osRelease, "fedora"):
-			installCmd = exec.Command("sh", "-c", "sudo dnf install -y https://protonvpn.com/download/protonvpn-stable-release/protonvpn-stable-release-1.0.0-1.noarch.rpm && sudo dnf install -y protonvpn-cli")
+			installScript = "sudo dnf install -y https://protonvpn.com/download/protonvpn-stable-release/protonvpn-stable-release-1.0.0-1.noarch.rpm && sudo dnf install -y protonvpn-cli"
 		case strings.Contains(osRelease, "rocky") || strings.Contains(osRelease, "rhel") || strings.Contains(osRelease, "centos"):
-			installCmd = exec.Command("sh", "-c", "sudo dnf install -y https://protonvpn.com/download/protonvpn-stable-release/protonvpn-stable-release-1.0.0-1.noarch.rpm && sudo dnf install -y protonvpn-cli")
+			installScript = "sudo dnf install -y https://protonvpn.com/download/protonvpn-stable-release/protonvpn-stable-release-1.0.0-1.noarch.rpm && sudo dnf install -y protonvpn-cli"
 		case strings.Contains(osRelease, "arch"):
-			installCmd = exec.Command("sh", "-c", "yay -S --noconfirm protonvpn-cli || (git clone https://aur.archlinux.org/protonvpn-cli.git && cd protonvpn-cli && makepkg -si --noconfirm)")
+			installScript = "yay -S --noconfirm protonvpn-cli || (git clone https://aur.archlinux.org/protonvpn-cli.git && cd protonvpn-cli && makepkg -si --noconfirm)"
 		default:
 			// Generic: download binary
-			installCmd = exec.Command("sh", "-c", "wget -q https://protonvpn.com/download/protonvpn-cli_linux -O /tmp/protonvpn && chmod +x /tmp/protonvpn && sudo mv /tmp/protonvpn /usr/local/bin/protonvpn")
+			installScript = "wget -q https://protonvpn.com/download/protonvpn-cli_linux -O /tmp/protonvpn && chmod +x /tmp/protonvpn && sudo mv /tmp/protonvpn /usr/local/bin/protonvpn"
 		}
 
-		out, err = installCmd.CombinedOutput()
+		out, err = combinedOutput("sh", "-c", installScript)
 		if err != nil {
-			output.NewError(fmt.Sprintf("Installation failed: %s\n%s", err.Error(), strings.TrimSpace(string(out))), "PROTON_INSTALL_ERROR").Print()
+			output.NewError(fmt.Sprintf("Installation failed: %s\n%s", err.Error(), strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 
@@ -115,21 +116,21 @@ var protonVpnLoginCmd = &cobra.Command{
 		passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
 
 		if username == "" {
-			output.NewError("Username required (use --username flag)", "PROTON_LOGIN_MISSING").Print()
+			output.NewError("Username required (use --username flag)", nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
 		if passwordStdin {
 			data, err := io.ReadAll(os.Stdin)
 			if err != nil {
-				output.NewError("Failed to read password from stdin", "PROTON_LOGIN_ERROR").Print()
+				output.NewError("Failed to read password from stdin", nuxerrors.ErrCodeInvalidInput).Print()
 				return
 			}
 			password = strings.TrimSpace(string(data))
 		}
 
 		if password == "" {
-			output.NewError("Password required (use --password flag or --password-stdin)", "PROTON_LOGIN_MISSING").Print()
+			output.NewError("Password required (use --password flag or --password-stdin)", nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
@@ -146,7 +147,7 @@ var protonVpnLoginCmd = &cobra.Command{
 		if err != nil {
 			protonCli, err = exec.LookPath("protonvpn")
 			if err != nil {
-				output.NewError("protonvpn-cli not found. Install it first: https://protonvpn.com/support/linux-cli", "PROTON_CLI_MISSING").Print()
+				output.NewError("protonvpn-cli not found. Install it first: https://protonvpn.com/support/linux-cli", nuxerrors.ErrCodeDependency).Print()
 				return
 			}
 		}
@@ -154,31 +155,31 @@ var protonVpnLoginCmd = &cobra.Command{
 		// Use protonvpn-cli with openvpn (if openvpn is available)
 		_, openvpnErr := exec.LookPath("openvpn")
 		if openvpnErr != nil {
-			output.NewError("openvpn not found. Install openvpn (dnf install openvpn) or use official ProtonVPN app.", "PROTON_DEPENDENCY_MISSING").Print()
+			output.NewError("openvpn not found. Install openvpn (dnf install openvpn) or use official ProtonVPN app.", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
 		// Try to initialize with OpenVPN credentials
 		// Note: The stdin input expects OpenVPN username/password, not account credentials
 		input := username + "\n" + password + "\n"
-		cmdRun := exec.Command(protonCli, "init")
-		cmdRun.Stdin = strings.NewReader(input)
-		out, err := cmdRun.CombinedOutput()
+		res, err := newExecutor().ExecWithInput(commandContext(), input, protonCli, "init")
+		out := res.Combined()
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("Login failed: %s\nNote: Make sure you're using OpenVPN credentials (not account credentials). Get them at: https://account.protonvpn.com/account", strings.TrimSpace(string(out))), "PROTON_LOGIN_ERROR").Print()
+			output.NewError(fmt.Sprintf("Login failed: %s\nNote: Make sure you're using OpenVPN credentials (not account credentials). Get them at: https://account.protonvpn.com/account", strings.TrimSpace(string(out))), nuxerrors.ErrCodeInvalidInput).Print()
 			return
 		}
 
 		// Save credentials to vault
 		v, err := vault.Load()
 		if err != nil {
-			v = vault.NewVault()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
 		}
 		v.APIKeys["proton_username"] = username
 		v.Config["proton_logged_in"] = true
 		if err := vault.Save(v); err != nil {
-			output.NewError(fmt.Sprintf("Failed to save credentials to vault: %s", err.Error()), "VAULT_SAVE_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to save credentials to vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
 			return
 		}
 
@@ -197,7 +198,7 @@ var protonVpnLoginCmd = &cobra.Command{
 		Short: "List Proton VPN servers with availability",
 		Run: func(cmd *cobra.Command, args []string) {
 			// Try protonvpn-cli first
-			out, err := exec.Command("protonvpn", "servers").CombinedOutput()
+			out, err := combinedOutput("protonvpn", "servers")
 			if err == nil {
 				// Parse output and display
 				lines := strings.Split(strings.TrimSpace(string(out)), "\n")
@@ -228,7 +229,7 @@ var protonVpnLoginCmd = &cobra.Command{
 				// Try alternative endpoint
 				resp, err = client.Get("https://api.protonmail.ch/vpn/logicals")
 				if err != nil {
-					output.NewError(fmt.Sprintf("Failed to fetch servers: %s", err.Error()), "PROTON_LIST_ERROR").Print()
+					output.NewError(fmt.Sprintf("Failed to fetch servers: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 					return
 				}
 			}
@@ -302,17 +303,17 @@ var protonVpnConnectCmd = &cobra.Command{
 
 		output.NewInfo(fmt.Sprintf("Connecting to Proton VPN (%s)...", server)).Print()
 
-		var out []byte
+		var out string
 		var err error
 
 		if server == "fastest" {
-			out, err = exec.Command("protonvpn", "connect", "--fastest").CombinedOutput()
+			out, err = combinedOutput("protonvpn", "connect", "--fastest")
 		} else {
-			out, err = exec.Command("protonvpn", "connect", server).CombinedOutput()
+			out, err = combinedOutput("protonvpn", "connect", server)
 		}
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to connect: %s", strings.TrimSpace(string(out))), "PROTON_VPN_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to connect: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 
@@ -331,9 +332,9 @@ var protonVpnFastestCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		output.NewInfo("Connecting to fastest Proton VPN server...").Print()
 
-		out, err := exec.Command("protonvpn", "connect", "--fastest").CombinedOutput()
+		out, err := combinedOutput("protonvpn", "connect", "--fastest")
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to connect to fastest server: %s", strings.TrimSpace(string(out))), "PROTON_VPN_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to connect to fastest server: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 
@@ -351,9 +352,9 @@ var protonVpnDisconnectCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		output.NewInfo("Disconnecting from Proton VPN...").Print()
 
-		out, err := exec.Command("protonvpn", "disconnect").CombinedOutput()
+		out, err := combinedOutput("protonvpn", "disconnect")
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to disconnect: %s", strings.TrimSpace(string(out))), "PROTON_VPN_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to disconnect: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 
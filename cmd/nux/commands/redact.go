@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/inventory"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/rsdenck/nux/internal/redact"
+	"github.com/rsdenck/nux/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// firstUserUID is where the accounts of people start in /etc/passwd; those
+// below belong to the system and name nothing about the host
+const firstUserUID = 1000
+
+// redactionRules returns the redaction rules of a provider: the configured
+// ones, or every category except for an Ollama running on this host. An
+// Ollama on the LAN or hosted elsewhere is masked like any other provider.
+func redactionRules(provider string, v *vault.Vault) string {
+	if v != nil {
+		if rules, ok := v.GetRedaction(provider); ok {
+			return rules
+		}
+	}
+	if provider == "ollama" && isLoopback(ollamaHost(v)) {
+		return redact.Off
+	}
+	return redact.All
+}
+
+// isLoopback reports whether the host of a server URL resolves to loopback
+// addresses only. A name that cannot be resolved is not trusted as local.
+func isLoopback(server string) bool {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
+// newRedactor returns the redactor of what is sent to a provider, knowing
+// the names of this host, of the --host target and of the inventory
+func newRedactor(provider string, v *vault.Vault) (*redact.Redactor, error) {
+	categories, err := redact.ParseRules(redactionRules(provider, v))
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "invalid redaction rules of "+provider).
+			WithHint(fmt.Sprintf("set them with 'nux ask config --provider %s --redact all'", provider))
+	}
+	if len(categories) == 0 {
+		return redact.New(nil, nil, nil), nil
+	}
+	return redact.New(categories, hostNames(), userNames()), nil
+}
+
+// hostNames returns the names the host and the hosts nux manages go by
+func hostNames() []string {
+	var names []string
+	if h, err := os.Hostname(); err == nil {
+		names = append(names, h, strings.Split(h, ".")[0])
+	}
+	if flagHost != "" {
+		names = append(names, flagHost)
+	}
+	if inv, err := inventory.Load(); err == nil {
+		for _, h := range inv.Hosts {
+			names = append(names, h.Name, h.SSHProfile())
+		}
+	}
+	return names
+}
+
+// userNames returns the accounts of people on the host the data comes from:
+// with --host, the target, read through the executor, else this host and the
+// user nux runs as
+func userNames() []string {
+	if flagHost != "" {
+		res, err := newExecutor().Exec(adapter.WithReadOnly(commandContext()), "getent", "passwd")
+		if err != nil || res == nil {
+			return nil
+		}
+		return passwdUsers(strings.NewReader(res.Stdout))
+	}
+
+	var names []string
+	if u, err := user.Current(); err == nil && u.Uid != "0" {
+		names = append(names, u.Username)
+	}
+	f, err := os.Open("/etc/passwd")
+	if err != nil {
+		return names
+	}
+	defer f.Close()
+	return append(names, passwdUsers(f)...)
+}
+
+// passwdUsers returns the accounts of people in a passwd file
+func passwdUsers(r io.Reader) []string {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || fields[0] == "nobody" {
+			continue
+		}
+		if uid, err := strconv.Atoi(fields[2]); err == nil && uid >= firstUserUID && uid < 65534 {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
+
+// canonicalProvider returns the name a provider goes by, which its
+// redaction rules are stored under
+func canonicalProvider(name string) string {
+	switch name {
+	case "":
+		return "ollama"
+	case "anthropic":
+		return "claude"
+	}
+	return name
+}
+
+var askRedactCmd = &cobra.Command{
+	Use:   "redact [text]",
+	Short: "Show what a provider would be sent, once redacted",
+	Long: `Redact text, or standard input, with the rules of a provider and print what
+it would be sent. Every question, host context and tool output sent to a
+provider goes through the same pass, and the placeholders in its answers are
+mapped back to the real values locally.
+
+Rules are set per provider with 'nux ask config --provider <name> --redact':
+all (the default, except for an Ollama on this host), off (the default for
+an Ollama on localhost), or a
+list of ` + strings.Join(redact.Categories, ", ") + `.`,
+	Example: `  nux ask redact "ssh admin@10.0.4.12 fails on db01.corp"
+  journalctl -u nginx -n 50 | nux ask redact --provider claude`,
+	Run: func(cmd *cobra.Command, args []string) {
+		provider, _ := cmd.Flags().GetString("provider")
+
+		text := strings.Join(args, " ")
+		if len(args) == 0 {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				output.NewError(fmt.Sprintf("failed to read input: %s", err.Error()), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+				return
+			}
+			text = string(data)
+		}
+		v, err := llmVault(provider)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		provider = canonicalProvider(provider)
+		r, err := newRedactor(provider, v)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeConfig).WithCause(err).Print()
+			return
+		}
+
+		redacted := r.Redact(text)
+		if plainText() {
+			fmt.Print(redacted)
+			if !strings.HasSuffix(redacted, "\n") {
+				fmt.Println()
+			}
+			return
+		}
+		output.NewSuccess(map[string]interface{}{
+			"provider": provider,
+			"rules":    redactionRules(provider, v),
+			"masked":   r.Count(),
+			"text":     redacted,
+		}).WithMessage("Redacted text").Print()
+	},
+}
+
+func init() {
+	askRedactCmd.Flags().String("provider", "", "Provider whose rules apply ("+llmProviders+", or an endpoint)")
+	askCmd.AddCommand(askRedactCmd)
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/redact"
+	"github.com/rsdenck/nux/internal/vault"
+)
+
+// Only an Ollama running on this host sees the data unmasked by default
+func TestRedactionRulesOllamaHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"", redact.Off},
+		{"http://localhost:11434", redact.Off},
+		{"http://127.0.0.1:11434", redact.Off},
+		{"[::1]:11434", redact.Off},
+		{"http://192.168.130.25:11434", redact.All},
+		{"https://ollama.example.com", redact.All},
+		{"http://unresolvable.invalid:11434", redact.All},
+	}
+	for _, tt := range tests {
+		v := &vault.Vault{Config: map[string]interface{}{}}
+		if tt.host != "" {
+			v.Config["ollama_host"] = tt.host
+		}
+		if got := redactionRules("ollama", v); got != tt.want {
+			t.Errorf("redactionRules(ollama at %q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+	if got := redactionRules("openai", nil); got != redact.All {
+		t.Errorf("redactionRules(openai) = %q, want %q", got, redact.All)
+	}
+}
+
+// Only the accounts of people name anything about the host
+func TestPasswdUsers(t *testing.T) {
+	passwd := `root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+alice:x:1000:1000:Alice:/home/alice:/bin/bash
+nobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin
+deploy:x:1001:1001::/home/deploy:/bin/sh`
+	want := []string{"alice", "deploy"}
+	if got := passwdUsers(strings.NewReader(passwd)); !reflect.DeepEqual(got, want) {
+		t.Errorf("passwdUsers() = %v, want %v", got, want)
+	}
+}
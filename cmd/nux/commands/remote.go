@@ -7,12 +7,11 @@ import (
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var remoteExecutor core.Executor = &core.RealExecutor{}
-
 var remoteCmd = &cobra.Command{
 	Use:   "remote",
 	Short: "Remote SSH connections",
@@ -27,7 +26,7 @@ var remoteListCmd = &cobra.Command{
 		sshConfig := os.Getenv("HOME") + "/.ssh/config"
 		file, err := os.Open(sshConfig)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to read SSH config: %s", err.Error()), "REMOTE_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to read SSH config: %s", err.Error()), nuxerrors.ErrCodeConfig).WithCause(err).Print()
 			return
 		}
 		defer file.Close()
@@ -62,35 +61,23 @@ var remoteExecCmd = &cobra.Command{
 		host := core.SanitizeInput(args[0])
 		command := core.SanitizeInput(strings.Join(args[1:], " "))
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"host":    host,
-				"command": command,
-				"dry_run": true,
-				"ssh_cmd": fmt.Sprintf("ssh %s '%s'", host, command),
-			}).Print()
-			return
-		}
 
-		out, err := remoteExecutor.CombinedOutput("ssh", host, command)
+		res, err := newExecutor().Exec(commandContext(), "ssh", host, command)
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("remote execution failed: %s", err.Error()), "REMOTE_EXEC_ERROR").Print()
+			output.NewError(fmt.Sprintf("remote execution failed: %s", err.Error()), nuxerrors.ErrCodeCommandFailed).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"host":    host,
 			"command": command,
-			"output":  out,
+			"output":  res.Stdout,
 		}).Print()
 	},
 }
 
 func init() {
-	remoteExecCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	remoteCmd.AddCommand(remoteListCmd)
 	remoteCmd.AddCommand(remoteExecCmd)
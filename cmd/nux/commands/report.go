@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// outFile is the --out file, created on the first write
+var outFile *lazyFile
+
+// applyFormat turns --format into the matching output flags
+func applyFormat() error {
+	switch flagFormat {
+	case "", "table":
+		return nil
+	case "json":
+		flagJSON = true
+	case "yaml":
+		flagYAML = true
+	case output.FormatHTML, output.FormatMarkdown, output.FormatCSV:
+		if flagJSON || flagYAML {
+			return fmt.Errorf("--format %s cannot be used with --json or --yaml", flagFormat)
+		}
+		if flagQuery != "" || flagTemplate != "" {
+			return fmt.Errorf("--format %s cannot be used with --query or --template", flagFormat)
+		}
+	default:
+		return fmt.Errorf("unknown format %q (available: table, json, yaml, html, md, csv)", flagFormat)
+	}
+	return nil
+}
+
+// isReportFormat reports whether --format asks for an HTML, Markdown or CSV
+// report
+func isReportFormat() bool {
+	switch flagFormat {
+	case output.FormatHTML, output.FormatMarkdown, output.FormatCSV:
+		return true
+	}
+	return false
+}
+
+// setReport installs the report renderer and the --out file. HTML and
+// Markdown reports describe the host they were taken on, so its profile is
+// detected up front.
+func setReport(cmd *cobra.Command, args []string) error {
+	if flagOut != "" {
+		if info, err := os.Stat(filepath.Dir(flagOut)); err != nil || !info.IsDir() {
+			return fmt.Errorf("cannot write %s: directory %s does not exist", flagOut, filepath.Dir(flagOut))
+		}
+		outFile = &lazyFile{path: flagOut}
+		output.SetOut(outFile)
+	}
+	if !isReportFormat() {
+		return nil
+	}
+
+	info := output.ReportInfo{
+		Command:   strings.Join(append([]string{cmd.CommandPath()}, args...), " "),
+		Generated: time.Now(),
+	}
+	if flagFormat != output.FormatCSV {
+		if flagOn != "" {
+			info.Host = flagOn
+		} else {
+			info.Host = getHostname()
+			// A report without the profile is still worth having
+			info.Profile, _ = detectProfile(newExecutor())
+		}
+	}
+	return output.SetReport(flagFormat, info)
+}
+
+// closeOut closes the --out file if anything was written to it
+func closeOut() {
+	if outFile != nil {
+		outFile.Close()
+	}
+}
+
+// lazyFile creates its file on the first write, so a command that fails
+// leaves no empty report behind
+type lazyFile struct {
+	path string
+	f    *os.File
+	err  error
+}
+
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if l.f == nil && l.err == nil {
+		l.f, l.err = os.Create(l.path)
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.f.Write(p)
+}
+
+func (l *lazyFile) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
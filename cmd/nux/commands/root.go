@@ -1,9 +1,15 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/logger"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
@@ -22,12 +28,35 @@ var (
 	flagVerbose bool
 	flagNoColor bool
 	flagLogFile string
+	flagHost    string
+	flagRecord  string
+	flagElevate string
+
+	// List view flags
+	flagColumns   []string
+	flagSortBy    string
+	flagFilters   []string
+	flagNoHeaders bool
+	flagQuery     string
+	flagTemplate  string
+	flagWatch     string
+
+	// Report flags
+	flagFormat string
+	flagOut    string
+
+	// Fan-out flags
+	flagOn          string
+	flagParallel    int
+	flagHostTimeout int
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "nux",
 	Short: "NUX — Linux CLI Manager",
 	Long:  `Uma CLI de nível de produção para administração abrangente de sistemas Linux.`,
+	// Execute reports errors in the output format, with their exit code
+	SilenceErrors: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		// ASCII Art com cor laranja (208)
 		fmt.Print("\033[38;5;208m")
@@ -97,6 +126,9 @@ var rootCmd = &cobra.Command{
 		fmt.Println("│  --quiet       Quiet mode                      │")
 		fmt.Println("│  --dry-run     Simulate actions                │")
 		fmt.Println("│  --timeout     Command timeout                 │")
+		fmt.Println("│  --host        Run on an ssh profile           │")
+		fmt.Println("│  --on          Run on inventory hosts/groups   │")
+		fmt.Println("│  --watch       Re-run and redraw every n secs  │")
 		fmt.Println("│  --no-color    Disable colors                  │")
 		fmt.Println("│  -v            Show version                    │")
 		fmt.Println("└────────────────────────────────────────────────┘")
@@ -130,7 +162,47 @@ var rootCmd = &cobra.Command{
 		if err := logger.Init(opts); err != nil {
 			fmt.Fprintf(os.Stderr, "Falha ao inicializar logger: %v\n", err)
 		}
+		if err := applyFormat(); err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+		}
 		output.SetFormat(flagJSON, flagYAML)
+		output.SetView(output.View{
+			Columns:   flagColumns,
+			SortBy:    flagSortBy,
+			Filters:   flagFilters,
+			NoHeaders: flagNoHeaders,
+		})
+		if err := setOutputRendering(); err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+		}
+
+		if cmd.Flags().Changed("timeout") {
+			commandTimeout = time.Duration(flagTimeout) * time.Second
+		}
+
+		if err := validateHost(cmd); err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+		}
+		if err := validateElevate(); err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+		}
+		if err := setReport(cmd, args); err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+		}
+		interval, err := validateWatch(cmd)
+		if err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+		}
+		if interval > 0 {
+			installWatch(cmd, interval)
+		}
+		if flagOn != "" {
+			os.Exit(runFanOut(cmd))
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		saveRecording()
+		printDryRunPlan()
 	},
 }
 
@@ -138,9 +210,19 @@ func Execute(version, commit, date string) {
 	if version != "" {
 		rootCmd.Version = version
 	}
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sessionCtx = ctx
+
+	err := rootCmd.ExecuteContext(ctx)
+	closeOut()
+	if err != nil {
+		// cobra only fails on bad flags, arguments or commands
+		exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+	}
+	if code := output.ExitStatus(); code != 0 {
+		stop()
+		os.Exit(code)
 	}
 }
 
@@ -150,8 +232,30 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&flagYAML, "yaml", false, "Saída em formato YAML")
 	rootCmd.PersistentFlags().BoolVar(&flagQuiet, "quiet", false, "Suprime a saída")
 	rootCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "Simula a execução sem fazer alterações")
-	rootCmd.PersistentFlags().IntVar(&flagTimeout, "timeout", 30, "Tempo limite em segundos")
+	rootCmd.PersistentFlags().IntVar(&flagTimeout, "timeout", 30, "Tempo limite em segundos das respostas de IA e, quando informado, de cada comando (0 desativa)")
 	rootCmd.PersistentFlags().BoolVar(&flagVerbose, "verbose", false, "Ativa o log detalhado")
 	rootCmd.PersistentFlags().StringVar(&flagLogFile, "log-file", "", "Caminho do arquivo de log (ativa o log de depuração para arquivo)")
 	rootCmd.PersistentFlags().BoolVar(&flagNoColor, "no-color", false, "Desativa saída colorida")
+	rootCmd.PersistentFlags().StringVar(&flagHost, "host", "", "Executa o comando em outro host (perfil do ~/.ssh/config)")
+	rootCmd.RegisterFlagCompletionFunc("host", completeHost)
+	rootCmd.PersistentFlags().StringVar(&flagOn, "on", "", "Executa o comando em grupos/hosts do inventário (separados por vírgula)")
+	rootCmd.PersistentFlags().IntVar(&flagParallel, "parallel", 10, "Número máximo de hosts em paralelo com --on")
+	rootCmd.PersistentFlags().IntVar(&flagHostTimeout, "host-timeout", 120, "Tempo limite em segundos por host com --on (0 desativa)")
+	rootCmd.RegisterFlagCompletionFunc("on", completeInventoryTarget)
+	rootCmd.PersistentFlags().StringVar(&flagElevate, "elevate", adapter.ElevateAuto, "Ferramenta para obter root: auto, sudo, doas, pkexec ou none")
+	rootCmd.RegisterFlagCompletionFunc("elevate", cobra.FixedCompletions(
+		[]string{adapter.ElevateAuto, adapter.ElevateSudo, adapter.ElevateDoas, adapter.ElevatePkexec, adapter.ElevateNone}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentFlags().StringSliceVar(&flagColumns, "columns", nil, "Colunas exibidas em listas, na ordem dada (ex: name,status,pid)")
+	rootCmd.PersistentFlags().StringVar(&flagSortBy, "sort-by", "", "Ordena listas pela coluna (prefixo - para ordem decrescente)")
+	rootCmd.PersistentFlags().StringArrayVar(&flagFilters, "filter", nil, "Mantém as linhas onde coluna=valor (pode repetir)")
+	rootCmd.PersistentFlags().BoolVar(&flagNoHeaders, "no-headers", false, "Omite cabeçalhos e bordas em listas de texto")
+	rootCmd.PersistentFlags().StringVar(&flagQuery, "query", "", "Seleciona campos da saída (ex: '.items[*].name', '.data.hostname')")
+	rootCmd.PersistentFlags().StringVar(&flagTemplate, "template", "", "Formata a saída com um template Go (ex: '{{range .Items}}{{.Name}}{{\"\\n\"}}{{end}}')")
+	rootCmd.PersistentFlags().StringVar(&flagWatch, "watch", "", "Executa o comando de novo a cada intervalo e redesenha a saída (ex: 2s, 500ms)")
+	rootCmd.PersistentFlags().StringVar(&flagFormat, "format", "", "Formato da saída: table, json, yaml, html, md ou csv")
+	rootCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		[]string{"table", "json", "yaml", output.FormatHTML, output.FormatMarkdown, output.FormatCSV}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentFlags().StringVar(&flagOut, "out", "", "Grava a saída em um arquivo em vez do terminal")
+	rootCmd.PersistentFlags().StringVar(&flagRecord, "record", "", "Grava os comandos executados e suas saídas em um arquivo de fixture")
+	rootCmd.PersistentFlags().MarkHidden("record")
 }
@@ -3,10 +3,10 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/spf13/cobra"
 )
 
@@ -81,74 +81,74 @@ Output is categorized automatically.`,
 
 func searchFiles(term string) {
 	// Try fd first, then find
-	if hasCommand("fd") {
-		exec.Command("fd", "-t", "f", "-t", "d", term).Run()
-	} else if hasCommand("find") {
-		exec.Command("find", "/", "-name", "*"+term+"*", "-maxdepth", "5").Run()
+	if commandAvailable("fd") {
+		searchRun("fd", "-t", "f", "-t", "d", term)
+	} else if commandAvailable("find") {
+		searchRun("find", "/", "-name", "*"+term+"*", "-maxdepth", "5")
 	} else {
-		exec.Command("ls", "-la", "/").Run()
+		searchRun("ls", "-la", "/")
 	}
 }
 
 func searchContents(term string) {
-	if hasCommand("rg") {
-		exec.Command("rg", "-i", term, "--max-count=5").Run()
-	} else if hasCommand("grep") {
-		exec.Command("grep", "-r", "-i", term, "/etc", "--include=*").Run()
+	if commandAvailable("rg") {
+		searchRun("rg", "-i", term, "--max-count=5")
+	} else if commandAvailable("grep") {
+		searchRun("grep", "-r", "-i", term, "/etc", "--include=*")
 	}
 }
 
 func searchProcesses(term string) {
-	if hasCommand("pgrep") {
-		exec.Command("pgrep", "-l", term).Run()
+	if commandAvailable("pgrep") {
+		searchRun("pgrep", "-l", term)
 	}
-	if hasCommand("ps") {
-		exec.Command("ps", "aux").Run() // Would need grep piping
+	if commandAvailable("ps") {
+		searchGrep(term, "ps", "aux")
 	}
 }
 
 func searchServices(term string) {
-	if hasCommand("systemctl") {
-		exec.Command("systemctl", "list-units", "--all").Run() // Would need grep
+	if commandAvailable("systemctl") {
+		searchGrep(term, "systemctl", "list-units", "--all")
 	}
 }
 
 func searchInstalledPackages(term string) {
-	if hasCommand("dpkg") {
-		exec.Command("sh", "-c", "dpkg -l | grep -i "+term).Run()
-	} else if hasCommand("rpm") {
-		exec.Command("sh", "-c", "rpm -qa | grep -i "+term).Run()
-	} else if hasCommand("pacman") {
-		exec.Command("pacman", "-Qs", term).Run()
-	} else if hasCommand("apk") {
-		exec.Command("apk", "info", term).Run()
+	if commandAvailable("dpkg") {
+		searchGrep(term, "dpkg", "-l")
+	} else if commandAvailable("rpm") {
+		searchGrep(term, "rpm", "-qa")
+	} else if commandAvailable("pacman") {
+		searchRun("pacman", "-Qs", term)
+	} else if commandAvailable("apk") {
+		searchRun("apk", "info", term)
 	}
 }
 
 func searchAvailablePackages(term string) {
-	if hasCommand("apt") {
-		exec.Command("apt", "search", term).Run()
-	} else if hasCommand("dnf") {
-		exec.Command("dnf", "search", term).Run()
-	} else if hasCommand("pacman") {
-		exec.Command("pacman", "-Ss", term).Run()
-	} else if hasCommand("zypper") {
-		exec.Command("zypper", "search", term).Run()
+	if commandAvailable("apt") {
+		searchRun("apt", "search", term)
+	} else if commandAvailable("dnf") {
+		searchRun("dnf", "search", term)
+	} else if commandAvailable("pacman") {
+		searchRun("pacman", "-Ss", term)
+	} else if commandAvailable("zypper") {
+		searchRun("zypper", "search", term)
 	}
 }
 
 func searchContainers(term string) {
-	if hasCommand("docker") {
-		exec.Command("docker", "ps", "-a", "--filter", "name="+term).Run()
+	if commandAvailable("docker") {
+		searchRun("docker", "ps", "-a", "--filter", "name="+term)
 	}
-	if hasCommand("podman") {
-		exec.Command("podman", "ps", "-a", "--filter", "name="+term).Run()
+	if commandAvailable("podman") {
+		searchRun("podman", "ps", "-a", "--filter", "name="+term)
 	}
 }
 
 func searchPorts(term string) {
-	if hasCommand("ss") {
-		exec.Command("ss", "-tuln").Run() // Would need grep
+	if commandAvailable("ss") {
+		searchGrep(term, "ss", "-tuln")
 	}
 }
 
@@ -163,9 +163,26 @@ func searchBinaries(term string) {
 	}
 }
 
-func hasCommand(cmd string) bool {
-	_, err := exec.LookPath(cmd)
-	return err == nil
+// searchRun runs a lookup and prints whatever it found
+func searchRun(command string, args ...string) {
+	res, _ := newExecutor().Exec(adapter.WithReadOnly(commandContext()), command, args...)
+	if res != nil && res.Stdout != "" {
+		fmt.Println(res.Stdout)
+	}
+}
+
+// searchGrep runs a listing command and prints only the lines mentioning term
+func searchGrep(term string, command string, args ...string) {
+	res, _ := newExecutor().Exec(adapter.WithReadOnly(commandContext()), command, args...)
+	if res == nil {
+		return
+	}
+	needle := strings.ToLower(term)
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		if strings.Contains(strings.ToLower(line), needle) {
+			fmt.Println(line)
+		}
+	}
 }
 
 func init() {
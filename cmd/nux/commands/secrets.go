@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/vault"
+)
+
+// loadSecret returns a secret of the vault, recording that it was used and
+// warning when it is about to expire
+func loadSecret(name string) (string, bool, error) {
+	v, err := vault.Load()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := v.UseSecret(name)
+	if !ok {
+		return "", false, nil
+	}
+	warnExpiring(v, name)
+	saveSecretUse(v)
+	return value, true, nil
+}
+
+// saveSecretUse records in the vault when the secrets read from v were used.
+// This is best effort, it must not fail the command.
+func saveSecretUse(v *vault.Vault) {
+	if v != nil {
+		vault.SaveUsage(v)
+	}
+}
+
+// secretEnv returns ctx with the --env variables of a skill or plugin, their
+// vault:// references resolved from v. A nil v is loaded, and saved with the
+// use of its secrets, only when a variable refers to it.
+func secretEnv(ctx context.Context, v *vault.Vault, pairs []string) (context.Context, error) {
+	if len(pairs) == 0 {
+		return ctx, nil
+	}
+	refs := vault.Refs(pairs)
+	if v == nil {
+		if len(refs) == 0 {
+			v = vault.NewVault()
+		} else {
+			var err error
+			if v, err = vault.Load(); err != nil {
+				return nil, err
+			}
+			defer saveSecretUse(v)
+		}
+	}
+	env, err := v.ResolveEnv(pairs)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		warnExpiring(v, refs...)
+	}
+	return adapter.WithEnv(ctx, env...), nil
+}
+
+// warnExpiring warns on stderr about the named secrets, all of them when no
+// name is given, that expire soon or have expired
+func warnExpiring(v *vault.Vault, names ...string) {
+	now := time.Now()
+	for _, s := range v.Expiring(now) {
+		if len(names) > 0 && !contains(names, s.Name) {
+			continue
+		}
+		expires := s.ExpiresAt()
+		verb := "expires"
+		if expires.Before(now) {
+			verb = "expired"
+		}
+		fmt.Fprintf(os.Stderr, "⚠ secret %s %s on %s, rotate it with 'nux vault rotate %s'\n", s.Name, verb, expires.Local().Format(time.DateOnly), s.Name)
+	}
+}
+
+// secretStatus describes whether a secret is still valid
+func secretStatus(s vault.Secret, now time.Time) string {
+	switch {
+	case s.ExpiresWithin(now, 0):
+		return "expired"
+	case s.ExpiresWithin(now, vault.ExpiryWarning):
+		return "expiring"
+	}
+	return "ok"
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
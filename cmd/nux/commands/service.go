@@ -5,12 +5,12 @@ import (
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/modules/service"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var serviceExecutor core.Executor = &core.RealExecutor{}
-
 var serviceCmd = &cobra.Command{
 	Use:   "service",
 	Short: "Service management",
@@ -66,10 +66,10 @@ var serviceListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List services",
 	Run: func(cmd *cobra.Command, args []string) {
-		out, err := serviceExecutor.CombinedOutput("systemctl", "list-units", "--type=service", "--all", "--no-pager")
+		out, err := combinedOutput("systemctl", "list-units", "--type=service", "--all", "--no-pager")
 		
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list services: %s", strings.TrimSpace(out)), "SERVICE_LIST_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to list services: %s", strings.TrimSpace(out)), nuxerrors.ErrCodeServiceQuery).WithCause(err).Print()
 			return
 		}
 
@@ -86,7 +86,9 @@ var serviceListCmd = &cobra.Command{
 			})
 		}
 
-		output.NewList(items, len(items)).WithMessage("Service list").Print()
+		output.NewList(items, len(items)).
+			WithColumns("name", "state", "enabled", "pid", "ports").
+			WithMessage("Service list").Print()
 	},
 }
 
@@ -120,7 +122,7 @@ func parseServiceOutput(out string) []ServiceInfo {
 		}
 
 		pid := "-"
-		pidOut, _ := serviceExecutor.CombinedOutput("systemctl", "show", name, "--property=MainPID", "--value")
+		pidOut, _ := combinedOutput("systemctl", "show", name, "--property=MainPID", "--value")
 		pidStr := strings.TrimSpace(pidOut)
 		if pidStr != "" && pidStr != "0" {
 			pid = pidStr
@@ -146,7 +148,7 @@ var serviceStatusCmd = &cobra.Command{
 		service := core.SanitizeInput(args[0])
 
 		// Get status output
-		out, _ := serviceExecutor.CombinedOutput("systemctl", "status", service)
+		out, _ := combinedOutput("systemctl", "status", service)
 		lines := strings.Split(strings.TrimSpace(out), "\n")
 
 		// Color codes
@@ -284,28 +286,19 @@ var serviceStartCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		service := core.SanitizeInput(args[0])
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 		green := "\033[92m"
 		reset := "\033[0m"
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"service": service,
-				"action":  "start",
-				"dry_run": true,
-				"command": fmt.Sprintf("systemctl start %s", service),
-			}).Print()
-			return
-		}
-
-		_, err := serviceExecutor.CombinedOutput("systemctl", "start", service)
+		_, err := newExecutor().Exec(commandContext(), "systemctl", "start", service)
 		
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to start service: %s", err.Error()), "SERVICE_START_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to start service: %s", err.Error()), nuxerrors.ErrCodeServiceStart).WithCause(err).Print()
 			return
 		}
 
-		fmt.Printf("%s✔ Service %s started successfully%s\n", green, service, reset)
+		if !flagDryRun {
+			fmt.Printf("%s✔ Service %s started successfully%s\n", green, service, reset)
+		}
 	},
 }
 
@@ -315,28 +308,19 @@ var serviceStopCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		service := core.SanitizeInput(args[0])
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 		orange := "\033[93m"
 		reset := "\033[0m"
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"service": service,
-				"action":  "stop",
-				"dry_run": true,
-				"command": fmt.Sprintf("systemctl stop %s", service),
-			}).Print()
-			return
-		}
-
-		_, err := serviceExecutor.CombinedOutput("systemctl", "stop", service)
+		_, err := newExecutor().Exec(commandContext(), "systemctl", "stop", service)
 		
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to stop service: %s", err.Error()), "SERVICE_STOP_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to stop service: %s", err.Error()), nuxerrors.ErrCodeServiceStop).WithCause(err).Print()
 			return
 		}
 
-		fmt.Printf("%s■ Service %s stopped%s\n", orange, service, reset)
+		if !flagDryRun {
+			fmt.Printf("%s■ Service %s stopped%s\n", orange, service, reset)
+		}
 	},
 }
 
@@ -345,30 +329,23 @@ var serviceEnableCmd = &cobra.Command{
 	Short: "Enable service at boot",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		service := core.SanitizeInput(args[0])
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"service": service,
-				"action":  "enable",
-				"dry_run": true,
-				"command": fmt.Sprintf("systemctl enable %s", service),
-			}).Print()
+		name := core.SanitizeInput(args[0])
+
+		manager, err := newServiceManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
 			return
 		}
 
-		_, err := serviceExecutor.CombinedOutput("systemctl", "enable", service)
-		
-		if err != nil {
-			output.NewError(fmt.Sprintf("failed to enable service: %s", err.Error()), "SERVICE_ENABLE_ERROR").Print()
+		if err := manager.EnableService(name); err != nil {
+			output.NewError(fmt.Sprintf("failed to enable service: %s", err.Error()), nuxerrors.ErrCodeServiceEnable).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
-			"service": service,
+			"service": name,
 			"action":  "enable",
-			"status":  "enabled",
+			"status":  actionStatus("enabled"),
 		}).Print()
 	},
 }
@@ -378,30 +355,23 @@ var serviceDisableCmd = &cobra.Command{
 	Short: "Disable service at boot",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		service := core.SanitizeInput(args[0])
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"service": service,
-				"action":  "disable",
-				"dry_run": true,
-				"command": fmt.Sprintf("systemctl disable %s", service),
-			}).Print()
+		name := core.SanitizeInput(args[0])
+
+		manager, err := newServiceManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
 			return
 		}
 
-		_, err := serviceExecutor.CombinedOutput("systemctl", "disable", service)
-		
-		if err != nil {
-			output.NewError(fmt.Sprintf("failed to disable service: %s", err.Error()), "SERVICE_DISABLE_ERROR").Print()
+		if err := manager.DisableService(name); err != nil {
+			output.NewError(fmt.Sprintf("failed to disable service: %s", err.Error()), nuxerrors.ErrCodeServiceDisable).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
-			"service": service,
+			"service": name,
 			"action":  "disable",
-			"status":  "disabled",
+			"status":  actionStatus("disabled"),
 		}).Print()
 	},
 }
@@ -412,31 +382,33 @@ var serviceRestartCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		service := core.SanitizeInput(args[0])
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 		green := "\033[92m"
 		reset := "\033[0m"
 
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"service": service,
-				"action":  "restart",
-				"dry_run": true,
-				"command": fmt.Sprintf("systemctl restart %s", service),
-			}).Print()
-			return
-		}
-
-		_, err := serviceExecutor.CombinedOutput("systemctl", "restart", service)
+		_, err := newExecutor().Exec(commandContext(), "systemctl", "restart", service)
 		
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to restart service: %s", err.Error()), "SERVICE_RESTART_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to restart service: %s", err.Error()), nuxerrors.ErrCodeServiceRestart).WithCause(err).Print()
 			return
 		}
 
-		fmt.Printf("%s↻ Service %s restarted successfully%s\n", green, service, reset)
+		if !flagDryRun {
+			fmt.Printf("%s↻ Service %s restarted successfully%s\n", green, service, reset)
+		}
 	},
 }
 
+// newServiceManager returns a service manager for the target host that
+// records enable/disable into the journal
+func newServiceManager() (*service.UniversalServiceManager, error) {
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
+	if err != nil {
+		return nil, err
+	}
+	return service.NewUniversalServiceManager(executor, profile).WithJournal(newJournal()), nil
+}
+
 func init() {
 	serviceListCmd.Flags().Bool("json", false, "Output in JSON format")
 	serviceCmd.AddCommand(serviceListCmd)
@@ -447,11 +419,6 @@ func init() {
 	serviceCmd.AddCommand(serviceEnableCmd)
 	serviceCmd.AddCommand(serviceDisableCmd)
 	
-	serviceStartCmd.Flags().Bool("dry-run", false, "Simulate command")
-	serviceStopCmd.Flags().Bool("dry-run", false, "Simulate command")
-	serviceRestartCmd.Flags().Bool("dry-run", false, "Simulate command")
-	serviceEnableCmd.Flags().Bool("dry-run", false, "Simulate command")
-	serviceDisableCmd.Flags().Bool("dry-run", false, "Simulate command")
 	
 	rootCmd.AddCommand(serviceCmd)
 	
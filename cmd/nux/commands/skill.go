@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"time"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/output"
 	"github.com/rsdenck/nux/internal/skill"
+	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -30,28 +35,44 @@ var skillInstallCmd = &cobra.Command{
 
 		s, err := skill.LoadSkillFromMD(skillName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeNotFound).WithCause(err))
 		}
 
 		fmt.Printf("Installing skill: %s\n", skillName)
 		fmt.Printf("Description: %s\n", s.Description)
 
-		v, err := skill.LoadVault()
+		v, err := vault.Load()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading vault: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(fmt.Sprintf("failed to load vault: %v", err), nuxerrors.ErrCodeVault).WithCause(err))
 		}
 
-		if contains(v.InstalledSkills, skillName) {
+		if v.IsInstalled(skillName) {
 			fmt.Printf("Skill %s is already installed\n", skillName)
 			return
 		}
 
-		v.InstalledSkills = append(v.InstalledSkills, skillName)
-		if err := skill.SaveVault(v); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving vault: %v\n", err)
-			os.Exit(1)
+		if skipInstall, _ := cmd.Flags().GetBool("skip-install"); !skipInstall {
+			env, _ := cmd.Flags().GetStringArray("env")
+			ctx, err := secretEnv(commandContext(), v, env)
+			if err != nil {
+				exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err))
+			}
+			pm := detectPackageManager()
+			if _, ok := s.InstallCommand(pm); ok {
+				handler, done := streamHandler()
+				err := s.Install(ctx, newExecutor(), pm, handler)
+				done()
+				if err != nil {
+					exitWithError(output.NewError(fmt.Sprintf("failed to install %s: %v", skillName, err), nuxerrors.ErrCodePkgInstall).WithCause(err))
+				}
+			} else {
+				fmt.Printf("No install command for %q, install %s manually: %s\n", pm, skillName, s.InstallCmd)
+			}
+		}
+
+		v.InstallSkill(skillName, time.Now().Format(time.RFC3339))
+		if err := vault.Save(v); err != nil {
+			exitWithError(output.NewError(fmt.Sprintf("failed to save vault: %v", err), nuxerrors.ErrCodeVault).WithCause(err))
 		}
 
 		fmt.Printf("Skill %s installed successfully\n", skillName)
@@ -68,8 +89,7 @@ var skillInfoCmd = &cobra.Command{
 
 		s, err := skill.LoadSkillFromMD(skillName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeNotFound).WithCause(err))
 		}
 
 		if flagJSON {
@@ -93,11 +113,10 @@ var skillListCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		skills, err := skill.ListSkills()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodePlugin).WithCause(err))
 		}
 
-		v, _ := skill.LoadVault()
+		v, _ := vault.Load()
 
 		if flagJSON {
 			data, _ := json.MarshalIndent(skills, "", "  ")
@@ -108,7 +127,7 @@ var skillListCmd = &cobra.Command{
 		fmt.Println("Available skills:")
 		for _, s := range skills {
 			installed := ""
-			if v != nil && contains(v.InstalledSkills, s) {
+			if v != nil && v.IsInstalled(s) {
 				installed = " [installed]"
 			}
 			fmt.Printf("  - %s%s\n", s, installed)
@@ -124,8 +143,7 @@ var skillSearchCmd = &cobra.Command{
 		query := args[0]
 		results, err := skill.SearchSkills(query)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodePlugin).WithCause(err))
 		}
 
 		if flagJSON {
@@ -148,26 +166,24 @@ var skillEnableCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		skillName := args[0]
 
-		v, err := skill.LoadVault()
+		v, err := vault.Load()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading vault: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(fmt.Sprintf("failed to load vault: %v", err), nuxerrors.ErrCodeVault).WithCause(err))
 		}
 
-		if !contains(v.InstalledSkills, skillName) {
-			fmt.Fprintf(os.Stderr, "Skill %s is not installed. Run 'nux skill install %s' first\n", skillName, skillName)
-			os.Exit(1)
+		if !v.IsInstalled(skillName) {
+			exitWithError(output.NewError(fmt.Sprintf("skill %s is not installed", skillName), nuxerrors.ErrCodeNotFound).
+				WithHint(fmt.Sprintf("run 'nux skill install %s' first", skillName)))
 		}
 
-		if contains(v.EnabledSkills, skillName) {
+		if v.Enabled[skillName] {
 			fmt.Printf("Skill %s is already enabled\n", skillName)
 			return
 		}
 
-		v.EnabledSkills = append(v.EnabledSkills, skillName)
-		if err := skill.SaveVault(v); err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving vault: %v\n", err)
-			os.Exit(1)
+		v.EnableSkill(skillName)
+		if err := vault.Save(v); err != nil {
+			exitWithError(output.NewError(fmt.Sprintf("failed to save vault: %v", err), nuxerrors.ErrCodeVault).WithCause(err))
 		}
 
 		fmt.Printf("Skill %s enabled successfully\n", skillName)
@@ -181,14 +197,18 @@ var skillSyncCmd = &cobra.Command{
 		fmt.Println("Syncing skills...")
 		fmt.Println("Checking for updates...")
 
-		v, err := skill.LoadVault()
+		v, err := vault.Load()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading vault: %v\n", err)
-			os.Exit(1)
+			exitWithError(output.NewError(fmt.Sprintf("failed to load vault: %v", err), nuxerrors.ErrCodeVault).WithCause(err))
 		}
 
-		fmt.Printf("Installed skills: %d\n", len(v.InstalledSkills))
-		for _, s := range v.InstalledSkills {
+		installed := make([]string, 0, len(v.Installed))
+		for name := range v.Installed {
+			installed = append(installed, name)
+		}
+		sort.Strings(installed)
+		fmt.Printf("Installed skills: %d\n", len(installed))
+		for _, s := range installed {
 			fmt.Printf("  - %s\n", s)
 		}
 		fmt.Println("Sync completed")
@@ -196,6 +216,9 @@ var skillSyncCmd = &cobra.Command{
 }
 
 func init() {
+	skillInstallCmd.Flags().Bool("skip-install", false, "Only register the skill, without installing its tool")
+	skillInstallCmd.Flags().StringArray("env", nil, "Set NAME=value for the install command; vault://name is read from the vault")
+
 	skillCmd.AddCommand(skillInstallCmd)
 	skillCmd.AddCommand(skillInfoCmd)
 	skillCmd.AddCommand(skillListCmd)
@@ -204,12 +227,3 @@ func init() {
 	skillCmd.AddCommand(skillSyncCmd)
 	rootCmd.AddCommand(skillCmd)
 }
-
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/output"
+)
+
+// progressWidth is the number of cells in the text-mode progress bar
+const progressWidth = 30
+
+// streamHandler returns the handler long-running commands stream their output
+// to. With --json every event is printed as one NDJSON line, and so is the
+// final result. Otherwise lines are printed as they arrive and progress is
+// drawn as a bar on stderr when it is a terminal. done removes the bar once
+// the command has finished.
+func streamHandler() (handler adapter.EventHandler, done func()) {
+	if flagJSON {
+		output.SetStreaming(true)
+		return func(ev adapter.Event) {
+			output.PrintEvent(ev)
+		}, func() {}
+	}
+
+	bar := &progressBar{enabled: isTerminal(os.Stderr)}
+	return func(ev adapter.Event) {
+		switch ev.Type {
+		case adapter.EventLine:
+			if flagQuiet {
+				return
+			}
+			bar.clear()
+			if ev.Stream == adapter.StreamStderr {
+				fmt.Fprintln(os.Stderr, ev.Line)
+			} else {
+				fmt.Println(ev.Line)
+			}
+			bar.redraw()
+		case adapter.EventProgress:
+			bar.update(ev)
+		}
+	}, bar.finish
+}
+
+// runStream runs a command on the target host, streaming its output
+func runStream(input string, command string, args ...string) (*adapter.CommandResult, error) {
+	handler, done := streamHandler()
+	defer done()
+	return adapter.RunStream(commandContext(), newExecutor(), input, handler, command, args...)
+}
+
+// progressBar keeps the last progress event drawn below the streamed lines
+type progressBar struct {
+	enabled bool
+	last    string
+}
+
+func (b *progressBar) update(ev adapter.Event) {
+	if !b.enabled {
+		return
+	}
+	filled := int(ev.Percent * progressWidth / 100)
+	b.last = fmt.Sprintf("[%s%s] %3.0f%%", strings.Repeat("#", filled), strings.Repeat(".", progressWidth-filled), ev.Percent)
+	if ev.Total > 0 {
+		b.last += fmt.Sprintf(" (%d/%d)", ev.Current, ev.Total)
+	}
+	if ev.Percent >= 100 {
+		b.clear()
+		b.last = ""
+		return
+	}
+	b.redraw()
+}
+
+func (b *progressBar) clear() {
+	if b.enabled && b.last != "" {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+func (b *progressBar) redraw() {
+	if b.enabled && b.last != "" {
+		fmt.Fprint(os.Stderr, "\r"+b.last)
+	}
+}
+
+// finish removes a bar left behind by a command that stopped short of 100%
+func (b *progressBar) finish() {
+	b.clear()
+	b.last = ""
+}
+
+// isTerminal reports whether f is a terminal. /dev/null is a character
+// device too, so it is ruled out explicitly.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
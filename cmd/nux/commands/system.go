@@ -7,13 +7,11 @@ import (
 	"runtime"
 	"strings"
 
-	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var systemExecutor core.Executor = &core.RealExecutor{}
-
 var systemCmd = &cobra.Command{
 	Use:   "system",
 	Short: "System information",
@@ -27,7 +25,7 @@ var systemInfoCmd = &cobra.Command{
 		info := map[string]interface{}{
 			"hostname": getHostname(),
 			"kernel":   getKernelVersion(),
-			"arch":     runtime.GOARCH,
+			"arch":     getArch(),
 			"os":       getOSInfo(),
 			"uptime":   getUptime(),
 			"load":     getLoadAverage(),
@@ -41,10 +39,10 @@ var systemUptimeCmd = &cobra.Command{
 	Use:   "uptime",
 	Short: "Show system uptime",
 	Run: func(cmd *cobra.Command, args []string) {
-		out, err := systemExecutor.CombinedOutput("uptime")
+		out, err := combinedOutput("uptime")
 
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to get uptime: %s", err.Error()), "SYSTEM_UPTIME_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to get uptime: %s", err.Error()), nuxerrors.ErrCodeCommandFailed).WithCause(err).Print()
 			return
 		}
 
@@ -66,12 +64,24 @@ var systemLoadCmd = &cobra.Command{
 }
 
 func getHostname() string {
+	if flagHost != "" {
+		out, _ := combinedOutput("uname", "-n")
+		return out
+	}
 	hostname, _ := os.Hostname()
 	return hostname
 }
 
+func getArch() string {
+	if flagHost != "" {
+		out, _ := combinedOutput("uname", "-m")
+		return out
+	}
+	return runtime.GOARCH
+}
+
 func getKernelVersion() string {
-	out, err := systemExecutor.CombinedOutput("uname", "-r")
+	out, err := combinedOutput("uname", "-r")
 	if err != nil {
 		return "unknown"
 	}
@@ -79,9 +89,8 @@ func getKernelVersion() string {
 }
 
 func getOSInfo() string {
-	if file, err := os.Open("/etc/os-release"); err == nil {
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
+	if release, err := combinedOutput("cat", "/etc/os-release"); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(release))
 		for scanner.Scan() {
 			line := scanner.Text()
 			if strings.HasPrefix(line, "PRETTY_NAME=") {
@@ -90,7 +99,7 @@ func getOSInfo() string {
 		}
 	}
 
-	out, err := systemExecutor.CombinedOutput("lsb_release", "-ds")
+	out, err := combinedOutput("lsb_release", "-ds")
 	if err == nil {
 		return strings.Trim(out, "\"")
 	}
@@ -99,7 +108,7 @@ func getOSInfo() string {
 }
 
 func getUptime() string {
-	out, err := systemExecutor.CombinedOutput("uptime", "-p")
+	out, err := combinedOutput("uptime", "-p")
 	if err != nil {
 		return "unknown"
 	}
@@ -107,7 +116,7 @@ func getUptime() string {
 }
 
 func getLoadAverage() string {
-	out, err := systemExecutor.CombinedOutput("cat", "/proc/loadavg")
+	out, err := combinedOutput("cat", "/proc/loadavg")
 	if err != nil {
 		return "unknown"
 	}
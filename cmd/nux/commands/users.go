@@ -5,12 +5,13 @@ import (
 	"strings"
 
 	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/modules/users"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
 
-var usersExecutor core.Executor = &core.RealExecutor{}
-
 var usersCmd = &cobra.Command{
 	Use:   "users",
 	Short: "User management",
@@ -21,100 +22,33 @@ var usersListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List users",
 	Run: func(cmd *cobra.Command, args []string) {
-		out, err := usersExecutor.CombinedOutput("getent", "passwd")
+		out, err := combinedOutput("getent", "passwd")
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list users: %s", err.Error()), "USERS_LIST_ERROR").Print()
-			return
-		}
-
-		lines := strings.Split(strings.TrimSpace(out), "\n")
-		if len(lines) == 0 {
-			output.NewInfo("No users found").Print()
+			output.NewError(fmt.Sprintf("failed to list users: %s", err.Error()), nuxerrors.ErrCodeUser).WithCause(err).Print()
 			return
 		}
 
-		// Fixed columns order
-		headers := []string{"USERNAME", "UID", "GID", "HOME", "SHELL"}
-		colWidths := make([]int, len(headers))
-		for i, h := range headers {
-			colWidths[i] = len(h) + 2
-		}
-
-		type userEntry struct {
-			username string
-			uid      string
-			gid      string
-			home     string
-			shell    string
-		}
-		var entries []userEntry
-
-		for _, line := range lines {
+		items := []map[string]interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
 			parts := strings.Split(line, ":")
 			if len(parts) < 7 {
 				continue
 			}
-			entry := userEntry{
-				username: parts[0],
-				uid:      parts[2],
-				gid:      parts[3],
-				home:     parts[5],
-				shell:    parts[6],
-			}
-			entries = append(entries, entry)
-
-			// Update widths
-			if len(entry.username)+2 > colWidths[0] {
-				colWidths[0] = len(entry.username) + 2
-			}
-			if len(entry.uid)+2 > colWidths[1] {
-				colWidths[1] = len(entry.uid) + 2
-			}
-			if len(entry.gid)+2 > colWidths[2] {
-				colWidths[2] = len(entry.gid) + 2
-			}
-			if len(entry.home)+2 > colWidths[3] {
-				colWidths[3] = len(entry.home) + 2
-			}
-			if len(entry.shell)+2 > colWidths[4] {
-				colWidths[4] = len(entry.shell) + 2
-			}
-		}
-
-		fmt.Println("User list")
-		printBorderUsers(colWidths, "┌", "┬", "┐")
-		printRowUsers(headers, colWidths)
-		printBorderUsers(colWidths, "├", "┼", "┤")
-
-		for _, e := range entries {
-			printRowUsers([]string{e.username, e.uid, e.gid, e.home, e.shell}, colWidths)
-		}
-
-		printBorderUsers(colWidths, "└", "┴", "┘")
-		fmt.Printf("\n%d users found\n", len(entries))
+			items = append(items, map[string]interface{}{
+				"username": parts[0],
+				"uid":      parts[2],
+				"gid":      parts[3],
+				"home":     parts[5],
+				"shell":    parts[6],
+			})
+		}
+
+		output.NewList(items, len(items)).
+			WithColumns("username", "uid", "gid", "home", "shell").
+			WithMessage("User list").Print()
 	},
 }
 
-func printBorderUsers(widths []int, left, middle, right string) {
-	fmt.Print(left)
-	for i, w := range widths {
-		fmt.Print(strings.Repeat("─", w))
-		if i < len(widths)-1 {
-			fmt.Print(middle)
-		}
-	}
-	fmt.Println(right)
-}
-
-func printRowUsers(values []string, widths []int) {
-	fmt.Print("│")
-	for i, v := range values {
-		fmt.Printf(" %-*s ", widths[i]-2, v)
-		fmt.Print("│")
-	}
-	fmt.Println()
-}
-
 var usersAddCmd = &cobra.Command{
 	Use:   "add <username>",
 	Short: "Add a new user",
@@ -126,38 +60,25 @@ var usersAddCmd = &cobra.Command{
 		shell, _ := cmd.Flags().GetString("shell")
 		group, _ := cmd.Flags().GetString("group")
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		cmdArgs := []string{}
-		if createHome {
-			cmdArgs = append(cmdArgs, "-m")
-		}
-		if shell != "" {
-			cmdArgs = append(cmdArgs, "-s", shell)
-		}
-		if group != "" {
-			cmdArgs = append(cmdArgs, "-g", group)
-		}
-		cmdArgs = append(cmdArgs, username)
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"username": username,
-				"dry_run":  true,
-				"command":  fmt.Sprintf("useradd %s", strings.Join(cmdArgs, " ")),
-			}).Print()
+		manager, err := newUserManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
 			return
 		}
 
-		_, err := usersExecutor.CombinedOutput("useradd", cmdArgs...)
+		err = manager.AddUser(username, ports.UserOptions{
+			GID:        group,
+			Shell:      shell,
+			CreateHome: createHome,
+		})
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to add user: %s", err.Error()), "USERS_ADD_ERROR").Print()
+			output.NewError(err.Error(), nuxerrors.ErrCodeUser).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"username": username,
-			"status":   "created",
+			"status":   actionStatus("created"),
 		}).Print()
 	},
 }
@@ -170,32 +91,21 @@ var usersDeleteCmd = &cobra.Command{
 		username := core.SanitizeInput(args[0])
 
 		removeHome, _ := cmd.Flags().GetBool("remove-home")
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		cmdArgs := []string{}
-		if removeHome {
-			cmdArgs = append(cmdArgs, "-r")
-		}
-		cmdArgs = append(cmdArgs, username)
-
-		if dryRun {
-			output.NewInfo(map[string]interface{}{
-				"username": username,
-				"dry_run":  true,
-				"command":  fmt.Sprintf("userdel %s", strings.Join(cmdArgs, " ")),
-			}).Print()
+		manager, err := newUserManager()
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeGeneral).WithCause(err).Print()
 			return
 		}
 
-		_, err := usersExecutor.CombinedOutput("userdel", cmdArgs...)
-		if err != nil {
-			output.NewError(fmt.Sprintf("failed to delete user: %s", err.Error()), "USERS_DELETE_ERROR").Print()
+		if err := manager.DeleteUser(username, removeHome); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeUser).WithCause(err).Print()
 			return
 		}
 
 		output.NewSuccess(map[string]interface{}{
 			"username": username,
-			"status":   "deleted",
+			"status":   actionStatus("deleted"),
 		}).Print()
 	},
 }
@@ -204,9 +114,9 @@ var groupsListCmd = &cobra.Command{
 	Use:   "groups",
 	Short: "List groups",
 	Run: func(cmd *cobra.Command, args []string) {
-		out, err := usersExecutor.CombinedOutput("getent", "group")
+		out, err := combinedOutput("getent", "group")
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to list groups: %s", err.Error()), "USERS_GROUPS_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to list groups: %s", err.Error()), nuxerrors.ErrCodeUser).WithCause(err).Print()
 			return
 		}
 
@@ -229,14 +139,23 @@ var groupsListCmd = &cobra.Command{
 	},
 }
 
+// newUserManager returns a user manager for the target host that records
+// added/deleted users into the journal
+func newUserManager() (*users.UniversalUserManager, error) {
+	executor := newExecutor()
+	profile, err := detectProfile(executor)
+	if err != nil {
+		return nil, err
+	}
+	return users.NewUniversalUserManager(executor, profile).WithJournal(newJournal()), nil
+}
+
 func init() {
 	usersAddCmd.Flags().Bool("create-home", true, "Create home directory")
 	usersAddCmd.Flags().String("shell", "", "Login shell")
 	usersAddCmd.Flags().String("group", "", "Primary group")
-	usersAddCmd.Flags().Bool("dry-run", false, "Simulate command")
 
 	usersDeleteCmd.Flags().Bool("remove-home", false, "Remove home directory")
-	usersDeleteCmd.Flags().Bool("dry-run", false, "Simulate command")
 
 	usersCmd.AddCommand(usersListCmd)
 	usersCmd.AddCommand(usersAddCmd)
@@ -2,7 +2,12 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/rsdenck/nux/internal/vault"
 	"github.com/spf13/cobra"
@@ -11,7 +16,13 @@ import (
 var vaultCmd = &cobra.Command{
 	Use:   "vault",
 	Short: "Manage NUX vault for secrets and API keys",
-	Long:  `Secure storage for API keys, tokens, and configuration. Vault file is stored at ~/.nux/vault.json with 0600 permissions.`,
+	Long: `Secure storage for API keys, tokens, and configuration.
+
+The vault file ~/.nux/vault.json is encrypted with AES-256-GCM, with a key
+derived from a passphrase with Argon2id. The passphrase is asked for on the
+terminal, or read from NUX_VAULT_PASSWORD; 'nux vault unlock' keeps it
+unlocked for a while. Vaults written in plaintext by earlier versions are
+converted with 'nux vault migrate'.`,
 }
 
 var vaultShowCmd = &cobra.Command{
@@ -20,18 +31,32 @@ var vaultShowCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		v, err := vault.Load()
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), "VAULT_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
 			return
 		}
 
+		path, _ := vault.Path()
+		encrypted, _ := vault.IsEncrypted()
+		agent := "locked"
+		if expires, ok := vault.AgentStatus(); ok {
+			agent = "unlocked"
+			if !expires.IsZero() {
+				agent = "unlocked until " + expires.Format(time.DateTime)
+			}
+		}
 		result := map[string]interface{}{
+			"path":      path,
+			"encrypted": encrypted,
+			"agent":     agent,
 			"version":   v.Version,
 			"installed": len(v.Installed),
 			"enabled":   len(v.Enabled),
 			"api_keys":  maskKeys(v.APIKeys),
 			"tokens":    len(v.Tokens),
+			"expiring":  len(v.Expiring(time.Now())),
 		}
 
+		warnExpiring(v)
 		output.NewSuccess(result).WithMessage("NUX Vault").Print()
 	},
 }
@@ -49,22 +74,40 @@ func maskKeys(keys map[string]string) map[string]string {
 }
 
 var vaultSetKeyCmd = &cobra.Command{
-	Use:   "set-key <service> <key>",
+	Use:   "set-key <service> [key]",
 	Short: "Set API key for a service",
-	Args:  cobra.ExactArgs(2),
+	Long: `Store the API key of a service. Without the key argument it is read from
+stdin, or asked for on the terminal, so it stays out of the shell history.`,
+	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		service := args[0]
-		key := args[1]
+		expires, ok := expiryFlag(cmd)
+		if !ok {
+			return
+		}
+		owner, _ := cmd.Flags().GetString("owner")
 
 		v, err := vault.Load()
 		if err != nil {
-			v = vault.NewVault()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
 		}
 
-		v.SetAPIKey(service, key)
+		var key string
+		if len(args) == 2 {
+			key = args[1]
+		} else if key, err = readSecret(service); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
+		}
+		if key == "" {
+			output.NewError("empty key", nuxerrors.ErrCodeInvalidInput).Print()
+			return
+		}
+		v.SetSecret(service, key, expires, owner)
 
 		if err := vault.Save(v); err != nil {
-			output.NewError(fmt.Sprintf("failed to save vault: %s", err.Error()), "VAULT_SAVE_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to save vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
 			return
 		}
 
@@ -82,15 +125,13 @@ var vaultGetKeyCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		service := args[0]
 
-		v, err := vault.Load()
+		key, ok, err := loadSecret(service)
 		if err != nil {
-			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), "VAULT_ERROR").Print()
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
 			return
 		}
-
-		key, ok := v.GetAPIKey(service)
 		if !ok {
-			output.NewError(fmt.Sprintf("no key found for service: %s", service), "VAULT_KEY_NOT_FOUND").Print()
+			output.NewError(fmt.Sprintf("no key found for service: %s", service), nuxerrors.ErrCodeNotFound).Print()
 			return
 		}
 
@@ -101,9 +142,359 @@ var vaultGetKeyCmd = &cobra.Command{
 	},
 }
 
+var vaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List secrets with their creation, expiry and last use",
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := vault.Load()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+
+		now := time.Now()
+		items := []map[string]interface{}{}
+		for _, s := range v.ListSecrets() {
+			kind := "api_key"
+			if s.Token {
+				kind = "token"
+			}
+			items = append(items, map[string]interface{}{
+				"name":      s.Name,
+				"type":      kind,
+				"status":    secretStatus(s, now),
+				"created":   s.Created,
+				"rotated":   s.Rotated,
+				"expires":   s.Expires,
+				"last_used": s.LastUsed,
+				"owner":     s.Owner,
+			})
+		}
+		output.NewList(items, len(items)).
+			WithColumns("name", "type", "status", "expires", "last_used", "created", "rotated", "owner").
+			Print()
+	},
+}
+
+var vaultRotateCmd = &cobra.Command{
+	Use:   "rotate <name>",
+	Short: "Replace the value of a secret",
+	Long: `Replace the value of a secret, read from stdin or asked for on the terminal.
+Without --expires, a secret that had an expiry is given the same lifetime
+again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		expires, ok := expiryFlag(cmd)
+		if !ok {
+			return
+		}
+
+		v, err := vault.Load()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		if _, ok := v.GetAPIKey(name); !ok {
+			output.NewError(fmt.Sprintf("no secret named %s", name), nuxerrors.ErrCodeNotFound).
+				WithHint("list secrets with 'nux vault list'").Print()
+			return
+		}
+
+		value, err := readSecret(name)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).WithCause(err).Print()
+			return
+		}
+		if value == "" {
+			output.NewError("empty secret", nuxerrors.ErrCodeInvalidInput).Print()
+			return
+		}
+		if err := v.Rotate(name, value, expires); err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		if err := vault.Save(v); err != nil {
+			output.NewError(fmt.Sprintf("failed to save vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+
+		meta := v.Metadata[name]
+		output.NewSuccess(map[string]interface{}{
+			"name":    name,
+			"rotated": meta.Rotated,
+			"expires": meta.Expires,
+		}).WithMessage("Secret rotated").Print()
+	},
+}
+
+var vaultExportCmd = &cobra.Command{
+	Use:   "export <file> [name...]",
+	Short: "Export secrets to an encrypted bundle",
+	Long: `Write the named secrets, all of them by default, with their metadata to an
+encrypted bundle for 'nux vault import' on another workstation. The bundle
+has a passphrase of its own, asked for on the terminal or read from
+NUX_BUNDLE_PASSWORD.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := args[0]
+		if _, err := os.Stat(file); err == nil {
+			output.NewError(fmt.Sprintf("%s already exists", file), nuxerrors.ErrCodeAlreadyExists).Print()
+			return
+		}
+
+		v, err := vault.Load()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		pass, err := vault.BundlePassphrase(true)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		data, err := v.Export(args[1:], pass)
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to export secrets: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			output.NewError(fmt.Sprintf("failed to write %s: %s", file, err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+
+		names := args[1:]
+		if len(names) == 0 {
+			for _, s := range v.ListSecrets() {
+				if !s.Token {
+					names = append(names, s.Name)
+				}
+			}
+		}
+		output.NewSuccess(map[string]interface{}{
+			"file":    file,
+			"secrets": names,
+		}).WithMessage("Secrets exported").Print()
+	},
+}
+
+var vaultImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import secrets from an encrypted bundle",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to read bundle: %s", err.Error()), nuxerrors.ErrCodeNotFound).WithCause(err).Print()
+			return
+		}
+
+		v, err := vault.Load()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to load vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		pass, err := vault.BundlePassphrase(false)
+		if err != nil {
+			output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		imported, skipped, err := v.Import(data, pass, overwrite)
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to import secrets: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		if err := vault.Save(v); err != nil {
+			output.NewError(fmt.Sprintf("failed to save vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+
+		warnExpiring(v, imported...)
+		result := map[string]interface{}{
+			"imported": imported,
+			"skipped":  skipped,
+		}
+		if imported == nil {
+			result["imported"] = []string{}
+		}
+		if skipped == nil {
+			result["skipped"] = []string{}
+		}
+		output.NewSuccess(result).WithMessage("Secrets imported").Print()
+	},
+}
+
+// expiryFlag reads --expires, reporting false after printing an error
+func expiryFlag(cmd *cobra.Command) (time.Time, bool) {
+	s, _ := cmd.Flags().GetString("expires")
+	if s == "" {
+		return time.Time{}, true
+	}
+	expires, err := vault.ParseExpiry(s, time.Now())
+	if err != nil {
+		output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).Print()
+		return time.Time{}, false
+	}
+	return expires, true
+}
+
+var vaultMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Encrypt a vault written in plaintext by an earlier version",
+	Long: `Encrypt the plaintext vault at ~/.nux/vault.json and merge the skill vault
+at ~/.skills/.nux.json into it. Keys already in the vault win over those of the
+skill vault. The plaintext files are replaced, and the skill vault is removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		migrated, err := vault.Migrate()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to migrate vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		message := "Vault encrypted"
+		if len(migrated) == 0 {
+			migrated = []string{}
+			message = "Nothing to migrate, the vault is already encrypted"
+		}
+
+		path, _ := vault.Path()
+		output.NewSuccess(map[string]interface{}{
+			"vault":    path,
+			"migrated": migrated,
+		}).WithMessage(message).Print()
+	},
+}
+
+var vaultUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Keep the vault unlocked for a while",
+	Long: `Ask for the vault passphrase once and start a background agent that keeps
+the derived key in locked memory, reachable only by your user over the
+0600 socket ~/.nux/agent.sock. Until the agent is locked or its --ttl runs
+out, commands read the vault without asking for the passphrase.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		if ttl < 0 {
+			output.NewError("--ttl must not be negative", nuxerrors.ErrCodeInvalidInput).Print()
+			return
+		}
+
+		key, err := vault.UnlockKey()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to unlock vault: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+		vault.LockAgent()
+		err = startVaultAgent(key, ttl)
+		key.Wipe()
+		if err != nil {
+			output.NewError(fmt.Sprintf("failed to start vault agent: %s", err.Error()), nuxerrors.ErrCodeVault).WithCause(err).Print()
+			return
+		}
+
+		socket, _ := vault.AgentSocket()
+		result := map[string]interface{}{
+			"socket":  socket,
+			"expires": "never",
+		}
+		if expires, _ := vault.AgentStatus(); !expires.IsZero() {
+			result["expires"] = expires.Format(time.DateTime)
+		}
+		output.NewSuccess(result).WithMessage("Vault unlocked").Print()
+	},
+}
+
+var vaultLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Stop the unlock agent and forget the vault key",
+	Run: func(cmd *cobra.Command, args []string) {
+		status := "locked"
+		if !vault.LockAgent() {
+			status = "already locked"
+		}
+		output.NewSuccess(map[string]interface{}{
+			"status": status,
+		}).Print()
+	},
+}
+
+// vaultAgentCmd is the agent 'vault unlock' starts in the background; the key
+// comes in on file descriptor 3
+var vaultAgentCmd = &cobra.Command{
+	Use:    "agent",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		key, err := vault.ReadKey(os.NewFile(3, "vault-key"))
+		if err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err))
+		}
+		if err := vault.ServeAgent(commandContext(), key, ttl); err != nil {
+			exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeVault).WithCause(err))
+		}
+	},
+}
+
+// startVaultAgent runs 'nux vault agent' in a session of its own, hands it
+// the key over a pipe and waits until it answers on its socket
+func startVaultAgent(key *vault.Key, ttl time.Duration) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate nux binary: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	agent := exec.Command(self, "vault", "agent", "--ttl", ttl.String())
+	agent.ExtraFiles = []*os.File{r}
+	agent.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err = agent.Start()
+	r.Close()
+	if err != nil {
+		w.Close()
+		return err
+	}
+	_, err = key.WriteTo(w)
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- agent.Wait() }()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if _, ok := vault.AgentStatus(); ok {
+			return nil
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("vault agent exited: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("vault agent did not start")
+}
+
 func init() {
+	vaultSetKeyCmd.Flags().String("expires", "", "When the key expires: days (90d), a duration (720h) or a date (2027-01-31)")
+	vaultSetKeyCmd.Flags().String("owner", "", "Who is responsible for the key (default: current user)")
+	vaultRotateCmd.Flags().String("expires", "", "New expiry: days (90d), a duration (720h) or a date (2027-01-31)")
+	vaultImportCmd.Flags().Bool("overwrite", false, "Replace secrets the vault already has")
+	vaultUnlockCmd.Flags().Duration("ttl", 15*time.Minute, "How long the key is kept, 0 until 'nux vault lock'")
+	vaultAgentCmd.Flags().Duration("ttl", 0, "How long the key is kept")
+
 	vaultCmd.AddCommand(vaultShowCmd)
 	vaultCmd.AddCommand(vaultSetKeyCmd)
 	vaultCmd.AddCommand(vaultGetKeyCmd)
+	vaultCmd.AddCommand(vaultListCmd)
+	vaultCmd.AddCommand(vaultRotateCmd)
+	vaultCmd.AddCommand(vaultExportCmd)
+	vaultCmd.AddCommand(vaultImportCmd)
+	vaultCmd.AddCommand(vaultMigrateCmd)
+	vaultCmd.AddCommand(vaultUnlockCmd)
+	vaultCmd.AddCommand(vaultLockCmd)
+	vaultCmd.AddCommand(vaultAgentCmd)
 	rootCmd.AddCommand(vaultCmd)
 }
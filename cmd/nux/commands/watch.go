@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// watchableCommands are the read-only commands --watch can run again and
+// again, by path below the root
+var watchableCommands = map[string]bool{
+	"system info": true, "system uptime": true, "system load": true,
+	"process list": true, "process info": true,
+	"service list": true, "service status": true, "status": true,
+	"container list": true, "wg status": true,
+	"disk list": true, "disk usage": true,
+	"network list": true, "network show": true,
+	"firewall list": true, "users list": true, "history": true,
+}
+
+// parseWatchInterval reads the --watch interval, a duration such as "500ms" or
+// "1m", or a number of seconds
+func parseWatchInterval(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		if secs <= 0 {
+			return 0, fmt.Errorf("--watch interval must be positive, got %q", s)
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --watch interval %q, expected seconds or a duration such as 2s", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--watch interval must be positive, got %q", s)
+	}
+	return d, nil
+}
+
+// validateWatch rejects --watch for commands that change the system or do not
+// print a snapshot
+func validateWatch(cmd *cobra.Command) (time.Duration, error) {
+	if flagWatch == "" {
+		return 0, nil
+	}
+	if flagOn != "" {
+		return 0, fmt.Errorf("--watch and --on cannot be used together")
+	}
+	if isReportFormat() {
+		return 0, fmt.Errorf("--watch cannot be used with --format %s", flagFormat)
+	}
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	if !watchableCommands[path] {
+		return 0, fmt.Errorf("--watch is not supported by 'nux %s'", path)
+	}
+	return parseWatchInterval(flagWatch)
+}
+
+// installWatch makes cmd run every interval until Ctrl-C instead of once
+func installWatch(cmd *cobra.Command, interval time.Duration) {
+	run := cmd.Run
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		runWatch(cmd, args, run, interval)
+	}
+}
+
+// runWatch runs the command at every tick and renders the last output it
+// printed, so the same invocation can feed a terminal or a log shipper
+func runWatch(cmd *cobra.Command, args []string, run func(*cobra.Command, []string), interval time.Duration) {
+	terminal := isTerminal(os.Stdout) && flagOut == ""
+	w := &output.Watch{
+		Command:   strings.Join(append([]string{cmd.Root().Name()}, os.Args[1:]...), " "),
+		Interval:  interval,
+		Redraw:    terminal,
+		Highlight: terminal && !flagNoColor,
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	ctx := commandContext()
+	for {
+		var last *output.Output
+		restore := output.Capture(func(o *output.Output) {
+			last = o
+		})
+		run(cmd, args)
+		restore()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if last != nil {
+			if err := w.Render(last, time.Now()); err != nil {
+				exitWithError(output.NewError(err.Error(), nuxerrors.ErrCodeInvalidInput))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
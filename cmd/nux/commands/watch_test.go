@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWatchInterval(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"2", 2 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"1m", time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseWatchInterval(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseWatchInterval(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"0", "-1s", "soon"} {
+		if _, err := parseWatchInterval(in); err == nil {
+			t.Errorf("Expected parseWatchInterval(%q) to fail", in)
+		}
+	}
+}
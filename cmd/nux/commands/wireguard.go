@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 	"golang.zx2c4.com/wireguard/wgctrl"
@@ -26,67 +27,53 @@ var wgStatusCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		client, err := wgctrl.New()
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to initialize wgctrl: %s", err.Error()), "WG_CTRL_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to initialize wgctrl: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 		defer client.Close()
 
 		devices, err := client.Devices()
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to list WireGuard devices: %s", err.Error()), "WG_LIST_ERROR").Print()
-			return
-		}
-
-		if len(devices) == 0 {
-			output.NewInfo("No WireGuard interfaces found").Print()
+			output.NewError(fmt.Sprintf("Failed to list WireGuard devices: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 
+		items := []map[string]interface{}{}
 		for _, d := range devices {
 			status := "active"
-			color := "\033[32m"
 			if len(d.Peers) == 0 {
 				status = "inactive"
-				color = "\033[33m"
 			}
 
-			handshake := ""
-			if len(d.Peers) > 0 {
-				latest := time.Time{}
-				for _, p := range d.Peers {
-					if p.LastHandshakeTime.After(latest) {
-						latest = p.LastHandshakeTime
-					}
-				}
-				if !latest.IsZero() {
-					ago := time.Since(latest).Round(time.Second)
-					handshake = fmt.Sprintf("%s ago", ago)
+			var latest time.Time
+			var sent, received int64
+			for _, p := range d.Peers {
+				if p.LastHandshakeTime.After(latest) {
+					latest = p.LastHandshakeTime
 				}
+				sent += p.TransmitBytes
+				received += p.ReceiveBytes
 			}
-
-			fmt.Printf("%s%s %s\033[0m\n", color, d.Name, status)
-			fmt.Printf("  Private key: %s\n", d.PrivateKey.String()[:16]+"...")
-			fmt.Printf("  Public key:  %s\n", d.PublicKey.String()[:16]+"...")
-			fmt.Printf("  Listen port: %d\n", d.ListenPort)
-			fmt.Printf("  Peers:       %d\n", len(d.Peers))
-			if handshake != "" {
-				fmt.Printf("  Handshake:   %s\n", handshake)
-			}
-			if len(d.Peers) > 0 {
-				fmt.Println("  Peers:")
-				for _, p := range d.Peers {
-					fmt.Printf("    - %s\n", p.PublicKey.String()[:16]+"...")
-					if p.Endpoint != nil {
-						fmt.Printf("      Endpoint: %s\n", p.Endpoint.String())
-					}
-					fmt.Printf("      Allowed IPs: %v\n", p.AllowedIPs)
-					tx := p.TransmitBytes
-					rx := p.ReceiveBytes
-					fmt.Printf("      Transfer: %s sent, %s received\n", formatBytes(tx), formatBytes(rx))
-				}
+			handshake := ""
+			if !latest.IsZero() {
+				handshake = fmt.Sprintf("%s ago", time.Since(latest).Round(time.Second))
 			}
-			fmt.Println()
+
+			items = append(items, map[string]interface{}{
+				"interface":   d.Name,
+				"status":      status,
+				"public_key":  d.PublicKey.String()[:16] + "...",
+				"listen_port": d.ListenPort,
+				"peers":       len(d.Peers),
+				"handshake":   handshake,
+				"sent":        formatBytes(sent),
+				"received":    formatBytes(received),
+			})
 		}
+
+		output.NewList(items, len(items)).
+			WithColumns("interface", "status", "public_key", "listen_port", "peers", "handshake", "sent", "received").
+			WithMessage("WireGuard interfaces").Print()
 	},
 }
 
@@ -118,7 +105,7 @@ var wgListCmd = &cobra.Command{
 		}
 
 		if !hasContent {
-			out, err := exec.Command("wg", "show", "interfaces").CombinedOutput()
+			out, err := combinedOutput("wg", "show", "interfaces")
 			if err == nil {
 				interfaces := strings.Fields(strings.TrimSpace(string(out)))
 				if len(interfaces) > 0 {
@@ -181,7 +168,7 @@ Example: nux wg connect /etc/wireguard/wg0.conf`,
 			// Try auto-detecting configs in /etc/wireguard/
 			configs, err := os.ReadDir("/etc/wireguard")
 			if err != nil {
-				output.NewError("No config specified and /etc/wireguard/ not found. Usage: nux wg connect <config.conf>", "WG_CONFIG_MISSING").Print()
+				output.NewError("No config specified and /etc/wireguard/ not found. Usage: nux wg connect <config.conf>", nuxerrors.ErrCodeInvalidInput).Print()
 				return
 			}
 			var confFiles []string
@@ -191,13 +178,13 @@ Example: nux wg connect /etc/wireguard/wg0.conf`,
 				}
 			}
 			if len(confFiles) == 0 {
-				output.NewError("No .conf files found in /etc/wireguard/. Specify a config file.", "WG_CONFIG_MISSING").Print()
+				output.NewError("No .conf files found in /etc/wireguard/. Specify a config file.", nuxerrors.ErrCodeInvalidInput).Print()
 				return
 			}
 			if len(confFiles) == 1 {
 				args = []string{strings.TrimSuffix(confFiles[0], ".conf")}
 			} else {
-				output.NewError(fmt.Sprintf("Multiple configs found: %v. Specify one.", confFiles), "WG_MULTIPLE_CONFIGS").Print()
+				output.NewError(fmt.Sprintf("Multiple configs found: %v. Specify one.", confFiles), nuxerrors.ErrCodeInvalidInput).Print()
 				return
 			}
 		}
@@ -205,9 +192,9 @@ Example: nux wg connect /etc/wireguard/wg0.conf`,
 		iface := strings.TrimSuffix(args[0], ".conf")
 		output.NewInfo(fmt.Sprintf("Connecting WireGuard interface %s...", iface)).Print()
 
-		out, err := exec.Command("wg-quick", "up", iface).CombinedOutput()
+		out, err := combinedOutput("wg-quick", "up", iface)
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to connect: %s", strings.TrimSpace(string(out))), "WG_CONNECT_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to connect: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
@@ -230,9 +217,9 @@ var wgDisconnectCmd = &cobra.Command{
 
 		output.NewInfo(fmt.Sprintf("Disconnecting WireGuard interface %s...", iface)).Print()
 
-		out, err := exec.Command("wg-quick", "down", iface).CombinedOutput()
+		out, err := combinedOutput("wg-quick", "down", iface)
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to disconnect: %s", strings.TrimSpace(string(out))), "WG_DISCONNECT_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to disconnect: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
@@ -258,13 +245,13 @@ var wgWarpGenerateCmd = &cobra.Command{
 		output.NewInfo("Generating Cloudflare Warp configuration...").Print()
 
 		if _, err := exec.LookPath("wgcf"); err != nil {
-			output.NewError("wgcf not found. Install it first: https://github.com/ViRb3/wgcf/releases", "WGCF_MISSING").Print()
+			output.NewError("wgcf not found. Install it first: https://github.com/ViRb3/wgcf/releases", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
-		out, err := exec.Command("wgcf", "generate").CombinedOutput()
+		out, err := combinedOutput("wgcf", "generate")
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to generate Warp config: %s", strings.TrimSpace(string(out))), "WGCF_GENERATE_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to generate Warp config: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
@@ -281,13 +268,13 @@ var wgWarpRegisterCmd = &cobra.Command{
 		output.NewInfo("Registering with Cloudflare Warp...").Print()
 
 		if _, err := exec.LookPath("wgcf"); err != nil {
-			output.NewError("wgcf not found. Install it first: https://github.com/ViRb3/wgcf/releases", "WGCF_MISSING").Print()
+			output.NewError("wgcf not found. Install it first: https://github.com/ViRb3/wgcf/releases", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
-		out, err := exec.Command("wgcf", "register", "--accept-tos").CombinedOutput()
+		out, err := combinedOutput("wgcf", "register", "--accept-tos")
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to register: %s", strings.TrimSpace(string(out))), "WGCF_REGISTER_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to register: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
@@ -304,13 +291,13 @@ var wgWarpConnectCmd = &cobra.Command{
 		output.NewInfo("Connecting to Cloudflare Warp...").Print()
 
 		if _, err := exec.LookPath("wgcf"); err != nil {
-			output.NewError("wgcf not found. Install it first: https://github.com/ViRb3/wgcf/releases", "WGCF_MISSING").Print()
+			output.NewError("wgcf not found. Install it first: https://github.com/ViRb3/wgcf/releases", nuxerrors.ErrCodeDependency).Print()
 			return
 		}
 
-		out, err := exec.Command("wgcf", "connect").CombinedOutput()
+		out, err := combinedOutput("wgcf", "connect")
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to connect to Warp: %s", strings.TrimSpace(string(out))), "WGCF_CONNECT_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to connect to Warp: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
@@ -326,11 +313,11 @@ var wgWarpDisconnectCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		output.NewInfo("Disconnecting from Cloudflare Warp...").Print()
 
-		out, err := exec.Command("wg-quick", "down", "wgcf").CombinedOutput()
+		out, err := combinedOutput("wg-quick", "down", "wgcf")
 		if err != nil {
-			out2, err2 := exec.Command("wgcf", "disconnect").CombinedOutput()
+			out2, err2 := combinedOutput("wgcf", "disconnect")
 			if err2 != nil {
-				output.NewError(fmt.Sprintf("Failed to disconnect Warp: %s", strings.TrimSpace(string(out2))), "WGCF_DISCONNECT_ERROR").Print()
+				output.NewError(fmt.Sprintf("Failed to disconnect Warp: %s", strings.TrimSpace(string(out2))), nuxerrors.ErrCodeVPN).Print()
 				return
 			}
 			_ = out2
@@ -368,7 +355,7 @@ var wgWarpStatusCmd = &cobra.Command{
 			}
 		}
 		// Fallback: try resolving warp
-		out, err := exec.Command("wg", "show", "wgcf").CombinedOutput()
+		out, err := combinedOutput("wg", "show", "wgcf")
 		if err != nil {
 			output.NewInfo("Cloudflare Warp is not connected").Print()
 			return
@@ -383,30 +370,30 @@ var wgInstallCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		output.NewInfo("Installing WireGuard tools...").Print()
 
-		out, err := exec.Command("cat", "/etc/os-release").CombinedOutput()
+		out, err := combinedOutput("cat", "/etc/os-release")
 		if err != nil {
-			output.NewError("Failed to detect OS", "WG_OS_DETECT").Print()
+			output.NewError("Failed to detect OS", nuxerrors.ErrCodeUnsupported).Print()
 			return
 		}
 		osRelease := strings.ToLower(string(out))
 
-		var installCmd *exec.Cmd
+		var installScript string
 		switch {
 		case strings.Contains(osRelease, "rocky") || strings.Contains(osRelease, "rhel") || strings.Contains(osRelease, "centos"):
-			installCmd = exec.Command("sh", "-c", "dnf install -y wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf")
+			installScript = "dnf install -y wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf"
 		case strings.Contains(osRelease, "fedora"):
-			installCmd = exec.Command("sh", "-c", "dnf install -y wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf")
+			installScript = "dnf install -y wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf"
 		case strings.Contains(osRelease, "ubuntu") || strings.Contains(osRelease, "debian"):
-			installCmd = exec.Command("sh", "-c", "apt-get install -y wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf")
+			installScript = "apt-get install -y wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf"
 		case strings.Contains(osRelease, "arch"):
-			installCmd = exec.Command("sh", "-c", "pacman -S --noconfirm wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf")
+			installScript = "pacman -S --noconfirm wireguard-tools && curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf"
 		default:
-			installCmd = exec.Command("sh", "-c", "curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf")
+			installScript = "curl -fsSL https://github.com/ViRb3/wgcf/releases/latest/download/wgcf_2.2.22_linux_amd64 -o /usr/local/bin/wgcf && chmod +x /usr/local/bin/wgcf"
 		}
 
-		installOut, err := installCmd.CombinedOutput()
+		installOut, err := combinedOutput("sh", "-c", installScript)
 		if err != nil {
-			output.NewError(fmt.Sprintf("Installation failed: %s", strings.TrimSpace(string(installOut))), "WG_INSTALL_ERROR").Print()
+			output.NewError(fmt.Sprintf("Installation failed: %s", strings.TrimSpace(string(installOut))), nuxerrors.ErrCodePkgInstall).Print()
 			return
 		}
 		output.NewSuccess(map[string]interface{}{
@@ -421,7 +408,7 @@ var wgQuickStatusCmd = &cobra.Command{
 	Short: "Show wg-quick managed interface status",
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check systemd for wg-quick services
-		out, err := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-pager").CombinedOutput()
+		out, err := combinedOutput("systemctl", "list-units", "--type=service", "--all", "--no-pager")
 		if err == nil {
 			lines := strings.Split(string(out), "\n")
 			headers := []string{"SERVICE", "STATUS"}
@@ -470,9 +457,9 @@ var wgShowCmd = &cobra.Command{
 		if len(args) > 0 {
 			wgArgs = append(wgArgs, args[0])
 		}
-		out, err := exec.Command("wg", wgArgs...).CombinedOutput()
+		out, err := combinedOutput("wg", wgArgs...)
 		if err != nil {
-			output.NewError(fmt.Sprintf("wg show failed: %s", strings.TrimSpace(string(out))), "WG_SHOW_ERROR").Print()
+			output.NewError(fmt.Sprintf("wg show failed: %s", strings.TrimSpace(string(out))), nuxerrors.ErrCodeVPN).Print()
 			return
 		}
 		fmt.Print(string(out))
@@ -485,7 +472,7 @@ var wgGenkeyCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		privateKey, err := wgtypes.GeneratePrivateKey()
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to generate key: %s", err.Error()), "WG_KEY_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to generate key: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 		publicKey := privateKey.PublicKey()
@@ -500,7 +487,7 @@ var wgGenpskCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		key, err := wgtypes.GenerateKey()
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to generate PSK: %s", err.Error()), "WG_PSK_ERROR").Print()
+			output.NewError(fmt.Sprintf("Failed to generate PSK: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 		fmt.Printf("Pre-shared key: %s\n", key.String())
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/output"
 	"github.com/spf13/cobra"
 )
@@ -37,20 +39,19 @@ var znetStartCmd = &cobra.Command{
 			output.NewInfo("ZeroNet not found. Installing...").Print()
 			installZeroNet(znetDir)
 			if _, err := os.Stat(znetPy); os.IsNotExist(err) {
-				output.NewError("Auto-install failed. Check 'nux znet doctor' for details.", "ZNET_INSTALL_ERR").Print()
+				output.NewError("Auto-install failed. Check 'nux znet doctor' for details.", nuxerrors.ErrCodeVPN).Print()
 				return
 			}
 		}
 
-cmdStart := exec.Command("python3", znetPy)
-	cmdStart.Dir = znetDir
-	if err := cmdStart.Start(); err != nil {
-		output.NewError(fmt.Sprintf("Failed to start ZeroNet: %s", err.Error()), "ZNET_START_ERR").Print()
-		return
-	}
+		pid, err := adapter.RunDetached(newExecutor(), znetDir, "python3", znetPy)
+		if err != nil {
+			output.NewError(fmt.Sprintf("Failed to start ZeroNet: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
+			return
+		}
 		pidFile := filepath.Join(znetDir, ".pid")
-		os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", cmdStart.Process.Pid)), 0644)
-		output.NewInfo(fmt.Sprintf("ZeroNet started (PID: %d)", cmdStart.Process.Pid)).Print()
+		os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", pid)), 0644)
+		output.NewInfo(fmt.Sprintf("ZeroNet started (PID: %d)", pid)).Print()
 		output.NewInfo("Web UI: http://127.0.0.1:43110").Print()
 	},
 }
@@ -68,12 +69,12 @@ var znetStopCmd = &cobra.Command{
 		pidBytes, err := os.ReadFile(pidFile)
 		if err == nil {
 			pid := strings.TrimSpace(string(pidBytes))
-			exec.Command("kill", pid).Run()
+			newExecutor().Exec(commandContext(), "kill", pid)
 			os.Remove(pidFile)
 			output.NewInfo(fmt.Sprintf("ZeroNet (PID %s) stopped", pid)).Print()
 			return
 		}
-		exec.Command("pkill", "-f", "zeronet.py").Run()
+		newExecutor().Exec(commandContext(), "pkill", "-f", "zeronet.py")
 		output.NewInfo("ZeroNet stopped").Print()
 	},
 }
@@ -118,7 +119,7 @@ var znetListCmd = &cobra.Command{
 		}
 		sites, err := fetchZeroNetSites()
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to query ZeroNet: %s", err.Error()), "ZNET_QUERY_ERR").Print()
+			output.NewError(fmt.Sprintf("Failed to query ZeroNet: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 		if len(sites) == 0 {
@@ -151,13 +152,13 @@ var znetConnectCmd = &cobra.Command{
 		}
 		if len(args) == 0 {
 			output.NewInfo("ZeroNet Web UI: http://127.0.0.1:43110").Print()
-			exec.Command("xdg-open", "http://127.0.0.1:43110").Start()
+			adapter.RunDetached(newExecutor(), "", "xdg-open", "http://127.0.0.1:43110")
 			return
 		}
 		addr := args[0]
 		url := fmt.Sprintf("http://127.0.0.1:43110/%s", addr)
 		output.NewInfo(fmt.Sprintf("Opening ZeroNet site: %s", url)).Print()
-		exec.Command("xdg-open", url).Start()
+		adapter.RunDetached(newExecutor(), "", "xdg-open", url)
 	},
 }
 
@@ -179,7 +180,7 @@ var znetPeersCmd = &cobra.Command{
 		}
 		sites, err := fetchZeroNetSites()
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to query peers: %s", err.Error()), "ZNET_PEERS_ERR").Print()
+			output.NewError(fmt.Sprintf("Failed to query peers: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 		totalPeers := 0
@@ -230,7 +231,7 @@ var znetLogsCmd = &cobra.Command{
 
 		data, err := os.ReadFile(logPath)
 		if err != nil {
-			output.NewError(fmt.Sprintf("Failed to read log: %s", err.Error()), "ZNET_LOG_ERR").Print()
+			output.NewError(fmt.Sprintf("Failed to read log: %s", err.Error()), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 			return
 		}
 		lines := strings.Split(string(data), "\n")
@@ -264,7 +265,7 @@ var znetDoctorCmd = &cobra.Command{
 		if _, err := os.Stat(znetPy); err == nil {
 			output.NewInfo("[OK] ZeroNet installed").Print()
 		} else {
-			output.NewError("[FAIL] ZeroNet not installed", "ZNET_DIAG").Print()
+			output.NewError("[FAIL] ZeroNet not installed", nuxerrors.ErrCodeDependency).Print()
 			issues++
 		}
 
@@ -312,7 +313,7 @@ var znetDoctorCmd = &cobra.Command{
 		if _, err := exec.LookPath("python3"); err == nil {
 			output.NewInfo("[OK] python3 found in PATH").Print()
 		} else {
-			output.NewError("[FAIL] python3 not found", "ZNET_DIAG").Print()
+			output.NewError("[FAIL] python3 not found", nuxerrors.ErrCodeDependency).Print()
 			issues++
 		}
 
@@ -335,23 +336,21 @@ func installZeroNet(znetDir string) {
 	os.MkdirAll(znetDir, 0755)
 	url := "https://github.com/ZeroNetX/ZeroNet/archive/refs/heads/master.tar.gz"
 	output.NewInfo("Downloading ZeroNet...").Print()
-	cmdTar := exec.Command("bash", "-c", fmt.Sprintf(
-		"curl -sL '%s' | tar -xzf - -C '%s' --strip-components=1", url, znetDir))
-	if out, err := cmdTar.CombinedOutput(); err != nil {
-		output.NewError(fmt.Sprintf("Failed to install ZeroNet: %s\n%s", err.Error(), string(out)), "ZNET_INSTALL_ERR").Print()
+	if out, err := combinedOutput("bash", "-c", fmt.Sprintf(
+		"curl -sL '%s' | tar -xzf - -C '%s' --strip-components=1", url, znetDir)); err != nil {
+		output.NewError(fmt.Sprintf("Failed to install ZeroNet: %s\n%s", err.Error(), string(out)), nuxerrors.ErrCodeVPN).WithCause(err).Print()
 		return
 	}
 	output.NewInfo("Applying Python 3 compatibility...").Print()
-	exec.Command("bash", "-c",
+	newExecutor().Exec(commandContext(), "bash", "-c",
 		fmt.Sprintf("2to3 -w --no-diffs %s/zeronet.py %s/start.py %s/src/main.py %s/src/Config.py 2>/dev/null; "+
 			"sed -i 's|python2.7|python3|g' %s/zeronet.py %s/start.py",
-			znetDir, znetDir, znetDir, znetDir, znetDir, znetDir)).Run()
+			znetDir, znetDir, znetDir, znetDir, znetDir, znetDir))
 
 	reqFile := filepath.Join(znetDir, "requirements.txt")
 	if _, err := os.Stat(reqFile); err == nil {
 		output.NewInfo("Installing Python dependencies...").Print()
-		cmdPip := exec.Command("pip3", "install", "-r", reqFile)
-		if out, err := cmdPip.CombinedOutput(); err != nil {
+		if out, err := combinedOutput("pip3", "install", "-r", reqFile); err != nil {
 			output.PrintWarningMessage(fmt.Sprintf("pip install warnings: %s", string(out)))
 		}
 	}
@@ -359,7 +358,7 @@ func installZeroNet(znetDir string) {
 }
 
 func isZeroNetRunning() bool {
-	out, err := exec.Command("pgrep", "-f", "zeronet.py").CombinedOutput()
+	out, err := combinedOutput("pgrep", "-f", "zeronet.py")
 	if err != nil {
 		return false
 	}
@@ -384,7 +383,7 @@ func fetchZeroNetSites() ([]znetSite, error) {
 }
 
 func isPortOpen(port int) bool {
-	out, err := exec.Command("ss", "-tlnp").CombinedOutput()
+	out, err := combinedOutput("ss", "-tlnp")
 	if err != nil {
 		return false
 	}
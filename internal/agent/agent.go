@@ -0,0 +1,140 @@
+package agent
+
+// Package agent runs a model in a loop where it may call tools backed by the
+// nux modules before it answers. Read-only tools run on their own, while
+// tools that change the host run only once approved. It also turns requests
+// into plans of commands, checked before they may run.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// DefaultMaxSteps bounds the rounds of tool calls of a run
+const DefaultMaxSteps = 10
+
+// maxOutput bounds the output of a tool sent back to the model
+const maxOutput = 16 * 1024
+
+// Tool is a tool of the catalog
+type Tool struct {
+	ports.Tool
+	// Mutating tools change the host, so they need approval to run
+	Mutating bool
+	Run      func(args json.RawMessage) (string, error)
+}
+
+// Step is one tool call of a run. Output and Err are set once it ran.
+type Step struct {
+	N        int
+	Call     ports.ToolCall
+	Mutating bool
+	// Approved is false for mutating calls that were refused, which do not run
+	Approved bool
+	Output   string
+	Err      error
+	Duration time.Duration
+}
+
+// Agent answers a conversation, calling tools as the model asks
+type Agent struct {
+	Provider ports.LLMProvider
+	Model    string
+	Tools    []Tool
+	// MaxSteps bounds the rounds of tool calls, DefaultMaxSteps if 0
+	MaxSteps int
+	// Approve decides whether a mutating call runs. Without it none does.
+	Approve func(call ports.ToolCall) bool
+	// OnCall is called before a tool runs, and OnResult after
+	OnCall   func(step Step)
+	OnResult func(step Step)
+}
+
+// Run sends the conversation with the catalog and runs the tools the model
+// calls, until it answers. It returns the answer and the conversation with
+// the calls and their results.
+func (a *Agent) Run(ctx context.Context, messages []ports.ChatMessage, onToken ports.TokenHandler) (*ports.ChatResponse, []ports.ChatMessage, error) {
+	tools := map[string]Tool{}
+	specs := make([]ports.Tool, 0, len(a.Tools))
+	for _, t := range a.Tools {
+		tools[t.Name] = t
+		specs = append(specs, t.Tool)
+	}
+	maxSteps := a.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	n := 0
+	for round := 0; ; round++ {
+		req := ports.ChatRequest{Model: a.Model, Messages: messages}
+		// The last round takes the tools away so the model has to answer
+		if round < maxSteps {
+			req.Tools = specs
+		}
+		resp, err := a.Provider.Chat(ctx, req, onToken)
+		if err != nil {
+			return nil, messages, err
+		}
+		if len(resp.ToolCalls) == 0 {
+			return resp, messages, nil
+		}
+		if round >= maxSteps {
+			return nil, messages, nuxerrors.New(nuxerrors.ErrCodeProvider, fmt.Sprintf("the model kept calling tools after %d rounds", maxSteps)).
+				WithHint("raise --max-steps or ask a narrower question")
+		}
+
+		messages = append(messages, ports.ChatMessage{Role: ports.RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			n++
+			step := a.call(ctx, n, tools, call)
+			result := step.Output
+			switch {
+			case !step.Approved:
+				result = "not run: the user did not approve this call"
+			case step.Err != nil:
+				result = "error: " + step.Err.Error()
+			}
+			messages = append(messages, ports.ChatMessage{Role: ports.RoleTool, Content: result, ToolCallID: call.ID, Name: call.Name})
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, messages, nuxerrors.Wrap(err, nuxerrors.ErrCodeCanceled, "agent interrupted")
+		}
+	}
+}
+
+// call runs one tool call, once approved when it changes the host
+func (a *Agent) call(ctx context.Context, n int, tools map[string]Tool, call ports.ToolCall) Step {
+	step := Step{N: n, Call: call, Approved: true}
+	tool, ok := tools[call.Name]
+	step.Mutating = tool.Mutating
+	if a.OnCall != nil {
+		a.OnCall(step)
+	}
+
+	switch {
+	case !ok:
+		step.Err = nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no tool %s", call.Name))
+	case ctx.Err() != nil:
+		step.Err = nuxerrors.Wrap(ctx.Err(), nuxerrors.ErrCodeCanceled, "agent interrupted")
+	case tool.Mutating && (a.Approve == nil || !a.Approve(call)):
+		step.Approved = false
+	default:
+		start := time.Now()
+		step.Output, step.Err = tool.Run(call.Arguments)
+		step.Duration = time.Since(start)
+		if len(step.Output) > maxOutput {
+			step.Output = step.Output[:maxOutput] + fmt.Sprintf("\n… output cut at %d bytes", maxOutput)
+		}
+	}
+
+	if a.OnResult != nil {
+		a.OnResult(step)
+	}
+	return step
+}
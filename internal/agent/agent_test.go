@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// scripted answers with its responses in turn, keeping the requests
+type scripted struct {
+	responses []ports.ChatResponse
+	requests  []ports.ChatRequest
+}
+
+func (s *scripted) Name() string         { return "scripted" }
+func (s *scripted) DefaultModel() string { return "test" }
+
+func (s *scripted) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	s.requests = append(s.requests, req)
+	resp := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return &resp, nil
+}
+
+type fakeServices struct {
+	ports.ServiceManager
+	restarted []string
+}
+
+func (f *fakeServices) GetServiceLogs(name string, lines int) (string, error) {
+	return strings.Repeat(name+" failed\n", lines), nil
+}
+
+func (f *fakeServices) RestartService(name string) error {
+	f.restarted = append(f.restarted, name)
+	return nil
+}
+
+func call(id, name, args string) ports.ToolCall {
+	return ports.ToolCall{ID: id, Name: name, Arguments: json.RawMessage(args)}
+}
+
+func TestRunCallsTools(t *testing.T) {
+	services := &fakeServices{}
+	p := &scripted{responses: []ports.ChatResponse{
+		{ToolCalls: []ports.ToolCall{call("1", "service_logs", `{"name":"nginx","lines":2}`)}},
+		{ToolCalls: []ports.ToolCall{call("2", "restart_service", `{"name":"nginx"}`)}},
+		{Content: "nginx was restarted"},
+	}}
+	var steps []Step
+	a := &Agent{
+		Provider: p,
+		Tools:    Catalog(Managers{Services: services}),
+		Approve:  func(c ports.ToolCall) bool { return c.Name == "restart_service" },
+		OnResult: func(s Step) { steps = append(steps, s) },
+	}
+	resp, messages, err := a.Run(context.Background(), []ports.ChatMessage{{Role: ports.RoleUser, Content: "fix nginx"}}, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.Content != "nginx was restarted" {
+		t.Errorf("Unexpected answer %q", resp.Content)
+	}
+	if len(steps) != 2 || steps[0].Output != "nginx failed\nnginx failed\n" || !steps[1].Mutating {
+		t.Errorf("Unexpected steps %+v", steps)
+	}
+	if len(services.restarted) != 1 || services.restarted[0] != "nginx" {
+		t.Errorf("Expected nginx to be restarted, got %v", services.restarted)
+	}
+	// user, then a call and its result per round
+	if len(messages) != 5 || messages[2].Role != ports.RoleTool || messages[2].ToolCallID != "1" {
+		t.Errorf("Unexpected conversation %+v", messages)
+	}
+	if len(p.requests[0].Tools) == 0 {
+		t.Error("The catalog should be sent with the request")
+	}
+}
+
+func TestRunRefusesUnapprovedCalls(t *testing.T) {
+	services := &fakeServices{}
+	p := &scripted{responses: []ports.ChatResponse{
+		{ToolCalls: []ports.ToolCall{call("1", "restart_service", `{"name":"nginx"}`), call("2", "format_disk", `{}`)}},
+		{Content: "not restarted"},
+	}}
+	var steps []Step
+	a := &Agent{Provider: p, Tools: Catalog(Managers{Services: services}), OnResult: func(s Step) { steps = append(steps, s) }}
+	if _, _, err := a.Run(context.Background(), nil, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(services.restarted) != 0 {
+		t.Errorf("A mutating call without approval should not run, restarted %v", services.restarted)
+	}
+	if steps[0].Approved || steps[1].Err == nil {
+		t.Errorf("Expected a refused call and an unknown tool, got %+v", steps)
+	}
+	results := p.requests[1].Messages
+	if !strings.HasPrefix(results[1].Content, "not run") || !strings.Contains(results[2].Content, "no tool format_disk") {
+		t.Errorf("The model should be told, got %+v", results)
+	}
+}
+
+func TestRunBoundsSteps(t *testing.T) {
+	p := &scripted{responses: []ports.ChatResponse{
+		{ToolCalls: []ports.ToolCall{call("1", "service_logs", `{"name":"nginx"}`)}},
+	}}
+	a := &Agent{Provider: p, Tools: Catalog(Managers{Services: &fakeServices{}}), MaxSteps: 2}
+	if _, _, err := a.Run(context.Background(), nil, nil); err == nil {
+		t.Fatal("A model that never answers should fail the run")
+	}
+	if len(p.requests) != 3 || p.requests[2].Tools != nil {
+		t.Errorf("The last round should be sent without tools, got %d requests", len(p.requests))
+	}
+}
+
+func TestDecodeArguments(t *testing.T) {
+	var a struct {
+		Name  string
+		Lines int
+	}
+	if err := decode(json.RawMessage(`{"name":"sshd","lines":5}`), &a, "name"); err != nil || a.Name != "sshd" || a.Lines != 5 {
+		t.Errorf("Unexpected arguments %+v, %v", a, err)
+	}
+	if err := decode(json.RawMessage(`{"lines":5}`), &a, "name"); err == nil {
+		t.Error("A missing required argument should fail")
+	}
+	if err := decode(json.RawMessage(`"nginx"`), &a); err == nil {
+		t.Error("Arguments that are not an object should fail")
+	}
+	if bound(0, 50, 500) != 50 || bound(9000, 50, 500) != 500 {
+		t.Error("Counts should be bounded")
+	}
+}
+
+func TestLogRecordsSteps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.jsonl")
+	l := NewLogAt(path, "web01")
+	l.Record(Step{N: 1, Call: call("1", "kill_process", `{"pid":42}`), Mutating: true})
+	l.Record(Step{N: 2, Call: call("2", "process_tree", `not json`), Approved: true, Output: strings.Repeat("x", maxLoggedOutput+10)})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Log missing: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(lines))
+	}
+	var first, second Entry
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+	if first.Run != l.Run() || first.Host != "web01" || first.Approved || !first.Mutating {
+		t.Errorf("Unexpected entry %+v", first)
+	}
+	if string(second.Arguments) != "{}" || len(second.Output) > maxLoggedOutput+len("…") {
+		t.Errorf("Unexpected entry %+v", second)
+	}
+	var nilLog *Log
+	nilLog.Record(Step{})
+}
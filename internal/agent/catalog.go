@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// Limits of the arguments the model may pass
+const (
+	defaultLogLines = 50
+	maxLogLines     = 500
+	defaultTopFiles = 10
+	maxTopFiles     = 100
+)
+
+// Managers are the modules the tools operate. A nil manager leaves its
+// tools out of the catalog.
+type Managers struct {
+	Doctor    ports.DoctorManager
+	Services  ports.ServiceManager
+	Processes ports.ProcessManager
+	Disks     ports.DiskManager
+}
+
+// Catalog returns the tools backed by m, read-only ones first
+func Catalog(m Managers) []Tool {
+	var tools []Tool
+	if m.Doctor != nil {
+		tools = append(tools, Tool{
+			Tool: spec("run_health_checks", "Run the health checks of the host: load, swap, root disk usage and TCP sockets in CLOSE_WAIT", nil),
+			Run: func(json.RawMessage) (string, error) {
+				return result(m.Doctor.RunChecks())
+			},
+		})
+	}
+	if m.Services != nil {
+		tools = append(tools,
+			Tool{
+				Tool: spec("list_services", "List the services of the host with their state", params{
+					"state": {Type: "string", Description: "Only list the services in this state, such as failed or active"},
+				}),
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct{ State string }
+					if err := decode(args, &a); err != nil {
+						return "", err
+					}
+					units, err := m.Services.ListServices()
+					if err != nil || a.State == "" {
+						return result(units, err)
+					}
+					var matched []ports.ServiceUnit
+					for _, u := range units {
+						if u.Status == a.State || u.ActiveState == a.State || u.SubState == a.State {
+							matched = append(matched, u)
+						}
+					}
+					return result(matched, nil)
+				},
+			},
+			Tool{
+				Tool: spec("service_status", "Show the status of a service", params{
+					"name": {Type: "string", Description: "Service name, such as nginx", Required: true},
+				}),
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct{ Name string }
+					if err := decode(args, &a, "name"); err != nil {
+						return "", err
+					}
+					return m.Services.GetServiceStatus(a.Name)
+				},
+			},
+			Tool{
+				Tool: spec("service_logs", "Read the latest log lines of a service from the journal", params{
+					"name":  {Type: "string", Description: "Service name, such as nginx", Required: true},
+					"lines": {Type: "integer", Description: fmt.Sprintf("Number of lines, %d by default and at most %d", defaultLogLines, maxLogLines)},
+				}),
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct {
+						Name  string
+						Lines int
+					}
+					if err := decode(args, &a, "name"); err != nil {
+						return "", err
+					}
+					return m.Services.GetServiceLogs(a.Name, bound(a.Lines, defaultLogLines, maxLogLines))
+				},
+			},
+		)
+	}
+	if m.Processes != nil {
+		tools = append(tools,
+			Tool{
+				Tool: spec("process_tree", "List the processes of the host as a tree, with their user and state", nil),
+				Run: func(json.RawMessage) (string, error) {
+					return result(m.Processes.GetProcessTree())
+				},
+			},
+			Tool{
+				Tool: spec("listening_ports", "List the ports the host listens on, with their processes", nil),
+				Run: func(json.RawMessage) (string, error) {
+					return result(m.Processes.GetAllListeningPorts())
+				},
+			},
+			Tool{
+				Tool: spec("process_open_files", "List the files a process has open", params{
+					"pid": {Type: "integer", Description: "Process id", Required: true},
+				}),
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct{ PID int }
+					if err := decode(args, &a, "pid"); err != nil {
+						return "", err
+					}
+					return result(m.Processes.GetOpenFiles(a.PID))
+				},
+			},
+		)
+	}
+	if m.Disks != nil {
+		tools = append(tools,
+			Tool{
+				Tool: spec("filesystem_usage", "Show the size, usage and free inodes of the filesystem holding a path", params{
+					"path": {Type: "string", Description: "Path on the filesystem, / by default"},
+				}),
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct{ Path string }
+					if err := decode(args, &a); err != nil {
+						return "", err
+					}
+					if a.Path == "" {
+						a.Path = "/"
+					}
+					return result(m.Disks.GetFilesystemUsage(a.Path))
+				},
+			},
+			Tool{
+				Tool: spec("top_files", "List the largest files and directories under a path", params{
+					"path":  {Type: "string", Description: "Directory to look into", Required: true},
+					"count": {Type: "integer", Description: fmt.Sprintf("Number of entries, %d by default and at most %d", defaultTopFiles, maxTopFiles)},
+				}),
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct {
+						Path  string
+						Count int
+					}
+					if err := decode(args, &a, "path"); err != nil {
+						return "", err
+					}
+					return result(m.Disks.GetTopFiles(a.Path, bound(a.Count, defaultTopFiles, maxTopFiles)))
+				},
+			},
+			Tool{
+				Tool: spec("block_devices", "List the block devices of the host with their size and mount points", nil),
+				Run: func(json.RawMessage) (string, error) {
+					return result(m.Disks.ListBlockDevices())
+				},
+			},
+		)
+	}
+
+	if m.Services != nil {
+		for _, action := range []struct {
+			name, verb string
+			run        func(string) error
+		}{
+			{"restart_service", "Restart", m.Services.RestartService},
+			{"start_service", "Start", m.Services.StartService},
+			{"stop_service", "Stop", m.Services.StopService},
+		} {
+			run := action.run
+			tools = append(tools, Tool{
+				Tool: spec(action.name, action.verb+" a service", params{
+					"name": {Type: "string", Description: "Service name, such as nginx", Required: true},
+				}),
+				Mutating: true,
+				Run: func(args json.RawMessage) (string, error) {
+					var a struct{ Name string }
+					if err := decode(args, &a, "name"); err != nil {
+						return "", err
+					}
+					if err := run(a.Name); err != nil {
+						return "", err
+					}
+					return "done", nil
+				},
+			})
+		}
+	}
+	if m.Processes != nil {
+		tools = append(tools, Tool{
+			Tool: spec("kill_process", "Send a signal to a process", params{
+				"pid":    {Type: "integer", Description: "Process id", Required: true},
+				"signal": {Type: "string", Description: "Signal, SIGTERM by default", Enum: []string{"SIGTERM", "SIGKILL", "SIGINT", "SIGHUP"}},
+			}),
+			Mutating: true,
+			Run: func(args json.RawMessage) (string, error) {
+				var a struct {
+					PID    int
+					Signal string
+				}
+				if err := decode(args, &a, "pid"); err != nil {
+					return "", err
+				}
+				if a.Signal == "" {
+					a.Signal = "SIGTERM"
+				}
+				if err := m.Processes.KillProcess(a.PID, a.Signal); err != nil {
+					return "", err
+				}
+				return "done", nil
+			},
+		})
+	}
+	return tools
+}
+
+// param describes an argument of a tool
+type param struct {
+	Type        string
+	Description string
+	Enum        []string
+	Required    bool
+}
+
+type params map[string]param
+
+// spec returns a tool declaration, its parameters as a JSON schema
+func spec(name, description string, ps params) ports.Tool {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for n, p := range ps {
+		prop := map[string]interface{}{"type": p.Type, "description": p.Description}
+		if len(p.Enum) > 0 {
+			prop["enum"] = p.Enum
+		}
+		properties[n] = prop
+		if p.Required {
+			required = append(required, n)
+		}
+	}
+	sort.Strings(required)
+	return ports.Tool{
+		Name:        name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		},
+	}
+}
+
+// decode reads the arguments of a call into v, whose fields match them
+// regardless of case, and checks the required ones were given
+func decode(args json.RawMessage, v interface{}, required ...string) error {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	var given map[string]json.RawMessage
+	if err := json.Unmarshal(args, &given); err != nil {
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "arguments must be a JSON object")
+	}
+	for _, name := range required {
+		if value, ok := given[name]; !ok || string(value) == "null" || string(value) == `""` {
+			return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "missing argument "+name)
+		}
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "invalid arguments: "+strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// result renders what a module returned as compact JSON
+func result(v interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeInternal, "failed to render tool output")
+	}
+	return string(data), nil
+}
+
+// bound returns n within (0, limit], def when unset
+func bound(n, def, limit int) int {
+	switch {
+	case n <= 0:
+		return def
+	case n > limit:
+		return limit
+	}
+	return n
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/logger"
+)
+
+const logDir = ".nux"
+const logFile = "agent.jsonl"
+
+// maxLoggedOutput bounds the output of a tool kept in the log
+const maxLoggedOutput = 4096
+
+// Entry is one logged tool call
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Run       string          `json:"run"`
+	Host      string          `json:"host,omitempty"`
+	Step      int             `json:"step"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Mutating  bool            `json:"mutating,omitempty"`
+	Approved  bool            `json:"approved"`
+	Output    string          `json:"output,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Duration  int64           `json:"duration_ms"`
+}
+
+// Log appends the tool calls of one run to ~/.nux/agent.jsonl. A nil *Log
+// records nothing.
+type Log struct {
+	path string
+	run  string
+	host string
+}
+
+// LogPath returns the location of the agent log
+func LogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to get home directory")
+	}
+	return filepath.Join(home, logDir, logFile), nil
+}
+
+// OpenLog returns the log of a new run against host ("" for the local machine)
+func OpenLog(host string) (*Log, error) {
+	path, err := LogPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewLogAt(path, host), nil
+}
+
+// NewLogAt returns the log of a new run stored at path
+func NewLogAt(path, host string) *Log {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return &Log{path: path, run: hex.EncodeToString(b), host: host}
+}
+
+// Run returns the id shared by the entries of the run
+func (l *Log) Run() string {
+	if l == nil {
+		return ""
+	}
+	return l.run
+}
+
+// Record appends a step that ran or was refused. Failing to write the log
+// must not stop the agent, so errors are only logged.
+func (l *Log) Record(step Step) {
+	if l == nil {
+		return
+	}
+	entry := Entry{
+		Time:      time.Now().UTC(),
+		Run:       l.run,
+		Host:      l.host,
+		Step:      step.N,
+		Tool:      step.Call.Name,
+		Arguments: step.Call.Arguments,
+		Mutating:  step.Mutating,
+		Approved:  step.Approved,
+		Output:    step.Output,
+		Duration:  step.Duration.Milliseconds(),
+	}
+	if len(entry.Output) > maxLoggedOutput {
+		entry.Output = entry.Output[:maxLoggedOutput] + "…"
+	}
+	if step.Err != nil {
+		entry.Error = step.Err.Error()
+	}
+	if !json.Valid(entry.Arguments) {
+		entry.Arguments = json.RawMessage("{}")
+	}
+	if err := l.append(entry); err != nil {
+		logger.Warn("failed to record %s in the agent log: %v", step.Call.Name, err)
+	}
+}
+
+func (l *Log) append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
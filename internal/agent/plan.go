@@ -0,0 +1,346 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rsdenck/nux/internal/core"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// PlanPrompt asks a model to turn a request into a plan it can be parsed from
+const PlanPrompt = `You turn a request about a Linux host into a plan of commands that nux
+will run on it once the user confirms. Answer with a JSON object only:
+
+{"explanation": "what the plan does and why", "steps": [{"command": "...", "explanation": "..."}]}
+
+Each command is a single nux or shell command line: no pipes, redirections,
+quotes, ";", "&&" or substitutions, no wrappers such as sh -c, env, nohup
+or timeout, and no sudo, nux elevates the commands that need root itself. Prefer nux commands where one exists. Leave out
+commands that only inspect the host unless the request needs their output,
+and never propose commands that destroy data beyond what was asked. When the
+request cannot be done safely, answer with no steps and explain why.`
+
+// Plan is the commands a model proposes for a request
+type Plan struct {
+	Explanation string     `json:"explanation"`
+	Steps       []PlanStep `json:"steps"`
+}
+
+// PlanStep is one command of a plan. Rejected gives the reason a command
+// failed validation, which keeps the whole plan from running.
+type PlanStep struct {
+	Command     string `json:"command"`
+	Explanation string `json:"explanation,omitempty"`
+	Rejected    string `json:"rejected,omitempty"`
+}
+
+// ParsePlan reads the plan from the answer of a model, which may wrap the
+// JSON object in text or a code fence
+func ParsePlan(answer string) (*Plan, error) {
+	start := strings.Index(answer, "{")
+	end := strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeProvider, "the model did not answer with a plan").
+			WithHint("try again, or with a model that follows instructions more closely")
+	}
+	var plan Plan
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &plan); err != nil {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeProvider, "the model answered with an invalid plan: "+strings.TrimPrefix(err.Error(), "json: ")).
+			WithHint("try again, or with a model that follows instructions more closely")
+	}
+	steps := plan.Steps[:0]
+	for _, s := range plan.Steps {
+		s.Command = strings.TrimSpace(s.Command)
+		if s.Command != "" {
+			steps = append(steps, s)
+		}
+	}
+	plan.Steps = steps
+	return &plan, nil
+}
+
+// Validate checks every step, recording why the rejected ones cannot run.
+// It reports whether the whole plan may run.
+func (p *Plan) Validate() bool {
+	ok := true
+	for i := range p.Steps {
+		p.Steps[i].Rejected = ""
+		if err := CheckCommand(p.Steps[i].Command); err != nil {
+			p.Steps[i].Rejected = err.Error()
+			ok = false
+		}
+	}
+	return ok
+}
+
+// protectedPaths must never be deleted, or have their owner or mode changed
+// recursively
+var protectedPaths = map[string]bool{
+	"/": true, "/*": true, "~": true, "~/": true, "/bin": true, "/boot": true,
+	"/dev": true, "/etc": true, "/home": true, "/lib": true, "/lib64": true,
+	"/opt": true, "/proc": true, "/root": true, "/sbin": true, "/srv": true,
+	"/sys": true, "/usr": true, "/var": true, "/var/lib": true,
+}
+
+// deniedCommands destroy data, take the host down or lock users out
+// whatever their arguments
+var deniedCommands = map[string]string{
+	"sudo": "nux elevates the commands that need root itself", "su": "nux elevates the commands that need root itself",
+	"doas": "nux elevates the commands that need root itself", "pkexec": "nux elevates the commands that need root itself",
+	"dd": "writes raw devices", "shred": "destroys data", "wipefs": "erases filesystem signatures",
+	"fdisk": "rewrites partition tables", "sfdisk": "rewrites partition tables", "parted": "rewrites partition tables",
+	"shutdown": "takes the host down", "reboot": "takes the host down", "halt": "takes the host down",
+	"poweroff": "takes the host down", "init": "changes the runlevel", "telinit": "changes the runlevel",
+	"userdel": "deletes users", "deluser": "deletes users", "groupdel": "deletes groups",
+	"eval": "runs arbitrary code", "exec": "replaces the shell", "source": "runs arbitrary code",
+}
+
+// wrapperCommands run the command given in their arguments, which the rules
+// of CheckCommand would not see, so they are refused whatever they run
+var wrapperCommands = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "fish": true,
+	"env": true, "nohup": true, "nice": true, "ionice": true, "timeout": true,
+	"xargs": true, "busybox": true, "setsid": true, "stdbuf": true, "chroot": true,
+	"watch": true, "command": true, "builtin": true, "time": true, "flock": true,
+	"nsenter": true, "unshare": true, "runuser": true, "systemd-run": true,
+}
+
+// deniedSystemctl are systemctl verbs that take the host down
+var deniedSystemctl = map[string]bool{
+	"reboot": true, "poweroff": true, "halt": true, "kexec": true, "isolate": true,
+	"emergency": true, "rescue": true, "soft-reboot": true,
+}
+
+// remoteAccessUnits are the services stopping which locks the user out
+var remoteAccessUnits = map[string]bool{"ssh": true, "sshd": true, "ssh.service": true, "sshd.service": true}
+
+// CheckCommand validates one command of a plan: it must pass
+// core.ValidateCommand, which rules out shell operators and quotes, and match
+// none of the destructive patterns
+func CheckCommand(command string) error {
+	if !core.ValidateCommand(command) {
+		return nuxerrors.New(nuxerrors.ErrCodeForbidden, "contains shell operators, quotes or substitutions")
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "empty command")
+	}
+	name := path.Base(fields[0])
+	args := fields[1:]
+	deny := func(reason string) error {
+		return nuxerrors.New(nuxerrors.ErrCodeForbidden, fmt.Sprintf("%s %s", name, reason))
+	}
+
+	if reason, ok := deniedCommands[name]; ok {
+		return deny(reason)
+	}
+	if wrapperCommands[name] {
+		return deny("runs another command, propose that command as the step instead")
+	}
+	if name == "nux" {
+		if reason := checkNux(args); reason != "" {
+			return deny(reason)
+		}
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(name, "mkfs"):
+		return deny("formats filesystems")
+	case name == "rm" && recursive(args) && touchesProtected(args):
+		return deny("deletes a system directory")
+	case name == "rm" && patternUnderProtected(args):
+		return deny("deletes what a pattern matches in a system directory")
+	case (name == "chmod" || name == "chown" || name == "chgrp") && recursive(args) && touchesProtected(args):
+		return deny("changes a system directory recursively")
+	case name == "find" && touchesProtected(args) && hasAny(args, "-delete", "-exec", "-execdir"):
+		return deny("deletes or runs commands across a system directory")
+	case name == "mv" && touchesProtected(args):
+		return deny("moves a system directory")
+	case name == "systemctl" && len(args) > 0:
+		verb := firstOperand(args)
+		if deniedSystemctl[verb] {
+			return deny(verb + " takes the host down")
+		}
+		if verb == "stop" || verb == "disable" || verb == "mask" {
+			for _, a := range args {
+				if remoteAccessUnits[a] {
+					return deny(verb + " " + a + " locks remote users out")
+				}
+			}
+		}
+	case name == "kill" || name == "pkill" || name == "killall":
+		for _, a := range args {
+			if a == "-1" || a == "1" || a == "sshd" {
+				return deny("kills init, every process or remote access")
+			}
+		}
+	}
+	for _, a := range args {
+		if strings.HasPrefix(a, "of=/dev/") {
+			return deny("writes raw devices")
+		}
+	}
+	return nil
+}
+
+// nuxGroups are the nux commands whose subcommands change the host in ways
+// the rules of CheckCommand refuse for the commands they run
+var nuxGroups = map[string]bool{"users": true, "service": true, "bash": true, "remote": true, "process": true, "firewall": true}
+
+// checkNux applies the rules of CheckCommand to the nux subcommands that
+// run the same commands, returning why one is refused. Global flags may come
+// before the subcommand.
+func checkNux(args []string) string {
+	var operands []string
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			operands = append(operands, a)
+		}
+	}
+	for i, group := range operands {
+		if !nuxGroups[group] {
+			continue
+		}
+		if i+1 >= len(operands) {
+			return ""
+		}
+		verb, targets := operands[i+1], operands[i+2:]
+		switch {
+		case group == "users" && verb == "delete":
+			return "users delete deletes users"
+		case group == "bash" && (verb == "exec" || verb == "script"), group == "remote" && verb == "exec":
+			return group + " " + verb + " runs arbitrary commands, propose them as steps instead"
+		case group == "service" && (verb == "stop" || verb == "disable"):
+			for _, t := range targets {
+				if remoteAccessUnits[t] {
+					return "service " + verb + " " + t + " locks remote users out"
+				}
+			}
+		case group == "process" && verb == "kill":
+			for _, t := range targets {
+				if t == "1" || t == "-1" {
+					return "process kill kills init or every process"
+				}
+			}
+		case group == "firewall" && (verb == "remove" || verb == "add" && flagValue(args, "--action") == "deny"):
+			if port := flagValue(args, "--port"); port == "22" || port == "ssh" {
+				return "firewall " + verb + " --port " + port + " locks remote users out"
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// flagValue returns the value of a flag given as --name value or
+// --name=value
+func flagValue(args []string, name string) string {
+	for i, a := range args {
+		if v, ok := strings.CutPrefix(a, name+"="); ok {
+			return v
+		}
+		if a == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// recursive reports whether args hold -r, -R or --recursive, alone or among
+// other short flags
+func recursive(args []string) bool {
+	for _, a := range args {
+		if a == "--recursive" {
+			return true
+		}
+		if strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && strings.ContainsAny(a, "rR") {
+			return true
+		}
+	}
+	return false
+}
+
+// touchesProtected reports whether an operand of args is a protected path,
+// or may expand to one once the shell running the plan sees it
+func touchesProtected(args []string) bool {
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		clean := a
+		if len(clean) > 1 {
+			clean = strings.TrimRight(clean, "/")
+		}
+		if protectedPaths[clean] || protectedPaths[path.Clean(a)] || mayExpandToProtected(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternUnderProtected reports whether an operand of args is a pattern
+// or variable that may expand to protected paths
+func patternUnderProtected(args []string) bool {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") && mayExpandToProtected(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellPatternChars are expanded by the shell, so an operand holding one
+// names paths that cannot be told from the plan
+const shellPatternChars = "$*?["
+
+// mayExpandToProtected reports whether the shell may expand an operand to
+// a protected path or to everything under one, such as /etc/*, ~/* or
+// $HOME. A variable may hold any path. A pattern is judged by the directory
+// it starts from, so /var/log/*.gz passes but /var/lib/* and /e* do not.
+func mayExpandToProtected(operand string) bool {
+	if strings.HasPrefix(operand, "~") && !strings.HasPrefix(operand, "~/") {
+		// ~ alone or ~user is a home directory
+		return true
+	}
+	i := strings.IndexAny(operand, shellPatternChars)
+	if i < 0 {
+		return false
+	}
+	if strings.Contains(operand, "$") || strings.Contains(operand[i:], "..") {
+		return true
+	}
+	slash := strings.LastIndex(operand[:i], "/")
+	if slash < 0 {
+		// Relative to the working directory, like *.gz
+		return false
+	}
+	dir := operand[:slash+1]
+	if dir != "/" {
+		dir = path.Clean(dir)
+	}
+	return protectedPaths[dir]
+}
+
+func hasAny(args []string, values ...string) bool {
+	for _, a := range args {
+		for _, v := range values {
+			if a == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstOperand returns the first argument that is not a flag
+func firstOperand(args []string) string {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return a
+		}
+	}
+	return ""
+}
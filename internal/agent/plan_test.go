@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePlan(t *testing.T) {
+	answer := "Here is the plan:\n```json\n" +
+		`{"explanation":"clean logs","steps":[{"command":"journalctl --vacuum-size=200M","explanation":"shrink the journal"},{"command":"  "}]}` +
+		"\n```"
+	plan, err := ParsePlan(answer)
+	if err != nil {
+		t.Fatalf("ParsePlan failed: %v", err)
+	}
+	if plan.Explanation != "clean logs" || len(plan.Steps) != 1 || plan.Steps[0].Command != "journalctl --vacuum-size=200M" {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if _, err := ParsePlan("just run rm"); err == nil {
+		t.Error("An answer without JSON should fail")
+	}
+	if _, err := ParsePlan(`{"steps": "rm"}`); err == nil {
+		t.Error("An answer with steps that are not a list should fail")
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	allowed := []string{
+		"journalctl --vacuum-size=200M",
+		"apt-get clean",
+		"rm -rf /var/cache/apt/archives",
+		"du -sh /var/log",
+		"find /var/log -name *.gz -mtime +30 -delete",
+		"systemctl restart nginx",
+		"nux disk top /var",
+		"rm -rf /var/log/nginx/*.gz",
+		"nux service restart sshd",
+		"nux service stop nginx",
+		"nux users add deploy",
+		"nux firewall add --port 443",
+		"nux firewall add --port 22",
+		"nux process kill 4242",
+		"rm -f ~/.cache/thumbnails/*",
+	}
+	for _, c := range allowed {
+		if err := CheckCommand(c); err != nil {
+			t.Errorf("%q should be allowed, got %v", c, err)
+		}
+	}
+
+	denied := []string{
+		"rm -rf /",
+		"rm -r --no-preserve-root /",
+		"rm -fr /var/",
+		"/bin/rm -Rf /etc",
+		"chmod -R 777 /",
+		"find / -name core -delete",
+		"mkfs.ext4 /dev/sda1",
+		"dd if=/dev/zero of=/dev/sda",
+		"sudo apt-get clean",
+		"systemctl reboot",
+		"systemctl stop sshd",
+		"kill -9 -1",
+		"du -sh /var/* | sort -h",
+		"echo $(id)",
+		"rm -rf /tmp/x; reboot",
+		"bash -c 'rm -rf /'",
+		"truncate -s 0 /var/log/syslog > /dev/null",
+		"rm -rf /etc/*",
+		"rm -rf /var/lib/*",
+		"rm -rf $HOME",
+		"rm -rf /home/$USER",
+		"rm -rf ~",
+		"rm -rf ~root",
+		"rm -rf ~/*",
+		"rm -rf /e*",
+		"rm -rf /u?r",
+		"rm -rf /[e]tc",
+		"rm -rf /tmp/*/../../etc",
+		"mv /etc/* /tmp",
+		"rm -f /etc/*",
+		"nux users delete root",
+		"nux --host db01 users delete alice",
+		"nux service stop sshd",
+		"nux service disable ssh.service",
+		"nux bash exec rm -rf /",
+		"nux bash script /tmp/cleanup.sh",
+		"nux remote exec db01 reboot",
+		"nux process kill 1",
+		"nux firewall remove --port 22",
+		"nux firewall add --port=22 --action=deny",
+		"/usr/local/bin/nux service stop sshd",
+		"chown -R nobody /var/*",
+		"env rm -rf /",
+		"sh -c reboot",
+		"/bin/bash -c poweroff",
+		"nohup poweroff",
+		"timeout 9 dd of=/dev/sda",
+		"nice mkfs.ext4 /dev/sdb",
+		"xargs rm -rf",
+		"busybox rm -rf /etc",
+		"setsid reboot",
+		"stdbuf -oL shutdown -h now",
+		"chroot /mnt userdel root",
+		"watch -n1 reboot",
+	}
+	for _, c := range denied {
+		if err := CheckCommand(c); err == nil {
+			t.Errorf("%q should be refused", c)
+		}
+	}
+}
+
+func TestValidateMarksRejectedSteps(t *testing.T) {
+	plan := &Plan{Steps: []PlanStep{{Command: "apt-get clean"}, {Command: "shutdown -h now"}}}
+	if plan.Validate() {
+		t.Fatal("A plan with a refused command should not validate")
+	}
+	if plan.Steps[0].Rejected != "" || !strings.Contains(plan.Steps[1].Rejected, "takes the host down") {
+		t.Errorf("Unexpected steps %+v", plan.Steps)
+	}
+}
+
+func TestTranscriptSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "transcripts")
+	tr := NewTranscript(dir, "free up space in /var")
+	tr.SetPlan("{}", &Plan{Explanation: "clean", Steps: []PlanStep{{Command: "apt-get clean"}}})
+	if err := tr.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	info, err := os.Stat(tr.Path())
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected a 0600 transcript, got %v, %v", info, err)
+	}
+	data, _ := os.ReadFile(tr.Path())
+	if !strings.Contains(string(data), `"command": "apt-get clean"`) {
+		t.Errorf("Unexpected transcript %s", data)
+	}
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+const transcriptsDir = ".nux/transcripts"
+
+// Transcript is a request turned into a plan, and what became of it
+type Transcript struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Host        string    `json:"host,omitempty"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model,omitempty"`
+	Request     string    `json:"request"`
+	Answer      string    `json:"answer"`
+	Explanation string    `json:"explanation,omitempty"`
+	// Confirmed is set once the user accepted the plan
+	Confirmed bool      `json:"confirmed"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Steps     []StepRun `json:"steps"`
+
+	dir string
+}
+
+// StepRun is a step of a plan with the outcome of running it
+type StepRun struct {
+	PlanStep
+	Ran      bool   `json:"ran"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms,omitempty"`
+}
+
+// TranscriptDir returns the directory transcripts are stored in
+func TranscriptDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to get home directory")
+	}
+	return filepath.Join(home, transcriptsDir), nil
+}
+
+// NewTranscript starts the transcript of request, stored in dir on Save
+func NewTranscript(dir, request string) *Transcript {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return &Transcript{ID: hex.EncodeToString(b), Time: time.Now().UTC(), Request: request, dir: dir}
+}
+
+// SetPlan records the plan the model answered with
+func (t *Transcript) SetPlan(answer string, plan *Plan) {
+	t.Answer = answer
+	t.Steps = nil
+	if plan == nil {
+		return
+	}
+	t.Explanation = plan.Explanation
+	for _, s := range plan.Steps {
+		t.Steps = append(t.Steps, StepRun{PlanStep: s})
+	}
+}
+
+// Path returns the file the transcript is saved to
+func (t *Transcript) Path() string {
+	return filepath.Join(t.dir, t.ID+".json")
+}
+
+// Save writes the transcript, readable by its owner only
+func (t *Transcript) Save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to encode transcript")
+	}
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to create transcripts directory")
+	}
+	if err := os.WriteFile(t.Path(), data, 0600); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to save transcript")
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// Sources of host context accepted by --context
+const (
+	SourceDoctor   = "doctor"
+	SourceMetrics  = "metrics"
+	SourceServices = "services"
+	SourceJournal  = "journal"
+	SourceProfile  = "profile"
+)
+
+// ContextSources lists the sources accepted by --context
+const ContextSources = "doctor, metrics, services, journal:<unit>, profile"
+
+// ContextSource is one source of host context, with the unit for journal
+type ContextSource struct {
+	Kind string
+	Unit string
+}
+
+// Section is the rendered data of a source. Tail sections, such as logs,
+// keep their last lines when they must be shortened.
+type Section struct {
+	Name string
+	Body string
+	Tail bool
+}
+
+// ParseSources parses a --context list such as "doctor,journal:nginx"
+func ParseSources(spec string) ([]ContextSource, error) {
+	var sources []ContextSource
+	seen := map[ContextSource]bool{}
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		kind, unit, hasUnit := strings.Cut(field, ":")
+		var src ContextSource
+		switch kind {
+		case SourceDoctor, SourceMetrics, SourceServices, SourceProfile:
+			if hasUnit {
+				return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("context source %s takes no unit", kind))
+			}
+			src = ContextSource{Kind: kind}
+		case SourceJournal:
+			if unit == "" {
+				return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "context source journal needs a unit, as in journal:nginx")
+			}
+			src = ContextSource{Kind: kind, Unit: unit}
+		default:
+			return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("unknown context source %q (available: %s)", field, ContextSources))
+		}
+		if !seen[src] {
+			seen[src] = true
+			sources = append(sources, src)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "no context source given (available: "+ContextSources+")")
+	}
+	return sources, nil
+}
+
+// Name returns how the source is titled in the preamble
+func (s ContextSource) Name() string {
+	if s.Unit != "" {
+		return s.Kind + ":" + s.Unit
+	}
+	return s.Kind
+}
+
+// FormatProfile renders the detected system profile
+func FormatProfile(p *domain.SystemProfile) string {
+	return fmt.Sprintf("distro=%s %s init=%s packages=%s firewall=%s network=%s environment=%s",
+		p.Distro, p.Version, p.InitSystem, p.PackageManager, p.Firewall, p.NetworkStack, p.Environment)
+}
+
+// FormatChecks renders health checks, one per line
+func FormatChecks(checks []ports.HealthCheck) string {
+	var b strings.Builder
+	for _, c := range checks {
+		fmt.Fprintf(&b, "%s: %s", c.Name, c.Status)
+		if c.Value != "" {
+			fmt.Fprintf(&b, " (%s)", c.Value)
+		}
+		if c.Message != "" {
+			fmt.Fprintf(&b, " %s", c.Message)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// FormatMetrics renders system metrics and the busiest processes
+func FormatMetrics(m ports.SystemMetrics) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "load=%.2f %.2f %.2f cpu=%.1f%%\n", m.LoadAvg[0], m.LoadAvg[1], m.LoadAvg[2], m.CPUUsage)
+	fmt.Fprintf(&b, "memory=%s/%s swap=%s/%s\n", size(m.MemUsed), size(m.MemTotal), size(m.SwapUsed), size(m.SwapTotal))
+	fmt.Fprintf(&b, "disk read=%s written=%s ops=%d\n", size(m.DiskIO.ReadBytes), size(m.DiskIO.WriteBytes), m.DiskIO.IOPS)
+	fmt.Fprintf(&b, "network rx=%s/s tx=%s/s\n", size(m.NetworkIO.RxRate), size(m.NetworkIO.TxRate))
+	for _, p := range m.TopProcesses {
+		fmt.Fprintf(&b, "process pid=%d user=%s cpu=%.1f%% memory=%s %s\n", p.PID, p.User, p.CPU, size(p.Memory), p.Name)
+	}
+	return b.String()
+}
+
+// FormatServices renders a count of the services and the ones not running
+// as they should: failed, or enabled but inactive
+func FormatServices(units []ports.ServiceUnit) string {
+	var b strings.Builder
+	active := 0
+	var troubled []ports.ServiceUnit
+	for _, u := range units {
+		switch {
+		case u.Status == "active":
+			active++
+		case u.Status == "failed" || u.Enabled:
+			troubled = append(troubled, u)
+		}
+	}
+	fmt.Fprintf(&b, "%d services, %d active\n", len(units), active)
+	for _, u := range troubled {
+		fmt.Fprintf(&b, "%s: %s", u.Name, u.Status)
+		if u.Enabled {
+			b.WriteString(" (enabled)")
+		}
+		if u.Description != "" {
+			fmt.Fprintf(&b, " %s", u.Description)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// RenderPreamble renders the sections under header within about budget
+// tokens. Every section gets an equal share of the budget, the share a small
+// section leaves unused going to the larger ones, and sections longer than
+// their share lose lines. trimmed names the sections that were shortened.
+func RenderPreamble(header string, sections []Section, budget int) (text string, trimmed []string) {
+	// Tokens are counted as EstimateTokens does, at four characters each
+	chars := budget*4 - len(header) - 1
+	for _, s := range sections {
+		chars -= len(sectionTitle(s.Name))
+	}
+
+	allowed := make([]int, len(sections))
+	remaining := make([]int, 0, len(sections))
+	for i, s := range sections {
+		allowed[i] = len(s.Body)
+		remaining = append(remaining, i)
+	}
+	for len(remaining) > 0 {
+		share := max(chars, 0) / len(remaining)
+		var over []int
+		for _, i := range remaining {
+			if allowed[i] <= share {
+				chars -= allowed[i]
+			} else {
+				over = append(over, i)
+			}
+		}
+		if len(over) == len(remaining) {
+			for _, i := range over {
+				allowed[i] = share
+			}
+			break
+		}
+		remaining = over
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteByte('\n')
+	for i, s := range sections {
+		body := strings.TrimRight(s.Body, "\n")
+		if len(s.Body) > allowed[i] {
+			body = shorten(body, allowed[i], s.Tail)
+			trimmed = append(trimmed, s.Name)
+		}
+		b.WriteString(sectionTitle(s.Name))
+		b.WriteString(body)
+		b.WriteByte('\n')
+	}
+	return b.String(), trimmed
+}
+
+func sectionTitle(name string) string {
+	return "[" + name + "]\n"
+}
+
+// shorten cuts body to about limit characters by whole lines, keeping the
+// last ones when tail is set, and says how many were left out
+func shorten(body string, limit int, tail bool) string {
+	lines := strings.Split(body, "\n")
+	note := func(n int) string { return fmt.Sprintf("… %d lines left out", n) }
+
+	var kept []string
+	used := len(note(len(lines))) + 1
+	for i := range lines {
+		line := lines[i]
+		if tail {
+			line = lines[len(lines)-1-i]
+		}
+		if used+len(line)+1 > limit {
+			break
+		}
+		used += len(line) + 1
+		kept = append(kept, line)
+	}
+	if len(kept) == 0 && limit > used {
+		// A single long line is cut rather than left out entirely
+		line := lines[0]
+		if tail {
+			line = lines[len(lines)-1]
+		}
+		kept = append(kept, cut(line, limit-used))
+	}
+
+	dropped := note(len(lines) - len(kept))
+	if len(lines) == len(kept) {
+		dropped = "… cut short"
+	}
+	if tail {
+		out := []string{dropped}
+		for i := len(kept) - 1; i >= 0; i-- {
+			out = append(out, kept[i])
+		}
+		return strings.Join(out, "\n")
+	}
+	return strings.Join(append(kept, dropped), "\n")
+}
+
+// cut shortens s to at most n bytes without splitting a character
+func cut(s string, n int) string {
+	for len(s) > n {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s + "…"
+}
+
+// size renders a byte count with a binary unit
+func size(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources("doctor, journal:nginx,doctor,metrics")
+	if err != nil {
+		t.Fatalf("ParseSources failed: %v", err)
+	}
+	var names []string
+	for _, s := range sources {
+		names = append(names, s.Name())
+	}
+	if strings.Join(names, ",") != "doctor,journal:nginx,metrics" {
+		t.Errorf("Unexpected sources %v", names)
+	}
+
+	for _, bad := range []string{"", "journal", "journal:", "doctor:x", "uptime"} {
+		if _, err := ParseSources(bad); err == nil {
+			t.Errorf("ParseSources(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRenderPreamble(t *testing.T) {
+	var logs []string
+	for i := 1; i <= 100; i++ {
+		logs = append(logs, fmt.Sprintf("line %03d of the journal", i))
+	}
+	sections := []Section{
+		{Name: "profile", Body: "distro=debian 12\n"},
+		{Name: "journal:nginx", Body: strings.Join(logs, "\n"), Tail: true},
+	}
+
+	text, trimmed := RenderPreamble("Host context:", sections, 10000)
+	if len(trimmed) != 0 || !strings.Contains(text, "line 001") {
+		t.Errorf("Everything should fit, trimmed %v", trimmed)
+	}
+
+	text, trimmed = RenderPreamble("Host context:", sections, 200)
+	if strings.Join(trimmed, ",") != "journal:nginx" {
+		t.Errorf("Only the journal should be shortened, got %v", trimmed)
+	}
+	if !strings.Contains(text, "distro=debian 12") {
+		t.Error("The small section should be kept whole")
+	}
+	if !strings.Contains(text, "line 100") || strings.Contains(text, "line 001") {
+		t.Error("The journal should keep its latest lines")
+	}
+	if !strings.Contains(text, "lines left out") {
+		t.Error("Shortened sections should say so")
+	}
+	if tokens := EstimateTokens(ports.ChatMessage{Content: text}); tokens > 210 {
+		t.Errorf("Expected about 200 tokens, got %d", tokens)
+	}
+}
+
+func TestFormatServices(t *testing.T) {
+	text := FormatServices([]ports.ServiceUnit{
+		{Name: "nginx", Status: "failed", Enabled: true},
+		{Name: "sshd", Status: "active", Enabled: true},
+		{Name: "cups", Status: "inactive"},
+	})
+	if !strings.HasPrefix(text, "3 services, 1 active\n") || !strings.Contains(text, "nginx: failed") {
+		t.Errorf("Unexpected services %q", text)
+	}
+	if strings.Contains(text, "cups") {
+		t.Error("Services stopped on purpose should be left out")
+	}
+}
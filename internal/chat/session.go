@@ -0,0 +1,303 @@
+package chat
+
+// Package chat keeps the conversations of 'nux ask chat' in ~/.nux/sessions,
+// one JSON file per session, fits them into the context window of the model
+// they are sent to, and renders the host context sent with the questions.
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+const sessionsDir = ".nux/sessions"
+
+// titleLength bounds the title taken from the first question
+const titleLength = 60
+
+// Session is a conversation with a model
+type Session struct {
+	ID       string              `json:"id"`
+	Title    string              `json:"title,omitempty"`
+	Provider string              `json:"provider"`
+	Model    string              `json:"model,omitempty"`
+	Created  time.Time           `json:"created"`
+	Updated  time.Time           `json:"updated"`
+	Messages []ports.ChatMessage `json:"messages"`
+
+	dir string
+}
+
+// Dir returns the directory sessions are stored in
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to get home directory")
+	}
+	return filepath.Join(home, sessionsDir), nil
+}
+
+// New starts a session stored in dir. It is written on the first Save.
+func New(dir, provider, model string) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return &Session{ID: id, Provider: provider, Model: model, Created: now, Updated: now, dir: dir}, nil
+}
+
+// Load reads the session with the given id, or the only one it is a prefix of
+func Load(dir, id string) (*Session, error) {
+	if !validID(id) {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid session id %q", id))
+	}
+	path := filepath.Join(dir, id+".json")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		matches, _ := filepath.Glob(filepath.Join(dir, id+"*.json"))
+		if len(matches) != 1 {
+			return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no session %s", id)).
+				WithHint("list sessions with 'nux ask sessions'")
+		}
+		path = matches[0]
+	}
+	return read(path)
+}
+
+func read(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to read session")
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, fmt.Sprintf("failed to parse session %s", filepath.Base(path)))
+	}
+	s.dir = filepath.Dir(path)
+	return &s, nil
+}
+
+// List returns the sessions in dir, most recently updated first
+func List(dir string) ([]*Session, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to list sessions")
+	}
+	var sessions []*Session
+	for _, path := range paths {
+		s, err := read(path)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+	return sessions, nil
+}
+
+// Delete removes the session with the given id
+func Delete(dir, id string) error {
+	s, err := Load(dir, id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, s.ID+".json")); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to delete session")
+	}
+	return nil
+}
+
+// Save writes the session. Conversations may hold anything pasted into them,
+// so the file is only readable by its owner.
+func (s *Session) Save() error {
+	s.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to marshal session")
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to create sessions directory")
+	}
+	tmp, err := os.CreateTemp(s.dir, "."+s.ID+"-*")
+	if err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to write session")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to write session")
+	}
+	if err := tmp.Close(); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to write session")
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, s.ID+".json")); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to write session")
+	}
+	return nil
+}
+
+// Add appends a message. The first question becomes the title.
+func (s *Session) Add(role, content string) {
+	if s.Title == "" && role == ports.RoleUser {
+		s.Title = title(content)
+	}
+	s.Messages = append(s.Messages, ports.ChatMessage{Role: role, Content: content})
+}
+
+// SetSystem replaces the system prompt, which guides every answer
+func (s *Session) SetSystem(prompt string) {
+	messages := []ports.ChatMessage{{Role: ports.RoleSystem, Content: prompt}}
+	for _, m := range s.Messages {
+		if m.Role != ports.RoleSystem {
+			messages = append(messages, m)
+		}
+	}
+	s.Messages = messages
+}
+
+// Clear forgets the conversation, keeping the system prompt
+func (s *Session) Clear() {
+	var kept []ports.ChatMessage
+	for _, m := range s.Messages {
+		if m.Role == ports.RoleSystem {
+			kept = append(kept, m)
+		}
+	}
+	s.Messages = kept
+	s.Title = ""
+}
+
+// Context returns the messages to send to a model with the given context
+// window: the system prompt and as many of the latest messages as fit, room
+// being left for the answer. dropped counts the earlier messages left out.
+// The latest message is always sent, even when it does not fit on its own.
+func (s *Session) Context(window int) (messages []ports.ChatMessage, dropped int) {
+	budget := window - answerReserve(window)
+	var system, rest []ports.ChatMessage
+	for _, m := range s.Messages {
+		if m.Role == ports.RoleSystem {
+			system = append(system, m)
+			budget -= EstimateTokens(m)
+		} else {
+			rest = append(rest, m)
+		}
+	}
+
+	start := len(rest)
+	for start > 0 {
+		cost := EstimateTokens(rest[start-1])
+		if cost > budget && start < len(rest) {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	// Some APIs want the conversation to open with a question
+	for start < len(rest)-1 && rest[start].Role != ports.RoleUser {
+		start++
+	}
+	return append(system, rest[start:]...), start
+}
+
+// answerReserve is the part of a context window kept for the answer
+func answerReserve(window int) int {
+	reserve := window / 4
+	if reserve > 4096 {
+		reserve = 4096
+	}
+	return reserve
+}
+
+// EstimateTokens approximates the tokens a message takes, at about four
+// characters per token plus the framing of the message
+func EstimateTokens(m ports.ChatMessage) int {
+	return utf8.RuneCountInString(m.Content)/4 + 4
+}
+
+// DefaultContextWindow is assumed for models not in contextWindows
+const DefaultContextWindow = 8192
+
+// contextWindows are the context windows of common model families, in
+// tokens, matched in order against the model name
+var contextWindows = []struct {
+	family string
+	tokens int
+}{
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4", 8192},
+	{"gpt-3.5", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"llama3.1", 131072},
+	{"llama-3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama-3.2", 131072},
+	{"llama3.3", 131072},
+	{"llama-3.3", 131072},
+	{"llama3", 8192},
+	{"llama-3", 8192},
+	{"qwen3", 40960},
+	{"qwen2.5", 32768},
+	{"mistral", 32768},
+	{"mixtral", 32768},
+	{"gemma", 8192},
+	{"minimax", 1000000},
+}
+
+// ContextWindow returns the context window of a model in tokens
+func ContextWindow(model string) int {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, w := range contextWindows {
+		if strings.HasPrefix(name, w.family) {
+			return w.tokens
+		}
+	}
+	return DefaultContextWindow
+}
+
+func title(content string) string {
+	t := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(t) > titleLength {
+		t = string([]rune(t)[:titleLength-1]) + "…"
+	}
+	return t
+}
+
+func newID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeSession, "failed to generate session id")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID keeps ids to the hex digits New generates, so they cannot point
+// outside the sessions directory
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+func TestSaveLoadListDelete(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	s, err := New(dir, "ollama", "qwen3")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	s.Add(ports.RoleUser, "why is   the disk full?")
+	s.Add(ports.RoleAssistant, "Check /var/log.")
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, s.ID+".json"))
+	if err != nil {
+		t.Fatalf("Session file missing: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	loaded, err := Load(dir, s.ID[:3])
+	if err != nil {
+		t.Fatalf("Load by prefix failed: %v", err)
+	}
+	if loaded.Title != "why is the disk full?" || len(loaded.Messages) != 2 {
+		t.Errorf("Unexpected session %+v", loaded)
+	}
+	if _, err := Load(dir, "../etc"); err == nil {
+		t.Error("An id outside the sessions directory should be refused")
+	}
+
+	other, _ := New(dir, "claude", "")
+	other.Save()
+	sessions, err := List(dir)
+	if err != nil || len(sessions) != 2 || sessions[0].ID != other.ID {
+		t.Errorf("Expected the latest session first, got %v, %v", sessions, err)
+	}
+
+	if err := Delete(dir, s.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Load(dir, s.ID); err == nil {
+		t.Error("A deleted session should not load")
+	}
+}
+
+func TestContext(t *testing.T) {
+	s := &Session{}
+	s.SetSystem("be brief")
+	long := strings.Repeat("x", 400)
+	for i := 0; i < 10; i++ {
+		s.Add(ports.RoleUser, long)
+		s.Add(ports.RoleAssistant, long)
+	}
+
+	messages, dropped := s.Context(1000000)
+	if dropped != 0 || len(messages) != 21 {
+		t.Errorf("Everything should fit, got %d messages, %d dropped", len(messages), dropped)
+	}
+
+	// 800 tokens leave 600 for the conversation, about five messages
+	messages, dropped = s.Context(800)
+	if messages[0].Role != ports.RoleSystem {
+		t.Error("The system prompt should always be sent")
+	}
+	if messages[1].Role != ports.RoleUser {
+		t.Errorf("The conversation should open with a question, got %s", messages[1].Role)
+	}
+	if dropped+len(messages)-1 != 20 || dropped == 0 {
+		t.Errorf("Expected earlier messages to be dropped, got %d messages, %d dropped", len(messages), dropped)
+	}
+
+	s.Clear()
+	s.Add(ports.RoleUser, strings.Repeat("y", 10000))
+	if messages, _ := s.Context(100); len(messages) != 2 {
+		t.Errorf("The latest message should be sent even when too long, got %d messages", len(messages))
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4o-mini":                128000,
+		"claude-3-5-sonnet-20241022": 200000,
+		"meta/llama-3.1-70b":         131072,
+		"Qwen2.5-Coder":              32768,
+		"unknown":                    DefaultContextWindow,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// PlanStep is a mutating command captured by DryRunExecutor instead of being run
+type PlanStep struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Stdin   string   `json:"stdin,omitempty"`
+}
+
+// String renders the step as a shell-like command line
+func (s PlanStep) String() string {
+	return ShellJoin(s.Command, s.Args...)
+}
+
+type readOnlyKey struct{}
+
+// WithReadOnly marks every command executed with the returned context as a
+// read-only probe, so DryRunExecutor runs it even if it cannot classify it
+// (e.g. "sh -c 'ss -tn | wc -l'").
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+func isMarkedReadOnly(ctx context.Context) bool {
+	v, _ := ctx.Value(readOnlyKey{}).(bool)
+	return v
+}
+
+// DryRunExecutor implements Executor for --dry-run. Read-only probes are
+// delegated to the wrapped executor so detection and parsing keep working,
+// while every mutating call is appended to the plan and reported as successful.
+type DryRunExecutor struct {
+	inner Executor
+	mu    sync.Mutex
+	steps []PlanStep
+}
+
+// NewDryRunExecutor wraps inner with plan recording
+func NewDryRunExecutor(inner Executor) *DryRunExecutor {
+	return &DryRunExecutor{inner: inner}
+}
+
+// Exec records or delegates a command
+func (e *DryRunExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return e.ExecWithInput(ctx, "", command, args...)
+}
+
+// ExecWithInput records or delegates a command with input
+func (e *DryRunExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	if isMarkedReadOnly(ctx) || IsReadOnly(command, args...) {
+		return e.inner.ExecWithInput(ctx, input, command, args...)
+	}
+
+	e.record(command, args, input)
+	return &CommandResult{}, nil
+}
+
+// Plan returns the mutating commands recorded so far, in execution order
+func (e *DryRunExecutor) Plan() []PlanStep {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]PlanStep(nil), e.steps...)
+}
+
+// readOnlyCommands never change system state, except with the arguments
+// mutatingArgs reports
+var readOnlyCommands = map[string]bool{
+	"cat": true, "head": true, "tail": true, "grep": true, "wc": true,
+	"ls": true, "stat": true, "readlink": true, "file": true,
+	"nproc": true, "free": true, "uptime": true, "uname": true,
+	"df": true, "du": true, "lsblk": true, "blkid": true, "findmnt": true,
+	"ps": true, "pgrep": true, "lsof": true, "ss": true, "netstat": true,
+	"id": true, "whoami": true, "getent": true, "groups": true,
+	"last": true, "lastb": true, "lastlog": true, "who": true, "w": true,
+	"journalctl": true, "systemd-detect-virt": true, "which": true,
+	"dig": true, "nslookup": true, "host": true, "traceroute": true, "ping": true,
+	"pvs": true, "vgs": true, "lvs": true, "rc-status": true, "apt-cache": true,
+	"dpkg-query": true, "rpm": true, "lscpu": true, "dmidecode": true,
+}
+
+// readOnlySubcommands lists, per tool, the first arguments that only inspect state
+var readOnlySubcommands = map[string][]string{
+	"systemctl":    {"status", "show", "list-units", "list-unit-files", "list-timers", "is-active", "is-enabled", "is-failed", "cat"},
+	"ufw":          {"status"},
+	"firewall-cmd": {"--state", "--list-all", "--list-ports", "--list-services", "--get-default-zone", "--get-active-zones"},
+	"iptables":     {"-L", "-S", "--list", "-nL"},
+	"nft":          {"list"},
+	"apt":          {"search", "show", "list", "policy"},
+	"dnf":          {"search", "info", "list"},
+	"yum":          {"search", "info", "list"},
+	"pacman":       {"-Q", "-Qi", "-Ql", "-Ss", "-Si"},
+	"zypper":       {"search", "se", "info"},
+	"apk":          {"search", "info"},
+	"crontab":      {"-l"},
+	"docker":       {"ps", "images", "inspect", "logs", "info", "version"},
+	"podman":       {"ps", "images", "inspect", "logs", "info", "version"},
+	"wg":           {"show"},
+	"hostname":     {},
+}
+
+// IsReadOnly reports whether a command only inspects state. Unknown commands
+// are treated as mutating so dry-run errs on the side of not touching the host.
+func IsReadOnly(command string, args ...string) bool {
+	if command == "sudo" || command == "doas" {
+		for i, a := range args {
+			if !strings.HasPrefix(a, "-") {
+				return IsReadOnly(a, args[i+1:]...)
+			}
+		}
+		return false
+	}
+
+	if readOnlyCommands[command] {
+		return !mutatingArgs(command, args)
+	}
+
+	// "service --status-all", "service nginx status", "rc-service nginx status"
+	if command == "service" || command == "rc-service" {
+		return len(args) > 0 && (args[0] == "--status-all" || args[len(args)-1] == "status")
+	}
+
+	if command == "ip" {
+		return ipReadOnly(args)
+	}
+
+	subs, ok := readOnlySubcommands[command]
+	if !ok {
+		return false
+	}
+	if len(args) == 0 {
+		// Bare "hostname" and friends print state
+		return true
+	}
+	for _, s := range subs {
+		if args[0] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ipObjects are the ip objects whose state can be listed, and ipShowVerbs
+// the verbs that list it
+var (
+	ipObjects = map[string]bool{
+		"addr": true, "a": true, "address": true, "link": true, "l": true,
+		"route": true, "r": true, "neigh": true, "n": true, "rule": true,
+	}
+	ipShowVerbs = map[string]bool{"show": true, "sh": true, "list": true, "ls": true, "lst": true, "get": true}
+)
+
+// ipOptionValues are the ip options followed by a value
+var ipOptionValues = map[string]bool{
+	"-f": true, "-family": true, "-n": true, "-netns": true, "-l": true, "-loops": true, "-rc": true, "-rcvbuf": true,
+}
+
+// ipReadOnly reports whether an ip command only lists state. Options such as
+// -br and -j come before the object, so they are skipped first: "ip -j link
+// set eth0 down" is a set. The object and the verb must both be known, with
+// no verb meaning show.
+func ipReadOnly(args []string) bool {
+	i := 0
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		switch {
+		case args[i] == "-b" || args[i] == "-batch" || args[i] == "-force":
+			// Commands read from a file
+			return false
+		case ipOptionValues[args[i]]:
+			i++
+		}
+	}
+	if i >= len(args) {
+		// Bare "ip" or options only, such as "ip -V"
+		return true
+	}
+	if !ipObjects[args[i]] {
+		return false
+	}
+	return i+1 >= len(args) || ipShowVerbs[args[i+1]]
+}
+
+// journalctlMaintenance are the journalctl flags that delete, rotate or
+// move the journal instead of reading it
+var journalctlMaintenance = map[string]bool{
+	"--rotate": true, "--flush": true, "--sync": true, "--setup-keys": true,
+	"--relinquish-var": true, "--smart-relinquish-var": true,
+}
+
+// rpmMutatingLong are the long rpm options that change the package database
+var rpmMutatingLong = map[string]bool{
+	"--import": true, "--install": true, "--upgrade": true, "--freshen": true,
+	"--reinstall": true, "--erase": true, "--rebuilddb": true, "--initdb": true,
+	"--setperms": true, "--setugids": true, "--restore": true,
+}
+
+// mutatingArgs reports whether args turn a command of readOnlyCommands into
+// one that changes state, such as "journalctl --vacuum-time=2d" or
+// "rpm -Uvh pkg.rpm"
+func mutatingArgs(command string, args []string) bool {
+	for _, a := range args {
+		switch command {
+		case "journalctl":
+			name, _, _ := strings.Cut(a, "=")
+			if strings.HasPrefix(name, "--vacuum-") || journalctlMaintenance[name] {
+				return true
+			}
+		case "rpm":
+			name, _, _ := strings.Cut(a, "=")
+			if rpmMutatingLong[name] {
+				return true
+			}
+			// The mode is the first letter of a group of short options: -ivh
+			// installs, while -qi queries
+			if len(a) > 1 && a[0] == '-' && a[1] != '-' && strings.ContainsRune("iUFe", rune(a[1])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExecInteractive records an interactive command, or hands the terminal over
+// when it only inspects state
+func (e *DryRunExecutor) ExecInteractive(ctx context.Context, command string, args ...string) error {
+	if isMarkedReadOnly(ctx) || IsReadOnly(command, args...) {
+		return RunInteractive(ctx, e.inner, command, args...)
+	}
+	e.record(command, args, "")
+	return nil
+}
+
+// ExecStream records a command, or streams it when it only inspects state
+func (e *DryRunExecutor) ExecStream(ctx context.Context, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error) {
+	if isMarkedReadOnly(ctx) || IsReadOnly(command, args...) {
+		return RunStream(ctx, e.inner, input, onEvent, command, args...)
+	}
+	e.record(command, args, input)
+	return &CommandResult{}, nil
+}
+
+// ExecDetached records a background command; nothing is started
+func (e *DryRunExecutor) ExecDetached(dir string, command string, args ...string) (int, error) {
+	e.record(command, args, "")
+	return 0, nil
+}
+
+func (e *DryRunExecutor) record(command string, args []string, input string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.steps = append(e.steps, PlanStep{
+		Command: command,
+		Args:    append([]string(nil), args...),
+		Stdin:   input,
+	})
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeExecutor struct {
+	calls []string
+}
+
+func (f *fakeExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return f.ExecWithInput(ctx, "", command, args...)
+}
+
+func (f *fakeExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	f.calls = append(f.calls, command)
+	return &CommandResult{Stdout: "ran"}, nil
+}
+
+func TestDryRunExecutorRecordsMutations(t *testing.T) {
+	inner := &fakeExecutor{}
+	e := NewDryRunExecutor(inner)
+	ctx := context.Background()
+
+	res, err := e.Exec(ctx, "cat", "/etc/os-release")
+	if err != nil || res.Stdout != "ran" {
+		t.Fatalf("read-only probe should be delegated, got %v %v", res, err)
+	}
+
+	if _, err := e.Exec(ctx, "apt", "install", "-y", "nginx"); err != nil {
+		t.Fatalf("mutating call should succeed in dry-run: %v", err)
+	}
+	if _, err := e.ExecWithInput(ctx, "0 5 * * * /bin/true\n", "crontab", "-"); err != nil {
+		t.Fatalf("mutating call should succeed in dry-run: %v", err)
+	}
+
+	if len(inner.calls) != 1 {
+		t.Errorf("Expected 1 delegated call, got %d", len(inner.calls))
+	}
+
+	plan := e.Plan()
+	if len(plan) != 2 {
+		t.Fatalf("Expected 2 plan steps, got %d", len(plan))
+	}
+	if plan[0].String() != "apt install -y nginx" {
+		t.Errorf("Unexpected first step: %s", plan[0].String())
+	}
+	if plan[1].Stdin == "" {
+		t.Error("Stdin should be recorded for crontab step")
+	}
+}
+
+func TestDryRunExecutorHonorsReadOnlyContext(t *testing.T) {
+	inner := &fakeExecutor{}
+	e := NewDryRunExecutor(inner)
+
+	_, _ = e.Exec(WithReadOnly(context.Background()), "sh", "-c", "ss -tnH state close-wait | wc -l")
+
+	if len(inner.calls) != 1 || len(e.Plan()) != 0 {
+		t.Errorf("Marked probe should run, got calls=%d plan=%d", len(inner.calls), len(e.Plan()))
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    bool
+	}{
+		{"systemctl", []string{"status", "nginx"}, true},
+		{"systemctl", []string{"restart", "nginx"}, false},
+		{"sudo", []string{"apt", "install", "-y", "vim"}, false},
+		{"sudo", []string{"-n", "cat", "/etc/shadow"}, true},
+		{"ip", []string{"addr", "show"}, true},
+		{"ip", []string{"addr", "add", "10.0.0.1/24", "dev", "eth0"}, false},
+		{"ip", []string{"-br", "addr"}, true},
+		{"ip", []string{"-j", "-4", "route", "show", "table", "main"}, true},
+		{"ip", []string{"-n", "ns1", "link"}, true},
+		{"ip", nil, true},
+		{"ip", []string{"-br", "addr", "add", "10.0.0.1/24", "dev", "eth0"}, false},
+		{"ip", []string{"-j", "link", "set", "eth0", "down"}, false},
+		{"ip", []string{"-json", "route", "flush", "all"}, false},
+		{"ip", []string{"-batch", "cmds.txt"}, false},
+		{"ip", []string{"netns", "add", "ns1"}, false},
+		{"service", []string{"nginx", "status"}, true},
+		{"crontab", []string{"-l"}, true},
+		{"crontab", []string{"-"}, false},
+		{"useradd", []string{"bob"}, false},
+		{"journalctl", []string{"-u", "nginx", "-n", "50"}, true},
+		{"journalctl", []string{"--vacuum-time=2d"}, false},
+		{"journalctl", []string{"--vacuum-size", "200M"}, false},
+		{"journalctl", []string{"--rotate"}, false},
+		{"journalctl", []string{"--flush"}, false},
+		{"journalctl", []string{"--setup-keys"}, false},
+		{"rpm", []string{"-qa"}, true},
+		{"rpm", []string{"-qi", "bash"}, true},
+		{"rpm", []string{"-ivh", "pkg.rpm"}, false},
+		{"rpm", []string{"-Uvh", "pkg.rpm"}, false},
+		{"rpm", []string{"-F", "pkg.rpm"}, false},
+		{"rpm", []string{"-e", "htop"}, false},
+		{"rpm", []string{"--import", "RPM-GPG-KEY"}, false},
+		{"sudo", []string{"journalctl", "--vacuum-files=2"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsReadOnly(tt.command, tt.args...); got != tt.want {
+			t.Errorf("IsReadOnly(%s %v) = %v, want %v", tt.command, tt.args, got, tt.want)
+		}
+	}
+}
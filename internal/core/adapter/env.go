@@ -0,0 +1,60 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+type envKey struct{}
+
+// WithEnv adds NAME=value variables to the environment of every command
+// executed with the returned context. Unlike arguments, they do not show up
+// in the dry-run plan, recordings or the process list, which makes them the
+// way to hand secrets to a command.
+func WithEnv(ctx context.Context, env ...string) context.Context {
+	// A fresh slice, so contexts derived from the same parent do not write
+	// into its backing array and see each other's variables
+	parent := envFrom(ctx)
+	merged := make([]string, 0, len(parent)+len(env))
+	merged = append(append(merged, parent...), env...)
+	return context.WithValue(ctx, envKey{}, merged)
+}
+
+func envFrom(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
+
+// commandEnv returns the environment of a local command, nil to inherit ours
+func commandEnv(ctx context.Context) []string {
+	env := envFrom(ctx)
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
+
+// envNames returns the names of the variables added with WithEnv
+func envNames(ctx context.Context) []string {
+	var names []string
+	for _, kv := range envFrom(ctx) {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	return names
+}
+
+// refuseRemoteEnv fails commands for a remote host that carry variables: ssh
+// does not forward them, and putting them on the remote command line would
+// expose them
+func refuseRemoteEnv(ctx context.Context, host string) error {
+	if names := envNames(ctx); len(names) > 0 {
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported,
+			fmt.Sprintf("cannot pass %s to %s: variables are only set for local commands", strings.Join(names, ", "), host))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+func TestWithEnv(t *testing.T) {
+	ctx := WithEnv(context.Background(), "NUX_TEST_SECRET=s3cret")
+	res, err := NewExecutor().Exec(ctx, "sh", "-c", `echo "$NUX_TEST_SECRET"`)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if res.Stdout != "s3cret" {
+		t.Errorf("Expected the variable to be set, got %q", res.Stdout)
+	}
+
+	res, err = NewExecutor().Exec(context.Background(), "sh", "-c", `echo "$NUX_TEST_SECRET"`)
+	if err != nil || res.Stdout != "" {
+		t.Errorf("Variables should only reach commands run with the context, got %q, %v", res.Stdout, err)
+	}
+}
+
+func TestWithEnvSiblings(t *testing.T) {
+	// Growing the variables leaves room at the end of the backing array
+	parent := WithEnv(WithEnv(context.Background(), "A=1", "B=2", "C=3"), "D=4")
+	first := WithEnv(parent, "TOKEN=first")
+	second := WithEnv(parent, "TOKEN=second")
+
+	if env := envFrom(first); env[len(env)-1] != "TOKEN=first" {
+		t.Errorf("Expected the first context to keep its variable, got %v", env)
+	}
+	if env := envFrom(second); env[len(env)-1] != "TOKEN=second" {
+		t.Errorf("Expected the second context to keep its variable, got %v", env)
+	}
+	if env := envFrom(parent); len(env) != 4 {
+		t.Errorf("Expected the parent to be unchanged, got %v", env)
+	}
+}
+
+func TestPrivilegeBrokerPreservesEnv(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{
+		probeInteraction("1000\nsudo", "sudo", "doas", "pkexec"),
+		{Command: "sudo", Args: []string{"-n", "true"}},
+		{Command: "sudo", Args: []string{"-n", "--preserve-env=TOKEN", "bash", "-c", "install-tool"}},
+	}}
+	replay := NewReplayExecutor(f)
+	b := NewPrivilegeBroker(replay)
+
+	ctx := WithEnv(WithPrivileged(context.Background()), "TOKEN=s3cret")
+	if _, err := b.Exec(ctx, "bash", "-c", "install-tool"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Errorf("Unused interactions: %v", unused)
+	}
+}
+
+func TestSSHExecutorRefusesEnv(t *testing.T) {
+	local := &recordingExecutor{}
+	e := NewSSHExecutor(local, "web01")
+
+	_, err := e.Exec(WithEnv(context.Background(), "TOKEN=s3cret"), "deploy")
+	if nuxerrors.CodeOf(err) != nuxerrors.ErrCodeUnsupported {
+		t.Errorf("Expected UNSUPPORTED, got %v", err)
+	}
+	if local.command != "" {
+		t.Errorf("Nothing should run, got %s %v", local.command, local.args)
+	}
+}
@@ -5,10 +5,14 @@ package adapter
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 )
 
 // CommandResult represents the result of an execution
@@ -19,22 +23,55 @@ type CommandResult struct {
 	Duration time.Duration
 }
 
+// Combined returns stdout and stderr joined, the way a terminal would show them
+func (r *CommandResult) Combined() string {
+	if r == nil {
+		return ""
+	}
+	switch {
+	case r.Stdout == "":
+		return r.Stderr
+	case r.Stderr == "":
+		return r.Stdout
+	}
+	return r.Stdout + "\n" + r.Stderr
+}
+
 // Executor defines the interface for executing commands
 type Executor interface {
 	Exec(ctx context.Context, command string, args ...string) (*CommandResult, error)
 	ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error)
 }
 
+// InteractiveExecutor is implemented by executors that can hand the terminal
+// to a command (ssh sessions, editors) or leave it running in the background
+// (tunnels, daemons).
+type InteractiveExecutor interface {
+	ExecInteractive(ctx context.Context, command string, args ...string) error
+	ExecDetached(dir string, command string, args ...string) (int, error)
+}
+
 // SystemExecutor implements Executor with safe execution patterns
 type SystemExecutor struct {
-	// mu sync.Mutex // Optional: use for concurrency limiting if needed globally
+	// Timeout bounds each command; zero disables it
+	Timeout time.Duration
+	// Session, when set, cancels every running command once it is done, so
+	// Ctrl-C reaches modules that execute with context.Background()
+	Session context.Context
 }
 
-// NewExecutor creates a new executor
+// NewExecutor creates a new executor. Commands are bounded only by the
+// context they are given, so modules keep their own limits.
 func NewExecutor() Executor {
 	return &SystemExecutor{}
 }
 
+// NewSessionExecutor creates an executor bounded by timeout whose commands
+// are cancelled together with session
+func NewSessionExecutor(session context.Context, timeout time.Duration) Executor {
+	return &SystemExecutor{Timeout: timeout, Session: session}
+}
+
 // Exec executes a command with context
 func (e *SystemExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
 	return e.ExecWithInput(ctx, "", command, args...)
@@ -42,12 +79,13 @@ func (e *SystemExecutor) Exec(ctx context.Context, command string, args ...strin
 
 // ExecWithInput executes a command with input and context
 func (e *SystemExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
-	// Sanitization could happen here, but usually args are safe with exec.Command
-	// We might want to log execution here for audit
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
 
 	start := time.Now()
 
 	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = commandEnv(ctx)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -60,27 +98,131 @@ func (e *SystemExecutor) ExecWithInput(ctx context.Context, input string, comman
 	err := cmd.Run()
 	duration := time.Since(start)
 
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			exitCode = -1 // Internal error or signal
-		}
-	}
-
 	result := &CommandResult{
 		Stdout:   strings.TrimSpace(stdout.String()),
 		Stderr:   strings.TrimSpace(stderr.String()),
-		ExitCode: exitCode,
+		ExitCode: exitCode(err),
 		Duration: duration,
 	}
 
-	// Wrap error if exit code is non-zero and err is nil (shouldn't happen with Run)
-	// Or if err is not nil, return it but also return result for inspection
 	if err != nil {
-		return result, fmt.Errorf("command execution failed: %w (stderr: %s)", err, result.Stderr)
+		return result, e.wrapError(ctx, command, err, result.Stderr)
 	}
 
 	return result, nil
 }
+
+// ExecInteractive runs a command attached to the current terminal
+func (e *SystemExecutor) ExecInteractive(ctx context.Context, command string, args ...string) error {
+	// Interactive sessions last as long as the user wants, so only
+	// cancellation (Ctrl-C, parent context) applies here, not Timeout.
+	ctx, cancel := e.withSession(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = commandEnv(ctx)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return e.wrapError(ctx, command, err, "")
+	}
+	return nil
+}
+
+// ExecDetached starts a command in dir without waiting for it and returns its PID
+func (e *SystemExecutor) ExecDetached(dir string, command string, args ...string) (int, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", command, err)
+	}
+	pid := cmd.Process.Pid
+	// Reap the child when it exits so it does not linger as a zombie
+	go cmd.Wait()
+	return pid, nil
+}
+
+func (e *SystemExecutor) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := e.withSession(ctx)
+	if e.Timeout <= 0 {
+		return ctx, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, e.Timeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
+}
+
+func (e *SystemExecutor) withSession(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if e.Session == nil {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(e.Session, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// wrapError turns timeouts and cancellations into NuxErrors so callers can
+// tell them apart from a command that simply exited non-zero
+func (e *SystemExecutor) wrapError(ctx context.Context, command string, err error, stderr string) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && e.Timeout <= 0:
+		// The limit was set by the module running the command
+		return nuxerrors.Wrapf(nuxerrors.ErrTimeout, nuxerrors.ErrCodeTimeout, "command %s exceeded its time limit", command)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nuxerrors.Wrapf(nuxerrors.ErrTimeout, nuxerrors.ErrCodeTimeout, "command %s timed out after %v", command, e.Timeout).
+			WithHint("raise --timeout, or set it to 0 to wait indefinitely")
+	case errors.Is(ctx.Err(), context.Canceled):
+		return nuxerrors.Wrapf(context.Canceled, nuxerrors.ErrCodeCanceled, "command %s was interrupted", command)
+	}
+	if stderr != "" {
+		return fmt.Errorf("command execution failed: %w (stderr: %s)", err, stderr)
+	}
+	return fmt.Errorf("command execution failed: %w", err)
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1 // Internal error or signal
+}
+
+// RunInteractive hands the terminal to a command when the executor supports it
+func RunInteractive(ctx context.Context, e Executor, command string, args ...string) error {
+	ie, ok := e.(InteractiveExecutor)
+	if !ok {
+		return nuxerrors.Wrapf(nuxerrors.ErrUnsupported, nuxerrors.ErrCodeUnsupported, "%T cannot run interactive commands", e)
+	}
+	return ie.ExecInteractive(ctx, command, args...)
+}
+
+// RunDetached starts a background command when the executor supports it
+func RunDetached(e Executor, dir string, command string, args ...string) (int, error) {
+	ie, ok := e.(InteractiveExecutor)
+	if !ok {
+		return 0, nuxerrors.Wrapf(nuxerrors.ErrUnsupported, nuxerrors.ErrCodeUnsupported, "%T cannot start background commands", e)
+	}
+	return ie.ExecDetached(dir, command, args...)
+}
+
+// Probe runs a read-only check that answers through its exit status, such as
+// "rpm -q curl" or "systemctl is-enabled nginx". known is false when the
+// check could not run at all, so callers can tell "no" from "cannot say".
+func Probe(ctx context.Context, e Executor, command string, args ...string) (yes bool, known bool) {
+	res, err := e.Exec(WithReadOnly(ctx), command, args...)
+	if err == nil {
+		return true, true
+	}
+	return false, res != nil && res.ExitCode > 0
+}
@@ -0,0 +1,55 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+func TestSystemExecutorResult(t *testing.T) {
+	e := NewExecutor()
+
+	res, err := e.Exec(context.Background(), "sh", "-c", "echo out; echo err >&2; exit 3")
+	if err == nil {
+		t.Fatal("Expected error for non-zero exit")
+	}
+	if res.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", res.ExitCode)
+	}
+	if res.Stdout != "out" || res.Stderr != "err" {
+		t.Errorf("Unexpected output: stdout=%q stderr=%q", res.Stdout, res.Stderr)
+	}
+	if res.Combined() != "out\nerr" {
+		t.Errorf("Unexpected combined output: %q", res.Combined())
+	}
+}
+
+func TestSystemExecutorTimeout(t *testing.T) {
+	e := &SystemExecutor{Timeout: 50 * time.Millisecond}
+
+	_, err := e.Exec(context.Background(), "sleep", "5")
+
+	var nerr *nuxerrors.NuxError
+	if !nuxerrors.As(err, &nerr) || nerr.Code != nuxerrors.ErrCodeTimeout {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+}
+
+func TestSystemExecutorSessionCancel(t *testing.T) {
+	session, cancel := context.WithCancel(context.Background())
+	e := NewSessionExecutor(session, 0)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := e.Exec(context.Background(), "sleep", "5")
+
+	var nerr *nuxerrors.NuxError
+	if !nuxerrors.As(err, &nerr) || nerr.Code != nuxerrors.ErrCodeCanceled {
+		t.Fatalf("Expected canceled error, got %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Cancelling the session should kill the running command")
+	}
+}
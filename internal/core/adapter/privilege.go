@@ -0,0 +1,320 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// Escalation modes accepted by PrivilegeBroker.Tool
+const (
+	ElevateAuto   = "auto"
+	ElevateSudo   = "sudo"
+	ElevateDoas   = "doas"
+	ElevatePkexec = "pkexec"
+	ElevateNone   = "none"
+)
+
+// elevationTools are tried in this order when the tool is detected
+var elevationTools = []string{ElevateSudo, ElevateDoas, ElevatePkexec}
+
+type privilegedKey struct{}
+
+// WithPrivileged marks every command executed with the returned context as
+// needing root, for calls the broker cannot classify on its own
+// (e.g. "bash -c 'dnf install lnav -y'").
+func WithPrivileged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, privilegedKey{}, true)
+}
+
+func isMarkedPrivileged(ctx context.Context) bool {
+	v, _ := ctx.Value(privilegedKey{}).(bool)
+	return v
+}
+
+// rootCommands need root for every invocation, even to inspect state
+var rootCommands = map[string]bool{
+	"useradd": true, "userdel": true, "usermod": true, "groupadd": true,
+	"groupdel": true, "groupmod": true, "chpasswd": true, "passwd": true,
+	"ufw": true, "iptables": true, "ip6tables": true, "nft": true, "firewall-cmd": true,
+	"pvs": true, "vgs": true, "lvs": true, "pvcreate": true, "pvremove": true,
+	"pvresize": true, "pvscan": true, "vgcreate": true, "vgextend": true, "vgreduce": true,
+	"vgremove": true, "vgscan": true, "lvcreate": true, "lvextend": true, "lvreduce": true,
+	"lvresize": true, "lvremove": true,
+	"resize2fs": true, "xfs_growfs": true, "mkfs": true, "mount": true, "umount": true,
+	"tcpdump": true, "wg": true, "wg-quick": true, "dmidecode": true, "lastb": true,
+	"apt-get": true, "dpkg": true, "modprobe": true, "reboot": true, "shutdown": true,
+}
+
+// rootWhenMutating need root unless the call only inspects state
+var rootWhenMutating = map[string]bool{
+	"systemctl": true, "service": true, "rc-service": true, "rc-update": true,
+	"apt": true, "dnf": true, "yum": true, "pacman": true, "zypper": true, "apk": true,
+	"ip": true, "hostnamectl": true, "timedatectl": true,
+}
+
+// NeedsRoot reports whether a command must run as root
+func NeedsRoot(command string, args ...string) bool {
+	if rootCommands[command] {
+		return true
+	}
+	if !rootWhenMutating[command] {
+		return false
+	}
+	for _, a := range args {
+		if a == "--user" {
+			// systemctl --user manages the caller's own units
+			return false
+		}
+	}
+	return !IsReadOnly(command, args...)
+}
+
+// permissionMarkers are stderr fragments of commands that failed for lack of
+// privileges
+var permissionMarkers = []string{
+	"permission denied",
+	"operation not permitted",
+	"must be root",
+	"are you root",
+	"need to be root",
+	"only root can",
+	"a password is required",
+	"not in the sudoers",
+	"not authorized",
+}
+
+// PrivilegeBroker implements Executor by running the commands that need root
+// through sudo, doas or pkexec, and everything else unprivileged. The tool is
+// detected and the password asked for once, on the first command that needs
+// it; the credential cached by the tool is reused for the rest of the session.
+type PrivilegeBroker struct {
+	inner Executor
+	// Tool forces sudo, doas or pkexec, or disables escalation with "none".
+	// Empty or "auto" uses the first one installed.
+	Tool string
+	// Interactive allows prompting for a password on the terminal
+	Interactive bool
+
+	mu       sync.Mutex
+	resolved bool
+	root     bool
+	tool     string
+	ready    bool
+	failed   error
+}
+
+// NewPrivilegeBroker wraps inner with privilege escalation
+func NewPrivilegeBroker(inner Executor) *PrivilegeBroker {
+	return &PrivilegeBroker{inner: inner}
+}
+
+// Exec executes a command, elevated when it needs root
+func (b *PrivilegeBroker) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return b.ExecWithInput(ctx, "", command, args...)
+}
+
+// ExecWithInput executes a command with input, elevated when it needs root
+func (b *PrivilegeBroker) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	name, elevated, err := b.elevate(ctx, true, command, args)
+	if err != nil {
+		return &CommandResult{ExitCode: -1}, err
+	}
+	res, err := b.inner.ExecWithInput(ctx, input, name, elevated...)
+	return res, permissionError(command, res, err)
+}
+
+// ExecStream executes a command, elevated when it needs root, streaming its output
+func (b *PrivilegeBroker) ExecStream(ctx context.Context, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error) {
+	name, elevated, err := b.elevate(ctx, true, command, args)
+	if err != nil {
+		return &CommandResult{ExitCode: -1}, err
+	}
+	res, err := RunStream(ctx, b.inner, input, onEvent, name, elevated...)
+	return res, permissionError(command, res, err)
+}
+
+// ExecInteractive runs a command with the terminal attached, elevated when it
+// needs root. The escalation tool may prompt on the terminal itself.
+func (b *PrivilegeBroker) ExecInteractive(ctx context.Context, command string, args ...string) error {
+	name, elevated, err := b.elevate(ctx, false, command, args)
+	if err != nil {
+		return err
+	}
+	return RunInteractive(ctx, b.inner, name, elevated...)
+}
+
+// ExecDetached starts a background command, elevated when it needs root
+func (b *PrivilegeBroker) ExecDetached(dir string, command string, args ...string) (int, error) {
+	name, elevated, err := b.elevate(context.Background(), true, command, args)
+	if err != nil {
+		return 0, err
+	}
+	return RunDetached(b.inner, dir, name, elevated...)
+}
+
+// elevate returns the command line that runs command with the privileges it
+// needs. batch makes sudo and doas fail instead of prompting, for commands
+// whose stdin is not the terminal. Read-only probes (WithReadOnly) never
+// prompt: they are elevated only when no password is needed, and otherwise
+// run unprivileged and degrade as they always have.
+func (b *PrivilegeBroker) elevate(ctx context.Context, batch bool, command string, args []string) (string, []string, error) {
+	if !isMarkedPrivileged(ctx) && !NeedsRoot(command, args...) {
+		return command, args, nil
+	}
+	for _, t := range elevationTools {
+		if command == t {
+			// The caller elevates explicitly
+			return command, args, nil
+		}
+	}
+
+	probe := isMarkedReadOnly(ctx)
+	tool, err := b.acquire(ctx, command, !probe)
+	if probe && err != nil {
+		return command, args, nil
+	}
+	if err != nil || tool == "" {
+		return command, args, err
+	}
+
+	var elevated []string
+	if batch && tool != ElevatePkexec {
+		elevated = append(elevated, "-n")
+	}
+	if names := envNames(ctx); len(names) > 0 {
+		// sudo and doas reset the environment; only sudo can be told to keep
+		// some of it from the command line
+		if tool != ElevateSudo {
+			return command, args, nuxerrors.New(nuxerrors.ErrCodeUnsupported,
+				fmt.Sprintf("cannot pass %s to %s through %s", strings.Join(names, ", "), command, tool)).
+				WithHint("use --elevate sudo, or run nux as root")
+		}
+		elevated = append(elevated, "--preserve-env="+strings.Join(names, ","))
+	}
+	elevated = append(elevated, command)
+	return tool, append(elevated, args...), nil
+}
+
+// acquire returns the tool to elevate with, "" when already root. The first
+// call detects the tool; the first one allowed to prompt obtains the
+// credential, so the password is asked for at most once.
+func (b *PrivilegeBroker) acquire(ctx context.Context, command string, prompt bool) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.resolved {
+		if err := b.resolve(ctx); err != nil {
+			return "", err
+		}
+		b.resolved = true
+	}
+	if b.root {
+		return "", nil
+	}
+	if b.tool == "" {
+		if b.Tool == ElevateNone {
+			return "", nuxerrors.New(nuxerrors.ErrCodePermission,
+				fmt.Sprintf("%s requires root and privilege escalation is disabled", command)).
+				WithHint("run nux as root or drop --elevate none")
+		}
+		return "", nuxerrors.New(nuxerrors.ErrCodePermission,
+			fmt.Sprintf("%s requires root and none of %s is available", command, strings.Join(b.candidates(), ", "))).
+			WithHint("install sudo or doas, or run nux as root")
+	}
+	if b.ready {
+		return b.tool, nil
+	}
+	if b.failed != nil {
+		return "", b.failed
+	}
+
+	if err := b.authenticate(ctx, command, prompt); err != nil {
+		return "", err
+	}
+	b.ready = true
+	return b.tool, nil
+}
+
+// resolve finds out, in one round trip, whether the target user is root and
+// which escalation tool is installed
+func (b *PrivilegeBroker) resolve(ctx context.Context) error {
+	probe := `id -u; for t in "$@"; do if command -v "$t" >/dev/null 2>&1; then echo "$t"; exit 0; fi; done`
+	res, err := b.inner.Exec(WithReadOnly(ctx), "sh", append([]string{"-c", probe, "sh"}, b.candidates()...)...)
+	if err != nil {
+		return fmt.Errorf("failed to check privileges: %w", err)
+	}
+
+	lines := strings.Fields(res.Stdout)
+	b.root = len(lines) > 0 && lines[0] == "0"
+	if len(lines) > 1 {
+		b.tool = lines[1]
+	}
+	return nil
+}
+
+func (b *PrivilegeBroker) candidates() []string {
+	switch b.Tool {
+	case "", ElevateAuto:
+		return elevationTools
+	case ElevateNone:
+		return nil
+	}
+	return []string{b.Tool}
+}
+
+// authenticate makes sure the tool can run commands without asking again,
+// prompting for the password when it cannot and prompt allows it. A failed
+// prompt is not retried. pkexec has no credential cache, so its agent asks on
+// every call and it is never used without prompting.
+func (b *PrivilegeBroker) authenticate(ctx context.Context, command string, prompt bool) error {
+	if b.tool == ElevatePkexec {
+		if !prompt {
+			return nuxerrors.New(nuxerrors.ErrCodePermission, "pkexec always prompts")
+		}
+		return nil
+	}
+	if _, err := b.inner.Exec(WithReadOnly(ctx), b.tool, "-n", "true"); err == nil {
+		return nil
+	}
+
+	if !prompt || !b.Interactive {
+		return nuxerrors.New(nuxerrors.ErrCodePermission,
+			fmt.Sprintf("%s requires root and %s needs a password", command, b.tool)).
+			WithHint(fmt.Sprintf("run nux from a terminal, or allow the command in %s without a password", b.tool))
+	}
+
+	args := []string{"-v"}
+	if b.tool == ElevateDoas {
+		// doas only remembers the password with "persist" in doas.conf
+		args = []string{"true"}
+	}
+	if err := RunInteractive(ctx, b.inner, b.tool, args...); err != nil {
+		b.failed = nuxerrors.Wrapf(err, nuxerrors.ErrCodePermission, "%s authentication failed", b.tool)
+		return b.failed
+	}
+	return nil
+}
+
+// permissionError turns the failure of a command that lacked privileges into
+// an ErrCodePermission error
+func permissionError(command string, res *CommandResult, err error) error {
+	if err == nil || res == nil {
+		return err
+	}
+	var nerr *nuxerrors.NuxError
+	if nuxerrors.As(err, &nerr) {
+		return err
+	}
+
+	stderr := strings.ToLower(res.Stderr)
+	for _, marker := range permissionMarkers {
+		if strings.Contains(stderr, marker) {
+			return nuxerrors.Wrapf(err, nuxerrors.ErrCodePermission, "%s: permission denied", command)
+		}
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+const privilegeProbe = `id -u; for t in "$@"; do if command -v "$t" >/dev/null 2>&1; then echo "$t"; exit 0; fi; done`
+
+func probeInteraction(stdout string, tools ...string) Interaction {
+	return Interaction{Command: "sh", Args: append([]string{"-c", privilegeProbe, "sh"}, tools...), Stdout: stdout}
+}
+
+func TestNeedsRoot(t *testing.T) {
+	tests := []struct {
+		cmd  []string
+		want bool
+	}{
+		{[]string{"useradd", "bob"}, true},
+		{[]string{"ufw", "status"}, true},
+		{[]string{"systemctl", "restart", "nginx"}, true},
+		{[]string{"systemctl", "status", "nginx"}, false},
+		{[]string{"systemctl", "--user", "restart", "app"}, false},
+		{[]string{"apt", "install", "-y", "curl"}, true},
+		{[]string{"apt", "search", "curl"}, false},
+		{[]string{"ip", "addr", "add", "10.0.0.1/24", "dev", "eth0"}, true},
+		{[]string{"crontab", "-l"}, false},
+		{[]string{"bash", "-c", "echo hi"}, false},
+	}
+	for _, tt := range tests {
+		if got := NeedsRoot(tt.cmd[0], tt.cmd[1:]...); got != tt.want {
+			t.Errorf("NeedsRoot(%v) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestPrivilegeBrokerElevatesOnlyWhatNeedsRoot(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{
+		probeInteraction("1000\nsudo", "sudo", "doas", "pkexec"),
+		{Command: "sudo", Args: []string{"-n", "true"}},
+		{Command: "sudo", Args: []string{"-n", "useradd", "bob"}},
+		{Command: "sudo", Args: []string{"-n", "systemctl", "restart", "nginx"}},
+		{Command: "sudo", Args: []string{"-n", "bash", "-c", "dnf install lnav -y"}},
+		{Command: "systemctl", Args: []string{"status", "nginx"}, Stdout: "active"},
+	}}
+	replay := NewReplayExecutor(f)
+	b := NewPrivilegeBroker(replay)
+	ctx := context.Background()
+
+	for _, cmd := range [][]string{
+		{"useradd", "bob"},
+		{"systemctl", "restart", "nginx"},
+		{"systemctl", "status", "nginx"},
+	} {
+		if _, err := b.Exec(ctx, cmd[0], cmd[1:]...); err != nil {
+			t.Fatalf("Exec(%v) failed: %v", cmd, err)
+		}
+	}
+	if _, err := b.Exec(WithPrivileged(ctx), "bash", "-c", "dnf install lnav -y"); err != nil {
+		t.Fatalf("Privileged exec failed: %v", err)
+	}
+
+	// Detection and authentication happen once for the whole session
+	if calls := replay.Calls(); len(calls) != 6 {
+		t.Errorf("Expected 6 calls, got %v", calls)
+	}
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Errorf("Unused interactions: %v", unused)
+	}
+}
+
+func TestPrivilegeBrokerAsRoot(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{
+		probeInteraction("0", "sudo", "doas", "pkexec"),
+		{Command: "ufw", Args: []string{"allow", "22/tcp"}},
+	}}
+	b := NewPrivilegeBroker(NewReplayExecutor(f))
+
+	if _, err := b.Exec(context.Background(), "ufw", "allow", "22/tcp"); err != nil {
+		t.Fatalf("Root should run commands directly: %v", err)
+	}
+}
+
+func TestPrivilegeBrokerPermissionErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		tool         string
+		interactions []Interaction
+	}{
+		{
+			name: "password needed without a terminal",
+			interactions: []Interaction{
+				probeInteraction("1000\nsudo", "sudo", "doas", "pkexec"),
+				{Command: "sudo", Args: []string{"-n", "true"}, ExitCode: 1, Stderr: "sudo: a password is required"},
+			},
+		},
+		{
+			name:         "no escalation tool",
+			interactions: []Interaction{probeInteraction("1000", "sudo", "doas", "pkexec")},
+		},
+		{
+			name:         "escalation disabled",
+			tool:         ElevateNone,
+			interactions: []Interaction{probeInteraction("1000")},
+		},
+		{
+			name: "command denied",
+			tool: ElevatePkexec,
+			interactions: []Interaction{
+				probeInteraction("1000\npkexec", "pkexec"),
+				{Command: "pkexec", Args: []string{"useradd", "bob"}, ExitCode: 127, Stderr: "Error executing command as another user: Not authorized"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		b := NewPrivilegeBroker(NewReplayExecutor(&Fixture{Interactions: tt.interactions}))
+		b.Tool = tt.tool
+
+		_, err := b.Exec(context.Background(), "useradd", "bob")
+		var nerr *nuxerrors.NuxError
+		if !nuxerrors.As(err, &nerr) || nerr.Code != nuxerrors.ErrCodePermission {
+			t.Errorf("%s: expected permission error, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestPrivilegeBrokerProbesNeverPrompt(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{
+		probeInteraction("1000\nsudo", "sudo", "doas", "pkexec"),
+		{Command: "sudo", Args: []string{"-n", "true"}, ExitCode: 1, Stderr: "sudo: a password is required"},
+		{Command: "ufw", Args: []string{"status"}, ExitCode: 1, Stderr: "ERROR: You need to be root to run this script"},
+	}}
+	b := NewPrivilegeBroker(NewReplayExecutor(f))
+	b.Interactive = true
+
+	// The probe runs unprivileged instead of asking for a password
+	_, err := b.Exec(WithReadOnly(context.Background()), "ufw", "status")
+	var nerr *nuxerrors.NuxError
+	if !nuxerrors.As(err, &nerr) || nerr.Code != nuxerrors.ErrCodePermission {
+		t.Fatalf("Expected permission error from the unprivileged probe, got %v", err)
+	}
+}
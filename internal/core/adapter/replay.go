@@ -0,0 +1,236 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// Interaction is one command and what it returned, as captured by
+// RecordingExecutor
+type Interaction struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args"`
+	Stdin    string   `json:"stdin,omitempty"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr"`
+	ExitCode int      `json:"exit_code"`
+	// Error is set when the command could not run or was killed
+	Error string `json:"error,omitempty"`
+}
+
+// String renders the interaction as a shell-like command line
+func (i Interaction) String() string {
+	return ShellJoin(i.Command, i.Args...)
+}
+
+func (i Interaction) matches(command string, args []string, input string) bool {
+	if len(args) == 0 && len(i.Args) == 0 {
+		return i.Command == command && i.Stdin == input
+	}
+	return i.Command == command && i.Stdin == input && reflect.DeepEqual(i.Args, args)
+}
+
+// Fixture is a command transcript captured on a real host, together with the
+// profile detected there
+type Fixture struct {
+	Name         string                `json:"name,omitempty"`
+	Profile      *domain.SystemProfile `json:"profile,omitempty"`
+	Interactions []Interaction         `json:"interactions"`
+}
+
+// LoadFixture reads a fixture file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture to path
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// RecordingExecutor runs commands through the wrapped executor and captures
+// every call into a fixture
+type RecordingExecutor struct {
+	inner   Executor
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecordingExecutor wraps inner with transcript recording
+func NewRecordingExecutor(inner Executor) *RecordingExecutor {
+	return &RecordingExecutor{inner: inner}
+}
+
+// Exec runs and records a command
+func (e *RecordingExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return e.ExecWithInput(ctx, "", command, args...)
+}
+
+// ExecWithInput runs and records a command with input
+func (e *RecordingExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	res, err := e.inner.ExecWithInput(ctx, input, command, args...)
+	e.record(command, args, input, res, err)
+	return res, err
+}
+
+// ExecStream runs and records a command, passing its output on as it arrives
+func (e *RecordingExecutor) ExecStream(ctx context.Context, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error) {
+	var stdout, stderr []string
+	res, err := RunStream(ctx, e.inner, input, func(ev Event) {
+		if ev.Type == EventLine {
+			if ev.Stream == StreamStderr {
+				stderr = append(stderr, ev.Line)
+			} else {
+				stdout = append(stdout, ev.Line)
+			}
+		}
+		onEvent(ev)
+	}, command, args...)
+
+	recorded := &CommandResult{Stdout: strings.Join(stdout, "\n"), Stderr: strings.Join(stderr, "\n")}
+	if res != nil {
+		recorded.ExitCode = res.ExitCode
+	}
+	e.record(command, args, input, recorded, err)
+	return res, err
+}
+
+func (e *RecordingExecutor) record(command string, args []string, input string, res *CommandResult, err error) {
+	i := Interaction{Command: command, Args: append([]string(nil), args...), Stdin: input}
+	if res != nil {
+		i.Stdout, i.Stderr, i.ExitCode = res.Stdout, res.Stderr, res.ExitCode
+	}
+	if err != nil && i.ExitCode <= 0 {
+		i.ExitCode = -1
+		i.Error = err.Error()
+	}
+
+	e.mu.Lock()
+	e.fixture.Interactions = append(e.fixture.Interactions, i)
+	e.mu.Unlock()
+}
+
+// SetProfile stores the profile detected on the recorded host
+func (e *RecordingExecutor) SetProfile(p *domain.SystemProfile) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fixture.Profile = p
+}
+
+// Fixture returns a copy of everything recorded so far
+func (e *RecordingExecutor) Fixture() *Fixture {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f := e.fixture
+	f.Interactions = append([]Interaction(nil), e.fixture.Interactions...)
+	return &f
+}
+
+// ReplayExecutor serves the results of a fixture instead of running
+// commands. Calls are matched on command, args and stdin; repeated calls
+// consume recorded interactions in order and then keep returning the last one.
+type ReplayExecutor struct {
+	fixture *Fixture
+	mu      sync.Mutex
+	used    []bool
+	calls   []Interaction
+}
+
+// NewReplayExecutor serves the interactions in f
+func NewReplayExecutor(f *Fixture) *ReplayExecutor {
+	return &ReplayExecutor{fixture: f, used: make([]bool, len(f.Interactions))}
+}
+
+// Exec replays a command
+func (e *ReplayExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return e.ExecWithInput(ctx, "", command, args...)
+}
+
+// ExecWithInput replays a command with input. A call missing from the
+// fixture fails with ErrCodeNotFound.
+func (e *ReplayExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls = append(e.calls, Interaction{Command: command, Args: append([]string(nil), args...), Stdin: input})
+
+	last := -1
+	for idx, i := range e.fixture.Interactions {
+		if !i.matches(command, args, input) {
+			continue
+		}
+		last = idx
+		if !e.used[idx] {
+			break
+		}
+	}
+	if last < 0 {
+		return &CommandResult{ExitCode: -1}, nuxerrors.New(nuxerrors.ErrCodeNotFound,
+			fmt.Sprintf("no recorded interaction for %s", ShellJoin(command, args...)))
+	}
+	e.used[last] = true
+
+	i := e.fixture.Interactions[last]
+	result := &CommandResult{Stdout: i.Stdout, Stderr: i.Stderr, ExitCode: i.ExitCode}
+	switch {
+	case i.Error != "":
+		return result, errors.New(i.Error)
+	case i.ExitCode != 0 && i.Stderr != "":
+		return result, fmt.Errorf("command execution failed: exit status %d (stderr: %s)", i.ExitCode, i.Stderr)
+	case i.ExitCode != 0:
+		return result, fmt.Errorf("command execution failed: exit status %d", i.ExitCode)
+	}
+	return result, nil
+}
+
+// Calls returns every command executed so far, in order
+func (e *ReplayExecutor) Calls() []Interaction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Interaction(nil), e.calls...)
+}
+
+// Unused returns the recorded interactions that were never replayed. Given
+// commands, only their interactions are returned, so a transcript shared by
+// several managers can be checked one manager at a time.
+func (e *ReplayExecutor) Unused(commands ...string) []Interaction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var unused []Interaction
+	for idx, i := range e.fixture.Interactions {
+		if e.used[idx] || (len(commands) > 0 && !slices.Contains(commands, i.Command)) {
+			continue
+		}
+		unused = append(unused, i)
+	}
+	return unused
+}
@@ -0,0 +1,94 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	inner := &recordingExecutor{result: &CommandResult{Stdout: "active", ExitCode: 0}}
+	rec := NewRecordingExecutor(inner)
+	rec.SetProfile(&domain.SystemProfile{Distro: "ubuntu", InitSystem: "systemd"})
+
+	if _, err := rec.Exec(context.Background(), "systemctl", "is-active", "nginx"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	inner.result = &CommandResult{Stderr: "Failed to start foo.service", ExitCode: 5}
+	inner.err = errors.New("exit status 5")
+	rec.ExecWithInput(context.Background(), "input", "systemctl", "start", "foo")
+
+	path := filepath.Join(t.TempDir(), "ubuntu.json")
+	if err := rec.Fixture().Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("LoadFixture failed: %v", err)
+	}
+	if fixture.Profile == nil || fixture.Profile.Distro != "ubuntu" {
+		t.Errorf("profile not saved: %+v", fixture.Profile)
+	}
+	if len(fixture.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(fixture.Interactions))
+	}
+
+	replay := NewReplayExecutor(fixture)
+	res, err := replay.Exec(context.Background(), "systemctl", "is-active", "nginx")
+	if err != nil || res.Stdout != "active" {
+		t.Errorf("replay = %+v, %v", res, err)
+	}
+
+	res, err = replay.ExecWithInput(context.Background(), "input", "systemctl", "start", "foo")
+	if err == nil || res.ExitCode != 5 || res.Stderr != "Failed to start foo.service" {
+		t.Errorf("expected recorded failure, got %+v, %v", res, err)
+	}
+
+	if len(replay.Unused()) != 0 {
+		t.Errorf("expected every interaction to be replayed, unused: %v", replay.Unused())
+	}
+}
+
+func TestReplayMatching(t *testing.T) {
+	replay := NewReplayExecutor(&Fixture{Interactions: []Interaction{
+		{Command: "crontab", Args: []string{"-l"}, Stdout: "first"},
+		{Command: "crontab", Args: []string{"-l"}, Stdout: "second"},
+		{Command: "crontab", Args: []string{"-"}, Stdin: "0 5 * * * backup\n"},
+	}})
+
+	for _, want := range []string{"first", "second", "second"} {
+		res, _ := replay.Exec(context.Background(), "crontab", "-l")
+		if res.Stdout != want {
+			t.Errorf("expected %q, got %q", want, res.Stdout)
+		}
+	}
+
+	if _, err := replay.ExecWithInput(context.Background(), "other\n", "crontab", "-"); err == nil {
+		t.Error("stdin should be part of the match")
+	}
+	var nuxErr *nuxerrors.NuxError
+	_, err := replay.Exec(context.Background(), "crontab", "-r")
+	if !errors.As(err, &nuxErr) || nuxErr.Code != nuxerrors.ErrCodeNotFound {
+		t.Errorf("expected ErrCodeNotFound for unrecorded call, got %v", err)
+	}
+
+	want := []string{"crontab -l", "crontab -l", "crontab -l", "crontab -", "crontab -r"}
+	var got []string
+	for _, c := range replay.Calls() {
+		got = append(got, c.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %v, want %v", got, want)
+	}
+	if unused := replay.Unused("crontab"); len(unused) != 1 || unused[0].Stdin != "0 5 * * * backup\n" {
+		t.Errorf("expected the crontab - interaction to be unused, got %v", unused)
+	}
+	if unused := replay.Unused("systemctl"); len(unused) != 0 {
+		t.Errorf("expected no unused systemctl interaction, got %v", unused)
+	}
+}
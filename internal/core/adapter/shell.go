@@ -0,0 +1,22 @@
+package adapter
+
+import "strings"
+
+// ShellQuote quotes s for a POSIX shell when it contains anything the shell
+// would interpret
+func ShellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"$`\\|&;<>*?()[]{}#~!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellJoin renders a command and its arguments as a single shell command line
+func ShellJoin(command string, args ...string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, ShellQuote(command))
+	for _, a := range args {
+		parts = append(parts, ShellQuote(a))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,106 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// sshConnectionError is the exit status ssh reserves for its own failures
+const sshConnectionError = 255
+
+// sshError classifies a failure of ssh itself: rejected credentials are not
+// worth retrying, an unreachable host may be
+func sshError(err error, host string, stderr string) error {
+	if strings.Contains(stderr, "Permission denied") {
+		return nuxerrors.Wrapf(err, nuxerrors.ErrCodeSSHAuth, "ssh to %s was refused", host).
+			WithHint(fmt.Sprintf("check the user and key of Host %s in ~/.ssh/config", host))
+	}
+	return nuxerrors.Wrapf(err, nuxerrors.ErrCodeSSHConnect, "ssh to %s failed", host).
+		WithHint(fmt.Sprintf("check that 'ssh %s' connects", host))
+}
+
+// SSHExecutor implements Executor by running every command on a remote host
+// through the system ssh client, so managers written against Executor work
+// unchanged on another box.
+type SSHExecutor struct {
+	local Executor
+	host  string
+	// ControlPath, when set, lets consecutive commands share one connection
+	// (ssh -o ControlMaster=auto), which keeps profile detection fast
+	ControlPath string
+}
+
+// NewSSHExecutor creates an executor for host, an alias from ~/.ssh/config.
+// The ssh process itself runs through local, which bounds it by the local
+// timeout and session.
+func NewSSHExecutor(local Executor, host string) *SSHExecutor {
+	return &SSHExecutor{local: local, host: host}
+}
+
+// Host returns the ssh profile commands are sent to
+func (e *SSHExecutor) Host() string {
+	return e.host
+}
+
+// Exec executes a command on the remote host
+func (e *SSHExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return e.ExecWithInput(ctx, "", command, args...)
+}
+
+// ExecWithInput executes a command on the remote host, forwarding input as its stdin
+func (e *SSHExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	if err := refuseRemoteEnv(ctx, e.host); err != nil {
+		return &CommandResult{ExitCode: -1}, err
+	}
+	sshArgs := append(e.sshOptions("-o", "BatchMode=yes"), e.host, "--", ShellJoin(command, args...))
+
+	res, err := e.local.ExecWithInput(ctx, input, "ssh", sshArgs...)
+	if err != nil && res != nil && res.ExitCode == sshConnectionError {
+		return res, sshError(err, e.host, res.Stderr)
+	}
+	return res, err
+}
+
+// ExecInteractive runs a command on the remote host with a terminal attached
+func (e *SSHExecutor) ExecInteractive(ctx context.Context, command string, args ...string) error {
+	if err := refuseRemoteEnv(ctx, e.host); err != nil {
+		return err
+	}
+	sshArgs := append(e.sshOptions("-t"), e.host, "--", ShellJoin(command, args...))
+	return RunInteractive(ctx, e.local, "ssh", sshArgs...)
+}
+
+// ExecDetached starts a command in the background on the remote host and
+// returns its remote PID
+func (e *SSHExecutor) ExecDetached(dir string, command string, args ...string) (int, error) {
+	script := fmt.Sprintf("nohup %s >/dev/null 2>&1 & echo $!", ShellJoin(command, args...))
+	if dir != "" {
+		script = "cd " + ShellQuote(dir) + " && " + script
+	}
+
+	res, err := e.Exec(context.Background(), "sh", "-c", script)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(res.Stdout))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected pid from %s: %q", e.host, res.Stdout)
+	}
+	return pid, nil
+}
+
+func (e *SSHExecutor) sshOptions(extra ...string) []string {
+	var opts []string
+	if e.ControlPath != "" {
+		opts = append(opts,
+			"-o", "ControlMaster=auto",
+			"-o", "ControlPath="+e.ControlPath,
+			"-o", "ControlPersist=60",
+		)
+	}
+	return append(opts, extra...)
+}
@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+type recordingExecutor struct {
+	command string
+	args    []string
+	input   string
+	result  *CommandResult
+	err     error
+}
+
+func (r *recordingExecutor) Exec(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return r.ExecWithInput(ctx, "", command, args...)
+}
+
+func (r *recordingExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*CommandResult, error) {
+	r.command, r.args, r.input = command, args, input
+	if r.result == nil {
+		r.result = &CommandResult{}
+	}
+	return r.result, r.err
+}
+
+func TestSSHExecutorBuildsRemoteCommand(t *testing.T) {
+	local := &recordingExecutor{}
+	e := NewSSHExecutor(local, "web01")
+
+	if _, err := e.ExecWithInput(context.Background(), "0 5 * * * /bin/true\n", "crontab", "-"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"-o", "BatchMode=yes", "web01", "--", "crontab -"}
+	if local.command != "ssh" || !reflect.DeepEqual(local.args, want) {
+		t.Errorf("Unexpected invocation: %s %v", local.command, local.args)
+	}
+	if local.input == "" {
+		t.Error("Input should be forwarded to ssh")
+	}
+}
+
+func TestSSHExecutorQuotesArguments(t *testing.T) {
+	local := &recordingExecutor{}
+	e := NewSSHExecutor(local, "web01")
+
+	_, _ = e.Exec(context.Background(), "sh", "-c", `command -v "$1"`, "sh", "apt")
+
+	got := local.args[len(local.args)-1]
+	want := `sh -c 'command -v "$1"' sh apt`
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestSSHExecutorConnectionError(t *testing.T) {
+	local := &recordingExecutor{
+		result: &CommandResult{ExitCode: 255, Stderr: "Could not resolve hostname"},
+		err:    fmt.Errorf("exit status 255"),
+	}
+	e := NewSSHExecutor(local, "web01")
+
+	_, err := e.Exec(context.Background(), "uptime")
+
+	var nerr *nuxerrors.NuxError
+	if !nuxerrors.As(err, &nerr) || nerr.Code != nuxerrors.ErrCodeSSHConnect {
+		t.Fatalf("Expected SSH connect error, got %v", err)
+	}
+}
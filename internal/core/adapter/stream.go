@@ -0,0 +1,234 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types delivered to an EventHandler
+const (
+	EventLine     = "line"
+	EventProgress = "progress"
+)
+
+// Streams a line event can come from
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// streamTailLines is how much stderr a streamed result keeps for error messages
+const streamTailLines = 20
+
+// maxLineBytes forces out a line that never ends, so a command printing
+// without newlines cannot grow the buffer without bound
+const maxLineBytes = 64 * 1024
+
+// waitDelay bounds how long a streamed command's output is drained after it
+// is killed, in case a child it spawned keeps the pipes open
+const waitDelay = 5 * time.Second
+
+// Event is one line of output or one progress update from a running command
+type Event struct {
+	Type    string  `json:"type"`
+	Stream  string  `json:"stream,omitempty"`
+	Line    string  `json:"line,omitempty"`
+	Current int     `json:"current,omitempty"`
+	Total   int     `json:"total,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// EventHandler receives events while a command runs. Calls are never concurrent.
+type EventHandler func(Event)
+
+// StreamingExecutor is implemented by executors that can deliver output while
+// the command is still running. The returned result does not hold stdout, and
+// only the last lines of stderr, so long commands run in constant memory.
+type StreamingExecutor interface {
+	ExecStream(ctx context.Context, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error)
+}
+
+// RunStream runs a command and feeds its output to onEvent, line by line as it
+// arrives when the executor supports streaming, or all at once when it exits
+func RunStream(ctx context.Context, e Executor, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error) {
+	if se, ok := e.(StreamingExecutor); ok {
+		return se.ExecStream(ctx, input, onEvent, command, args...)
+	}
+
+	res, err := e.ExecWithInput(ctx, input, command, args...)
+	if res != nil {
+		for _, line := range splitLines(res.Stdout) {
+			emitLine(onEvent, StreamStdout, line)
+		}
+		for _, line := range splitLines(res.Stderr) {
+			emitLine(onEvent, StreamStderr, line)
+		}
+	}
+	return res, err
+}
+
+// ExecStream executes a command, delivering its output as it is produced
+func (e *SystemExecutor) ExecStream(ctx context.Context, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = commandEnv(ctx)
+	cmd.WaitDelay = waitDelay
+
+	var mu sync.Mutex
+	tail := make([]string, 0, streamTailLines)
+	emit := func(stream, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stream == StreamStderr {
+			if len(tail) == streamTailLines {
+				tail = tail[1:]
+			}
+			tail = append(tail, line)
+		}
+		emitLine(onEvent, stream, line)
+	}
+
+	stdout := &lineWriter{stream: StreamStdout, emit: emit}
+	stderr := &lineWriter{stream: StreamStderr, emit: emit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	result := &CommandResult{
+		Stderr:   strings.TrimSpace(strings.Join(tail, "\n")),
+		ExitCode: exitCode(err),
+		Duration: time.Since(start),
+	}
+
+	if err != nil {
+		return result, e.wrapError(ctx, command, err, result.Stderr)
+	}
+
+	return result, nil
+}
+
+// ExecStream executes a command on the remote host, delivering its output as
+// it is produced
+func (e *SSHExecutor) ExecStream(ctx context.Context, input string, onEvent EventHandler, command string, args ...string) (*CommandResult, error) {
+	if err := refuseRemoteEnv(ctx, e.host); err != nil {
+		return &CommandResult{ExitCode: -1}, err
+	}
+	sshArgs := append(e.sshOptions("-o", "BatchMode=yes"), e.host, "--", ShellJoin(command, args...))
+
+	res, err := RunStream(ctx, e.local, input, onEvent, "ssh", sshArgs...)
+	if err != nil && res != nil && res.ExitCode == sshConnectionError {
+		return res, sshError(err, e.host, res.Stderr)
+	}
+	return res, err
+}
+
+// lineWriter splits what a command writes into lines. Carriage returns end a
+// line too, so progress meters that redraw in place are seen on every update.
+type lineWriter struct {
+	stream string
+	emit   func(stream, line string)
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.send(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= maxLineBytes {
+		w.Flush()
+	}
+	return len(p), nil
+}
+
+// Flush emits a trailing line that was not terminated
+func (w *lineWriter) Flush() {
+	w.send(w.buf)
+	w.buf = nil
+}
+
+func (w *lineWriter) send(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	w.emit(w.stream, string(line))
+}
+
+func emitLine(onEvent EventHandler, stream, line string) {
+	onEvent(Event{Type: EventLine, Stream: stream, Line: line})
+	if p, ok := ParseProgress(line); ok {
+		onEvent(p)
+	}
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+var (
+	// "(3/12)" as printed by pacman, apk and zypper
+	countParenRe = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+	// "  Upgrading : bash-5.2-1.x86_64    3/12" as printed by dnf and yum
+	countSuffixRe = regexp.MustCompile(`\s(\d+)/(\d+)\s*$`)
+	// "Progress: [ 45%]" as printed by apt, or any other percentage
+	percentRe = regexp.MustCompile(`(\d{1,3}(?:\.\d+)?)\s?%`)
+)
+
+// ParseProgress recognises the progress counters package managers and
+// downloaders print, such as "(3/12)", "3/12" at the end of a line or "45%"
+func ParseProgress(line string) (Event, bool) {
+	for _, re := range []*regexp.Regexp{countParenRe, countSuffixRe} {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		current, _ := strconv.Atoi(m[1])
+		total, _ := strconv.Atoi(m[2])
+		if total == 0 || current > total {
+			continue
+		}
+		return Event{
+			Type:    EventProgress,
+			Line:    line,
+			Current: current,
+			Total:   total,
+			Percent: float64(current) * 100 / float64(total),
+		}, true
+	}
+
+	if m := percentRe.FindStringSubmatch(line); m != nil {
+		percent, err := strconv.ParseFloat(m[1], 64)
+		if err == nil && percent <= 100 {
+			return Event{Type: EventProgress, Line: line, Percent: percent}, true
+		}
+	}
+	return Event{}, false
+}
@@ -0,0 +1,137 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+func TestSystemExecutorStreamsLinesAsTheyArrive(t *testing.T) {
+	e := NewExecutor().(*SystemExecutor)
+
+	start := time.Now()
+	var firstAt time.Duration
+	var lines []Event
+	res, err := e.ExecStream(context.Background(), "", func(ev Event) {
+		if ev.Type != EventLine {
+			return
+		}
+		if len(lines) == 0 {
+			firstAt = time.Since(start)
+		}
+		lines = append(lines, ev)
+	}, "sh", "-c", "echo one; sleep 0.5; echo two >&2; printf three")
+	if err != nil {
+		t.Fatalf("ExecStream failed: %v", err)
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %+v", lines)
+	}
+	if lines[0].Line != "one" || lines[1].Stream != StreamStderr || lines[2].Line != "three" {
+		t.Errorf("Unexpected lines: %+v", lines)
+	}
+	if firstAt >= 500*time.Millisecond {
+		t.Errorf("First line arrived after the command finished (%v)", firstAt)
+	}
+	if res.Stdout != "" || res.Stderr != "two" {
+		t.Errorf("Streamed result should keep only stderr: %+v", res)
+	}
+}
+
+func TestSystemExecutorStreamFailure(t *testing.T) {
+	e := NewExecutor().(*SystemExecutor)
+
+	res, err := e.ExecStream(context.Background(), "data", func(Event) {}, "sh", "-c", "cat; echo boom >&2; exit 2")
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Expected error with stderr, got %v", err)
+	}
+	if res.ExitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d", res.ExitCode)
+	}
+
+	e.Timeout = 50 * time.Millisecond
+	_, err = e.ExecStream(context.Background(), "", func(Event) {}, "sleep", "5")
+	var nerr *nuxerrors.NuxError
+	if !nuxerrors.As(err, &nerr) || nerr.Code != nuxerrors.ErrCodeTimeout {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+}
+
+func TestRunStreamFallsBackToBufferedOutput(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{
+		{Command: "dnf", Args: []string{"upgrade", "-y"}, Stdout: "Upgrading : bash 1/2\nUpgrading : curl 2/2\nComplete!"},
+	}}
+
+	var events []Event
+	_, err := RunStream(context.Background(), NewReplayExecutor(f), "", func(ev Event) {
+		events = append(events, ev)
+	}, "dnf", "upgrade", "-y")
+	if err != nil {
+		t.Fatalf("RunStream failed: %v", err)
+	}
+
+	var progress []Event
+	for _, ev := range events {
+		if ev.Type == EventProgress {
+			progress = append(progress, ev)
+		}
+	}
+	if len(events) != 5 || len(progress) != 2 {
+		t.Fatalf("Expected 3 lines and 2 progress events, got %+v", events)
+	}
+	if progress[1].Current != 2 || progress[1].Total != 2 || progress[1].Percent != 100 {
+		t.Errorf("Unexpected progress: %+v", progress[1])
+	}
+}
+
+func TestDryRunExecutorStream(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{{Command: "ls", Stdout: "a\nb"}}}
+	e := NewDryRunExecutor(NewReplayExecutor(f))
+
+	var lines int
+	if _, err := RunStream(context.Background(), e, "", func(Event) { lines++ }, "ls"); err != nil {
+		t.Fatalf("Read-only stream failed: %v", err)
+	}
+	if lines != 2 {
+		t.Errorf("Read-only command should stream, got %d events", lines)
+	}
+
+	if _, err := RunStream(context.Background(), e, "", func(Event) { t.Error("Mutating command should not run") }, "apt", "upgrade", "-y"); err != nil {
+		t.Fatalf("Mutating stream failed: %v", err)
+	}
+	if plan := e.Plan(); len(plan) != 1 || plan[0].String() != "apt upgrade -y" {
+		t.Errorf("Unexpected plan: %v", plan)
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line    string
+		ok      bool
+		current int
+		total   int
+		percent float64
+	}{
+		{"(3/12) upgrading bash", true, 3, 12, 25},
+		{"  Upgrading        : curl-8.5.0-1.fc39.x86_64      1/4", true, 1, 4, 25},
+		{"Progress: [ 45%]", true, 0, 0, 45},
+		{"Get:1 http://archive.ubuntu.com jammy InRelease [270 kB]", false, 0, 0, 0},
+		{"inet 10.0.0.1/24 brd 10.0.0.255", false, 0, 0, 0},
+		{"(5/3) bogus", false, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		ev, ok := ParseProgress(tt.line)
+		if ok != tt.ok {
+			t.Errorf("ParseProgress(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && (ev.Current != tt.current || ev.Total != tt.total || ev.Percent != tt.percent) {
+			t.Errorf("ParseProgress(%q) = %+v", tt.line, ev)
+		}
+	}
+}
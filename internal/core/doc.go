@@ -1,17 +1,18 @@
 // Package core provides core functionality for NUX CLI.
 //
 // This package includes:
-//   - Executor: Interface for executing system commands
-//   - RealExecutor: Implementation that executes real system commands
 //   - SanitizeInput: Sanitize user input to prevent shell injection
 //   - ValidatePath: Validate file paths for security
+//   - ValidateCommand: Reject commands containing shell metacharacters
+//
+// Commands are executed through adapter.Executor (internal/core/adapter).
 //
 // Example usage:
 //
-//	executor := &core.RealExecutor{}
-//	result, err := executor.Run("ls", "-la")
+//	executor := adapter.NewExecutor()
+//	result, err := executor.Exec(ctx, "ls", "-la", core.SanitizeInput(dir))
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-//	fmt.Println(result)
-package core
\ No newline at end of file
+//	fmt.Println(result.Stdout)
+package core
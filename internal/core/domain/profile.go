@@ -4,11 +4,11 @@ package domain
 
 // SystemProfile represents the detected system environment
 type SystemProfile struct {
-	Distro         string // Ubuntu, Fedora, Arch, etc.
-	Version        string // 22.04, 39, etc.
-	InitSystem     string // systemd, openrc, runit, sysvinit
-	PackageManager string // apt, dnf, yum, pacman, zypper, apk
-	Firewall       string // nftables, iptables, firewalld, ufw
-	NetworkStack   string // netplan, ifcfg, interfaces, NetworkManager, systemd-networkd
-	Environment    string // WSL, Docker, Kubernetes, VM, BareMetal
+	Distro         string `json:"distro"`          // Ubuntu, Fedora, Arch, etc.
+	Version        string `json:"version"`         // 22.04, 39, etc.
+	InitSystem     string `json:"init_system"`     // systemd, openrc, runit, sysvinit
+	PackageManager string `json:"package_manager"` // apt, dnf, yum, pacman, zypper, apk
+	Firewall       string `json:"firewall"`        // nftables, iptables, firewalld, ufw
+	NetworkStack   string `json:"network_stack"`   // netplan, ifcfg, interfaces, NetworkManager, systemd-networkd
+	Environment    string `json:"environment"`     // WSL, Docker, Kubernetes, VM, BareMetal
 }
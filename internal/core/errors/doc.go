@@ -14,6 +14,10 @@
 //   - Disk: ErrCodeDiskRead, ErrCodeDiskWrite, ErrCodeLVM
 //   - Security: ErrCodeAudit, ErrCodeSecurityScan
 //
+// Every code maps to a process exit code (ExitCode, ExitCodeOf), and
+// timeouts and connection failures are retryable (IsRetryable). CodeOf
+// reports the innermost code of a wrapped error, the actual cause.
+//
 // Example usage:
 //
 //	err := errors.New(errors.ErrCodeNotFound, "user not found")
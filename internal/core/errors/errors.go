@@ -37,20 +37,31 @@ const (
 	ErrCodePermission     ErrorCode = "PERMISSION_DENIED"
 	ErrCodeAlreadyExists  ErrorCode = "ALREADY_EXISTS"
 	ErrCodeNetwork        ErrorCode = "NETWORK_ERROR"
+	ErrCodeCanceled       ErrorCode = "CANCELED"
+	ErrCodeUnsupported    ErrorCode = "UNSUPPORTED"
+	ErrCodeDependency     ErrorCode = "DEPENDENCY_MISSING"
+	ErrCodeConfig         ErrorCode = "CONFIG_ERROR"
+	ErrCodeCommandFailed  ErrorCode = "COMMAND_FAILED"
 
 	// Service errors
 	ErrCodeServiceStart   ErrorCode = "SERVICE_START_FAILED"
 	ErrCodeServiceStop    ErrorCode = "SERVICE_STOP_FAILED"
 	ErrCodeServiceRestart ErrorCode = "SERVICE_RESTART_FAILED"
+	ErrCodeServiceEnable  ErrorCode = "SERVICE_ENABLE_FAILED"
+	ErrCodeServiceDisable ErrorCode = "SERVICE_DISABLE_FAILED"
+	ErrCodeServiceQuery   ErrorCode = "SERVICE_QUERY_FAILED"
 
 	// Package errors
 	ErrCodePkgInstall ErrorCode = "PKG_INSTALL_FAILED"
 	ErrCodePkgRemove  ErrorCode = "PKG_REMOVE_FAILED"
 	ErrCodePkgUpdate  ErrorCode = "PKG_UPDATE_FAILED"
+	ErrCodePkgClean   ErrorCode = "PKG_CLEAN_FAILED"
 
 	// Network errors
 	ErrCodeNetworkConfig ErrorCode = "NETWORK_CONFIG_FAILED"
 	ErrCodeFirewall      ErrorCode = "FIREWALL_ERROR"
+	ErrCodeVPN           ErrorCode = "VPN_ERROR"
+	ErrCodeGeoIP         ErrorCode = "GEOIP_ERROR"
 
 	// Disk errors
 	ErrCodeDiskRead  ErrorCode = "DISK_READ_FAILED"
@@ -62,8 +73,23 @@ const (
 	ErrCodeSecurityScan ErrorCode = "SECURITY_SCAN_FAILED"
 
 	// Container errors
-	ErrCodeContainerStart ErrorCode = "CONTAINER_START_FAILED"
-	ErrCodeContainerStop  ErrorCode = "CONTAINER_STOP_FAILED"
+	ErrCodeContainerStart  ErrorCode = "CONTAINER_START_FAILED"
+	ErrCodeContainerStop   ErrorCode = "CONTAINER_STOP_FAILED"
+	ErrCodeContainerRemove ErrorCode = "CONTAINER_REMOVE_FAILED"
+
+	// Process and user errors
+	ErrCodeProcess ErrorCode = "PROCESS_ERROR"
+	ErrCodeUser    ErrorCode = "USER_ERROR"
+
+	// AI provider errors
+	ErrCodeProvider ErrorCode = "AI_PROVIDER_ERROR"
+
+	// Local state errors
+	ErrCodeVault     ErrorCode = "VAULT_ERROR"
+	ErrCodeInventory ErrorCode = "INVENTORY_ERROR"
+	ErrCodeJournal   ErrorCode = "JOURNAL_ERROR"
+	ErrCodePlugin    ErrorCode = "PLUGIN_ERROR"
+	ErrCodeSession   ErrorCode = "SESSION_ERROR"
 
 	// SSH errors
 	ErrCodeSSHConnect ErrorCode = "SSH_CONNECT_FAILED"
@@ -75,6 +101,22 @@ type NuxError struct {
 	Code    ErrorCode
 	Message string
 	Err     error
+	// Retryable marks failures that may succeed if tried again unchanged
+	Retryable bool
+	// Hint tells the user how to fix the failure
+	Hint string
+}
+
+// WithHint sets the hint shown with the error
+func (e *NuxError) WithHint(hint string) *NuxError {
+	e.Hint = hint
+	return e
+}
+
+// WithRetryable marks the error as retryable
+func (e *NuxError) WithRetryable() *NuxError {
+	e.Retryable = true
+	return e
 }
 
 func (e *NuxError) Error() string {
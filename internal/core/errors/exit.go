@@ -0,0 +1,169 @@
+package errors
+
+import "errors"
+
+// Process exit codes, one per failure class, so scripts can branch on the
+// kind of failure without parsing messages
+const (
+	ExitOK          = 0
+	ExitGeneral     = 1
+	ExitUsage       = 2
+	ExitNotFound    = 3
+	ExitPermission  = 4
+	ExitTimeout     = 5
+	ExitNetwork     = 6
+	ExitUnsupported = 7
+	ExitConflict    = 8
+	ExitConfig      = 9
+	ExitCommand     = 10
+	ExitService     = 11
+	ExitPackage     = 12
+	ExitNetworking  = 13
+	ExitDisk        = 14
+	ExitSecurity    = 15
+	ExitContainer   = 16
+	ExitSystem      = 17
+	ExitProvider    = 18
+	ExitState       = 19
+	ExitCanceled    = 130
+)
+
+// exitCodes maps every error code to the exit code of its class. Codes not
+// listed exit with ExitGeneral.
+var exitCodes = map[ErrorCode]int{
+	ErrCodeGeneral:  ExitGeneral,
+	ErrCodeInternal: ExitGeneral,
+
+	ErrCodeInvalidInput: ExitUsage,
+
+	ErrCodeNotFound: ExitNotFound,
+
+	ErrCodePermission:   ExitPermission,
+	ErrCodeUnauthorized: ExitPermission,
+	ErrCodeForbidden:    ExitPermission,
+	ErrCodeSSHAuth:      ExitPermission,
+
+	ErrCodeTimeout: ExitTimeout,
+
+	ErrCodeNetwork:    ExitNetwork,
+	ErrCodeSSHConnect: ExitNetwork,
+
+	ErrCodeUnsupported: ExitUnsupported,
+	ErrCodeDependency:  ExitUnsupported,
+
+	ErrCodeAlreadyExists: ExitConflict,
+
+	ErrCodeConfig: ExitConfig,
+
+	ErrCodeCommandFailed: ExitCommand,
+
+	ErrCodeServiceStart:   ExitService,
+	ErrCodeServiceStop:    ExitService,
+	ErrCodeServiceRestart: ExitService,
+	ErrCodeServiceEnable:  ExitService,
+	ErrCodeServiceDisable: ExitService,
+	ErrCodeServiceQuery:   ExitService,
+
+	ErrCodePkgInstall: ExitPackage,
+	ErrCodePkgRemove:  ExitPackage,
+	ErrCodePkgUpdate:  ExitPackage,
+	ErrCodePkgClean:   ExitPackage,
+
+	ErrCodeNetworkConfig: ExitNetworking,
+	ErrCodeFirewall:      ExitNetworking,
+	ErrCodeVPN:           ExitNetworking,
+	ErrCodeGeoIP:         ExitNetworking,
+
+	ErrCodeDiskRead:  ExitDisk,
+	ErrCodeDiskWrite: ExitDisk,
+	ErrCodeLVM:       ExitDisk,
+
+	ErrCodeAudit:        ExitSecurity,
+	ErrCodeSecurityScan: ExitSecurity,
+
+	ErrCodeContainerStart:  ExitContainer,
+	ErrCodeContainerStop:   ExitContainer,
+	ErrCodeContainerRemove: ExitContainer,
+
+	ErrCodeProcess: ExitSystem,
+	ErrCodeUser:    ExitSystem,
+
+	ErrCodeProvider: ExitProvider,
+
+	ErrCodeVault:     ExitState,
+	ErrCodeInventory: ExitState,
+	ErrCodeJournal:   ExitState,
+	ErrCodePlugin:    ExitState,
+	ErrCodeSession:   ExitState,
+
+	ErrCodeCanceled: ExitCanceled,
+}
+
+// retryableCodes fail for transient reasons
+var retryableCodes = map[ErrorCode]bool{
+	ErrCodeTimeout:    true,
+	ErrCodeNetwork:    true,
+	ErrCodeSSHConnect: true,
+}
+
+// ExitCode returns the process exit code for an error code
+func ExitCode(code ErrorCode) int {
+	if exit, ok := exitCodes[code]; ok {
+		return exit
+	}
+	return ExitGeneral
+}
+
+// IsRetryableCode reports whether errors with code are transient
+func IsRetryableCode(code ErrorCode) bool {
+	return retryableCodes[code]
+}
+
+// CodeOf returns the code of the innermost NuxError in err's chain, which
+// names the actual cause (a timeout, a denied permission) rather than the
+// operation that failed, or "" when err carries no code
+func CodeOf(err error) ErrorCode {
+	var code ErrorCode
+	walk(err, func(e *NuxError) {
+		code = e.Code
+	})
+	return code
+}
+
+// HintOf returns the outermost hint in err's chain
+func HintOf(err error) string {
+	var hint string
+	walk(err, func(e *NuxError) {
+		if hint == "" {
+			hint = e.Hint
+		}
+	})
+	return hint
+}
+
+// IsRetryable reports whether any error in err's chain is retryable
+func IsRetryable(err error) bool {
+	retryable := false
+	walk(err, func(e *NuxError) {
+		retryable = retryable || e.Retryable || IsRetryableCode(e.Code)
+	})
+	return retryable
+}
+
+// ExitCodeOf returns the process exit code for err
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	return ExitCode(CodeOf(err))
+}
+
+// walk calls fn for every NuxError in err's chain, outermost first
+func walk(err error, fn func(*NuxError)) {
+	for err != nil {
+		if e, ok := err.(*NuxError); ok {
+			fn(e)
+		}
+		err = errors.Unwrap(err)
+	}
+}
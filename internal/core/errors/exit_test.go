@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		code ErrorCode
+		want int
+	}{
+		{ErrCodeInvalidInput, ExitUsage},
+		{ErrCodeNotFound, ExitNotFound},
+		{ErrCodeSSHAuth, ExitPermission},
+		{ErrCodeServiceStart, ExitService},
+		{ErrCodeCanceled, ExitCanceled},
+		{"SOMETHING_ELSE", ExitGeneral},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.code); got != tt.want {
+			t.Errorf("ExitCode(%s) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCodeOfInnermost(t *testing.T) {
+	cause := New(ErrCodeTimeout, "command timed out").WithHint("raise --timeout")
+	err := Wrap(cause, ErrCodeServiceRestart, "failed to restart nginx")
+	wrapped := fmt.Errorf("web01: %w", err)
+
+	if got := CodeOf(wrapped); got != ErrCodeTimeout {
+		t.Errorf("CodeOf() = %s, want %s", got, ErrCodeTimeout)
+	}
+	if got := ExitCodeOf(wrapped); got != ExitTimeout {
+		t.Errorf("ExitCodeOf() = %d, want %d", got, ExitTimeout)
+	}
+	if got := HintOf(wrapped); got != "raise --timeout" {
+		t.Errorf("HintOf() = %q", got)
+	}
+	if !IsRetryable(wrapped) {
+		t.Error("Expected a timeout to be retryable")
+	}
+
+	if CodeOf(fmt.Errorf("plain")) != "" || ExitCodeOf(nil) != ExitOK {
+		t.Error("Expected no code for plain and nil errors")
+	}
+	if IsRetryable(New(ErrCodeNotFound, "missing")) {
+		t.Error("Expected not found to be permanent")
+	}
+	if !IsRetryable(New(ErrCodeProvider, "rate limited").WithRetryable()) {
+		t.Error("Expected WithRetryable to mark the error retryable")
+	}
+}
@@ -0,0 +1,125 @@
+package ports
+
+import (
+	"context"
+	"encoding/json"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// Roles of a ChatMessage
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	// RoleTool messages carry the result of a tool call back to the model
+	RoleTool = "tool"
+)
+
+// ChatMessage is one turn of a conversation with a language model
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the tools an assistant message asks to call
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID and Name identify the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// Tool is a function a model may ask to call
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments, an object
+	Parameters map[string]interface{}
+}
+
+// ToolCall is a call a model asks for, with its arguments as a JSON object
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatRequest asks a model to continue a conversation
+type ChatRequest struct {
+	Model    string
+	Messages []ChatMessage
+	// MaxTokens bounds the answer, 0 for the provider default
+	MaxTokens int
+	// Tools the model may call instead of answering
+	Tools []Tool
+}
+
+// ChatResponse is the complete answer of a model
+type ChatResponse struct {
+	Provider string
+	Model    string
+	Content  string
+	// ToolCalls are the tools the model asks to call before it answers
+	ToolCalls []ToolCall
+	// Usage is what the request cost, zero when the provider does not say
+	Usage Usage
+}
+
+// Usage counts the tokens of a request as the provider billed them
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// TokenHandler receives the answer piece by piece while it is generated
+type TokenHandler func(token string)
+
+// LLMProvider defines the interface for AI backends
+type LLMProvider interface {
+	// Name identifies the provider, such as "ollama"
+	Name() string
+
+	// DefaultModel is used for requests that do not name a model
+	DefaultModel() string
+
+	// Chat sends the conversation and returns the answer. When onToken is
+	// not nil the answer is streamed to it as it arrives.
+	Chat(ctx context.Context, req ChatRequest, onToken TokenHandler) (*ChatResponse, error)
+}
+
+// Model is a model served by a provider
+type Model struct {
+	ID string
+	// Owner is the organization or server that publishes it, when known
+	Owner string
+}
+
+// ModelLister is implemented by providers that can list their models
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]Model, error)
+}
+
+// Embedder is implemented by providers that turn text into vectors, so
+// passages can be compared by meaning
+type Embedder interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// ListModels lists the models of p, failing with ErrCodeUnsupported when it
+// is not a ModelLister. Providers wrapping another one forward to it.
+func ListModels(ctx context.Context, p LLMProvider) ([]Model, error) {
+	lister, ok := p.(ModelLister)
+	if !ok {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeUnsupported, "provider "+p.Name()+" cannot list its models")
+	}
+	return lister.ListModels(ctx)
+}
+
+// AsEmbedder returns p as an Embedder, failing with ErrCodeUnsupported when
+// it cannot embed text
+func AsEmbedder(p LLMProvider) (Embedder, error) {
+	e, ok := p.(Embedder)
+	if !ok {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeUnsupported, "provider "+p.Name()+" cannot embed text")
+	}
+	return e, nil
+}
@@ -30,10 +30,11 @@ type UserManager interface {
 }
 
 type UserOptions struct {
-	UID     string
-	GID     string
-	Groups  []string
-	Shell   string
-	HomeDir string
-	Comment string
+	UID        string
+	GID        string
+	Groups     []string
+	Shell      string
+	HomeDir    string
+	CreateHome bool
+	Comment    string
 }
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"strings"
+)
+
+// SanitizeInput prevents shell injection attacks
+func SanitizeInput(input string) string {
+	dangerous := []string{";", "&&", "||", "`", "$(", "${", "|", ">", "<", "\n", "\r"}
+	result := input
+	for _, d := range dangerous {
+		result = strings.ReplaceAll(result, d, "")
+	}
+	return strings.TrimSpace(result)
+}
+
+// ValidatePath ensures path is safe
+func ValidatePath(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	if strings.Contains(path, "..") {
+		return false
+	}
+	return true
+}
+
+// ValidateCommand ensures command contains no dangerous characters
+func ValidateCommand(cmd string) bool {
+	dangerous := []string{";", "&&", "||", "`", "$(", "${", "|", ">", "<", "\n", "\r", "'", "\""}
+	for _, d := range dangerous {
+		if strings.Contains(cmd, d) {
+			return false
+		}
+	}
+	return true
+}
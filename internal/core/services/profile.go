@@ -4,8 +4,6 @@ package services
 
 import (
 	"context"
-	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -25,34 +23,79 @@ func NewProfileEngine(executor adapter.Executor) *ProfileEngine {
 	}
 }
 
+// probedCommands and probedPaths are checked in one round trip each, so
+// detection stays fast when the executor talks to a remote host
+var (
+	probedCommands = []string{
+		"apt", "dnf", "yum", "pacman", "zypper", "apk",
+		"ufw", "firewall-cmd", "nft", "iptables",
+		"nmcli", "networkctl",
+	}
+	probedPaths = []string{
+		"/run/openrc", "/run/runit", "/.dockerenv",
+		"/etc/netplan", "/etc/sysconfig/network-scripts", "/etc/network/interfaces",
+	}
+)
+
 // DetectProfile scans the system and returns the profile
 func (e *ProfileEngine) DetectProfile() (*domain.SystemProfile, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	// Detection only inspects the system, so it also runs under --dry-run
+	ctx = adapter.WithReadOnly(ctx)
 
 	profile := &domain.SystemProfile{}
+	commands := e.lookPaths(ctx, probedCommands)
+	paths := e.existingPaths(ctx, probedPaths)
 
 	// 1. Detect Distro
 	e.detectDistro(ctx, profile)
 
 	// 2. Detect Init System
-	e.detectInitSystem(ctx, profile)
+	e.detectInitSystem(ctx, profile, paths)
 
 	// 3. Detect Package Manager
-	e.detectPackageManager(profile)
+	e.detectPackageManager(profile, commands)
 
 	// 4. Detect Firewall
-	e.detectFirewall(ctx, profile)
+	e.detectFirewall(ctx, profile, commands)
 
 	// 5. Detect Network Stack
-	e.detectNetworkStack(profile)
+	e.detectNetworkStack(profile, commands, paths)
 
 	// 6. Detect Environment
-	e.detectEnvironment(ctx, profile)
+	e.detectEnvironment(ctx, profile, paths)
 
 	return profile, nil
 }
 
+// lookPaths reports which of names are installed on the target system
+func (e *ProfileEngine) lookPaths(ctx context.Context, names []string) map[string]bool {
+	script := `for c in "$@"; do command -v "$c" >/dev/null 2>&1 && echo "$c"; done; true`
+	return e.probe(ctx, script, names)
+}
+
+// existingPaths reports which of paths exist on the target system
+func (e *ProfileEngine) existingPaths(ctx context.Context, paths []string) map[string]bool {
+	script := `for p in "$@"; do [ -e "$p" ] && echo "$p"; done; true`
+	return e.probe(ctx, script, paths)
+}
+
+func (e *ProfileEngine) probe(ctx context.Context, script string, items []string) map[string]bool {
+	found := make(map[string]bool)
+	args := append([]string{"-c", script, "sh"}, items...)
+	out, err := e.executor.Exec(ctx, "sh", args...)
+	if err != nil {
+		return found
+	}
+	for _, line := range strings.Split(out.Stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			found[line] = true
+		}
+	}
+	return found
+}
+
 func (e *ProfileEngine) detectDistro(ctx context.Context, p *domain.SystemProfile) {
 	// Try /etc/os-release
 	out, err := e.executor.Exec(ctx, "cat", "/etc/os-release")
@@ -69,7 +112,7 @@ func (e *ProfileEngine) detectDistro(ctx context.Context, p *domain.SystemProfil
 	}
 }
 
-func (e *ProfileEngine) detectInitSystem(ctx context.Context, p *domain.SystemProfile) {
+func (e *ProfileEngine) detectInitSystem(ctx context.Context, p *domain.SystemProfile, paths map[string]bool) {
 	// Check PID 1
 	out, err := e.executor.Exec(ctx, "readlink", "/proc/1/exe")
 	if err == nil {
@@ -79,7 +122,7 @@ func (e *ProfileEngine) detectInitSystem(ctx context.Context, p *domain.SystemPr
 		}
 		if strings.Contains(out.Stdout, "init") {
 			// Could be sysv or openrc, check further
-			if _, err := os.Stat("/run/openrc"); err == nil {
+			if paths["/run/openrc"] {
 				p.InitSystem = "openrc"
 				return
 			}
@@ -88,26 +131,25 @@ func (e *ProfileEngine) detectInitSystem(ctx context.Context, p *domain.SystemPr
 		}
 	}
 	// Check for runit
-	if _, err := os.Stat("/run/runit"); err == nil {
+	if paths["/run/runit"] {
 		p.InitSystem = "runit"
 		return
 	}
 }
 
-func (e *ProfileEngine) detectPackageManager(p *domain.SystemProfile) {
+func (e *ProfileEngine) detectPackageManager(p *domain.SystemProfile, commands map[string]bool) {
 	managers := []string{"apt", "dnf", "yum", "pacman", "zypper", "apk"}
 	for _, mgr := range managers {
-		_, err := exec.LookPath(mgr)
-		if err == nil {
+		if commands[mgr] {
 			p.PackageManager = mgr
 			return
 		}
 	}
 }
 
-func (e *ProfileEngine) detectFirewall(ctx context.Context, p *domain.SystemProfile) {
+func (e *ProfileEngine) detectFirewall(ctx context.Context, p *domain.SystemProfile, commands map[string]bool) {
 	// Priority: ufw > firewalld > nftables > iptables
-	if _, err := exec.LookPath("ufw"); err == nil {
+	if commands["ufw"] {
 		// Check if active
 		out, _ := e.executor.Exec(ctx, "ufw", "status")
 		if strings.Contains(out.Stdout, "active") {
@@ -115,47 +157,47 @@ func (e *ProfileEngine) detectFirewall(ctx context.Context, p *domain.SystemProf
 			return
 		}
 	}
-	if _, err := exec.LookPath("firewall-cmd"); err == nil {
+	if commands["firewall-cmd"] {
 		out, _ := e.executor.Exec(ctx, "firewall-cmd", "--state")
 		if strings.Contains(out.Stdout, "running") {
 			p.Firewall = "firewalld"
 			return
 		}
 	}
-	if _, err := exec.LookPath("nft"); err == nil {
+	if commands["nft"] {
 		p.Firewall = "nftables"
 		return
 	}
-	if _, err := exec.LookPath("iptables"); err == nil {
+	if commands["iptables"] {
 		p.Firewall = "iptables"
 		return
 	}
 }
 
-func (e *ProfileEngine) detectNetworkStack(p *domain.SystemProfile) {
-	if _, err := os.Stat("/etc/netplan"); err == nil {
+func (e *ProfileEngine) detectNetworkStack(p *domain.SystemProfile, commands map[string]bool, paths map[string]bool) {
+	if paths["/etc/netplan"] {
 		p.NetworkStack = "netplan"
 		return
 	}
-	if _, err := exec.LookPath("nmcli"); err == nil {
+	if commands["nmcli"] {
 		p.NetworkStack = "NetworkManager"
 		return
 	}
-	if _, err := os.Stat("/etc/sysconfig/network-scripts"); err == nil {
+	if paths["/etc/sysconfig/network-scripts"] {
 		p.NetworkStack = "ifcfg" // RHEL/CentOS
 		return
 	}
-	if _, err := os.Stat("/etc/network/interfaces"); err == nil {
+	if paths["/etc/network/interfaces"] {
 		p.NetworkStack = "interfaces" // Debian/Old Ubuntu
 		return
 	}
-	if _, err := exec.LookPath("networkctl"); err == nil {
+	if commands["networkctl"] {
 		p.NetworkStack = "systemd-networkd"
 		return
 	}
 }
 
-func (e *ProfileEngine) detectEnvironment(ctx context.Context, p *domain.SystemProfile) {
+func (e *ProfileEngine) detectEnvironment(ctx context.Context, p *domain.SystemProfile, paths map[string]bool) {
 	// WSL
 	out, _ := e.executor.Exec(ctx, "cat", "/proc/version")
 	if strings.Contains(strings.ToLower(out.Stdout), "microsoft") {
@@ -163,7 +205,7 @@ func (e *ProfileEngine) detectEnvironment(ctx context.Context, p *domain.SystemP
 		return
 	}
 	// Docker
-	if _, err := os.Stat("/.dockerenv"); err == nil {
+	if paths["/.dockerenv"] {
 		p.Environment = "Docker"
 		return
 	}
@@ -0,0 +1,250 @@
+package inventory
+
+// Package inventory keeps the list of managed hosts, their groups and
+// per-host variables in ~/.nux/inventory.yaml.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const inventoryDir = ".nux"
+const inventoryFile = "inventory.yaml"
+
+// AllGroup matches every host in the inventory
+const AllGroup = "all"
+
+// Inventory is the on-disk host inventory
+type Inventory struct {
+	Hosts  map[string]*Host    `yaml:"hosts"`
+	Groups map[string][]string `yaml:"groups,omitempty"`
+}
+
+// Host is a managed machine reachable through an ssh profile
+type Host struct {
+	Name    string            `yaml:"-"`
+	Profile string            `yaml:"profile,omitempty"`
+	Groups  []string          `yaml:"groups,omitempty"`
+	Vars    map[string]string `yaml:"vars,omitempty"`
+}
+
+// SSHProfile returns the ~/.ssh/config alias used to reach the host
+func (h *Host) SSHProfile() string {
+	if h.Profile != "" {
+		return h.Profile
+	}
+	return h.Name
+}
+
+// Path returns the location of the inventory file
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeInventory, "failed to get home directory")
+	}
+	return filepath.Join(home, inventoryDir, inventoryFile), nil
+}
+
+// New returns an empty inventory
+func New() *Inventory {
+	return &Inventory{
+		Hosts:  make(map[string]*Host),
+		Groups: make(map[string][]string),
+	}
+}
+
+// Load reads the inventory, returning an empty one if the file does not exist
+func Load() (*Inventory, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return New(), nil
+		}
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeInventory, "failed to read inventory")
+	}
+	return Parse(data)
+}
+
+// Parse decodes an inventory document
+func Parse(data []byte) (*Inventory, error) {
+	inv := New()
+	if err := yaml.Unmarshal(data, inv); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeInventory, "failed to parse inventory")
+	}
+	if inv.Hosts == nil {
+		inv.Hosts = make(map[string]*Host)
+	}
+	if inv.Groups == nil {
+		inv.Groups = make(map[string][]string)
+	}
+	for name, h := range inv.Hosts {
+		if h == nil {
+			h = &Host{}
+			inv.Hosts[name] = h
+		}
+		h.Name = name
+	}
+	return inv, nil
+}
+
+// Save writes the inventory back to disk
+func Save(inv *Inventory) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeInventory, "failed to create inventory directory")
+	}
+
+	data, err := yaml.Marshal(inv)
+	if err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeInventory, "failed to marshal inventory")
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeInventory, "failed to write inventory")
+	}
+	return nil
+}
+
+// AddHost adds or replaces a host
+func (inv *Inventory) AddHost(h *Host) {
+	inv.Hosts[h.Name] = h
+}
+
+// RemoveHost deletes a host and its group memberships
+func (inv *Inventory) RemoveHost(name string) bool {
+	if _, ok := inv.Hosts[name]; !ok {
+		return false
+	}
+	delete(inv.Hosts, name)
+	for g, members := range inv.Groups {
+		inv.Groups[g] = without(members, name)
+	}
+	return true
+}
+
+// Seed adds every ssh profile not yet in the inventory and returns the new hosts
+func (inv *Inventory) Seed(profiles []string) []string {
+	var added []string
+	for _, p := range profiles {
+		if _, ok := inv.Hosts[p]; ok {
+			continue
+		}
+		inv.Hosts[p] = &Host{Name: p}
+		added = append(added, p)
+	}
+	return added
+}
+
+// HostNames returns all host names sorted
+func (inv *Inventory) HostNames() []string {
+	names := make([]string, 0, len(inv.Hosts))
+	for name := range inv.Hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupMembers returns every group with its members, merging the groups
+// section with the groups listed on each host
+func (inv *Inventory) GroupMembers() map[string][]string {
+	members := make(map[string]map[string]bool)
+	add := func(group, host string) {
+		if members[group] == nil {
+			members[group] = make(map[string]bool)
+		}
+		members[group][host] = true
+	}
+
+	for group, hosts := range inv.Groups {
+		for _, h := range hosts {
+			add(group, h)
+		}
+	}
+	for name, h := range inv.Hosts {
+		for _, g := range h.Groups {
+			add(g, name)
+		}
+	}
+
+	result := make(map[string][]string, len(members))
+	for group, set := range members {
+		for h := range set {
+			result[group] = append(result[group], h)
+		}
+		sort.Strings(result[group])
+	}
+	return result
+}
+
+// Resolve expands a target into hosts. A target is a comma separated list of
+// group names, host names or "all".
+func (inv *Inventory) Resolve(target string) ([]*Host, error) {
+	groups := inv.GroupMembers()
+	seen := make(map[string]bool)
+	var hosts []*Host
+
+	add := func(name string) error {
+		h, ok := inv.Hosts[name]
+		if !ok {
+			return nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("host %q is not in the inventory", name))
+		}
+		if !seen[name] {
+			seen[name] = true
+			hosts = append(hosts, h)
+		}
+		return nil
+	}
+
+	for _, part := range strings.Split(target, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			continue
+		case part == AllGroup:
+			for _, name := range inv.HostNames() {
+				add(name)
+			}
+		case groups[part] != nil:
+			for _, name := range groups[part] {
+				if err := add(name); err != nil {
+					return nil, nuxerrors.Wrapf(err, nuxerrors.ErrCodeInventory, "group %s", part)
+				}
+			}
+		case inv.Hosts[part] != nil:
+			add(part)
+		default:
+			return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("unknown group or host %q", part))
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no hosts match %q", target))
+	}
+	return hosts, nil
+}
+
+func without(list []string, name string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if v != name {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package inventory
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+const sample = `
+hosts:
+  web01:
+    groups: [web]
+    vars:
+      role: frontend
+  web02:
+    profile: web02-admin
+  db01:
+groups:
+  web: [web02]
+  db: [db01]
+`
+
+func names(hosts []*Host) []string {
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, h.Name)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	inv, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got := inv.HostNames(); !reflect.DeepEqual(got, []string{"db01", "web01", "web02"}) {
+		t.Errorf("unexpected hosts: %v", got)
+	}
+	if inv.Hosts["db01"] == nil || inv.Hosts["db01"].Name != "db01" {
+		t.Error("empty host entry should be initialized with its name")
+	}
+	if got := inv.Hosts["web02"].SSHProfile(); got != "web02-admin" {
+		t.Errorf("expected explicit profile, got %s", got)
+	}
+	if got := inv.Hosts["web01"].SSHProfile(); got != "web01" {
+		t.Errorf("expected profile to default to host name, got %s", got)
+	}
+
+	groups := inv.GroupMembers()
+	if !reflect.DeepEqual(groups["web"], []string{"web01", "web02"}) {
+		t.Errorf("expected web group to merge both sources, got %v", groups["web"])
+	}
+}
+
+func TestResolve(t *testing.T) {
+	inv, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"web", []string{"web01", "web02"}},
+		{"db,web01", []string{"db01", "web01"}},
+		{"web,web01", []string{"web01", "web02"}},
+		{"all", []string{"db01", "web01", "web02"}},
+	}
+	for _, tt := range tests {
+		hosts, err := inv.Resolve(tt.target)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", tt.target, err)
+			continue
+		}
+		if got := names(hosts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Resolve(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+
+	for _, target := range []string{"nope", "", " , "} {
+		if _, err := inv.Resolve(target); err == nil {
+			t.Errorf("Resolve(%q) should fail", target)
+		}
+	}
+}
+
+func TestResolveUnknownGroupMember(t *testing.T) {
+	inv, err := Parse([]byte("hosts:\n  web01:\ngroups:\n  web: [web01, gone]\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := inv.Resolve("web"); err == nil {
+		t.Error("expected error for group member missing from hosts")
+	}
+}
+
+func TestSeedAndRemove(t *testing.T) {
+	inv := New()
+	inv.AddHost(&Host{Name: "web01", Groups: []string{"web"}})
+	inv.Groups["web"] = []string{"web01"}
+
+	added := inv.Seed([]string{"web01", "db01"})
+	if !reflect.DeepEqual(added, []string{"db01"}) {
+		t.Errorf("Seed should only add new profiles, got %v", added)
+	}
+	if len(inv.Hosts["web01"].Groups) != 1 {
+		t.Error("Seed should not overwrite existing hosts")
+	}
+
+	if !inv.RemoveHost("web01") {
+		t.Fatal("RemoveHost should report removal")
+	}
+	if inv.RemoveHost("web01") {
+		t.Error("RemoveHost should report missing host")
+	}
+	if len(inv.GroupMembers()["web"]) != 0 {
+		t.Error("RemoveHost should drop group memberships")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	inv, err := Load()
+	if err != nil {
+		t.Fatalf("Load without file failed: %v", err)
+	}
+	if len(inv.Hosts) != 0 {
+		t.Error("expected empty inventory")
+	}
+
+	inv.AddHost(&Host{Name: "web01", Vars: map[string]string{"role": "frontend"}})
+	if err := Save(inv); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	path, _ := Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("inventory not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Hosts["web01"].Vars["role"] != "frontend" {
+		t.Errorf("vars not persisted: %+v", loaded.Hosts["web01"])
+	}
+}
@@ -0,0 +1,212 @@
+package journal
+
+// Package journal appends every state-changing operation to
+// ~/.nux/journal.jsonl together with what is needed to revert it.
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/logger"
+)
+
+const journalDir = ".nux"
+const journalFile = "journal.jsonl"
+
+// Journaled operations
+const (
+	OpFirewallAllow  = "firewall.allow"
+	OpFirewallBlock  = "firewall.block"
+	OpServiceEnable  = "service.enable"
+	OpServiceDisable = "service.disable"
+	OpCronAdd        = "cron.add"
+	OpCronRemove     = "cron.remove"
+	OpUserAdd        = "user.add"
+	OpUserDelete     = "user.delete"
+	OpPackageInstall = "package.install"
+	OpPackageRemove  = "package.remove"
+)
+
+// inverses maps each operation to the one that reverts it. Deleting a user
+// loses its data, so it has no inverse.
+var inverses = map[string]string{
+	OpFirewallAllow:  OpFirewallBlock,
+	OpFirewallBlock:  OpFirewallAllow,
+	OpServiceEnable:  OpServiceDisable,
+	OpServiceDisable: OpServiceEnable,
+	OpCronAdd:        OpCronRemove,
+	OpCronRemove:     OpCronAdd,
+	OpUserAdd:        OpUserDelete,
+	OpPackageInstall: OpPackageRemove,
+	OpPackageRemove:  OpPackageInstall,
+}
+
+// Inverse returns the operation that reverts op
+func Inverse(op string) (string, bool) {
+	inv, ok := inverses[op]
+	return inv, ok
+}
+
+// Entry is one journaled operation. The params of an entry are also the
+// params of its inverse.
+type Entry struct {
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Host      string            `json:"host,omitempty"`
+	Operation string            `json:"operation"`
+	Params    map[string]string `json:"params,omitempty"`
+	Undoes    string            `json:"undoes,omitempty"`
+}
+
+// Journal appends entries for operations run against one host. A nil
+// *Journal records nothing, so managers can always call Record.
+type Journal struct {
+	path   string
+	host   string
+	undoes string
+}
+
+// Path returns the location of the journal file
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to get home directory")
+	}
+	return filepath.Join(home, journalDir, journalFile), nil
+}
+
+// Open returns the journal for operations on host ("" for the local machine)
+func Open(host string) (*Journal, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return NewAt(path, host), nil
+}
+
+// NewAt returns a journal stored at path
+func NewAt(path, host string) *Journal {
+	return &Journal{path: path, host: host}
+}
+
+// Undoing returns a journal whose entries are marked as reverting id
+func (j *Journal) Undoing(id string) *Journal {
+	if j == nil {
+		return nil
+	}
+	return &Journal{path: j.path, host: j.host, undoes: id}
+}
+
+// Record appends a completed operation. Failing to write the journal must not
+// fail the operation itself, so errors are only logged.
+func (j *Journal) Record(op string, params map[string]string) {
+	if j == nil {
+		return
+	}
+	if _, err := j.Append(op, params); err != nil {
+		logger.Warn("failed to record %s in the journal: %v", op, err)
+	}
+}
+
+// Append writes a new entry and returns it
+func (j *Journal) Append(op string, params map[string]string) (*Entry, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		ID:        id,
+		Time:      time.Now().UTC(),
+		Host:      j.host,
+		Operation: op,
+		Params:    params,
+		Undoes:    j.undoes,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to marshal journal entry")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to create journal directory")
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to open journal")
+	}
+	defer f.Close()
+
+	// One write per line keeps concurrent --on appends from interleaving
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to write journal")
+	}
+	return entry, nil
+}
+
+// Entries returns every entry, oldest first. Unreadable lines are skipped.
+func (j *Journal) Entries() ([]*Entry, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to read journal")
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.ID == "" {
+			continue
+		}
+		entries = append(entries, &e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to read journal")
+	}
+	return entries, nil
+}
+
+// Find returns the entry with the given id
+func (j *Journal) Find(id string) (*Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no journal entry with id %s", id))
+}
+
+// UndoneBy maps the id of every reverted entry to the entry that reverted it
+func UndoneBy(entries []*Entry) map[string]string {
+	undone := make(map[string]string)
+	for _, e := range entries {
+		if e.Undoes != "" {
+			undone[e.Undoes] = e.ID
+		}
+	}
+	return undone
+}
+
+func newID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeJournal, "failed to generate journal id")
+	}
+	return hex.EncodeToString(b), nil
+}
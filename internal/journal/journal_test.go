@@ -0,0 +1,77 @@
+package journal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendAndEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j := NewAt(path, "web01")
+
+	first, err := j.Append(OpServiceEnable, map[string]string{"service": "nginx"})
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	undo, err := j.Undoing(first.ID).Append(OpServiceDisable, first.Params)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Host != "web01" || entries[0].Params["service"] != "nginx" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Undoes != first.ID {
+		t.Errorf("expected undo entry to reference %s, got %q", first.ID, entries[1].Undoes)
+	}
+
+	if by := UndoneBy(entries)[first.ID]; by != undo.ID {
+		t.Errorf("expected %s to be undone by %s, got %q", first.ID, undo.ID, by)
+	}
+
+	found, err := j.Find(undo.ID)
+	if err != nil || found.Operation != OpServiceDisable {
+		t.Errorf("Find(%s) = %+v, %v", undo.ID, found, err)
+	}
+	if _, err := j.Find("missing"); err == nil {
+		t.Error("expected error for unknown id")
+	}
+}
+
+func TestEntriesWithoutFile(t *testing.T) {
+	j := NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries, got %v, %v", entries, err)
+	}
+}
+
+func TestNilJournal(t *testing.T) {
+	var j *Journal
+	// Managers without a journal call Record unconditionally
+	j.Record(OpFirewallAllow, map[string]string{"port": "80"})
+	if j.Undoing("abc") != nil {
+		t.Error("Undoing on a nil journal should stay nil")
+	}
+}
+
+func TestInverse(t *testing.T) {
+	for op, inv := range inverses {
+		if op == OpUserAdd {
+			continue
+		}
+		if back, ok := Inverse(inv); !ok || back != op {
+			t.Errorf("inverse of %s should revert to %s, got %q", inv, op, back)
+		}
+	}
+	if _, ok := Inverse(OpUserDelete); ok {
+		t.Error("user deletion should not be reversible")
+	}
+}
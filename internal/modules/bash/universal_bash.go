@@ -11,12 +11,15 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 )
 
 // UniversalBashManager implements BashManager for all Linux distributions
 type UniversalBashManager struct {
 	executor adapter.Executor
 	profile  *domain.SystemProfile
+	onEvent  adapter.EventHandler
+	ctx      context.Context
 }
 
 // NewUniversalBashManager creates a new instance of UniversalBashManager
@@ -27,42 +30,72 @@ func NewUniversalBashManager(executor adapter.Executor, profile *domain.SystemPr
 	}
 }
 
+// WithStream delivers script output to onEvent while it runs instead of
+// returning it, so long scripts are observable and run in constant memory
+func (m *UniversalBashManager) WithStream(onEvent adapter.EventHandler) *UniversalBashManager {
+	m.onEvent = onEvent
+	return m
+}
+
+// WithContext runs commands under ctx, so they are cancelled with it and
+// carry its markers, such as adapter.WithPrivileged
+func (m *UniversalBashManager) WithContext(ctx context.Context) *UniversalBashManager {
+	m.ctx = ctx
+	return m
+}
+
+func (m *UniversalBashManager) context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
 // RunCommand executes a single bash command
 func (m *UniversalBashManager) RunCommand(cmd string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(m.context(), 5*time.Minute)
 	defer cancel()
 
 	// Use bash explicitly to run the command
 	res, err := m.executor.Exec(ctx, "bash", "-c", cmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute command: %w\nOutput: %s", err, res.Stderr)
+		// The executor error already carries the stderr of the command
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeCommandFailed, "failed to execute command")
 	}
 	return res.Stdout, nil
 }
 
-// RunScript executes a bash script from a file
+// RunScript executes a bash script from a file. With WithStream the output
+// goes to the handler and the returned string is empty.
 func (m *UniversalBashManager) RunScript(path string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute) // Scripts might take longer
+	ctx, cancel := context.WithTimeout(m.context(), 30*time.Minute) // Scripts might take longer
 	defer cancel()
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve path: %w", err)
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeCommandFailed, "failed to resolve path")
 	}
 
 	if _, errStat := os.Stat(absPath); os.IsNotExist(errStat) {
-		return "", fmt.Errorf("script file not found: %s", absPath)
+		return "", nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("script file not found: %s", absPath))
 	}
 
 	// Make sure script is executable
 	_, err = m.executor.Exec(ctx, "chmod", "+x", absPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to make script executable: %w", err)
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeCommandFailed, "failed to make script executable")
+	}
+
+	if m.onEvent != nil {
+		if _, err := adapter.RunStream(ctx, m.executor, "", m.onEvent, "bash", absPath); err != nil {
+			return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeCommandFailed, "failed to execute script")
+		}
+		return "", nil
 	}
 
 	res, err := m.executor.Exec(ctx, "bash", absPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute script: %w\nOutput: %s", err, res.Stderr)
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeCommandFailed, "failed to execute script")
 	}
 	return res.Stdout, nil
 }
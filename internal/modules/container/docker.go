@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"strings"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -27,9 +28,10 @@ func (m *DockerManager) ListContainers(all bool) ([]ports.Container, error) {
 	if err != nil {
 		// Check if docker is installed/running
 		if strings.Contains(string(output), "Is the docker daemon running") {
-			return nil, fmt.Errorf("docker daemon is not running")
+			return nil, nuxerrors.New(nuxerrors.ErrCodeDependency, "docker daemon is not running").
+				WithHint("start it with 'systemctl start docker'")
 		}
-		return nil, fmt.Errorf("failed to list containers: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeCommandFailed, "failed to list containers")
 	}
 
 	var containers []ports.Container
@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"strings"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -142,5 +143,5 @@ func (m *LinuxContainerManager) findRuntimeForContainer(id string) (string, erro
 			return runtime, nil
 		}
 	}
-	return "", fmt.Errorf("container %s not found in any runtime", id)
+	return "", nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("container %s not found in any runtime", id))
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -110,7 +111,7 @@ func (m *UniversalContainerManager) GetContainerLogs(id string, tail int) (strin
 		}
 		lastErr = err
 	}
-	return "", fmt.Errorf("failed to get logs from any runtime: %v", lastErr)
+	return "", nuxerrors.Wrap(lastErr, nuxerrors.ErrCodeCommandFailed, "failed to get logs from any runtime")
 }
 
 func (m *UniversalContainerManager) PruneSystem() (string, error) {
@@ -143,5 +144,5 @@ func (m *UniversalContainerManager) runOnAnyRuntimeArgs(args ...string) error {
 		}
 		lastErr = err
 	}
-	return fmt.Errorf("operation failed on all runtimes: %v", lastErr)
+	return nuxerrors.Wrap(lastErr, nuxerrors.ErrCodeCommandFailed, "operation failed on all runtimes")
 }
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"syscall"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -36,12 +37,12 @@ func (m *LinuxDiskManager) ListBlockDevices() ([]ports.BlockDevice, error) {
 	cmd := exec.Command("lsblk", "-J", "-o", "NAME,SIZE,TYPE,MOUNTPOINT")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("lsblk failed: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeDiskRead, "lsblk failed")
 	}
 
 	var result lsblkOutputLinux
 	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse lsblk json: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeDiskRead, "failed to parse lsblk json")
 	}
 
 	return convertDevicesLinux(result.BlockDevices), nil
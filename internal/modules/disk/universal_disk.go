@@ -11,6 +11,7 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -45,12 +46,12 @@ func (m *UniversalDiskManager) ListBlockDevices() ([]ports.BlockDevice, error) {
 	ctx := context.Background()
 	res, err := m.executor.Exec(ctx, "lsblk", "-J", "-o", "NAME,SIZE,TYPE,MOUNTPOINT")
 	if err != nil {
-		return nil, fmt.Errorf("lsblk failed: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeDiskRead, "lsblk failed")
 	}
 
 	var result lsblkOutput
 	if err := json.Unmarshal([]byte(res.Stdout), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse lsblk json: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeDiskRead, "failed to parse lsblk json")
 	}
 
 	return convertDevices(result.BlockDevices), nil
@@ -84,13 +85,13 @@ func (m *UniversalDiskManager) GetFilesystemUsage(path string) (ports.Filesystem
 
 	lines := strings.Split(res.Stdout, "\n")
 	if len(lines) < 2 {
-		return ports.FilesystemUsage{}, fmt.Errorf("unexpected df output")
+		return ports.FilesystemUsage{}, nuxerrors.New(nuxerrors.ErrCodeDiskRead, "unexpected df output")
 	}
 
 	// Filesystem     1B-blocks      Used Available Use% Mounted on
 	fields := strings.Fields(lines[1])
 	if len(fields) < 4 {
-		return ports.FilesystemUsage{}, fmt.Errorf("unexpected df fields")
+		return ports.FilesystemUsage{}, nuxerrors.New(nuxerrors.ErrCodeDiskRead, "unexpected df fields")
 	}
 
 	total, _ := strconv.ParseUint(fields[1], 10, 64)
@@ -104,7 +105,7 @@ func (m *UniversalDiskManager) GetFilesystemUsage(path string) (ports.Filesystem
 	}
 	linesI := strings.Split(resI.Stdout, "\n")
 	if len(linesI) < 2 {
-		return ports.FilesystemUsage{}, fmt.Errorf("unexpected df -i output")
+		return ports.FilesystemUsage{}, nuxerrors.New(nuxerrors.ErrCodeDiskRead, "unexpected df -i output")
 	}
 	fieldsI := strings.Fields(linesI[1])
 	inodes, _ := strconv.ParseUint(fieldsI[1], 10, 64)
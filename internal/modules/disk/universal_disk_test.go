@@ -0,0 +1,34 @@
+package disk
+
+import (
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/core/domain"
+)
+
+// Cleanup only deletes, so under --dry-run every command it runs must land
+// in the plan and none may reach the host
+func TestCleanupDryRun(t *testing.T) {
+	host := adapter.NewReplayExecutor(&adapter.Fixture{})
+	dry := adapter.NewDryRunExecutor(host)
+	m := NewUniversalDiskManager(dry, &domain.SystemProfile{PackageManager: "apt"})
+
+	if err := m.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if calls := host.Calls(); len(calls) != 0 {
+		t.Errorf("dry-run should not run anything, ran %v", calls)
+	}
+
+	want := []string{"apt-get clean", "apt-get autoremove -y", "journalctl --vacuum-time=2d"}
+	plan := dry.Plan()
+	if len(plan) != len(want) {
+		t.Fatalf("expected plan %v, got %v", want, plan)
+	}
+	for i, step := range plan {
+		if step.String() != want[i] {
+			t.Errorf("step %d: expected %q, got %q", i+1, want[i], step.String())
+		}
+	}
+}
@@ -165,7 +165,8 @@ func (m *UniversalDoctorManager) checkDiskRoot() (ports.HealthCheck, error) {
 }
 
 func (m *UniversalDoctorManager) checkTCPCloseWait() (ports.HealthCheck, error) {
-	ctx := context.Background()
+	// The pipeline only counts sockets, so it is safe to run under --dry-run
+	ctx := adapter.WithReadOnly(context.Background())
 	// ss -tn state close-wait | wc -l
 	// We use shell for pipe
 	res, err := m.executor.Exec(ctx, "sh", "-c", "ss -tn state close-wait | wc -l")
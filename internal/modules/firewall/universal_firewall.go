@@ -9,7 +9,9 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/journal"
 )
 
 const (
@@ -25,6 +27,7 @@ const (
 type UniversalFirewallManager struct {
 	executor adapter.Executor
 	profile  *domain.SystemProfile
+	journal  *journal.Journal
 }
 
 // NewUniversalFirewallManager creates a new firewall manager
@@ -35,15 +38,23 @@ func NewUniversalFirewallManager(executor adapter.Executor, profile *domain.Syst
 	}
 }
 
+// WithJournal records allowed and blocked ports into j
+func (m *UniversalFirewallManager) WithJournal(j *journal.Journal) *UniversalFirewallManager {
+	m.journal = j
+	return m
+}
+
 // DetectFirewall returns the detected firewall manager
 func (m *UniversalFirewallManager) DetectFirewall() (string, error) {
 	if m.profile.Firewall == "" {
-		return "", fmt.Errorf("no supported firewall manager detected in profile")
+		return "", nuxerrors.New(nuxerrors.ErrCodeUnsupported, "no supported firewall manager detected in profile")
 	}
 	return m.profile.Firewall, nil
 }
 
-// AllowPort allows incoming traffic on a port
+// AllowPort allows incoming traffic on a port. A port that is already open
+// is left alone, so no duplicate rule is added and nothing is journaled. The
+// journal keeps the state the port was in, which RestorePort goes back to.
 func (m *UniversalFirewallManager) AllowPort(port string, protocol string) error {
 	ctx := context.Background()
 	var args []string
@@ -63,24 +74,29 @@ func (m *UniversalFirewallManager) AllowPort(port string, protocol string) error
 	case iptablesCmd:
 		args = []string{"-A", "INPUT", "-p", protocol, "--dport", port, "-j", "ACCEPT"}
 	case nftablesSvc:
-		// nft add rule inet filter input tcp dport 80 accept
-		// Simplified assumption: table inet filter, chain input exists
-		args = []string{"add", "rule", "inet", "filter", "input", protocol, "dport", port, "accept"}
-		execCmd = nftCmd
+		return m.setNftPort(ctx, port, protocol, portAllowed)
 	default:
-		return fmt.Errorf("unsupported firewall manager: %s", cmd)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported firewall manager: %s", cmd))
 	}
 
+	state, known := m.portState(ctx, port, protocol)
+	if known && state == portAllowed {
+		return nil
+	}
 	_, err := m.executor.Exec(ctx, execCmd, args...)
 
 	// Reload firewalld if needed
 	if err == nil && cmd == firewalldSvc {
 		_, _ = m.executor.Exec(ctx, firewallCmd, "--reload")
 	}
+	if err == nil && known {
+		m.record(portAllowed, port, protocol, state)
+	}
 	return err
 }
 
-// BlockPort blocks incoming traffic on a port
+// BlockPort blocks incoming traffic on a port. A port that is already closed
+// is left alone and nothing is journaled.
 func (m *UniversalFirewallManager) BlockPort(port string, protocol string) error {
 	ctx := context.Background()
 	var args []string
@@ -99,20 +115,202 @@ func (m *UniversalFirewallManager) BlockPort(port string, protocol string) error
 	case iptablesCmd:
 		args = []string{"-D", "INPUT", "-p", protocol, "--dport", port, "-j", "ACCEPT"}
 	case nftablesSvc:
-		// nft add rule inet filter input tcp dport 80 drop
-		args = []string{"add", "rule", "inet", "filter", "input", protocol, "dport", port, "drop"}
-		execCmd = nftCmd
+		return m.setNftPort(ctx, port, protocol, portDenied)
 	default:
-		return fmt.Errorf("unsupported firewall manager: %s", cmd)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported firewall manager: %s", cmd))
 	}
 
+	state, known := m.portState(ctx, port, protocol)
+	if known && state == portDenied {
+		return nil
+	}
 	_, err := m.executor.Exec(ctx, execCmd, args...)
 	if err == nil && cmd == firewalldSvc {
 		_, _ = m.executor.Exec(ctx, firewallCmd, "--reload")
 	}
+	if err == nil && known {
+		m.record(portDenied, port, protocol, state)
+	}
 	return err
 }
 
+// RestorePort puts a port back in the state journaled with an AllowPort or
+// BlockPort, which is how they are undone. Where a port has rules of its own
+// (ufw, nftables) the rule that was added is deleted, rather than countered
+// with the opposite one: blocking an allowed port would leave an explicit
+// deny behind, and allowing a blocked one would open a port the default
+// policy kept closed.
+func (m *UniversalFirewallManager) RestorePort(port, protocol, previous string) error {
+	ctx := context.Background()
+	switch m.profile.Firewall {
+	case ufwCmd:
+		state, known := m.portState(ctx, port, protocol)
+		if !known {
+			return nuxerrors.New(nuxerrors.ErrCodeFirewall, "cannot read the ufw rules of "+port+"/"+protocol)
+		}
+		if state == previous {
+			return nil
+		}
+		rule := port + "/" + protocol
+		if state != "" {
+			if _, err := m.executor.Exec(ctx, ufwCmd, "delete", state, rule); err != nil {
+				return err
+			}
+		}
+		if previous != "" {
+			if _, err := m.executor.Exec(ctx, ufwCmd, previous, rule); err != nil {
+				return err
+			}
+		}
+		m.record(previous, port, protocol, state)
+		return nil
+	case nftablesSvc:
+		return m.setNftPort(ctx, port, protocol, previous)
+	}
+	if previous == portAllowed {
+		return m.AllowPort(port, protocol)
+	}
+	return m.BlockPort(port, protocol)
+}
+
+// States of a port as portState reports them and the journal keeps them
+const (
+	portAllowed = "allow"
+	portDenied  = "deny"
+)
+
+// record journals a port put in state, from the previous one. A port left
+// with no rule of its own is journaled as blocked, which it is again for the
+// firewalls that have no such state.
+func (m *UniversalFirewallManager) record(state, port, protocol, previous string) {
+	op := journal.OpFirewallBlock
+	if state == portAllowed {
+		op = journal.OpFirewallAllow
+	}
+	m.journal.Record(op, map[string]string{"port": port, "protocol": protocol, "previous": previous})
+}
+
+// portState returns "allow" when a rule opens the port, "deny" when the port
+// is closed, the action of another ufw rule ("reject", "limit") and "" when
+// a ufw or nftables port has no rule of its own. known is false when the
+// rules could not be read.
+func (m *UniversalFirewallManager) portState(ctx context.Context, port, protocol string) (state string, known bool) {
+	var open bool
+	switch m.profile.Firewall {
+	case ufwCmd:
+		// "8080/tcp                   ALLOW       Anywhere"
+		res, err := m.executor.Exec(adapter.WithReadOnly(ctx), ufwCmd, "status")
+		if err != nil {
+			return "", false
+		}
+		for _, line := range strings.Split(res.Stdout, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == port+"/"+protocol && fields[1] != "(v6)" {
+				return strings.ToLower(fields[1]), true
+			}
+		}
+		return "", true
+	case nftablesSvc:
+		handles, err := m.nftPortRules(ctx, port, protocol)
+		if err != nil {
+			return "", false
+		}
+		return nftState(handles), true
+	case firewalldSvc:
+		open, known = adapter.Probe(ctx, m.executor, firewallCmd, "--permanent", "--query-port", port+"/"+protocol)
+	case iptablesCmd:
+		open, known = adapter.Probe(ctx, m.executor, iptablesCmd, "-C", "INPUT", "-p", protocol, "--dport", port, "-j", "ACCEPT")
+	}
+	if open {
+		return portAllowed, known
+	}
+	return portDenied, known
+}
+
+// nftVerdicts are the verdicts of the nftables rules a port state stands for
+var nftVerdicts = map[string]string{portAllowed: "accept", portDenied: "drop"}
+
+// nftState returns the state of a port from the handles of its rules. A drop
+// rule is inserted at the top of the chain, so it wins over accept rules.
+func nftState(handles map[string][]string) string {
+	switch {
+	case len(handles["drop"]) > 0:
+		return portDenied
+	case len(handles["accept"]) > 0:
+		return portAllowed
+	}
+	return ""
+}
+
+// setNftPort makes state ("allow", "deny" or "" for no rule) the only rules
+// of the input chain for a port. Rules with another verdict are deleted by
+// handle, so blocking a port that was allowed removes the accept rule instead
+// of adding a drop rule that would never be reached. A drop rule is inserted
+// at the top of the chain to win over broader accept rules.
+func (m *UniversalFirewallManager) setNftPort(ctx context.Context, port, protocol, state string) error {
+	handles, err := m.nftPortRules(ctx, port, protocol)
+	if err != nil {
+		return err
+	}
+	verdict := nftVerdicts[state]
+	stale := 0
+	for v, hs := range handles {
+		if v != verdict {
+			stale += len(hs)
+		}
+	}
+	if stale == 0 && (verdict == "" || len(handles[verdict]) > 0) {
+		return nil
+	}
+	previous := nftState(handles)
+
+	for _, v := range []string{"accept", "drop"} {
+		if v == verdict {
+			continue
+		}
+		for _, handle := range handles[v] {
+			if _, err := m.executor.Exec(ctx, nftCmd, "delete", "rule", "inet", "filter", "input", "handle", handle); err != nil {
+				return err
+			}
+		}
+	}
+	if verdict != "" && len(handles[verdict]) == 0 {
+		position := "add"
+		if verdict == "drop" {
+			position = "insert"
+		}
+		// Simplified assumption: table inet filter, chain input exists
+		if _, err := m.executor.Exec(ctx, nftCmd, position, "rule", "inet", "filter", "input", protocol, "dport", port, verdict); err != nil {
+			return err
+		}
+	}
+	m.record(state, port, protocol, previous)
+	return nil
+}
+
+// nftPortRules returns the handles of the rules of the input chain that
+// accept or drop a port, keyed by verdict
+func (m *UniversalFirewallManager) nftPortRules(ctx context.Context, port, protocol string) (map[string][]string, error) {
+	res, err := m.executor.Exec(adapter.WithReadOnly(ctx), nftCmd, "-a", "list", "chain", "inet", "filter", "input")
+	if err != nil {
+		return nil, err
+	}
+	// "tcp dport 8080 accept # handle 7"
+	handles := map[string][]string{}
+	match := protocol + " dport " + port + " "
+	for _, line := range strings.Split(res.Stdout, "\n") {
+		rule, handle, ok := strings.Cut(strings.TrimSpace(line), " # handle ")
+		if !ok || !strings.HasPrefix(rule, match) {
+			continue
+		}
+		verdict := strings.TrimPrefix(rule, match)
+		if verdict == "accept" || verdict == "drop" {
+			handles[verdict] = append(handles[verdict], strings.TrimSpace(handle))
+		}
+	}
+	return handles, nil
+}
+
 // ListRules returns the current rules
 func (m *UniversalFirewallManager) ListRules() ([]ports.FirewallRule, error) {
 	ctx := context.Background()
@@ -253,7 +451,7 @@ func (m *UniversalFirewallManager) Enable() error {
 		_, err := m.executor.Exec(ctx, "systemctl", "enable", "--now", nftablesSvc)
 		return err
 	}
-	return fmt.Errorf("unsupported firewall manager: %s", cmd)
+	return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported firewall manager: %s", cmd))
 }
 
 // Disable disables the firewall
@@ -275,5 +473,5 @@ func (m *UniversalFirewallManager) Disable() error {
 		_, err := m.executor.Exec(ctx, "systemctl", "disable", "--now", nftablesSvc)
 		return err
 	}
-	return fmt.Errorf("unsupported firewall manager: %s", cmd)
+	return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported firewall manager: %s", cmd))
 }
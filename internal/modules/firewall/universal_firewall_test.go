@@ -0,0 +1,168 @@
+package firewall
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/core/domain"
+	"github.com/rsdenck/nux/internal/journal"
+)
+
+// firewallCommands are the commands of the firewalls in the transcripts; a
+// call the manager skipped leaves one of their interactions unused
+var firewallCommands = []string{"ufw", "firewall-cmd", "iptables", "nft"}
+
+func TestFirewallManagerAcrossDistros(t *testing.T) {
+	fixtures, _ := filepath.Glob(filepath.Join("..", "testdata", "*.json"))
+	if len(fixtures) == 0 {
+		t.Fatal("no distro fixtures found")
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			fixture, err := adapter.LoadFixture(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+			replay := adapter.NewReplayExecutor(fixture)
+			m := NewUniversalFirewallManager(replay, fixture.Profile).WithJournal(j)
+
+			if err := m.AllowPort("8080", "tcp"); err != nil {
+				t.Errorf("AllowPort failed: %v", err)
+			}
+			if err := m.BlockPort("8080", "tcp"); err != nil {
+				t.Errorf("BlockPort failed: %v", err)
+			}
+
+			entries, err := j.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 || entries[0].Operation != journal.OpFirewallAllow || entries[1].Operation != journal.OpFirewallBlock {
+				t.Errorf("unexpected journal entries: %+v", entries)
+			}
+
+			if unused := replay.Unused(firewallCommands...); len(unused) != 0 {
+				t.Errorf("recorded calls were never made: %v", unused)
+			}
+		})
+	}
+}
+
+// On nftables a drop rule appended after the accept rule is never reached,
+// so blocking an allowed port, as undoing AllowPort does, must delete the
+// accept rule by its handle
+func TestNftablesBlockDeletesAllowRule(t *testing.T) {
+	fixture, err := adapter.LoadFixture(filepath.Join("..", "testdata", "arch.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := adapter.NewReplayExecutor(fixture)
+	m := NewUniversalFirewallManager(replay, fixture.Profile)
+
+	if err := m.AllowPort("8080", "tcp"); err != nil {
+		t.Fatalf("AllowPort failed: %v", err)
+	}
+	if err := m.BlockPort("8080", "tcp"); err != nil {
+		t.Fatalf("BlockPort failed: %v", err)
+	}
+
+	want := []string{
+		"nft -a list chain inet filter input",
+		"nft add rule inet filter input tcp dport 8080 accept",
+		"nft -a list chain inet filter input",
+		"nft delete rule inet filter input handle 7",
+		"nft insert rule inet filter input tcp dport 8080 drop",
+	}
+	calls := replay.Calls()
+	if len(calls) != len(want) {
+		t.Fatalf("expected calls %v, got %v", want, calls)
+	}
+	for i, call := range calls {
+		if call.String() != want[i] {
+			t.Errorf("call %d: expected %q, got %q", i+1, want[i], call.String())
+		}
+	}
+}
+
+// A port that is already open gets no second rule and no journal entry,
+// whose undo would close it
+func TestAllowOpenPortNotJournaled(t *testing.T) {
+	fixture := &adapter.Fixture{Interactions: []adapter.Interaction{
+		{Command: "firewall-cmd", Args: []string{"--permanent", "--query-port", "443/tcp"}, Stdout: "yes"},
+	}}
+	j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+	replay := adapter.NewReplayExecutor(fixture)
+	m := NewUniversalFirewallManager(replay, &domain.SystemProfile{Firewall: "firewalld"}).WithJournal(j)
+
+	if err := m.AllowPort("443", "tcp"); err != nil {
+		t.Fatalf("AllowPort failed: %v", err)
+	}
+	if calls := replay.Calls(); len(calls) != 1 {
+		t.Errorf("only the probe should run, ran %v", calls)
+	}
+	if entries, _ := j.Entries(); len(entries) != 0 {
+		t.Errorf("nothing changed, yet the allow was journaled: %+v", entries)
+	}
+}
+
+// Undoing a block on a port ufw had no rule for deletes the deny rule rather
+// than allowing the port, which the default policy kept closed
+func TestUfwRestoreDeletesAddedRule(t *testing.T) {
+	noRule := "Status: active\n\nTo                         Action      From\n--                         ------      ----\n22/tcp                     ALLOW       Anywhere"
+	fixture := &adapter.Fixture{Interactions: []adapter.Interaction{
+		{Command: "ufw", Args: []string{"status"}, Stdout: noRule},
+		{Command: "ufw", Args: []string{"deny", "8080/tcp"}, Stdout: "Rule added"},
+		{Command: "ufw", Args: []string{"status"}, Stdout: noRule + "\n8080/tcp                   DENY        Anywhere"},
+		{Command: "ufw", Args: []string{"delete", "deny", "8080/tcp"}, Stdout: "Rule deleted"},
+	}}
+	j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+	replay := adapter.NewReplayExecutor(fixture)
+	m := NewUniversalFirewallManager(replay, &domain.SystemProfile{Firewall: "ufw"}).WithJournal(j)
+
+	if err := m.BlockPort("8080", "tcp"); err != nil {
+		t.Fatalf("BlockPort failed: %v", err)
+	}
+	entries, _ := j.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected the block to be journaled, got %+v", entries)
+	}
+	previous, ok := entries[0].Params["previous"]
+	if !ok || previous != "" {
+		t.Fatalf("expected no previous rule to be journaled, got %+v", entries[0].Params)
+	}
+	if err := m.RestorePort("8080", "tcp", previous); err != nil {
+		t.Fatalf("RestorePort failed: %v", err)
+	}
+
+	calls := replay.Calls()
+	if last := calls[len(calls)-1].String(); last != "ufw delete deny 8080/tcp" {
+		t.Errorf("expected the deny rule to be deleted, ran %v", calls)
+	}
+	if unused := replay.Unused("ufw"); len(unused) != 0 {
+		t.Errorf("recorded calls were never made: %v", unused)
+	}
+}
+
+// Undoing an allow on nftables removes the accept rule and adds no drop
+func TestNftablesRestoreDeletesAddedRule(t *testing.T) {
+	list := []string{"-a", "list", "chain", "inet", "filter", "input"}
+	chain := "table inet filter {\n\tchain input { # handle 1\n\t\ttcp dport 22 accept # handle 6\n"
+	fixture := &adapter.Fixture{Interactions: []adapter.Interaction{
+		{Command: "nft", Args: list, Stdout: chain + "\t\ttcp dport 8080 accept # handle 7\n\t}\n}"},
+		{Command: "nft", Args: []string{"delete", "rule", "inet", "filter", "input", "handle", "7"}},
+	}}
+	replay := adapter.NewReplayExecutor(fixture)
+	m := NewUniversalFirewallManager(replay, &domain.SystemProfile{Firewall: "nftables"})
+
+	if err := m.RestorePort("8080", "tcp", ""); err != nil {
+		t.Fatalf("RestorePort failed: %v", err)
+	}
+	if calls := replay.Calls(); len(calls) != 2 {
+		t.Errorf("expected only the accept rule to be deleted, ran %v", calls)
+	}
+}
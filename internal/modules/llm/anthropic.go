@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// anthropicVersion is the version of the Messages API requests are made for
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens is sent when a request sets no limit, since the API
+// requires one
+const anthropicMaxTokens = 8192
+
+// AnthropicProvider talks to the Anthropic Messages API
+type AnthropicProvider struct {
+	client
+	baseURL string
+	apiKey  string
+}
+
+// NewAnthropicProvider returns a provider for Claude models
+func NewAnthropicProvider(apiKey string, timeout time.Duration) ports.LLMProvider {
+	return &AnthropicProvider{
+		client:  newClient("Claude", "claude", timeout),
+		baseURL: "https://api.anthropic.com/v1",
+		apiKey:  apiKey,
+	}
+}
+
+func (p *AnthropicProvider) Name() string { return "claude" }
+
+func (p *AnthropicProvider) DefaultModel() string { return "claude-3-5-sonnet-20241022" }
+
+// anthropicMessage holds its content as text, or as blocks when it carries
+// tool calls or their results
+type anthropicMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	MaxTokens int                `json:"max_tokens"`
+	Stream    bool               `json:"stream"`
+}
+
+// anthropicEvent is one server-sent event of a streamed message
+type anthropicEvent struct {
+	Type         string `json:"type"`
+	Index        int    `json:"index"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+	// message_start counts the input tokens, and message_delta the output
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Chat implements ports.LLMProvider with /messages. System messages go to
+// the separate system prompt the API expects, and tool results to user
+// messages.
+func (p *AnthropicProvider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	payload := anthropicRequest{Model: modelOf(p, req), MaxTokens: req.MaxTokens, Stream: true}
+	if payload.MaxTokens == 0 {
+		payload.MaxTokens = anthropicMaxTokens
+	}
+	for _, m := range req.Messages {
+		if m.Role == ports.RoleSystem {
+			if payload.System != "" {
+				payload.System += "\n\n"
+			}
+			payload.System += m.Content
+			continue
+		}
+		payload.Messages = appendAnthropicMessage(payload.Messages, m)
+	}
+	for _, t := range req.Tools {
+		payload.Tools = append(payload.Tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
+	}
+
+	body, err := p.post(ctx, p.baseURL+"/messages", p.headers(), payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	answer := &collect{onToken: onToken}
+	// Tool calls stream as pieces of their input, by content block
+	var calls []ports.ToolCall
+	inputs := map[int]*strings.Builder{}
+	blocks := map[int]int{}
+	var usage ports.Usage
+	err = readSSE(body, func(data []byte) error {
+		var event anthropicEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return p.badResponse(err)
+		}
+		switch event.Type {
+		case "message_start":
+			usage.PromptTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			usage.CompletionTokens = event.Usage.OutputTokens
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				blocks[event.Index] = len(calls)
+				inputs[event.Index] = &strings.Builder{}
+				calls = append(calls, ports.ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name})
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				answer.add(event.Delta.Text)
+			case "input_json_delta":
+				if input, ok := inputs[event.Index]; ok {
+					input.WriteString(event.Delta.PartialJSON)
+				}
+			}
+		case "message_stop":
+			return io.EOF
+		case "error":
+			err := nuxerrors.New(nuxerrors.ErrCodeProvider, "Claude: "+event.Error.Message)
+			if event.Error.Type == "overloaded_error" {
+				err = err.WithRetryable()
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for index, i := range blocks {
+		calls[i].Arguments = toolArguments(inputs[index].String())
+	}
+	return &ports.ChatResponse{Provider: p.Name(), Model: payload.Model, Content: answer.String(), ToolCalls: calls, Usage: usage}, nil
+}
+
+// appendAnthropicMessage appends m in the shape of the Messages API. Tool
+// results are user messages, and the results of one answer's calls go into
+// one message.
+func appendAnthropicMessage(messages []anthropicMessage, m ports.ChatMessage) []anthropicMessage {
+	switch {
+	case m.Role == ports.RoleTool:
+		result := anthropicBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+		if n := len(messages); n > 0 && messages[n-1].Role == ports.RoleUser {
+			if blocks, ok := messages[n-1].Content.([]anthropicBlock); ok {
+				messages[n-1].Content = append(blocks, result)
+				return messages
+			}
+		}
+		return append(messages, anthropicMessage{Role: ports.RoleUser, Content: []anthropicBlock{result}})
+	case len(m.ToolCalls) > 0:
+		var blocks []anthropicBlock
+		if m.Content != "" {
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+		}
+		for _, call := range m.ToolCalls {
+			blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: objectArguments(call.Arguments)})
+		}
+		return append(messages, anthropicMessage{Role: m.Role, Content: blocks})
+	}
+	return append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+}
+
+// ListModels implements ports.ModelLister with /models
+func (p *AnthropicProvider) ListModels(ctx context.Context) ([]ports.Model, error) {
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := p.get(ctx, p.baseURL+"/models", p.headers(), &list); err != nil {
+		return nil, err
+	}
+	models := make([]ports.Model, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ports.Model{ID: m.ID, Owner: "anthropic"})
+	}
+	return models, nil
+}
+
+func (p *AnthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// Link is a provider of a Chain, with the model it is asked for
+type Link struct {
+	Provider ports.LLMProvider
+	// Model overrides the model of the request, empty to keep it
+	Model string
+}
+
+// Chain implements ports.LLMProvider by asking its providers in turn, moving
+// on when one fails before it starts answering, such as a LAN Ollama that is
+// down or a cloud provider still refusing after its retries
+type Chain struct {
+	name  string
+	links []Link
+	// OnFallback is told about each provider given up for the next one
+	OnFallback func(failed, next string, err error)
+}
+
+// NewChain returns a chain of links, named after its spec such as
+// "ollama,nvidia,openai"
+func NewChain(name string, links []Link) *Chain {
+	return &Chain{name: name, links: links}
+}
+
+func (c *Chain) Name() string { return c.name }
+
+// DefaultModel is the model of the first provider
+func (c *Chain) DefaultModel() string {
+	if c.links[0].Model != "" {
+		return c.links[0].Model
+	}
+	return c.links[0].Provider.DefaultModel()
+}
+
+// Chat implements ports.LLMProvider. A provider that fails after part of
+// the answer was streamed is not replaced, since the next one would repeat
+// it; neither is a canceled request.
+func (c *Chain) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	var errs []string
+	for i, link := range c.links {
+		linkReq := req
+		if link.Model != "" {
+			linkReq.Model = link.Model
+		}
+		streamed := false
+		var handler ports.TokenHandler
+		if onToken != nil {
+			handler = func(token string) {
+				streamed = true
+				onToken(token)
+			}
+		}
+
+		resp, err := link.Provider.Chat(ctx, linkReq, handler)
+		if err == nil {
+			return resp, nil
+		}
+		if streamed || ctx.Err() != nil || nuxerrors.CodeOf(err) == nuxerrors.ErrCodeCanceled || i == len(c.links)-1 {
+			if len(errs) == 0 {
+				return nil, err
+			}
+			return nil, nuxerrors.Wrap(err, nuxerrors.CodeOf(err), "every provider failed ("+strings.Join(errs, "; ")+")")
+		}
+		errs = append(errs, link.Provider.Name()+": "+err.Error())
+		if c.OnFallback != nil {
+			c.OnFallback(link.Provider.Name(), c.links[i+1].Provider.Name(), err)
+		}
+	}
+	return nil, nuxerrors.New(nuxerrors.ErrCodeConfig, "empty provider chain")
+}
+
+// ListModels implements ports.ModelLister with the models of the first
+// provider
+func (c *Chain) ListModels(ctx context.Context) ([]ports.Model, error) {
+	return ports.ListModels(ctx, c.links[0].Provider)
+}
@@ -0,0 +1,362 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// maxErrorBody bounds how much of a failed response is read for its message
+const maxErrorBody = 64 * 1024
+
+// maxLineBytes bounds one line of a streamed response
+const maxLineBytes = 1024 * 1024
+
+// Retries of a request refused for a while, such as on 429 or 503
+const (
+	maxRetries   = 3
+	retryBackoff = 500 * time.Millisecond
+	// maxRetryWait bounds how long a Retry-After is waited for; beyond it
+	// the request fails, leaving a fallback provider to answer
+	maxRetryWait = 30 * time.Second
+)
+
+// client sends the requests of a provider. Its timeout bounds the wait for
+// the answer to start and every pause while it streams, not the whole answer,
+// so a long answer that keeps arriving is never cut off.
+type client struct {
+	// name is the provider as shown in messages, such as "OpenAI"
+	name string
+	// keyName is the vault secret holding the API key, for hints
+	keyName string
+	timeout time.Duration
+	http    *http.Client
+	// retries of a request refused with 408, 429 or 5xx, waiting backoff
+	// then twice as long each time, or as long as the Retry-After of the
+	// response
+	retries int
+	backoff time.Duration
+}
+
+func newClient(name, keyName string, timeout time.Duration) client {
+	return client{name: name, keyName: keyName, timeout: timeout, http: &http.Client{}, retries: maxRetries, backoff: retryBackoff}
+}
+
+// stream is the body of a successful response. Reading it keeps the timeout
+// from firing; Close must be called once done.
+type stream struct {
+	c       *client
+	body    io.ReadCloser
+	timer   *time.Timer
+	expired atomic.Bool
+	cancel  context.CancelFunc
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	if n > 0 && s.timer != nil {
+		s.timer.Reset(s.c.timeout)
+	}
+	if err != nil && err != io.EOF {
+		return n, s.c.transportError(err, &s.expired)
+	}
+	return n, err
+}
+
+func (s *stream) Close() error {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.cancel()
+	return s.body.Close()
+}
+
+// post sends payload as JSON and returns the body of the response. Failed
+// requests are turned into errors carrying the message of the provider.
+func (c *client) post(ctx context.Context, url string, headers map[string]string, payload interface{}) (*stream, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeInternal, "failed to marshal request")
+	}
+	return c.do(ctx, http.MethodPost, url, headers, data)
+}
+
+// get fetches url and decodes its JSON body into v
+func (c *client) get(ctx context.Context, url string, headers map[string]string, v interface{}) error {
+	body, err := c.do(ctx, http.MethodGet, url, headers, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		if nuxerrors.CodeOf(err) != "" {
+			return err
+		}
+		return c.badResponse(err)
+	}
+	return nil
+}
+
+// do sends a request, again after a pause while it fails in a way that may
+// pass. Only the start of the answer is retried, so nothing streamed is
+// received twice.
+func (c *client) do(ctx context.Context, method, url string, headers map[string]string, data []byte) (*stream, error) {
+	for attempt := 0; ; attempt++ {
+		s, wait, err := c.send(ctx, method, url, headers, data)
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			return s, err
+		}
+		if wait > maxRetryWait {
+			return nil, err
+		}
+		// Jitter keeps clients refused together from retrying together
+		backoff := c.backoff << attempt
+		backoff += time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+		if wait < backoff {
+			wait = backoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nuxerrors.Wrap(ctx.Err(), nuxerrors.ErrCodeCanceled, fmt.Sprintf("request to %s canceled", c.name))
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a failed request may succeed if sent again:
+// one the provider refused for a while, as on 429 or 503. A server that
+// cannot be reached or timed out is given up at once, for a fallback to
+// answer.
+func retryable(err error) bool {
+	return nuxerrors.CodeOf(err) == nuxerrors.ErrCodeProvider && nuxerrors.IsRetryable(err)
+}
+
+// send makes one attempt at a request. wait is the Retry-After of a
+// response refused for a while.
+func (c *client) send(ctx context.Context, method, url string, headers map[string]string, data []byte) (s *stream, wait time.Duration, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s = &stream{c: c, cancel: cancel}
+	if c.timeout > 0 {
+		s.timer = time.AfterFunc(c.timeout, func() {
+			s.expired.Store(true)
+			cancel()
+		})
+	}
+
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		s.stop()
+		return nil, 0, nuxerrors.Wrap(err, nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid %s URL %s", c.name, url))
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		s.stop()
+		return nil, 0, c.transportError(err, &s.expired)
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		s.stop()
+		return nil, retryAfter(resp.Header), c.statusError(resp.StatusCode, body)
+	}
+	s.body = resp.Body
+	return s, 0, nil
+}
+
+// retryAfter returns how long a response asks to wait before trying again,
+// from Retry-After in seconds or as a date, or the retry-after-ms of OpenAI
+func retryAfter(h http.Header) time.Duration {
+	if ms, err := strconv.Atoi(h.Get("Retry-After-Ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+func (s *stream) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.cancel()
+}
+
+// transportError describes a request that got no complete answer
+func (c *client) transportError(err error, expired *atomic.Bool) error {
+	if expired.Load() {
+		return nuxerrors.New(nuxerrors.ErrCodeTimeout, fmt.Sprintf("%s did not answer within %s", c.name, c.timeout)).
+			WithHint("raise the limit with --timeout").
+			WithRetryable()
+	}
+	if errors.Is(err, context.Canceled) {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeCanceled, fmt.Sprintf("request to %s canceled", c.name))
+	}
+	return nuxerrors.Wrap(err, nuxerrors.ErrCodeNetwork, fmt.Sprintf("failed to connect to %s", c.name)).
+		WithRetryable()
+}
+
+// statusError maps a failed HTTP response to an error code
+func (c *client) statusError(status int, body []byte) error {
+	msg := fmt.Sprintf("%s: %s", c.name, errorMessage(status, body))
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		err := nuxerrors.New(nuxerrors.ErrCodeUnauthorized, msg)
+		if c.keyName != "" {
+			err = err.WithHint(fmt.Sprintf("check the API key with 'nux vault set-key %s'", c.keyName))
+		}
+		return err
+	case status == http.StatusNotFound:
+		return nuxerrors.New(nuxerrors.ErrCodeNotFound, msg).WithHint("check the model name with --model")
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500:
+		return nuxerrors.New(nuxerrors.ErrCodeProvider, msg).WithRetryable()
+	case status >= 400 && status < 500:
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, msg)
+	}
+	return nuxerrors.New(nuxerrors.ErrCodeProvider, msg)
+}
+
+// errorMessage extracts the message of an error response. Providers put it in
+// "error", either directly or as its "message".
+func errorMessage(status int, body []byte) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		var text string
+		var nested struct {
+			Message string `json:"message"`
+		}
+		switch {
+		case json.Unmarshal(parsed.Error, &text) == nil && text != "":
+			return text
+		case json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "":
+			return nested.Message
+		case parsed.Message != "":
+			return parsed.Message
+		}
+	}
+	if text := strings.TrimSpace(string(body)); text != "" && len(text) < 200 && !strings.HasPrefix(text, "<") {
+		return text
+	}
+	return fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
+
+// readLines calls fn with each non-empty line of r
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readSSE calls fn with the data of each server-sent event of r, until the
+// OpenAI "[DONE]" marker
+func readSSE(r io.Reader, fn func(data []byte) error) error {
+	err := readLines(r, func(line []byte) error {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok {
+			return nil
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return io.EOF
+		}
+		return fn(data)
+	})
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// badResponse reports a response that could not be understood
+func (c *client) badResponse(err error) error {
+	return nuxerrors.Wrap(err, nuxerrors.ErrCodeProvider, fmt.Sprintf("failed to parse %s response", c.name))
+}
+
+// collect accumulates the answer and forwards it to onToken
+type collect struct {
+	strings.Builder
+	onToken func(string)
+}
+
+func (c *collect) add(token string) {
+	if token == "" {
+		return
+	}
+	c.WriteString(token)
+	if c.onToken != nil {
+		c.onToken(token)
+	}
+}
+
+// toolArguments returns arguments streamed as text as JSON. Arguments that
+// are not valid JSON are kept as a string, for the tool to reject.
+func toolArguments(s string) json.RawMessage {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	quoted, _ := json.Marshal(s)
+	return quoted
+}
+
+// objectArguments returns the arguments of a call as a JSON object, for
+// APIs that take them as one
+func objectArguments(args json.RawMessage) json.RawMessage {
+	if trimmed := strings.TrimSpace(string(args)); strings.HasPrefix(trimmed, "{") {
+		return json.RawMessage(trimmed)
+	}
+	return json.RawMessage("{}")
+}
+
+// callID returns an id for the n-th call of an answer, for APIs whose calls
+// have none
+func callID(n int) string {
+	return fmt.Sprintf("call_%d", n)
+}
@@ -0,0 +1,19 @@
+// Package llm implements ports.LLMProvider for the AI backends nux talks to.
+//
+// This package includes:
+//   - OllamaProvider: a local or remote Ollama server, streamed as NDJSON
+//   - OpenAIProvider: OpenAI, NVIDIA Build and any OpenAI-compatible server such
+//     as llama.cpp or vLLM, streamed as SSE
+//   - AnthropicProvider: Claude models through the Messages API, streamed as SSE
+//
+// Every provider also implements ports.ModelLister. Failed requests are
+// mapped to nux error codes, with rate limits and server errors marked
+// retryable.
+//
+// Example usage:
+//
+//	p := llm.NewOllamaProvider("", 30*time.Second)
+//	resp, err := p.Chat(ctx, ports.ChatRequest{
+//	    Messages: []ports.ChatMessage{{Role: ports.RoleUser, Content: "uptime?"}},
+//	}, func(token string) { fmt.Print(token) })
+package llm
@@ -0,0 +1,458 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+func question(q string) ports.ChatRequest {
+	return ports.ChatRequest{Messages: []ports.ChatMessage{
+		{Role: ports.RoleSystem, Content: "be brief"},
+		{Role: ports.RoleUser, Content: q},
+	}}
+}
+
+func TestOllamaChatStreams(t *testing.T) {
+	var got ollamaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"up "},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"3 days"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":26,"eval_count":4}`)
+	}))
+	defer srv.Close()
+
+	var tokens []string
+	p := NewOllamaProvider(srv.URL, time.Second)
+	resp, err := p.Chat(context.Background(), question("uptime?"), func(t string) { tokens = append(tokens, t) })
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "up 3 days" || strings.Join(tokens, "|") != "up |3 days" {
+		t.Errorf("Unexpected answer %q, tokens %v", resp.Content, tokens)
+	}
+	if got.Model != "qwen3-coder" || !got.Stream || len(got.Messages) != 2 {
+		t.Errorf("Unexpected request %+v", got)
+	}
+	if resp.Usage != (ports.Usage{PromptTokens: 26, CompletionTokens: 4}) {
+		t.Errorf("Unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIChatStreams(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("Missing API key, got %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" world\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":18,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider("sk-test", time.Second).(*OpenAIProvider)
+	p.baseURL = srv.URL
+	resp, err := p.Chat(context.Background(), question("hi"), nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "hello world" || resp.Model != "gpt-4o-mini" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if resp.Usage != (ports.Usage{PromptTokens: 18, CompletionTokens: 2}) {
+		t.Errorf("Unexpected usage %+v", resp.Usage)
+	}
+	if opts, ok := got["stream_options"].(map[string]interface{}); !ok || opts["include_usage"] != true {
+		t.Errorf("Usage should be asked for, got %v", got["stream_options"])
+	}
+}
+
+func TestAnthropicChatStreams(t *testing.T) {
+	var got anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":9,\"output_tokens\":1}}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"ok\"}}\n\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":3}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewAnthropicProvider("key", time.Second).(*AnthropicProvider)
+	p.baseURL = srv.URL
+	resp, err := p.Chat(context.Background(), question("hi"), nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("Unexpected answer %q", resp.Content)
+	}
+	if got.System != "be brief" || len(got.Messages) != 1 || got.MaxTokens != anthropicMaxTokens {
+		t.Errorf("System prompt should be sent apart, got %+v", got)
+	}
+	if resp.Usage != (ports.Usage{PromptTokens: 9, CompletionTokens: 3}) {
+		t.Errorf("Unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		code      nuxerrors.ErrorCode
+		message   string
+		retryable bool
+	}{
+		{401, `{"error":{"message":"Incorrect API key provided"}}`, nuxerrors.ErrCodeUnauthorized, "Incorrect API key provided", false},
+		{404, `{"error":"model 'nope' not found"}`, nuxerrors.ErrCodeNotFound, "model 'nope' not found", false},
+		{429, `{"error":{"type":"rate_limit_error","message":"slow down"}}`, nuxerrors.ErrCodeProvider, "slow down", true},
+		{503, `<html>down</html>`, nuxerrors.ErrCodeProvider, "503 Service Unavailable", true},
+		{400, `{"message":"bad field"}`, nuxerrors.ErrCodeInvalidInput, "bad field", false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			fmt.Fprint(w, tt.body)
+		}))
+		p := NewOpenAIProvider("sk-test", time.Second).(*OpenAIProvider)
+		p.baseURL = srv.URL
+		p.backoff = time.Millisecond
+		_, err := p.Chat(context.Background(), question("hi"), nil)
+		srv.Close()
+
+		if err == nil {
+			t.Errorf("%d: expected an error", tt.status)
+			continue
+		}
+		if code := nuxerrors.CodeOf(err); code != tt.code {
+			t.Errorf("%d: expected code %s, got %s", tt.status, tt.code, code)
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%d: expected message %q in %q", tt.status, tt.message, err.Error())
+		}
+		if nuxerrors.IsRetryable(err) != tt.retryable {
+			t.Errorf("%d: expected retryable %v", tt.status, tt.retryable)
+		}
+	}
+}
+
+func TestRetriesHonorRetryAfter(t *testing.T) {
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		if len(times) == 1 {
+			w.Header().Set("Retry-After-Ms", "200")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"ok"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, time.Second).(*OllamaProvider)
+	p.backoff = time.Millisecond
+	resp, err := p.Chat(context.Background(), question("hi"), nil)
+	if err != nil {
+		t.Fatalf("Chat should succeed once retried: %v", err)
+	}
+	if resp.Content != "ok" || len(times) != 2 {
+		t.Fatalf("Expected an answer on the second request, got %q after %d", resp.Content, len(times))
+	}
+	if wait := times[1].Sub(times[0]); wait < 200*time.Millisecond {
+		t.Errorf("Retry-After should be waited for, retried after %s", wait)
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		requests   int
+	}{
+		{http.StatusServiceUnavailable, "", 1 + maxRetries},
+		{http.StatusBadRequest, "", 1},
+		{http.StatusUnauthorized, "", 1},
+		// Waiting that long is left to a fallback provider
+		{http.StatusTooManyRequests, "3600", 1},
+	}
+	for _, tt := range tests {
+		requests := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if tt.retryAfter != "" {
+				w.Header().Set("Retry-After", tt.retryAfter)
+			}
+			w.WriteHeader(tt.status)
+		}))
+		p := NewOllamaProvider(srv.URL, time.Second).(*OllamaProvider)
+		p.backoff = time.Millisecond
+		_, err := p.Chat(context.Background(), question("hi"), nil)
+		srv.Close()
+
+		if err == nil || requests != tt.requests {
+			t.Errorf("%d: expected an error after %d requests, got %v after %d", tt.status, tt.requests, err, requests)
+		}
+	}
+}
+
+// fakeProvider answers with its tokens, or fails after streaming them
+type fakeProvider struct {
+	name   string
+	tokens []string
+	err    error
+	model  string
+}
+
+func (f *fakeProvider) Name() string         { return f.name }
+func (f *fakeProvider) DefaultModel() string { return f.name + "-model" }
+
+func (f *fakeProvider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	f.model = req.Model
+	for _, t := range f.tokens {
+		onToken(t)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ports.ChatResponse{Provider: f.name, Model: req.Model, Content: strings.Join(f.tokens, "")}, nil
+}
+
+func TestChainFallsBack(t *testing.T) {
+	down := &fakeProvider{name: "ollama", err: nuxerrors.New(nuxerrors.ErrCodeNetwork, "connection refused")}
+	busy := &fakeProvider{name: "nvidia", err: nuxerrors.New(nuxerrors.ErrCodeProvider, "503").WithRetryable()}
+	up := &fakeProvider{name: "openai", tokens: []string{"fine"}}
+	c := NewChain("ollama,nvidia,openai", []Link{{Provider: down}, {Provider: busy, Model: "meta/llama"}, {Provider: up}})
+	var fallbacks []string
+	c.OnFallback = func(failed, next string, err error) { fallbacks = append(fallbacks, failed+">"+next) }
+
+	var tokens []string
+	resp, err := c.Chat(context.Background(), ports.ChatRequest{Model: "gpt-4o"}, func(t string) { tokens = append(tokens, t) })
+	if err != nil {
+		t.Fatalf("Chat should fall back: %v", err)
+	}
+	if resp.Provider != "openai" || strings.Join(tokens, "") != "fine" {
+		t.Errorf("Unexpected answer %+v, tokens %v", resp, tokens)
+	}
+	if strings.Join(fallbacks, ",") != "ollama>nvidia,nvidia>openai" {
+		t.Errorf("Unexpected fallbacks %v", fallbacks)
+	}
+	if busy.model != "meta/llama" || up.model != "gpt-4o" {
+		t.Errorf("Links should get their own model or the requested one, got %q and %q", busy.model, up.model)
+	}
+}
+
+func TestChainKeepsPartialAnswers(t *testing.T) {
+	cut := &fakeProvider{name: "ollama", tokens: []string{"half"}, err: nuxerrors.New(nuxerrors.ErrCodeNetwork, "reset")}
+	next := &fakeProvider{name: "openai", tokens: []string{"whole"}}
+	c := NewChain("ollama,openai", []Link{{Provider: cut}, {Provider: next}})
+
+	_, err := c.Chat(context.Background(), question("hi"), func(string) {})
+	if nuxerrors.CodeOf(err) != nuxerrors.ErrCodeNetwork || next.model != "" {
+		t.Errorf("An answer cut short should not be asked again elsewhere, got %v", err)
+	}
+
+	down := &fakeProvider{name: "ollama", err: nuxerrors.New(nuxerrors.ErrCodeNetwork, "refused")}
+	last := &fakeProvider{name: "openai", err: nuxerrors.New(nuxerrors.ErrCodeUnauthorized, "bad key")}
+	c = NewChain("ollama,openai", []Link{{Provider: down}, {Provider: last}})
+	_, err = c.Chat(context.Background(), question("hi"), nil)
+	if nuxerrors.CodeOf(err) != nuxerrors.ErrCodeUnauthorized || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("Every failure should be reported, got %v", err)
+	}
+}
+
+func TestTimeoutBoundsPauses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Keeps streaming past the timeout, which only bounds each pause
+		for i := 0; i < 4; i++ {
+			fmt.Fprintln(w, `{"message":{"content":"."}}`)
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+		// Then stalls
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, 250*time.Millisecond)
+	_, err := p.Chat(context.Background(), question("hi"), nil)
+	if code := nuxerrors.CodeOf(err); code != nuxerrors.ErrCodeTimeout {
+		t.Fatalf("Expected a timeout once the stream stalls, got %v", err)
+	}
+	if !nuxerrors.IsRetryable(err) {
+		t.Error("A timeout should be retryable")
+	}
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("No API key was configured, got %q", auth)
+		}
+		switch r.URL.Path {
+		case "/v1/models":
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen2.5","owned_by":"vllm"}]}`)
+		case "/v1/chat/completions":
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n")
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewOpenAICompatibleProvider("vllm", srv.URL+"/", "", "qwen2.5", time.Second)
+	if p.Name() != "vllm" || p.DefaultModel() != "qwen2.5" {
+		t.Errorf("Unexpected provider %s/%s", p.Name(), p.DefaultModel())
+	}
+	models, err := p.(ports.ModelLister).ListModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "qwen2.5" || models[0].Owner != "vllm" {
+		t.Fatalf("Unexpected models %+v, %v", models, err)
+	}
+	resp, err := p.Chat(context.Background(), question("hi"), nil)
+	if err != nil || resp.Content != "ok" {
+		t.Fatalf("Unexpected answer %+v, %v", resp, err)
+	}
+
+	// A base URL with a path is used as given
+	if got := apiBase("http://gpu01:8080/openai/v1/"); got != "http://gpu01:8080/openai/v1" {
+		t.Errorf("Unexpected API base %s", got)
+	}
+}
+
+func toolRequest() ports.ChatRequest {
+	return ports.ChatRequest{
+		Messages: []ports.ChatMessage{
+			{Role: ports.RoleUser, Content: "why is nginx down?"},
+			{Role: ports.RoleAssistant, ToolCalls: []ports.ToolCall{{ID: "t1", Name: "service_status", Arguments: json.RawMessage(`{"name":"nginx"}`)}}},
+			{Role: ports.RoleTool, ToolCallID: "t1", Name: "service_status", Content: "failed"},
+		},
+		Tools: []ports.Tool{{Name: "service_logs", Description: "logs", Parameters: map[string]interface{}{"type": "object"}}},
+	}
+}
+
+func TestOpenAIToolCalls(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"c1\",\"function\":{\"name\":\"service_logs\",\"arguments\":\"\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"name\\\":\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"nginx\\\"}\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider("sk-test", time.Second).(*OpenAIProvider)
+	p.baseURL = srv.URL
+	resp, err := p.Chat(context.Background(), toolRequest(), nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "c1" || string(resp.ToolCalls[0].Arguments) != `{"name":"nginx"}` {
+		t.Errorf("Unexpected tool calls %+v", resp.ToolCalls)
+	}
+	messages := got["messages"].([]interface{})
+	if tools, ok := got["tools"].([]interface{}); !ok || len(tools) != 1 || len(messages) != 3 {
+		t.Errorf("Unexpected request %+v", got)
+	}
+	if result := messages[2].(map[string]interface{}); result["tool_call_id"] != "t1" {
+		t.Errorf("The tool result should answer its call, got %+v", result)
+	}
+}
+
+func TestAnthropicToolCalls(t *testing.T) {
+	var got struct {
+		Messages []struct {
+			Role    string
+			Content json.RawMessage
+		}
+		Tools []anthropicTool
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"checking\"}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"tu1\",\"name\":\"service_logs\"}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"name\\\": \\\"nginx\\\"}\"}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewAnthropicProvider("key", time.Second).(*AnthropicProvider)
+	p.baseURL = srv.URL
+	resp, err := p.Chat(context.Background(), toolRequest(), nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "checking" || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "tu1" || string(resp.ToolCalls[0].Arguments) != `{"name": "nginx"}` {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if len(got.Messages) != 3 || got.Messages[2].Role != ports.RoleUser || !strings.Contains(string(got.Messages[2].Content), `"tool_use_id":"t1"`) {
+		t.Errorf("The tool result should be sent as a user message, got %+v", got.Messages)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Name != "service_logs" {
+		t.Errorf("Unexpected tools %+v", got.Tools)
+	}
+}
+
+func TestOllamaToolCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"service_logs","arguments":{"name":"nginx"}}}]},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, time.Second)
+	resp, err := p.Chat(context.Background(), toolRequest(), nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_0" || string(resp.ToolCalls[0].Arguments) != `{"name":"nginx"}` {
+		t.Errorf("Unexpected tool calls %+v", resp.ToolCalls)
+	}
+}
+
+func TestOllamaEmbed(t *testing.T) {
+	var got struct {
+		Model string
+		Input []string
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"embeddings":[[0.1,0.2],[0.3,0.4]]}`)
+	}))
+	defer srv.Close()
+
+	p := NewOllamaProvider(srv.URL, time.Second).(ports.Embedder)
+	vectors, err := p.Embed(context.Background(), "", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][1] != 0.4 || got.Model != DefaultEmbedModel || len(got.Input) != 2 {
+		t.Errorf("Unexpected vectors %v for request %+v", vectors, got)
+	}
+	if _, err := p.Embed(context.Background(), "", []string{"a"}); err == nil {
+		t.Error("A count mismatch should fail")
+	}
+}
@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// DefaultOllamaHost is where a local Ollama listens
+const DefaultOllamaHost = "http://localhost:11434"
+
+// DefaultEmbedModel is the Ollama model texts are embedded with
+const DefaultEmbedModel = "nomic-embed-text"
+
+// OllamaProvider talks to an Ollama server
+type OllamaProvider struct {
+	client
+	host string
+}
+
+// NewOllamaProvider returns a provider for the Ollama server at host, the
+// local one when host is empty
+func NewOllamaProvider(host string, timeout time.Duration) ports.LLMProvider {
+	if host == "" {
+		host = DefaultOllamaHost
+	}
+	return &OllamaProvider{client: newClient("Ollama", "", timeout), host: strings.TrimRight(host, "/")}
+}
+
+func (p *OllamaProvider) Name() string { return "ollama" }
+
+func (p *OllamaProvider) DefaultModel() string { return "qwen3-coder" }
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []functionTool  `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]int  `json:"options,omitempty"`
+}
+
+// functionTool declares a tool the way Ollama and the OpenAI API take it
+type functionTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	} `json:"function"`
+}
+
+func functionTools(tools []ports.Tool) []functionTool {
+	var out []functionTool
+	for _, t := range tools {
+		f := functionTool{Type: "function"}
+		f.Function.Name = t.Name
+		f.Function.Description = t.Description
+		f.Function.Parameters = t.Parameters
+		out = append(out, f)
+	}
+	return out
+}
+
+// ollamaChunk is one line of the NDJSON stream of /api/chat
+type ollamaChunk struct {
+	Model   string        `json:"model"`
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error"`
+	// The last chunk counts the tokens of the prompt and of the answer
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// Chat implements ports.LLMProvider with /api/chat
+func (p *OllamaProvider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	payload := ollamaRequest{Model: modelOf(p, req), Tools: functionTools(req.Tools), Stream: true}
+	for _, m := range req.Messages {
+		msg := ollamaMessage{Role: m.Role, Content: m.Content}
+		for _, call := range m.ToolCalls {
+			var c ollamaToolCall
+			c.Function.Name = call.Name
+			c.Function.Arguments = objectArguments(call.Arguments)
+			msg.ToolCalls = append(msg.ToolCalls, c)
+		}
+		if m.Role == ports.RoleTool {
+			msg.ToolName = m.Name
+		}
+		payload.Messages = append(payload.Messages, msg)
+	}
+	if req.MaxTokens > 0 {
+		payload.Options = map[string]int{"num_predict": req.MaxTokens}
+	}
+
+	body, err := p.post(ctx, p.host+"/api/chat", nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	answer := &collect{onToken: onToken}
+	var calls []ports.ToolCall
+	var usage ports.Usage
+	err = readLines(body, func(line []byte) error {
+		var chunk ollamaChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return p.badResponse(err)
+		}
+		if chunk.Error != "" {
+			return nuxerrors.New(nuxerrors.ErrCodeProvider, "Ollama: "+chunk.Error)
+		}
+		answer.add(chunk.Message.Content)
+		for _, c := range chunk.Message.ToolCalls {
+			calls = append(calls, ports.ToolCall{ID: callID(len(calls)), Name: c.Function.Name, Arguments: toolArguments(string(c.Function.Arguments))})
+		}
+		if chunk.Done {
+			usage = ports.Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			return io.EOF
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &ports.ChatResponse{Provider: p.Name(), Model: payload.Model, Content: answer.String(), ToolCalls: calls, Usage: usage}, nil
+}
+
+// ListModels implements ports.ModelLister with the models pulled into the
+// server
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]ports.Model, error) {
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := p.get(ctx, p.host+"/api/tags", nil, &tags); err != nil {
+		return nil, err
+	}
+	models := make([]ports.Model, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, ports.Model{ID: m.Name})
+	}
+	return models, nil
+}
+
+// Embed implements ports.Embedder with /api/embed, DefaultEmbedModel when
+// model is empty
+func (p *OllamaProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if model == "" {
+		model = DefaultEmbedModel
+	}
+	body, err := p.post(ctx, p.host+"/api/embed", nil, map[string]interface{}{"model": model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		if nuxerrors.CodeOf(err) != "" {
+			return nil, err
+		}
+		return nil, p.badResponse(err)
+	}
+	if resp.Error != "" {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeProvider, "Ollama: "+resp.Error)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeProvider, fmt.Sprintf("Ollama returned %d embeddings for %d texts", len(resp.Embeddings), len(texts)))
+	}
+	return resp.Embeddings, nil
+}
+
+// modelOf returns the model of req, or the default of p
+func modelOf(p ports.LLMProvider, req ports.ChatRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.DefaultModel()
+}
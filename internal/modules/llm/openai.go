@@ -0,0 +1,232 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// OpenAIProvider talks to the OpenAI chat completions API, or any server
+// that implements it
+type OpenAIProvider struct {
+	client
+	id      string
+	baseURL string
+	apiKey  string
+	model   string
+	// params are sent with every request, such as a temperature
+	params map[string]interface{}
+	// usage asks for the token counts at the end of the stream, which not
+	// every server implementing the API accepts
+	usage bool
+}
+
+// NewOpenAIProvider returns a provider for OpenAI
+func NewOpenAIProvider(apiKey string, timeout time.Duration) ports.LLMProvider {
+	return &OpenAIProvider{
+		client:  newClient("OpenAI", "openai", timeout),
+		id:      "openai",
+		baseURL: "https://api.openai.com/v1",
+		apiKey:  apiKey,
+		model:   "gpt-4o-mini",
+		usage:   true,
+	}
+}
+
+// NewNvidiaProvider returns a provider for NVIDIA Build, which serves its
+// models through the OpenAI API
+func NewNvidiaProvider(apiKey string, timeout time.Duration) ports.LLMProvider {
+	return &OpenAIProvider{
+		client:  newClient("NVIDIA Build", "nvidia", timeout),
+		id:      "nvidia",
+		baseURL: "https://integrate.api.nvidia.com/v1",
+		apiKey:  apiKey,
+		model:   "minimaxai/minimax-m2.7",
+		params:  map[string]interface{}{"temperature": 1, "top_p": 0.95, "max_tokens": 8192},
+		usage:   true,
+	}
+}
+
+// NewOpenAICompatibleProvider returns a provider for a server implementing
+// the OpenAI API, such as llama.cpp or vLLM, known to nux as name. A baseURL
+// without a path gets the usual /v1. apiKey may be empty for servers without
+// authentication.
+func NewOpenAICompatibleProvider(name, baseURL, apiKey, model string, timeout time.Duration) ports.LLMProvider {
+	return &OpenAIProvider{
+		client:  newClient(name, "", timeout),
+		id:      name,
+		baseURL: apiBase(baseURL),
+		apiKey:  apiKey,
+		model:   model,
+	}
+}
+
+// apiBase returns the root of the OpenAI API on a server
+func apiBase(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if u, err := url.Parse(baseURL); err == nil && u.Path == "" {
+		return baseURL + "/v1"
+	}
+	return baseURL
+}
+
+func (p *OpenAIProvider) Name() string { return p.id }
+
+func (p *OpenAIProvider) DefaultModel() string { return p.model }
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall is a tool call, or a piece of one while it streams
+type openAIToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAIChunk is one event of a streamed chat completion
+type openAIChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	// Usage comes with the last event, without choices
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Chat implements ports.LLMProvider with /chat/completions
+func (p *OpenAIProvider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	model := modelOf(p, req)
+	messages := make([]openAIMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		msg := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for i, call := range m.ToolCalls {
+			c := openAIToolCall{Index: i, ID: call.ID, Type: "function"}
+			c.Function.Name = call.Name
+			c.Function.Arguments = string(objectArguments(call.Arguments))
+			msg.ToolCalls = append(msg.ToolCalls, c)
+		}
+		messages = append(messages, msg)
+	}
+	payload := map[string]interface{}{}
+	for k, v := range p.params {
+		payload[k] = v
+	}
+	payload["model"] = model
+	payload["messages"] = messages
+	payload["stream"] = true
+	if req.MaxTokens > 0 {
+		payload["max_tokens"] = req.MaxTokens
+	}
+	if len(req.Tools) > 0 {
+		payload["tools"] = functionTools(req.Tools)
+	}
+	if p.usage {
+		payload["stream_options"] = map[string]bool{"include_usage": true}
+	}
+
+	headers := p.headers()
+	headers["Accept"] = "text/event-stream"
+	body, err := p.post(ctx, p.baseURL+"/chat/completions", headers, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	answer := &collect{onToken: onToken}
+	// Tool calls stream as pieces of their arguments, by index
+	var calls []*openAIToolCall
+	var usage ports.Usage
+	err = readSSE(body, func(data []byte) error {
+		var chunk openAIChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return p.badResponse(err)
+		}
+		if chunk.Error != nil {
+			return nuxerrors.New(nuxerrors.ErrCodeProvider, p.name+": "+chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = ports.Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+		for _, choice := range chunk.Choices {
+			answer.add(choice.Delta.Content)
+			for _, piece := range choice.Delta.ToolCalls {
+				for len(calls) <= piece.Index {
+					calls = append(calls, &openAIToolCall{})
+				}
+				call := calls[piece.Index]
+				if piece.ID != "" {
+					call.ID = piece.ID
+				}
+				if piece.Function.Name != "" {
+					call.Function.Name = piece.Function.Name
+				}
+				call.Function.Arguments += piece.Function.Arguments
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &ports.ChatResponse{Provider: p.id, Model: model, Content: answer.String(), Usage: usage}
+	for i, call := range calls {
+		if call.Function.Name == "" {
+			continue
+		}
+		id := call.ID
+		if id == "" {
+			id = callID(i)
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ports.ToolCall{ID: id, Name: call.Function.Name, Arguments: toolArguments(call.Function.Arguments)})
+	}
+	return resp, nil
+}
+
+// ListModels implements ports.ModelLister with /models
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]ports.Model, error) {
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := p.get(ctx, p.baseURL+"/models", p.headers(), &list); err != nil {
+		return nil, err
+	}
+	models := make([]ports.Model, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ports.Model{ID: m.ID, Owner: m.OwnedBy})
+	}
+	return models, nil
+}
+
+func (p *OpenAIProvider) headers() map[string]string {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	return headers
+}
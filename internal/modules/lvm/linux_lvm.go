@@ -3,47 +3,34 @@ package lvm
 // Package lvm provides Logical Volume Manager capabilities.
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
-// LinuxLVMManager implements ports.LVMManager using Linux LVM tools
+// LinuxLVMManager implements ports.LVMManager using Linux LVM tools. The LVM
+// tools need root, so executor is expected to be a privilege broker.
 type LinuxLVMManager struct {
-	// Options like sudo can be injected
-	Sudo bool
+	executor adapter.Executor
 }
 
-func NewLinuxLVMManager(sudo bool) ports.LVMManager {
-	return &LinuxLVMManager{Sudo: sudo}
+func NewLinuxLVMManager(executor adapter.Executor) ports.LVMManager {
+	return &LinuxLVMManager{executor: executor}
 }
 
 func (m *LinuxLVMManager) runCommand(args ...string) (string, error) {
-	var cmd *exec.Cmd
-	if m.Sudo {
-		cmdArgs := append([]string{"sudo"}, args...)
-		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	} else {
-		cmd = exec.Command(args[0], args[1:]...)
-	}
-	
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-	
-	err := cmd.Run()
+	res, err := m.executor.Exec(context.Background(), args[0], args[1:]...)
 	if err != nil {
-		return "", fmt.Errorf("lvm command failed: %w, stderr: %s", err, stderr.String())
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeLVM, "lvm command failed")
 	}
-	
-	return strings.TrimSpace(out.String()), nil
+
+	return res.Stdout, nil
 }
 
 // JSON structs for LVM reports
@@ -64,7 +51,7 @@ func (m *LinuxLVMManager) ListPhysicalVolumes() ([]ports.PhysicalVolume, error)
 
 	var report lvmReport
 	if err := json.Unmarshal([]byte(out), &report); err != nil {
-		return nil, fmt.Errorf("failed to parse pvs output: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeLVM, "failed to parse pvs output")
 	}
 
 	var pvs []ports.PhysicalVolume
@@ -89,7 +76,7 @@ func (m *LinuxLVMManager) ListVolumeGroups() ([]ports.VolumeGroup, error) {
 
 	var report lvmReport
 	if err := json.Unmarshal([]byte(out), &report); err != nil {
-		return nil, fmt.Errorf("failed to parse vgs output: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeLVM, "failed to parse vgs output")
 	}
 
 	var vgs []ports.VolumeGroup
@@ -118,7 +105,7 @@ func (m *LinuxLVMManager) ListLogicalVolumes() ([]ports.LogicalVolume, error) {
 
 	var report lvmReport
 	if err := json.Unmarshal([]byte(out), &report); err != nil {
-		return nil, fmt.Errorf("failed to parse lvs output: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeLVM, "failed to parse lvs output")
 	}
 
 	var lvs []ports.LogicalVolume
@@ -138,7 +125,7 @@ func (m *LinuxLVMManager) ListLogicalVolumes() ([]ports.LogicalVolume, error) {
 
 func (m *LinuxLVMManager) CreateLogicalVolume(vgName string, lvName string, size string) error {
 	if vgName == "" || lvName == "" || size == "" {
-		return fmt.Errorf("invalid arguments: vgName, lvName and size are required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "invalid arguments: vgName, lvName and size are required")
 	}
 	_, err := m.runCommand("lvcreate", "-L", size, "-n", lvName, vgName)
 	return err
@@ -146,7 +133,7 @@ func (m *LinuxLVMManager) CreateLogicalVolume(vgName string, lvName string, size
 
 func (m *LinuxLVMManager) ExtendLogicalVolume(lvPath string, size string) error {
 	if lvPath == "" || size == "" {
-		return fmt.Errorf("invalid arguments: lvPath and size are required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "invalid arguments: lvPath and size are required")
 	}
 	_, err := m.runCommand("lvextend", "-L", "+"+size, lvPath) 
 	return err
@@ -154,7 +141,7 @@ func (m *LinuxLVMManager) ExtendLogicalVolume(lvPath string, size string) error
 
 func (m *LinuxLVMManager) ReduceLogicalVolume(lvPath string, size string) error {
 	if lvPath == "" || size == "" {
-		return fmt.Errorf("invalid arguments: lvPath and size are required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "invalid arguments: lvPath and size are required")
 	}
 	_, err := m.runCommand("lvreduce", "-L", "-"+size, lvPath)
 	return err
@@ -162,7 +149,7 @@ func (m *LinuxLVMManager) ReduceLogicalVolume(lvPath string, size string) error
 
 func (m *LinuxLVMManager) RemoveLogicalVolume(lvPath string) error {
 	if lvPath == "" {
-		return fmt.Errorf("invalid arguments: lvPath is required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "invalid arguments: lvPath is required")
 	}
 	_, err := m.runCommand("lvremove", "-f", lvPath)
 	return err
@@ -179,20 +166,20 @@ func (m *LinuxLVMManager) ResizeFileSystem(lvPath string) error {
 	// For now, assume resize2fs or let the user handle it if complex.
 	// But requirements say "Professional".
 	// Let's find mount point.
-	out, err := exec.Command("findmnt", "-n", "-o", "TARGET", "--source", lvPath).Output()
+	res, err := m.executor.Exec(context.Background(), "findmnt", "-n", "-o", "TARGET", "--source", lvPath)
 	if err == nil {
-		mountPoint := strings.TrimSpace(string(out))
+		mountPoint := strings.TrimSpace(res.Stdout)
 		if mountPoint != "" {
 			_, err = m.runCommand("xfs_growfs", mountPoint)
 			return err
 		}
 	}
-	return fmt.Errorf("resize2fs failed and could not determine mountpoint for xfs_growfs")
+	return nuxerrors.New(nuxerrors.ErrCodeLVM, "resize2fs failed and could not determine mountpoint for xfs_growfs")
 }
 
 func (m *LinuxLVMManager) CreatePhysicalVolume(device string) error {
 	if device == "" {
-		return fmt.Errorf("device path is required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "device path is required")
 	}
 	_, err := m.runCommand("pvcreate", device)
 	return err
@@ -200,7 +187,7 @@ func (m *LinuxLVMManager) CreatePhysicalVolume(device string) error {
 
 func (m *LinuxLVMManager) RemovePhysicalVolume(device string) error {
 	if device == "" {
-		return fmt.Errorf("device path is required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "device path is required")
 	}
 	_, err := m.runCommand("pvremove", device)
 	return err
@@ -208,7 +195,7 @@ func (m *LinuxLVMManager) RemovePhysicalVolume(device string) error {
 
 func (m *LinuxLVMManager) ResizePhysicalVolume(device string) error {
 	if device == "" {
-		return fmt.Errorf("device path is required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "device path is required")
 	}
 	_, err := m.runCommand("pvresize", device)
 	return err
@@ -216,7 +203,7 @@ func (m *LinuxLVMManager) ResizePhysicalVolume(device string) error {
 
 func (m *LinuxLVMManager) CreateVolumeGroup(vgName string, pvs []string) error {
 	if vgName == "" || len(pvs) == 0 {
-		return fmt.Errorf("vgName and at least one PV are required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "vgName and at least one PV are required")
 	}
 	args := append([]string{"vgcreate", vgName}, pvs...)
 	_, err := m.runCommand(args...)
@@ -225,7 +212,7 @@ func (m *LinuxLVMManager) CreateVolumeGroup(vgName string, pvs []string) error {
 
 func (m *LinuxLVMManager) ExtendVolumeGroup(vgName string, pvName string) error {
 	if vgName == "" || pvName == "" {
-		return fmt.Errorf("vgName and pvName are required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "vgName and pvName are required")
 	}
 	_, err := m.runCommand("vgextend", vgName, pvName)
 	return err
@@ -233,7 +220,7 @@ func (m *LinuxLVMManager) ExtendVolumeGroup(vgName string, pvName string) error
 
 func (m *LinuxLVMManager) ReduceVolumeGroup(vgName string, pvName string) error {
 	if vgName == "" || pvName == "" {
-		return fmt.Errorf("vgName and pvName are required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "vgName and pvName are required")
 	}
 	_, err := m.runCommand("vgreduce", vgName, pvName)
 	return err
@@ -241,7 +228,7 @@ func (m *LinuxLVMManager) ReduceVolumeGroup(vgName string, pvName string) error
 
 func (m *LinuxLVMManager) RemoveVolumeGroup(vgName string) error {
 	if vgName == "" {
-		return fmt.Errorf("vgName is required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "vgName is required")
 	}
 	_, err := m.runCommand("vgremove", "-f", vgName)
 	return err
@@ -259,17 +246,18 @@ func (m *LinuxLVMManager) ScanDevices() error {
 func (m *LinuxLVMManager) RescanSCSI() error {
 	// Rescan SCSI hosts
 	// echo "- - -" > /sys/class/scsi_host/hostX/scan
-	hosts, err := os.ReadDir("/sys/class/scsi_host")
+	ctx := context.Background()
+	res, err := m.executor.Exec(adapter.WithReadOnly(ctx), "ls", "/sys/class/scsi_host")
 	if err != nil {
-		return fmt.Errorf("failed to read scsi_host: %w", err)
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeLVM, "failed to read scsi_host")
 	}
 
-	for _, host := range hosts {
-		scanPath := fmt.Sprintf("/sys/class/scsi_host/%s/scan", host.Name())
-		if err := os.WriteFile(scanPath, []byte("- - -"), 0200); err != nil {
+	for _, host := range strings.Fields(res.Stdout) {
+		scanPath := fmt.Sprintf("/sys/class/scsi_host/%s/scan", host)
+		if _, err := m.executor.ExecWithInput(adapter.WithPrivileged(ctx), "- - -", "tee", scanPath); err != nil {
 			// Don't fail completely, just log error conceptually
 			// In production code we might collect errors
-			continue 
+			continue
 		}
 	}
 	return nil
@@ -4,12 +4,12 @@ package network
 
 import (
 	"bufio"
-	"fmt"
 	"net"
 	"os"
 	"os/exec"
 	"strings"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -22,10 +22,10 @@ func NewLinuxNetworkManager() ports.NetworkManager {
 func (m *LinuxNetworkManager) ValidateConfig(config ports.NetworkConfig) error {
 	// Basic validation
 	if config.Interface == "" {
-		return fmt.Errorf("interface name is required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "interface name is required")
 	}
 	if !config.DHCP && config.IP == "" {
-		return fmt.Errorf("IP address is required for static configuration")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "IP address is required for static configuration")
 	}
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -19,6 +20,7 @@ import (
 type UniversalNetworkManager struct {
 	executor adapter.Executor
 	profile  *domain.SystemProfile
+	onEvent  adapter.EventHandler
 }
 
 // NewUniversalNetworkManager creates a new network manager
@@ -29,11 +31,18 @@ func NewUniversalNetworkManager(executor adapter.Executor, profile *domain.Syste
 	}
 }
 
+// WithStream delivers captured packets to onEvent as tcpdump prints them
+// instead of returning them once the capture ends
+func (m *UniversalNetworkManager) WithStream(onEvent adapter.EventHandler) *UniversalNetworkManager {
+	m.onEvent = onEvent
+	return m
+}
+
 // GetInterfaces returns detailed interface info
 func (m *UniversalNetworkManager) GetInterfaces() ([]ports.NetworkInterface, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get interfaces: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeNetworkConfig, "failed to get interfaces")
 	}
 
 	var result []ports.NetworkInterface
@@ -86,16 +95,16 @@ func (m *UniversalNetworkManager) GetActiveStack() (string, error) {
 func (m *UniversalNetworkManager) ApplyConfig(config ports.NetworkConfig) error {
 	// Simplified implementation for demo purposes
 	// Real implementation requires handling multiple network managers complex configs
-	return fmt.Errorf("apply config not fully implemented for %s", m.profile.NetworkStack)
+	return nuxerrors.New(nuxerrors.ErrCodeNetworkConfig, fmt.Sprintf("apply config not fully implemented for %s", m.profile.NetworkStack))
 }
 
 // ValidateConfig validates the configuration syntax
 func (m *UniversalNetworkManager) ValidateConfig(config ports.NetworkConfig) error {
 	if config.Interface == "" {
-		return fmt.Errorf("interface name required")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "interface name required")
 	}
 	if !config.DHCP && config.IP == "" {
-		return fmt.Errorf("IP address required for static config")
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "IP address required for static config")
 	}
 	return nil
 }
@@ -199,7 +208,8 @@ func (m *UniversalNetworkManager) RunNmap(target string, options string) ([]port
 	return RunNativePortScan(target, ports)
 }
 
-// RunTcpdump runs tcpdump on an interface
+// RunTcpdump runs tcpdump on an interface. With WithStream the packets go to
+// the handler and the returned string is empty.
 func (m *UniversalNetworkManager) RunTcpdump(interfaceName string, filter string, durationSeconds int) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(durationSeconds)*time.Second)
 	defer cancel()
@@ -209,6 +219,14 @@ func (m *UniversalNetworkManager) RunTcpdump(interfaceName string, filter string
 		args = append(args, filter)
 	}
 
+	if m.onEvent != nil {
+		_, err := adapter.RunStream(ctx, m.executor, "", m.onEvent, "tcpdump", args...)
+		if err != nil && ctx.Err() != context.DeadlineExceeded {
+			return "", err
+		}
+		return "", nil
+	}
+
 	res, err := m.executor.Exec(ctx, "tcpdump", args...)
 	if err != nil {
 		// Timeout is expected
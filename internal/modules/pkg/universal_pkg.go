@@ -9,7 +9,9 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/journal"
 )
 
 const (
@@ -21,6 +23,7 @@ const (
 type UniversalPackageManager struct {
 	executor adapter.Executor
 	profile  *domain.SystemProfile
+	journal  *journal.Journal
 }
 
 // NewUniversalPackageManager creates a new package manager
@@ -31,10 +34,16 @@ func NewUniversalPackageManager(executor adapter.Executor, profile *domain.Syste
 	}
 }
 
+// WithJournal records installed and removed packages into j
+func (m *UniversalPackageManager) WithJournal(j *journal.Journal) *UniversalPackageManager {
+	m.journal = j
+	return m
+}
+
 // DetectManager is now handled by ProfileEngine, but we keep this for interface compliance or internal check
 func (m *UniversalPackageManager) DetectManager() (string, error) {
 	if m.profile.PackageManager == "" {
-		return "", fmt.Errorf("no supported package manager detected in profile")
+		return "", nuxerrors.New(nuxerrors.ErrCodeUnsupported, "no supported package manager detected in profile")
 	}
 	return m.profile.PackageManager, nil
 }
@@ -57,10 +66,15 @@ func (m *UniversalPackageManager) Install(packageName string) error {
 	case "apk":
 		args = []string{"add", packageName}
 	default:
-		return fmt.Errorf("unsupported package manager: %s", cmd)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported package manager: %s", cmd))
 	}
 
+	// Undoing the install of a package that was already there would remove it
+	installed, known := m.installed(ctx, packageName)
 	_, err := m.executor.Exec(ctx, cmd, args...)
+	if err == nil && known && !installed {
+		m.journal.Record(journal.OpPackageInstall, map[string]string{"package": packageName})
+	}
 	return err
 }
 
@@ -82,13 +96,38 @@ func (m *UniversalPackageManager) Remove(packageName string) error {
 	case "apk":
 		args = []string{"del", packageName}
 	default:
-		return fmt.Errorf("unsupported package manager: %s", cmd)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported package manager: %s", cmd))
 	}
 
+	installed, known := m.installed(ctx, packageName)
 	_, err := m.executor.Exec(ctx, cmd, args...)
+	if err == nil && known && installed {
+		m.journal.Record(journal.OpPackageRemove, map[string]string{"package": packageName})
+	}
 	return err
 }
 
+// installed reports whether a package is installed. known is false when the
+// package database could not be queried.
+func (m *UniversalPackageManager) installed(ctx context.Context, packageName string) (installed bool, known bool) {
+	switch m.profile.PackageManager {
+	case "apt":
+		// dpkg-query also knows removed packages that left their configuration
+		res, err := m.executor.Exec(adapter.WithReadOnly(ctx), "dpkg-query", "-W", "-f=${Status}", packageName)
+		if err != nil {
+			return false, res != nil && res.ExitCode > 0
+		}
+		return strings.HasSuffix(strings.TrimSpace(res.Stdout), " installed"), true
+	case dnfCmd, "yum", "zypper":
+		return adapter.Probe(ctx, m.executor, "rpm", "-q", packageName)
+	case pacmanCmd:
+		return adapter.Probe(ctx, m.executor, pacmanCmd, "-Q", packageName)
+	case "apk":
+		return adapter.Probe(ctx, m.executor, "apk", "info", "-e", packageName)
+	}
+	return false, false
+}
+
 // Update updates the package list
 func (m *UniversalPackageManager) Update() error {
 	ctx := context.Background()
@@ -107,7 +146,7 @@ func (m *UniversalPackageManager) Update() error {
 	case "apk":
 		args = []string{"update"}
 	default:
-		return fmt.Errorf("unsupported package manager: %s", cmd)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported package manager: %s", cmd))
 	}
 
 	_, err := m.executor.Exec(ctx, cmd, args...)
@@ -132,7 +171,7 @@ func (m *UniversalPackageManager) Upgrade() error {
 	case "apk":
 		args = []string{"upgrade"}
 	default:
-		return fmt.Errorf("unsupported package manager: %s", cmd)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported package manager: %s", cmd))
 	}
 
 	_, err := m.executor.Exec(ctx, cmd, args...)
@@ -155,7 +194,7 @@ func (m *UniversalPackageManager) Search(query string) ([]ports.PackageInfo, err
 	case "apk":
 		args = []string{"search", "-v", query}
 	default:
-		return nil, fmt.Errorf("search not implemented for %s", cmdName)
+		return nil, nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("search not implemented for %s", cmdName))
 	}
 
 	res, err := m.executor.Exec(ctx, cmdName, args...)
@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/core/domain"
+	"github.com/rsdenck/nux/internal/journal"
+)
+
+// packageCommands are the commands of the package managers and databases in
+// the transcripts; a call the manager skipped leaves one of them unused
+var packageCommands = []string{"apt", "dpkg-query", "dnf", "yum", "rpm", "pacman", "zypper", "apk"}
+
+// Every distro transcript in ../testdata is replayed against the manager, so
+// a command that differs from what the real package manager saw fails the test
+func TestPackageManagerAcrossDistros(t *testing.T) {
+	fixtures, _ := filepath.Glob(filepath.Join("..", "testdata", "*.json"))
+	if len(fixtures) == 0 {
+		t.Fatal("no distro fixtures found")
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			fixture, err := adapter.LoadFixture(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+			replay := adapter.NewReplayExecutor(fixture)
+			m := NewUniversalPackageManager(replay, fixture.Profile).WithJournal(j)
+
+			if pm, err := m.DetectManager(); err != nil || pm != fixture.Profile.PackageManager {
+				t.Errorf("DetectManager() = %q, %v", pm, err)
+			}
+			if err := m.Install("htop"); err != nil {
+				t.Errorf("Install failed: %v", err)
+			}
+			if err := m.Remove("htop"); err != nil {
+				t.Errorf("Remove failed: %v", err)
+			}
+			if err := m.Update(); err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+			if err := m.Upgrade(); err != nil {
+				t.Errorf("Upgrade failed: %v", err)
+			}
+
+			entries, err := j.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 || entries[0].Operation != journal.OpPackageInstall || entries[1].Operation != journal.OpPackageRemove {
+				t.Errorf("unexpected journal entries: %+v", entries)
+			}
+
+			if unused := replay.Unused(packageCommands...); len(unused) != 0 {
+				t.Errorf("recorded calls were never made: %v", unused)
+			}
+		})
+	}
+}
+
+// Undoing the install of a package that was already there would remove it
+func TestInstallPresentPackageNotJournaled(t *testing.T) {
+	fixture := &adapter.Fixture{Interactions: []adapter.Interaction{
+		{Command: "dpkg-query", Args: []string{"-W", "-f=${Status}", "curl"}, Stdout: "install ok installed"},
+		{Command: "apt", Args: []string{"install", "-y", "curl"}, Stdout: "curl is already the newest version (7.81.0-1ubuntu1.15)."},
+	}}
+	j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+	m := NewUniversalPackageManager(adapter.NewReplayExecutor(fixture), &domain.SystemProfile{PackageManager: "apt"}).WithJournal(j)
+
+	if err := m.Install("curl"); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if entries, _ := j.Entries(); len(entries) != 0 {
+		t.Errorf("nothing changed, yet the install was journaled: %+v", entries)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package plugin
+
+import "os/exec"
+
+// dropPrivileges is not supported on this platform
+func dropPrivileges(cmd *exec.Cmd) bool {
+	return false
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// dropPrivileges makes cmd run as the user who invoked nux through sudo or
+// doas, so plugins never inherit root. It reports false when nux was started
+// as root directly and there is no other user to run as.
+func dropPrivileges(cmd *exec.Cmd) bool {
+	uid, errUID := strconv.ParseUint(os.Getenv("SUDO_UID"), 10, 32)
+	gid, errGID := strconv.ParseUint(os.Getenv("SUDO_GID"), 10, 32)
+	if errUID != nil || errGID != nil || uid == 0 {
+		return false
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)},
+	}
+	return true
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	"github.com/rsdenck/nux/internal/core/logger"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -221,15 +222,16 @@ func (m *UniversalPluginManager) ExecutePlugin(ctx context.Context, name string,
 		defer cancel()
 	}
 
-	// 2. Drop privileges if root? (Not implemented here for portability, but warned)
-	if os.Geteuid() == 0 {
-		fmt.Println("Warning: Running plugin as root is dangerous!")
-	}
-
-	// 3. Secure Execution (Sandbox)
+	// 2. Secure Execution (Sandbox)
 	// Use direct exec.CommandContext instead of adapter to control environment and streams
 	cmd := exec.CommandContext(ctx, path, args...)
 
+	// 3. Plugins never need root: commands that do are elevated by the
+	// privilege broker, so drop back to the invoking user when run as root
+	if os.Geteuid() == 0 && !dropPrivileges(cmd) {
+		logger.Warn("running plugin %s as root", name)
+	}
+
 	// Environment sanitization - only pass essential variables
 	cmd.Env = []string{
 		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -27,7 +28,7 @@ func (m *LinuxProcessManager) KillProcess(pid int, signal string) error {
 	// Find the process
 	process, err := os.FindProcess(pid)
 	if err != nil {
-		return fmt.Errorf("failed to find process %d: %w", pid, err)
+		return nuxerrors.Wrapf(err, nuxerrors.ErrCodeProcess, "failed to find process %d", pid)
 	}
 
 	var sig syscall.Signal
@@ -39,14 +40,14 @@ func (m *LinuxProcessManager) KillProcess(pid int, signal string) error {
 	case "SIGINT":
 		sig = syscall.SIGINT
 	default:
-		return fmt.Errorf("unsupported signal: %s", signal)
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported signal: %s", signal))
 	}
 
 	// Send signal to kernel
 	// Effect: Process receives signal and terminates or handles it
 	// Resource: /proc/{pid}
 	if err := process.Signal(sig); err != nil {
-		return fmt.Errorf("failed to send signal %s to process %d: %w", signal, pid, err)
+		return nuxerrors.Wrapf(err, nuxerrors.ErrCodeProcess, "failed to send signal %s to process %d", signal, pid)
 	}
 
 	// Verification Protocol
@@ -60,7 +61,7 @@ func (m *LinuxProcessManager) KillProcess(pid int, signal string) error {
 			// It might be a zombie, check state
 			state, _ := getProcessState(pid)
 			if state != "Z" { // Z is Zombie
-				return fmt.Errorf("process %d still exists after SIGKILL", pid)
+				return nuxerrors.New(nuxerrors.ErrCodeProcess, fmt.Sprintf("process %d still exists after SIGKILL", pid))
 			}
 		}
 	}
@@ -155,7 +156,7 @@ func getProcessState(pid int) (string, error) {
 	s := string(data)
 	closeParen := strings.LastIndex(s, ")")
 	if closeParen == -1 || closeParen+2 >= len(s) {
-		return "", fmt.Errorf("parse error")
+		return "", nuxerrors.New(nuxerrors.ErrCodeProcess, "parse error")
 	}
 	// state is the character after ") "
 	return string(s[closeParen+2]), nil
@@ -172,7 +173,7 @@ func (m *LinuxProcessManager) KillZombies() (int, error) {
 
 	procs, err := os.ReadDir("/proc")
 	if err != nil {
-		return 0, fmt.Errorf("failed to read /proc: %w", err)
+		return 0, nuxerrors.Wrap(err, nuxerrors.ErrCodeProcess, "failed to read /proc")
 	}
 
 	zombieCount := 0
@@ -245,13 +246,13 @@ func getPPID(pid int) (int, error) {
 	s := string(data)
 	closeParen := strings.LastIndex(s, ")")
 	if closeParen == -1 || closeParen+2 >= len(s) {
-		return 0, fmt.Errorf("parse error")
+		return 0, nuxerrors.New(nuxerrors.ErrCodeProcess, "parse error")
 	}
 	// Fields after ") ": state ppid pgrp ...
 	rest := s[closeParen+2:]
 	fields := strings.Fields(rest)
 	if len(fields) < 2 {
-		return 0, fmt.Errorf("parse error")
+		return 0, nuxerrors.New(nuxerrors.ErrCodeProcess, "parse error")
 	}
 	// 0 is state, 1 is ppid
 	return strconv.Atoi(fields[1])
@@ -10,6 +10,7 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -44,7 +45,7 @@ func (m *UniversalProcessManager) KillProcess(pid int, signal string) error {
 
 	_, err := m.executor.Exec(ctx, "kill", sig, strconv.Itoa(pid))
 	if err != nil {
-		return fmt.Errorf("failed to kill process %d: %w", pid, err)
+		return nuxerrors.Wrapf(err, nuxerrors.ErrCodeProcess, "failed to kill process %d", pid)
 	}
 	return nil
 }
@@ -55,7 +56,7 @@ func (m *UniversalProcessManager) ReniceProcess(pid int, priority int) error {
 	// renice -n priority -p pid
 	_, err := m.executor.Exec(ctx, "renice", "-n", strconv.Itoa(priority), "-p", strconv.Itoa(pid))
 	if err != nil {
-		return fmt.Errorf("failed to renice process %d: %w", pid, err)
+		return nuxerrors.Wrapf(err, nuxerrors.ErrCodeProcess, "failed to renice process %d", pid)
 	}
 	return nil
 }
@@ -108,7 +109,7 @@ func (m *UniversalProcessManager) GetProcessTree() ([]ports.ProcessNode, error)
 	// "args" would be full command line
 	res, err := m.executor.Exec(ctx, "ps", "-eo", "pid,ppid,user,stat,comm")
 	if err != nil {
-		return nil, fmt.Errorf("failed to list processes: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeProcess, "failed to list processes")
 	}
 
 	lines := strings.Split(res.Stdout, "\n")
@@ -236,7 +237,7 @@ func (m *UniversalProcessManager) GetOpenFiles(pid int) ([]string, error) {
 	// ls -l /proc/<pid>/fd
 	res, err := m.executor.Exec(ctx, "ls", "-l", fmt.Sprintf("/proc/%d/fd", pid))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get open files for pid %d: %w", pid, err)
+		return nil, nuxerrors.Wrapf(err, nuxerrors.ErrCodeProcess, "failed to get open files for pid %d", pid)
 	}
 
 	lines := strings.Split(res.Stdout, "\n")
@@ -263,7 +264,7 @@ func (m *UniversalProcessManager) GetProcessPorts(pid int) ([]string, error) {
 	if err != nil {
 		// Fallback to netstat if ss fails?
 		// But instructions say "modern Linux". ss is modern.
-		return nil, fmt.Errorf("failed to get process ports: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeProcess, "failed to get process ports")
 	}
 
 	lines := strings.Split(res.Stdout, "\n")
@@ -296,7 +297,7 @@ func (m *UniversalProcessManager) GetAllListeningPorts() ([]string, error) {
 	// ss -nltu
 	res, err := m.executor.Exec(ctx, "ss", "-nltu")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get listening ports: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeProcess, "failed to get listening ports")
 	}
 
 	lines := strings.Split(res.Stdout, "\n")
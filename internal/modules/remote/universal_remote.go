@@ -7,7 +7,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -64,11 +63,7 @@ func (m *UniversalRemoteManager) ListProfiles() ([]string, error) {
 func (m *UniversalRemoteManager) Connect(profile string) error {
 	// Connect is interactive, so we cannot use m.executor.Exec which captures output.
 	// We need to attach Stdin/Stdout/Stderr directly.
-	cmd := exec.Command("ssh", profile)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return adapter.RunInteractive(context.Background(), m.executor, "ssh", profile)
 }
 
 func (m *UniversalRemoteManager) Execute(profile string, command string) (string, error) {
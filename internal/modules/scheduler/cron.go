@@ -1,33 +1,45 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 
+	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/journal"
 )
 
-type LinuxSchedulerManager struct{}
+var _ ports.SchedulerManager = (*LinuxSchedulerManager)(nil)
 
-func NewLinuxSchedulerManager() ports.SchedulerManager {
-	return &LinuxSchedulerManager{}
+type LinuxSchedulerManager struct {
+	executor adapter.Executor
+	journal  *journal.Journal
+}
+
+func NewLinuxSchedulerManager(executor adapter.Executor) *LinuxSchedulerManager {
+	return &LinuxSchedulerManager{executor: executor}
+}
+
+// WithJournal records added and removed cron jobs into j
+func (m *LinuxSchedulerManager) WithJournal(j *journal.Journal) *LinuxSchedulerManager {
+	m.journal = j
+	return m
 }
 
 func (m *LinuxSchedulerManager) ListCronJobs() ([]ports.CronJob, error) {
 	// List for current user
-	cmd := exec.Command("crontab", "-l")
-	output, err := cmd.CombinedOutput()
+	res, err := m.executor.Exec(context.Background(), "crontab", "-l")
 	if err != nil {
 		// crontab -l returns error if no crontab for user, which is fine
-		if strings.Contains(string(output), "no crontab for") {
+		if res != nil && strings.Contains(res.Stderr, "no crontab for") {
 			return []ports.CronJob{}, nil
 		}
 		return nil, err
 	}
 
 	var jobs []ports.CronJob
-	lines := strings.Split(string(output), "\n")
+	lines := strings.Split(res.Stdout, "\n")
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -80,9 +92,11 @@ func (m *LinuxSchedulerManager) AddCronJob(job ports.CronJob) error {
 
 	input := strings.Join(lines, "\n") + "\n"
 
-	cmd := exec.Command("crontab", "-")
-	cmd.Stdin = strings.NewReader(input)
-	return cmd.Run()
+	_, err := m.executor.ExecWithInput(context.Background(), input, "crontab", "-")
+	if err == nil {
+		m.journal.Record(journal.OpCronAdd, map[string]string{"schedule": job.Schedule, "command": job.Command})
+	}
+	return err
 }
 
 func (m *LinuxSchedulerManager) RemoveCronJob(job ports.CronJob) error {
@@ -108,9 +122,11 @@ func (m *LinuxSchedulerManager) RemoveCronJob(job ports.CronJob) error {
 
 	input := strings.Join(lines, "\n") + "\n"
 
-	cmd := exec.Command("crontab", "-")
-	cmd.Stdin = strings.NewReader(input)
-	return cmd.Run()
+	_, err = m.executor.ExecWithInput(context.Background(), input, "crontab", "-")
+	if err == nil {
+		m.journal.Record(journal.OpCronRemove, map[string]string{"schedule": job.Schedule, "command": job.Command})
+	}
+	return err
 }
 
 func (m *LinuxSchedulerManager) ListTimers(all bool) ([]ports.SystemdTimer, error) {
@@ -119,14 +135,13 @@ func (m *LinuxSchedulerManager) ListTimers(all bool) ([]ports.SystemdTimer, erro
 		args = append(args, "--all")
 	}
 
-	cmd := exec.Command("systemctl", args...)
-	output, err := cmd.CombinedOutput()
+	res, err := m.executor.Exec(context.Background(), "systemctl", args...)
 	if err != nil {
 		return nil, err
 	}
 
 	var timers []ports.SystemdTimer
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	lines := strings.Split(res.Stdout, "\n")
 	for _, line := range lines {
 		if line == "" {
 			continue
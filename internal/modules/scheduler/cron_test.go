@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+)
+
+// unreachableExecutor fails like an SSH executor whose host is down, with no
+// result at all
+type unreachableExecutor struct{}
+
+func (unreachableExecutor) Exec(ctx context.Context, command string, args ...string) (*adapter.CommandResult, error) {
+	return nil, errors.New("ssh: connect to host db01 port 22: Connection refused")
+}
+
+func (unreachableExecutor) ExecWithInput(ctx context.Context, input string, command string, args ...string) (*adapter.CommandResult, error) {
+	return nil, errors.New("ssh: connect to host db01 port 22: Connection refused")
+}
+
+func TestListCronJobsWithoutResult(t *testing.T) {
+	jobs, err := NewLinuxSchedulerManager(unreachableExecutor{}).ListCronJobs()
+	if err == nil {
+		t.Fatalf("expected the connection error, got jobs %v", jobs)
+	}
+}
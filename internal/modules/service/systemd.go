@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"strings"
 
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
@@ -19,7 +20,7 @@ func (m *SystemdManager) ListServices() ([]ports.ServiceUnit, error) {
 	cmd := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-pager", "--no-legend")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list services: %w", err)
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeServiceQuery, "failed to list services")
 	}
 
 	var services []ports.ServiceUnit
@@ -11,13 +11,16 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/journal"
 )
 
 // UniversalServiceManager implements ports.ServiceManager
 type UniversalServiceManager struct {
 	executor adapter.Executor
 	profile  *domain.SystemProfile
+	journal  *journal.Journal
 }
 
 // NewUniversalServiceManager creates a new service manager
@@ -28,6 +31,12 @@ func NewUniversalServiceManager(executor adapter.Executor, profile *domain.Syste
 	}
 }
 
+// WithJournal records enabled and disabled services into j
+func (m *UniversalServiceManager) WithJournal(j *journal.Journal) *UniversalServiceManager {
+	m.journal = j
+	return m
+}
+
 // ListServices returns a list of system services
 func (m *UniversalServiceManager) ListServices() ([]ports.ServiceUnit, error) {
 	ctx := context.Background()
@@ -41,7 +50,7 @@ func (m *UniversalServiceManager) ListServices() ([]ports.ServiceUnit, error) {
 	case "openrc":
 		return m.listOpenRCServices(ctx)
 	default:
-		return nil, fmt.Errorf("unsupported init system: %s", init)
+		return nil, nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported init system: %s", init))
 	}
 }
 
@@ -181,7 +190,8 @@ func (m *UniversalServiceManager) listOpenRCServices(ctx context.Context) ([]por
 		return nil, err
 	}
 
-	reService := regexp.MustCompile(`^\s*(\S+)\s+\[(\S+)\]`)
+	// rc-status pads the state: " sshd   [  started  ]"
+	reService := regexp.MustCompile(`^\s*(\S+)\s+\[\s*(\S+)\s*\]`)
 
 	var services []ports.ServiceUnit
 	lines := strings.Split(res.Stdout, "\n")
@@ -228,7 +238,7 @@ func (m *UniversalServiceManager) StartService(name string) error {
 		_, err := m.executor.Exec(ctx, "sv", "start", name)
 		return err
 	}
-	return fmt.Errorf("unsupported init system")
+	return nuxerrors.New(nuxerrors.ErrCodeUnsupported, "unsupported init system")
 }
 
 // StopService stops a service
@@ -248,7 +258,7 @@ func (m *UniversalServiceManager) StopService(name string) error {
 		_, err := m.executor.Exec(ctx, "sv", "stop", name)
 		return err
 	}
-	return fmt.Errorf("unsupported init system")
+	return nuxerrors.New(nuxerrors.ErrCodeUnsupported, "unsupported init system")
 }
 
 // RestartService restarts a service
@@ -268,41 +278,84 @@ func (m *UniversalServiceManager) RestartService(name string) error {
 		_, err := m.executor.Exec(ctx, "sv", "restart", name)
 		return err
 	}
-	return fmt.Errorf("unsupported init system")
+	return nuxerrors.New(nuxerrors.ErrCodeUnsupported, "unsupported init system")
 }
 
 // EnableService enables a service to start on boot
 func (m *UniversalServiceManager) EnableService(name string) error {
 	ctx := context.Background()
+	// Undoing the enable of a service that was already enabled would disable it
+	enabled, known := m.enabled(ctx, name)
+	var err error
 	switch m.profile.InitSystem {
 	case "systemd":
-		_, err := m.executor.Exec(ctx, "systemctl", "enable", name)
-		return err
+		_, err = m.executor.Exec(ctx, "systemctl", "enable", name)
 	case "sysvinit":
-		_, err := m.executor.Exec(ctx, "update-rc.d", name, "enable")
-		return err
+		_, err = m.executor.Exec(ctx, "update-rc.d", name, "enable")
 	case "openrc":
-		_, err := m.executor.Exec(ctx, "rc-update", "add", name, "default")
-		return err
+		_, err = m.executor.Exec(ctx, "rc-update", "add", name, "default")
+	default:
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported init system for enable: %s", m.profile.InitSystem))
+	}
+	if err == nil && known && !enabled {
+		m.journal.Record(journal.OpServiceEnable, map[string]string{"service": name})
 	}
-	return fmt.Errorf("unsupported init system for enable: %s", m.profile.InitSystem)
+	return err
 }
 
 // DisableService disables a service from starting on boot
 func (m *UniversalServiceManager) DisableService(name string) error {
 	ctx := context.Background()
+	enabled, known := m.enabled(ctx, name)
+	var err error
 	switch m.profile.InitSystem {
 	case "systemd":
-		_, err := m.executor.Exec(ctx, "systemctl", "disable", name)
-		return err
+		_, err = m.executor.Exec(ctx, "systemctl", "disable", name)
 	case "sysvinit":
-		_, err := m.executor.Exec(ctx, "update-rc.d", name, "disable")
-		return err
+		_, err = m.executor.Exec(ctx, "update-rc.d", name, "disable")
 	case "openrc":
-		_, err := m.executor.Exec(ctx, "rc-update", "del", name, "default")
-		return err
+		_, err = m.executor.Exec(ctx, "rc-update", "del", name, "default")
+	default:
+		return nuxerrors.New(nuxerrors.ErrCodeUnsupported, fmt.Sprintf("unsupported init system for disable: %s", m.profile.InitSystem))
+	}
+	if err == nil && known && enabled {
+		m.journal.Record(journal.OpServiceDisable, map[string]string{"service": name})
+	}
+	return err
+}
+
+// enabled reports whether a service starts on boot. known is false when the
+// init system could not tell.
+func (m *UniversalServiceManager) enabled(ctx context.Context, name string) (enabled bool, known bool) {
+	switch m.profile.InitSystem {
+	case "systemd":
+		return adapter.Probe(ctx, m.executor, "systemctl", "is-enabled", "--quiet", name)
+	case "sysvinit":
+		// update-rc.d enables a service by adding S links in the runlevels
+		res, err := m.executor.Exec(adapter.WithReadOnly(ctx), "ls", "/etc/rc2.d")
+		if err != nil {
+			return false, false
+		}
+		for _, link := range strings.Fields(res.Stdout) {
+			if len(link) == len(name)+3 && link[0] == 'S' && strings.HasSuffix(link, name) {
+				return true, true
+			}
+		}
+		return false, true
+	case "openrc":
+		// "  nginx | default"
+		res, err := m.executor.Exec(adapter.WithReadOnly(ctx), "rc-update", "show", "default")
+		if err != nil {
+			return false, false
+		}
+		for _, line := range strings.Split(res.Stdout, "\n") {
+			if svc, _, ok := strings.Cut(line, "|"); ok && strings.TrimSpace(svc) == name {
+				return true, true
+			}
+		}
+		return false, true
 	}
-	return fmt.Errorf("unsupported init system for disable: %s", m.profile.InitSystem)
+	return false, false
 }
 
 // GetServiceStatus returns the status output of a service
@@ -319,7 +372,7 @@ func (m *UniversalServiceManager) GetServiceStatus(name string) (string, error)
 		res, err := m.executor.Exec(ctx, "rc-service", name, "status")
 		return res.Stdout, err
 	}
-	return "", fmt.Errorf("unsupported init system")
+	return "", nuxerrors.New(nuxerrors.ErrCodeUnsupported, "unsupported init system")
 }
 
 // GetServiceLogs returns the logs for a service (journald or log file)
@@ -329,5 +382,5 @@ func (m *UniversalServiceManager) GetServiceLogs(name string, lines int) (string
 		res, err := m.executor.Exec(ctx, "journalctl", "-u", name, "-n", fmt.Sprintf("%d", lines), "--no-pager")
 		return res.Stdout, err
 	}
-	return "", fmt.Errorf("log retrieval only supported for systemd")
+	return "", nuxerrors.New(nuxerrors.ErrCodeUnsupported, "log retrieval only supported for systemd")
 }
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
+	"github.com/rsdenck/nux/internal/core/domain"
+	"github.com/rsdenck/nux/internal/journal"
+)
+
+// serviceCommands are the commands of the init systems in the transcripts; a
+// call the manager skipped leaves one of their interactions unused
+var serviceCommands = []string{"systemctl", "rc-update", "rc-service", "rc-status", "service", "update-rc.d"}
+
+func TestServiceManagerAcrossDistros(t *testing.T) {
+	fixtures, _ := filepath.Glob(filepath.Join("..", "testdata", "*.json"))
+	if len(fixtures) == 0 {
+		t.Fatal("no distro fixtures found")
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			fixture, err := adapter.LoadFixture(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+			replay := adapter.NewReplayExecutor(fixture)
+			m := NewUniversalServiceManager(replay, fixture.Profile).WithJournal(j)
+
+			if err := m.EnableService("nginx"); err != nil {
+				t.Errorf("EnableService failed: %v", err)
+			}
+			if err := m.DisableService("nginx"); err != nil {
+				t.Errorf("DisableService failed: %v", err)
+			}
+			if err := m.StartService("nginx"); err != nil {
+				t.Errorf("StartService failed: %v", err)
+			}
+
+			entries, err := j.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 || entries[0].Operation != journal.OpServiceEnable || entries[1].Operation != journal.OpServiceDisable {
+				t.Errorf("unexpected journal entries: %+v", entries)
+			}
+
+			services, err := m.ListServices()
+			if err != nil {
+				t.Fatalf("ListServices failed: %v", err)
+			}
+			found := false
+			for _, s := range services {
+				if strings.TrimSuffix(s.Name, ".service") == "sshd" || strings.TrimSuffix(s.Name, ".service") == "ssh" {
+					found = true
+					if s.ActiveState != "active" {
+						t.Errorf("expected %s to be active, got %q", s.Name, s.ActiveState)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("ssh service not parsed from %d services: %+v", len(services), services)
+			}
+
+			if unused := replay.Unused(serviceCommands...); len(unused) != 0 {
+				t.Errorf("recorded calls were never made: %v", unused)
+			}
+		})
+	}
+}
+
+// Undoing the enable of a service that was already enabled would disable it
+func TestEnableEnabledServiceNotJournaled(t *testing.T) {
+	fixture := &adapter.Fixture{Interactions: []adapter.Interaction{
+		{Command: "systemctl", Args: []string{"is-enabled", "--quiet", "sshd"}},
+		{Command: "systemctl", Args: []string{"enable", "sshd"}},
+	}}
+	j := journal.NewAt(filepath.Join(t.TempDir(), "journal.jsonl"), "")
+	m := NewUniversalServiceManager(adapter.NewReplayExecutor(fixture), &domain.SystemProfile{InitSystem: "systemd"}).WithJournal(j)
+
+	if err := m.EnableService("sshd"); err != nil {
+		t.Fatalf("EnableService failed: %v", err)
+	}
+	if entries, _ := j.Entries(); len(entries) != 0 {
+		t.Errorf("nothing changed, yet the enable was journaled: %+v", entries)
+	}
+}
@@ -3,6 +3,7 @@ package ssh
 // Package ssh provides SSH configuration parsing and connection management.
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,16 +12,18 @@ import (
 	"strings"
 
 	"github.com/kevinburke/ssh_config"
+	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/ports"
 )
 
 // NativeSSHClient implements ports.SSHClient by parsing ~/.ssh/config directly
 type NativeSSHClient struct {
+	executor   adapter.Executor
 	configPath string
 }
 
 // NewNativeSSHClient creates a new client that reads the user's SSH config
-func NewNativeSSHClient() (ports.SSHClient, error) {
+func NewNativeSSHClient(executor adapter.Executor) (ports.SSHClient, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user home directory: %w", err)
@@ -28,6 +31,7 @@ func NewNativeSSHClient() (ports.SSHClient, error) {
 
 	configPath := filepath.Join(home, ".ssh", "config")
 	return &NativeSSHClient{
+		executor:   executor,
 		configPath: configPath,
 	}, nil
 }
@@ -76,12 +80,7 @@ func (c *NativeSSHClient) Connect(profile string) error {
 		return fmt.Errorf("ssh binary not found in PATH")
 	}
 
-	cmd := exec.Command(sshPath, profile)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	return cmd.Run()
+	return adapter.RunInteractive(context.Background(), c.executor, sshPath, profile)
 }
 
 // Execute runs a command on the remote host and returns the output
@@ -91,13 +90,12 @@ func (c *NativeSSHClient) Execute(profile string, command string) (string, error
 		return "", fmt.Errorf("ssh binary not found in PATH")
 	}
 
-	cmd := exec.Command(sshPath, profile, command)
-	output, err := cmd.CombinedOutput()
+	res, err := c.executor.Exec(context.Background(), sshPath, profile, command)
 	if err != nil {
-		return "", fmt.Errorf("ssh execution failed: %w, output: %s", err, string(output))
+		return "", fmt.Errorf("ssh execution failed: %w, output: %s", err, res.Combined())
 	}
 
-	return string(output), nil
+	return res.Combined(), nil
 }
 
 // CreateTunnel establishes an SSH tunnel
@@ -119,14 +117,11 @@ func (c *NativeSSHClient) CreateTunnel(profile string, localPort, remoteHost, re
 
 	// -N: Do not execute a remote command.
 	// -f: Requests ssh to go to background just before command execution.
-	cmd := exec.Command(sshPath, "-N", flag, tunnelArg, profile)
-	
 	// Since we want to manage the process, we don't use -f which forks.
 	// We start it and keep the process handle.
 	// Note: This simple implementation doesn't track tunnels persistently across app restarts yet,
 	// but it replaces the rsd-sshm wrapper logic.
-	
-	if err := cmd.Start(); err != nil {
+	if _, err := adapter.RunDetached(c.executor, "", sshPath, "-N", flag, tunnelArg, profile); err != nil {
 		return fmt.Errorf("failed to start tunnel: %w", err)
 	}
 
@@ -148,10 +143,9 @@ func (c *NativeSSHClient) CopyFile(profile string, src string, dest string) erro
 		return fmt.Errorf("scp binary not found in PATH")
 	}
 
-	cmd := exec.Command(scpPath, src, fmt.Sprintf("%s:%s", profile, dest))
-	output, err := cmd.CombinedOutput()
+	res, err := c.executor.Exec(context.Background(), scpPath, src, fmt.Sprintf("%s:%s", profile, dest))
 	if err != nil {
-		return fmt.Errorf("scp failed: %w, output: %s", err, string(output))
+		return fmt.Errorf("scp failed: %w, output: %s", err, res.Combined())
 	}
 	return nil
 }
@@ -10,13 +10,16 @@ import (
 
 	"github.com/rsdenck/nux/internal/core/adapter"
 	"github.com/rsdenck/nux/internal/core/domain"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 	"github.com/rsdenck/nux/internal/core/ports"
+	"github.com/rsdenck/nux/internal/journal"
 )
 
 // UniversalUserManager implements UserManager using system tools
 type UniversalUserManager struct {
 	executor adapter.Executor
 	profile  *domain.SystemProfile
+	journal  *journal.Journal
 }
 
 // NewUniversalUserManager creates a new instance
@@ -27,6 +30,12 @@ func NewUniversalUserManager(executor adapter.Executor, profile *domain.SystemPr
 	}
 }
 
+// WithJournal records added and deleted users into j
+func (m *UniversalUserManager) WithJournal(j *journal.Journal) *UniversalUserManager {
+	m.journal = j
+	return m
+}
+
 func (m *UniversalUserManager) ListUsers() ([]ports.User, error) {
 	ctx := context.Background()
 	// cat /etc/passwd
@@ -101,6 +110,8 @@ func (m *UniversalUserManager) AddUser(username string, options ports.UserOption
 	}
 	if options.HomeDir != "" {
 		args = append(args, "-d", options.HomeDir)
+	}
+	if options.HomeDir != "" || options.CreateHome {
 		args = append(args, "-m") // Create home
 	}
 	if options.Comment != "" {
@@ -111,8 +122,15 @@ func (m *UniversalUserManager) AddUser(username string, options ports.UserOption
 
 	_, err := m.executor.Exec(ctx, "useradd", args...)
 	if err != nil {
-		return fmt.Errorf("failed to add user: %w", err)
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeUser, "failed to add user")
+	}
+
+	// Undo removes the home directory only if useradd created it
+	removeHome := "false"
+	if options.HomeDir != "" || options.CreateHome {
+		removeHome = "true"
 	}
+	m.journal.Record(journal.OpUserAdd, map[string]string{"username": username, "remove_home": removeHome})
 	return nil
 }
 
@@ -126,8 +144,9 @@ func (m *UniversalUserManager) DeleteUser(username string, removeHome bool) erro
 
 	_, err := m.executor.Exec(ctx, "userdel", args...)
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeUser, "failed to delete user")
 	}
+	m.journal.Record(journal.OpUserDelete, map[string]string{"username": username, "remove_home": fmt.Sprintf("%t", removeHome)})
 	return nil
 }
 
@@ -159,7 +178,7 @@ func (m *UniversalUserManager) ModifyUser(username string, options ports.UserOpt
 
 	_, err := m.executor.Exec(ctx, "usermod", args...)
 	if err != nil {
-		return fmt.Errorf("failed to modify user: %w", err)
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeUser, "failed to modify user")
 	}
 	return nil
 }
@@ -175,7 +194,7 @@ func (m *UniversalUserManager) AddGroup(groupname string, gid string) error {
 
 	_, err := m.executor.Exec(ctx, "groupadd", args...)
 	if err != nil {
-		return fmt.Errorf("failed to add group: %w", err)
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeUser, "failed to add group")
 	}
 	return nil
 }
@@ -185,7 +204,7 @@ func (m *UniversalUserManager) DeleteGroup(groupname string) error {
 
 	_, err := m.executor.Exec(ctx, "groupdel", groupname)
 	if err != nil {
-		return fmt.Errorf("failed to delete group: %w", err)
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeUser, "failed to delete group")
 	}
 	return nil
 }
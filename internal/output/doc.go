@@ -7,6 +7,8 @@
 //   - NewInfo: Create info output
 //   - NewList: Create list output with pagination
 //   - PrintCompactTable: Print formatted tables
+//   - SetReport: Render outputs as HTML, Markdown or CSV reports
+//   - SetOut: Write outputs to a file instead of the terminal
 //
 // Example usage:
 //
@@ -1,15 +1,51 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"gopkg.in/yaml.v3"
 )
 
 var jsonOutput bool
+var yamlOutput bool
+
+// exitStatus is the exit code of the last error printed, see ExitStatus
+var exitStatus int
+
+// streaming prints every JSON document on one line, see SetStreaming
+var streaming bool
+
+// stdout receives rendered outputs, see SetOut
+var stdout io.Writer = os.Stdout
 
 func SetFormat(json bool, yaml bool) {
 	jsonOutput = json
+	yamlOutput = yaml && !json
+}
+
+// SetStreaming makes JSON output one compact document per line, so progress
+// events and the final result can be read as NDJSON
+func SetStreaming(on bool) {
+	streaming = on
+}
+
+// SetOut sends rendered outputs to w instead of the terminal, as requested
+// with --out. Errors are still printed on the terminal.
+func SetOut(w io.Writer) {
+	stdout = w
+}
+
+// PrintEvent writes a progress event as a single JSON line
+func PrintEvent(event interface{}) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintln(stdout, string(data))
 }
 
 type Output struct {
@@ -20,6 +56,14 @@ type Output struct {
 	Message string      `json:"message,omitempty"`
 	Total   int         `json:"total,omitempty"`
 	Items   interface{} `json:"items,omitempty"`
+
+	// Error details, see NewError
+	Hint      string `json:"hint,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+	ExitCode  int    `json:"exit_code,omitempty"`
+
+	// columns declares the leading columns of Items
+	columns []string
 }
 
 func NewSuccess(data interface{}) *Output {
@@ -36,14 +80,39 @@ func NewInfo(data interface{}) *Output {
 	}
 }
 
-func NewError(err string, code string) *Output {
+// NewError creates an error output. Its exit code and whether it is retryable
+// follow from code.
+func NewError(err string, code nuxerrors.ErrorCode) *Output {
 	return &Output{
-		Status: "error",
-		Error:  err,
-		Code:   code,
+		Status:    "error",
+		Error:     err,
+		Code:      string(code),
+		Retryable: nuxerrors.IsRetryableCode(code),
+		ExitCode:  nuxerrors.ExitCode(code),
 	}
 }
 
+// WithCause takes the code, hint and retryability of the error that made the
+// operation fail, when it carries them: a command that timed out reports
+// TIMEOUT rather than the code of the operation
+func (o *Output) WithCause(err error) *Output {
+	if code := nuxerrors.CodeOf(err); code != "" {
+		o.Code = string(code)
+		o.ExitCode = nuxerrors.ExitCode(code)
+	}
+	if hint := nuxerrors.HintOf(err); hint != "" {
+		o.Hint = hint
+	}
+	o.Retryable = o.Retryable || nuxerrors.IsRetryable(err)
+	return o
+}
+
+// WithHint tells the user how to fix the error
+func (o *Output) WithHint(hint string) *Output {
+	o.Hint = hint
+	return o
+}
+
 func NewList(items interface{}, total int) *Output {
 	return &Output{
 		Status: "success",
@@ -57,20 +126,89 @@ func (o *Output) WithMessage(msg string) *Output {
 	return o
 }
 
+// WithColumns declares the order of the leading columns of a list; any other
+// key follows in alphabetical order
+func (o *Output) WithColumns(columns ...string) *Output {
+	o.columns = columns
+	return o
+}
+
+// ExitStatus returns the exit code the process should end with: the exit code
+// of the last error printed, or 0
+func ExitStatus() int {
+	return exitStatus
+}
+
 func (o *Output) Print() {
+	if capture != nil {
+		capture(o)
+		return
+	}
+	if o.Status == "error" {
+		exitStatus = o.ExitCode
+		if exitStatus == 0 {
+			exitStatus = nuxerrors.ExitGeneral
+		}
+		if stdout != os.Stdout {
+			saved := stdout
+			stdout = os.Stdout
+			defer func() { stdout = saved }()
+		}
+	}
+
+	if o.Items != nil {
+		if err := o.applyView(); err != nil {
+			NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).Print()
+			return
+		}
+	}
+	o.render()
+}
+
+// render prints o, once the view has been applied
+func (o *Output) render() {
+	if o.Status != "error" && (query != nil || outputTemplate != nil) {
+		render := printQuery
+		if outputTemplate != nil {
+			render = printTemplate
+		}
+		if err := render(o); err != nil {
+			NewError(err.Error(), nuxerrors.ErrCodeInvalidInput).Print()
+		}
+		return
+	}
+
+	if o.Status != "error" && reportFormat != "" {
+		if err := printReport(o); err != nil {
+			NewError(fmt.Sprintf("failed to write report: %s", err), nuxerrors.ErrCodeGeneral).Print()
+		}
+		return
+	}
+
+	if jsonOutput && streaming {
+		PrintEvent(o)
+		return
+	}
 	if jsonOutput {
 		data, _ := json.MarshalIndent(o, "", "  ")
-		fmt.Println(string(data))
+		fmt.Fprintln(stdout, string(data))
+		return
+	}
+	if yamlOutput {
+		printYAML(o)
 		return
 	}
 
 	if o.Status == "error" {
-		fmt.Printf("✖ %s\n", o.Error)
+		fmt.Fprintf(stdout, "✖ %s\n", o.Error)
+		if o.Hint != "" {
+			fmt.Fprintf(stdout, "  hint: %s\n", o.Hint)
+		}
 		return
 	}
 
 	if o.Status == "info" {
-		fmt.Printf("⚠ %s\n", o.Message)
+		fmt.Fprintf(stdout, "⚠ %s\n", o.Message)
 		if o.Data != nil {
 			printDataAsFormattedTable(o.Data)
 		}
@@ -85,67 +223,78 @@ func (o *Output) Print() {
 	}
 }
 
-// printItemsAsFormattedTable prints items in the exact format from output.md
-func printItemsAsFormattedTable(items interface{}, total int, message string) {
-	// Convert to []map[string]interface{}
-	if slice, ok := items.([]map[string]interface{}); ok {
-		if len(slice) == 0 {
-			fmt.Println("No items found")
-			return
-		}
-
-		// Get headers from first item
-		headers := make([]string, 0)
-		for k := range slice[0] {
-			headers = append(headers, strings.ToUpper(k))
-		}
-
-		// Calculate column widths (minimum: header length + 2)
-		widths := make([]int, len(headers))
-		for i, h := range headers {
-			widths[i] = len(h) + 2
-		}
-
-		// Adjust widths based on data
-		for _, item := range slice {
-			keys := getKeys(item)
-			for j, k := range keys {
-				val := fmt.Sprintf("%v", item[k])
-				if len(val)+2 > widths[j] {
-					widths[j] = len(val) + 2
-				}
-			}
-		}
-
-		// Print header with message
-		if message != "" {
-			fmt.Printf("\n%s\n", message)
-		}
-
-		// Print top border
-		printTopBorder(widths)
-
-		// Print header row
-		printRow(headers, widths)
+// applyView turns list items into a Table with the declared column order and
+// applies --filter, --sort-by and --columns to it
+func (o *Output) applyView() error {
+	t, ok := TableFromItems(o.Items, o.columns...)
+	if !ok {
+		return nil
+	}
+	before := len(t.Rows)
+	if err := t.Apply(view); err != nil {
+		return err
+	}
+	if len(t.Rows) != before {
+		o.Total = len(t.Rows)
+	}
+	o.Items = t
+	return nil
+}
 
-		// Print separator
-		printSeparator(widths)
+// printYAML renders v as YAML with the same keys, in the same order, as the
+// JSON output
+func printYAML(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(stdout, "✖ %s\n", err)
+		return
+	}
+	// JSON is valid YAML, so decoding it into a node keeps the key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		fmt.Fprintf(stdout, "✖ %s\n", err)
+		return
+	}
+	blockStyle(&node)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		fmt.Fprintf(stdout, "✖ %s\n", err)
+		return
+	}
+	fmt.Fprint(stdout, buf.String())
+}
 
-		// Print rows
-		for _, item := range slice {
-			keys := getKeys(item)
-			values := make([]string, len(keys))
-			for j, k := range keys {
-				values[j] = fmt.Sprintf("%v", item[k])
-			}
-			printRow(values, widths)
-		}
+// blockStyle drops the flow and quoting style inherited from JSON
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
 
-		// Print bottom border
-		printBottomBorder(widths)
+// printItemsAsFormattedTable prints items in the exact format from output.md
+func printItemsAsFormattedTable(items interface{}, total int, message string) {
+	t, ok := items.(*Table)
+	if !ok {
+		return
+	}
+	if view.NoHeaders {
+		t.Render(false)
+		return
+	}
+	if len(t.Rows) == 0 {
+		fmt.Fprintln(stdout, "No items found")
+		return
+	}
 
-		fmt.Printf("\n%d items found\n", total)
+	// Print header with message
+	if message != "" {
+		fmt.Fprintf(stdout, "\n%s\n", message)
 	}
+	t.Render(true)
+	fmt.Fprintf(stdout, "\n%d items found\n", total)
 }
 
 // printDataAsFormattedTable prints a map as a simple key-value table
@@ -174,65 +323,77 @@ func printDataAsFormattedTable(data interface{}) {
 		printRow(headers, widths)
 		printSeparator(widths)
 
-		for k, v := range m {
-			printRow([]string{k, fmt.Sprintf("%v", v)}, widths)
+		for _, k := range getKeys(m) {
+			value := fmt.Sprintf("%v", m[k])
+			printMarkedRow([]string{k, value}, widths, []bool{false, changed(k, "value", value)})
 		}
 
 		printBottomBorder(widths)
 	} else {
 		// Fallback to JSON
 		d, _ := json.MarshalIndent(data, "", "  ")
-		fmt.Println(string(d))
+		fmt.Fprintln(stdout, string(d))
 	}
 }
 
 func printTopBorder(widths []int) {
-	fmt.Print("┌")
+	fmt.Fprint(stdout, "┌")
 	for i, w := range widths {
-		fmt.Print(strings.Repeat("─", w))
+		fmt.Fprint(stdout, strings.Repeat("─", w))
 		if i < len(widths)-1 {
-			fmt.Print("┬")
+			fmt.Fprint(stdout, "┬")
 		}
 	}
-	fmt.Println("┐")
+	fmt.Fprintln(stdout, "┐")
 }
 
 func printSeparator(widths []int) {
-	fmt.Print("├")
+	fmt.Fprint(stdout, "├")
 	for i, w := range widths {
-		fmt.Print(strings.Repeat("─", w))
+		fmt.Fprint(stdout, strings.Repeat("─", w))
 		if i < len(widths)-1 {
-			fmt.Print("┼")
+			fmt.Fprint(stdout, "┼")
 		}
 	}
-	fmt.Println("┤")
+	fmt.Fprintln(stdout, "┤")
 }
 
 func printBottomBorder(widths []int) {
-	fmt.Print("└")
+	fmt.Fprint(stdout, "└")
 	for i, w := range widths {
-		fmt.Print(strings.Repeat("─", w))
+		fmt.Fprint(stdout, strings.Repeat("─", w))
 		if i < len(widths)-1 {
-			fmt.Print("┴")
+			fmt.Fprint(stdout, "┴")
 		}
 	}
-	fmt.Println("┘")
+	fmt.Fprintln(stdout, "┘")
 }
 
 func printRow(values []string, widths []int) {
-	fmt.Print("│")
+	printMarkedRow(values, widths, nil)
+}
+
+// printMarkedRow prints a row, highlighting the values marked as changed
+func printMarkedRow(values []string, widths []int, marked []bool) {
+	fmt.Fprint(stdout, "│")
 	for i, v := range values {
-		fmt.Printf(" %-*s ", widths[i]-2, v)
-		fmt.Print("│")
+		v = fmt.Sprintf("%-*s", widths[i]-2, v)
+		if i < len(marked) && marked[i] {
+			v = highlightText(v)
+		}
+		fmt.Fprintf(stdout, " %s ", v)
+		fmt.Fprint(stdout, "│")
 	}
-	fmt.Println()
+	fmt.Fprintln(stdout)
 }
 
+// getKeys returns the keys of m sorted, so headers and rows line up
 func getKeys(m map[string]interface{}) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
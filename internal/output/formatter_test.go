@@ -3,6 +3,8 @@ package output
 import (
 	"testing"
 	"encoding/json"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
 )
 
 func TestNewSuccess(t *testing.T) {
@@ -124,3 +126,21 @@ func TestOutputStatus(t *testing.T) {
 		t.Errorf("Expected Status to be 'info', got %s", info.Status)
 	}
 }
+
+func TestErrorWithCause(t *testing.T) {
+	cause := nuxerrors.New(nuxerrors.ErrCodeSSHConnect, "connection refused").WithHint("check that 'ssh web01' connects")
+	out := NewError("failed to list services", nuxerrors.ErrCodeServiceQuery).WithCause(cause)
+
+	if out.Code != string(nuxerrors.ErrCodeSSHConnect) || out.ExitCode != nuxerrors.ExitNetwork {
+		t.Errorf("Expected the cause's code and exit code, got %s/%d", out.Code, out.ExitCode)
+	}
+	if !out.Retryable || out.Hint == "" {
+		t.Errorf("Expected a retryable error with a hint, got %+v", out)
+	}
+
+	defer func() { exitStatus = 0 }()
+	NewError("bad flag", nuxerrors.ErrCodeInvalidInput).Print()
+	if ExitStatus() != nuxerrors.ExitUsage {
+		t.Errorf("Expected exit status %d, got %d", nuxerrors.ExitUsage, ExitStatus())
+	}
+}
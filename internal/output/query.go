@@ -0,0 +1,394 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// query and outputTemplate replace the usual rendering of successful outputs,
+// see SetQuery and SetTemplate
+var (
+	query          *Query
+	outputTemplate *template.Template
+)
+
+// SetQuery selects the part of every successful output to print, as given
+// with --query. An empty expression restores the full output.
+func SetQuery(expr string) error {
+	if expr == "" {
+		query = nil
+		return nil
+	}
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return err
+	}
+	query = q
+	return nil
+}
+
+// SetTemplate renders every successful output with a Go text/template, as
+// given with --template. An empty text restores the usual rendering.
+func SetTemplate(text string) error {
+	if text == "" {
+		outputTemplate = nil
+		return nil
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(sep string, v []interface{}) string {
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = fmt.Sprintf("%v", e)
+		}
+		return strings.Join(parts, sep)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Query is a JSONPath-style expression over the JSON form of an Output, such
+// as ".items[*].name", "$.data.hostname" or ".items[?state==running].pid"
+type Query struct {
+	expr  string
+	steps []queryStep
+}
+
+type queryStep struct {
+	key   string // field name
+	index *int   // [n], negative counts from the end
+	all   bool   // [*]
+	// [?field==value] and [?field!=value]
+	field  string
+	value  string
+	negate bool
+}
+
+func (s queryStep) projects() bool {
+	return s.all || s.field != ""
+}
+
+// ParseQuery parses a query expression
+func ParseQuery(expr string) (*Query, error) {
+	q := &Query{expr: expr}
+	rest := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			if rest == "" || rest[0] == '[' {
+				continue
+			}
+			fallthrough
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, q.invalid("empty field name")
+			}
+			q.steps = append(q.steps, queryStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, q.invalid("missing ]")
+			}
+			step, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, q.invalid(err.Error())
+			}
+			q.steps = append(q.steps, step)
+			rest = rest[end+1:]
+		}
+	}
+	return q, nil
+}
+
+func parseBracket(inner string) (queryStep, error) {
+	switch {
+	case inner == "*":
+		return queryStep{all: true}, nil
+	case strings.HasPrefix(inner, "?"):
+		cond := inner[1:]
+		op := "=="
+		if strings.Contains(cond, "!=") {
+			op = "!="
+		}
+		field, value, ok := strings.Cut(cond, op)
+		if !ok || field == "" {
+			return queryStep{}, fmt.Errorf("expected [?field==value], got [%s]", inner)
+		}
+		return queryStep{field: strings.TrimSpace(field), value: strings.Trim(strings.TrimSpace(value), `"'`), negate: op == "!="}, nil
+	case strings.HasPrefix(inner, `"`) || strings.HasPrefix(inner, "'"):
+		return queryStep{key: strings.Trim(inner, `"'`)}, nil
+	}
+	n, err := strconv.Atoi(inner)
+	if err != nil {
+		return queryStep{}, fmt.Errorf("invalid index [%s]", inner)
+	}
+	return queryStep{index: &n}, nil
+}
+
+func (q *Query) invalid(reason string) error {
+	return fmt.Errorf("invalid query %q: %s", q.expr, reason)
+}
+
+// Eval applies the query to a value decoded from JSON. Missing fields yield
+// nil; after [*] or a filter they are skipped.
+func (q *Query) Eval(v interface{}) (interface{}, error) {
+	return evalSteps(v, q.steps)
+}
+
+func evalSteps(v interface{}, steps []queryStep) (interface{}, error) {
+	for i, s := range steps {
+		if v == nil {
+			return nil, nil
+		}
+		if !s.projects() {
+			next, err := evalStep(v, s)
+			if err != nil {
+				return nil, err
+			}
+			v = next
+			continue
+		}
+
+		list, ok := v.([]interface{})
+		if !ok {
+			if m, isMap := v.(map[string]interface{}); isMap && s.all {
+				// [*] on an object walks its values
+				for _, k := range getKeys(m) {
+					list = append(list, m[k])
+				}
+			} else {
+				return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+			}
+		}
+		out := []interface{}{}
+		for _, e := range list {
+			if s.field != "" && !matches(e, s) {
+				continue
+			}
+			r, err := evalSteps(e, steps[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			if r != nil {
+				out = append(out, r)
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func evalStep(v interface{}, s queryStep) (interface{}, error) {
+	if s.index != nil {
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s", typeName(v))
+		}
+		n := *s.index
+		if n < 0 {
+			n += len(list)
+		}
+		if n < 0 || n >= len(list) {
+			return nil, nil
+		}
+		return list[n], nil
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot get field %q of %s", s.key, typeName(v))
+	}
+	return lookup(m, s.key), nil
+}
+
+// lookup returns the field key of m, falling back to a case-insensitive match
+// so ".Items[0].Name" works like ".items[0].name"
+func lookup(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for _, k := range getKeys(m) {
+		if strings.EqualFold(k, key) {
+			return m[k]
+		}
+	}
+	return nil
+}
+
+func matches(v interface{}, s queryStep) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	field := lookup(m, s.field)
+	equal := field != nil && fmt.Sprintf("%v", field) == s.value
+	return equal != s.negate
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// genericValue returns the JSON form of v as maps, lists and scalars, keeping
+// numbers as written
+func genericValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// printQuery prints the part of o selected by the query: strings and numbers
+// as plain lines, like jq -r, and objects as JSON or YAML
+func printQuery(o *Output) error {
+	doc, err := genericValue(o)
+	if err != nil {
+		return err
+	}
+	result, err := query.Eval(doc)
+	if err != nil {
+		return fmt.Errorf("query %q: %w", query.expr, err)
+	}
+
+	if list, ok := result.([]interface{}); ok && allScalars(list) {
+		for _, e := range list {
+			fmt.Fprintln(stdout, scalarText(e))
+		}
+		return nil
+	}
+	if isScalar(result) {
+		fmt.Fprintln(stdout, scalarText(result))
+		return nil
+	}
+	if yamlOutput {
+		printYAML(result)
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, string(data))
+	return nil
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+func allScalars(list []interface{}) bool {
+	for _, e := range list {
+		if !isScalar(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func scalarText(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// printTemplate renders o with the template. Fields can be referred to by
+// their JSON name or capitalized, as in {{range .Items}}{{.Name}}{{end}}.
+func printTemplate(o *Output) error {
+	doc, err := genericValue(o)
+	if err != nil {
+		return err
+	}
+	if err := outputTemplate.Execute(stdout, templateData(doc)); err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	return nil
+}
+
+// templateData adds a capitalized alias for every object key, "host_name"
+// becoming "HostName", without replacing keys that already exist
+func templateData(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v)*2)
+		for k, e := range v {
+			out[k] = templateData(e)
+		}
+		for k := range v {
+			if alias := exportedName(k); alias != "" {
+				if _, exists := out[alias]; !exists {
+					out[alias] = out[k]
+				}
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = templateData(e)
+		}
+		return out
+	}
+	return v
+}
+
+func exportedName(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		if r == '_' || r == '-' {
+			upper = true
+			continue
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return ""
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func queryDoc(t *testing.T) interface{} {
+	out := NewList(processItems(), 3).WithMessage("Service list")
+	doc, err := genericValue(out)
+	if err != nil {
+		t.Fatalf("genericValue failed: %v", err)
+	}
+	return doc
+}
+
+func TestQueryEval(t *testing.T) {
+	doc := queryDoc(t)
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{".message", "Service list"},
+		{"$.items[0].name", "nginx"},
+		{".Items[-1].Name", "cron"},
+		{".items[*].name", []interface{}{"nginx", "sshd", "cron"}},
+		{".items[?status==running].name", []interface{}{"nginx", "sshd"}},
+		{".items[?status!=running].pid", []interface{}{"0"}},
+		{".items[5].name", nil},
+		{".missing", nil},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		got, err := q.Eval(doc)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		// Numbers are kept as written
+		if list, ok := got.([]interface{}); ok {
+			for i, e := range list {
+				list[i] = scalarText(e)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Eval(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, expr := range []string{".items[", ".items[x]", ".items[?status]", "..name"} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("Expected ParseQuery(%q) to fail", expr)
+		}
+	}
+
+	q, _ := ParseQuery(".message.text")
+	if _, err := q.Eval(queryDoc(t)); err == nil {
+		t.Error("Expected an error getting a field of a string")
+	}
+}
+
+func TestTemplateData(t *testing.T) {
+	if err := SetTemplate(`{{range .Items}}{{.Name}}:{{.pid}} {{end}}{{.Total}}`); err != nil {
+		t.Fatalf("SetTemplate failed: %v", err)
+	}
+	defer SetTemplate("")
+
+	doc := queryDoc(t)
+	var buf bytes.Buffer
+	if err := outputTemplate.Execute(&buf, templateData(doc)); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "nginx:120 sshd:9 cron:0 3"; buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+
+	if got := exportedName("ps_info"); got != "PsInfo" {
+		t.Errorf("exportedName(ps_info) = %q", got)
+	}
+	if err := SetTemplate("{{.Items"); err == nil {
+		t.Error("Expected an invalid template to be rejected")
+	}
+}
@@ -0,0 +1,286 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/rsdenck/nux/internal/core/domain"
+)
+
+// Report formats accepted by --format, besides table, json and yaml
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "md"
+	FormatCSV      = "csv"
+)
+
+// ReportInfo describes where and when a report was generated. HTML and
+// Markdown reports show it above the results.
+type ReportInfo struct {
+	Command   string
+	Host      string
+	Profile   *domain.SystemProfile
+	Generated time.Time
+}
+
+// reportFormat renders every successful output as a report, see SetReport
+var (
+	reportFormat string
+	reportInfo   ReportInfo
+)
+
+// SetReport renders every successful output as an HTML, Markdown or CSV
+// report, as requested with --format. An empty format restores the usual
+// rendering.
+func SetReport(format string, info ReportInfo) error {
+	switch format {
+	case "", FormatHTML, FormatMarkdown, FormatCSV:
+	default:
+		return fmt.Errorf("unknown report format %q (available: html, md, csv)", format)
+	}
+	reportFormat = format
+	reportInfo = info
+	return nil
+}
+
+// printReport renders o in the report format
+func printReport(o *Output) error {
+	t := reportTable(o)
+	switch reportFormat {
+	case FormatCSV:
+		return printCSV(t)
+	case FormatMarkdown:
+		printMarkdown(o, t)
+		return nil
+	}
+	return printHTML(o, t)
+}
+
+// reportTable returns the rows of o: its list items, or its data as key-value
+// rows
+func reportTable(o *Output) *Table {
+	if o.Items != nil {
+		if t, ok := TableFromItems(o.Items, o.columns...); ok {
+			return t
+		}
+	}
+	if m, ok := o.Data.(map[string]interface{}); ok {
+		t := NewTable("key", "value")
+		for _, k := range getKeys(m) {
+			t.AddRow(map[string]interface{}{"key": k, "value": m[k]})
+		}
+		return t
+	}
+	if t, ok := TableFromItems(o.Data); ok {
+		return t
+	}
+	t := NewTable("value")
+	if o.Data != nil {
+		t.AddRow(map[string]interface{}{"value": o.Data})
+	}
+	return t
+}
+
+// reportCell returns the text of a cell, with nested objects and lists as JSON
+func reportCell(row map[string]interface{}, key string) string {
+	switch v := row[key].(type) {
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return cell(row, key)
+}
+
+func printCSV(t *Table) error {
+	w := csv.NewWriter(stdout)
+	if !view.NoHeaders {
+		if err := w.Write(t.Columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			record[i] = reportCell(row, c)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printMarkdown(o *Output, t *Table) {
+	fmt.Fprintf(stdout, "# %s\n\n", reportTitle())
+	if reportInfo.Host != "" {
+		fmt.Fprintf(stdout, "- **Host:** %s\n", reportInfo.Host)
+	}
+	if p := reportInfo.Profile; p != nil {
+		fmt.Fprintf(stdout, "- **System:** %s\n", profileSummary(p))
+	}
+	fmt.Fprintf(stdout, "- **Generated:** %s\n\n", reportInfo.Generated.Format(time.RFC3339))
+	if o.Message != "" {
+		fmt.Fprintf(stdout, "%s\n\n", markdownEscape(o.Message))
+	}
+
+	if len(t.Rows) == 0 {
+		fmt.Fprintln(stdout, "No items found")
+		return
+	}
+	header := make([]string, len(t.Columns))
+	rule := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = markdownEscape(c)
+		rule[i] = "---"
+	}
+	fmt.Fprintf(stdout, "| %s |\n| %s |\n", strings.Join(header, " | "), strings.Join(rule, " | "))
+	for _, row := range t.Rows {
+		cells := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			cells[i] = markdownEscape(reportCell(row, c))
+		}
+		fmt.Fprintf(stdout, "| %s |\n", strings.Join(cells, " | "))
+	}
+	if o.Items != nil {
+		fmt.Fprintf(stdout, "\n%d items found\n", o.Total)
+	}
+}
+
+// markdownEscape keeps a value inside its table cell
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func reportTitle() string {
+	if reportInfo.Command == "" {
+		return "nux report"
+	}
+	return reportInfo.Command
+}
+
+// profileSummary describes a system profile in one line, such as
+// "Ubuntu 22.04, systemd, apt, ufw, VM"
+func profileSummary(p *domain.SystemProfile) string {
+	var parts []string
+	for _, s := range []string{strings.TrimSpace(p.Distro + " " + p.Version), p.InitSystem, p.PackageManager, p.Firewall, p.Environment} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportStatusClasses color the status and state cells of HTML reports
+var reportStatusClasses = map[string]string{
+	"ok": "good", "success": "good", "running": "good", "active": "good", "enabled": "good",
+	"warning": "warn", "inactive": "warn", "stopped": "warn", "planned": "warn",
+	"critical": "bad", "failed": "bad", "error": "bad", "dead": "bad",
+}
+
+type htmlCell struct {
+	Text  string
+	Class string
+}
+
+type htmlReport struct {
+	Title     string
+	Host      string
+	Profile   *domain.SystemProfile
+	Generated string
+	Message   string
+	Columns   []string
+	Rows      [][]htmlCell
+	Total     int
+	IsList    bool
+}
+
+func printHTML(o *Output, t *Table) error {
+	r := htmlReport{
+		Title:     reportTitle(),
+		Host:      reportInfo.Host,
+		Profile:   reportInfo.Profile,
+		Generated: reportInfo.Generated.Format(time.RFC3339),
+		Message:   o.Message,
+		Columns:   t.Columns,
+		Total:     o.Total,
+		IsList:    o.Items != nil,
+	}
+	for _, row := range t.Rows {
+		cells := make([]htmlCell, len(t.Columns))
+		for i, c := range t.Columns {
+			text := reportCell(row, c)
+			cells[i] = htmlCell{Text: text}
+			if c == "status" || c == "state" {
+				cells[i].Class = reportStatusClasses[strings.ToLower(text)]
+			}
+		}
+		r.Rows = append(r.Rows, cells)
+	}
+	return htmlTemplate.Execute(stdout, r)
+}
+
+// htmlTemplate is a self-contained page: styles are inline so the report can
+// be attached to a ticket as a single file
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{"upper": strings.ToUpper}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; }
+h1 { font-size: 1.4rem; font-family: ui-monospace, Menlo, Consolas, monospace; }
+table { border-collapse: collapse; margin: 1rem 0; font-size: 0.9rem; }
+th, td { border: 1px solid #d0d7de; padding: 0.35rem 0.7rem; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td { font-family: ui-monospace, Menlo, Consolas, monospace; white-space: pre-wrap; }
+table.meta th { width: 10rem; }
+.good { color: #1a7f37; font-weight: 600; }
+.warn { color: #9a6700; font-weight: 600; }
+.bad { color: #cf222e; font-weight: 600; }
+footer { color: #656d76; font-size: 0.8rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table class="meta">
+{{- if .Host}}
+<tr><th>Host</th><td>{{.Host}}</td></tr>
+{{- end}}
+{{- with .Profile}}
+<tr><th>Distribution</th><td>{{.Distro}} {{.Version}}</td></tr>
+<tr><th>Init system</th><td>{{.InitSystem}}</td></tr>
+<tr><th>Package manager</th><td>{{.PackageManager}}</td></tr>
+<tr><th>Firewall</th><td>{{.Firewall}}</td></tr>
+<tr><th>Network stack</th><td>{{.NetworkStack}}</td></tr>
+<tr><th>Environment</th><td>{{.Environment}}</td></tr>
+{{- end}}
+<tr><th>Generated</th><td>{{.Generated}}</td></tr>
+</table>
+{{- if .Message}}
+<p>{{.Message}}</p>
+{{- end}}
+{{- if .Rows}}
+<table>
+<tr>{{range .Columns}}<th>{{upper .}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td{{if .Class}} class="{{.Class}}"{{end}}>{{.Text}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No items found</p>
+{{- end}}
+{{- if .IsList}}
+<p>{{.Total}} items found</p>
+{{- end}}
+<footer>Generated by nux</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsdenck/nux/internal/core/domain"
+)
+
+func renderReport(t *testing.T, format string, o *Output) string {
+	info := ReportInfo{
+		Command:   "nux service list",
+		Host:      "web01",
+		Profile:   &domain.SystemProfile{Distro: "Ubuntu", Version: "22.04", InitSystem: "systemd"},
+		Generated: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := SetReport(format, info); err != nil {
+		t.Fatalf("SetReport failed: %v", err)
+	}
+	defer SetReport("", ReportInfo{})
+
+	var buf bytes.Buffer
+	SetOut(&buf)
+	defer SetOut(os.Stdout)
+	o.Print()
+	return buf.String()
+}
+
+func TestCSVReport(t *testing.T) {
+	items := processItems()
+	items[0]["name"] = "nginx, main"
+	got := renderReport(t, FormatCSV, NewList(items, 3).WithColumns("name", "status"))
+	want := "name,status,pid\n\"nginx, main\",running,120\nsshd,running,9\ncron,stopped,0\n"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	got = renderReport(t, FormatCSV, NewSuccess(map[string]interface{}{"kernel": "6.1", "load": []interface{}{1, 2}}))
+	if want := "key,value\nkernel,6.1\nload,\"[1,2]\"\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	items := []map[string]interface{}{{"name": "a|b", "status": "running"}}
+	got := renderReport(t, FormatMarkdown, NewList(items, 1).WithColumns("name").WithMessage("Service list"))
+	for _, want := range []string{
+		"# nux service list\n",
+		"- **Host:** web01\n",
+		"- **System:** Ubuntu 22.04, systemd\n",
+		"| name | status |\n| --- | --- |\n| a\\|b | running |\n",
+		"1 items found",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	items := []map[string]interface{}{{"name": "<script>", "status": "failed"}}
+	got := renderReport(t, FormatHTML, NewList(items, 1).WithColumns("name"))
+	for _, want := range []string{
+		"<title>nux service list</title>",
+		"<td>Ubuntu 22.04</td>",
+		"<td>&lt;script&gt;</td>",
+		`<td class="bad">failed</td>`,
+		"<td>2026-01-02T03:04:05Z</td>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the report", want)
+		}
+	}
+}
+
+func TestReportErrorsGoToTerminal(t *testing.T) {
+	got := renderReport(t, FormatHTML, NewError("boom", "COMMAND_FAILED"))
+	exitStatus = 0
+	if got != "" {
+		t.Errorf("Expected errors to stay out of the report, got %q", got)
+	}
+	if err := SetReport("pdf", ReportInfo{}); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,308 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Table is a list of rows with a declared column order. Every renderer
+// (text, JSON, YAML) walks the columns in that order, so output is stable
+// between runs.
+type Table struct {
+	Columns []string
+	Rows    []map[string]interface{}
+}
+
+// View selects, filters and sorts the rows of list outputs, as requested with
+// --columns, --filter, --sort-by and --no-headers
+type View struct {
+	Columns   []string
+	SortBy    string
+	Filters   []string
+	NoHeaders bool
+}
+
+var view View
+
+// SetView applies v to every list printed afterwards
+func SetView(v View) {
+	view = v
+}
+
+// NewTable creates an empty table with the given columns
+func NewTable(columns ...string) *Table {
+	return &Table{Columns: columns}
+}
+
+// AddRow appends a row; keys missing from the columns are added at the end
+func (t *Table) AddRow(row map[string]interface{}) {
+	t.Rows = append(t.Rows, row)
+	for _, k := range getKeys(row) {
+		if !t.hasColumn(k) {
+			t.Columns = append(t.Columns, k)
+		}
+	}
+}
+
+// TableFromItems builds a table from list items: maps, or structs whose JSON
+// field order becomes the column order. leading columns come first, the
+// remaining keys follow sorted. It reports false for items that are not rows.
+func TableFromItems(items interface{}, leading ...string) (*Table, bool) {
+	if t, ok := items.(*Table); ok {
+		return t, true
+	}
+
+	rows, ok := items.([]map[string]interface{})
+	if !ok {
+		v := reflect.ValueOf(items)
+		if !v.IsValid() || v.Kind() != reflect.Slice {
+			return nil, false
+		}
+		data, err := json.Marshal(items)
+		if err != nil {
+			return nil, false
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		// Keep numbers as written instead of turning them into floats
+		dec.UseNumber()
+		if err := dec.Decode(&rows); err != nil {
+			return nil, false
+		}
+		leading = append(leading, structColumns(v.Type().Elem())...)
+	}
+
+	t := NewTable()
+	for _, c := range leading {
+		if !t.hasColumn(c) {
+			t.Columns = append(t.Columns, c)
+		}
+	}
+	for _, row := range rows {
+		t.AddRow(row)
+	}
+	return t, true
+}
+
+// structColumns returns the JSON names of the fields of a struct type in
+// declaration order
+func structColumns(typ reflect.Type) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	var columns []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+func (t *Table) hasColumn(key string) bool {
+	for _, c := range t.Columns {
+		if c == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply filters, sorts and selects the columns of the table as v requests
+func (t *Table) Apply(v View) error {
+	for _, f := range v.Filters {
+		if err := t.Filter(f); err != nil {
+			return err
+		}
+	}
+	if v.SortBy != "" {
+		if err := t.SortBy(v.SortBy); err != nil {
+			return err
+		}
+	}
+	if len(v.Columns) > 0 {
+		return t.Select(v.Columns)
+	}
+	return nil
+}
+
+// Select keeps only the given columns, in the given order
+func (t *Table) Select(columns []string) error {
+	for _, c := range columns {
+		if !t.hasColumn(c) {
+			return t.unknownColumn(c)
+		}
+	}
+	t.Columns = columns
+	return nil
+}
+
+// Filter keeps the rows where key equals value, given as "key=value"
+func (t *Table) Filter(expr string) error {
+	key, value, ok := strings.Cut(expr, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid filter %q, expected key=value", expr)
+	}
+	if !t.hasColumn(key) {
+		return t.unknownColumn(key)
+	}
+
+	rows := t.Rows[:0]
+	for _, row := range t.Rows {
+		if strings.EqualFold(cell(row, key), value) {
+			rows = append(rows, row)
+		}
+	}
+	t.Rows = rows
+	return nil
+}
+
+// SortBy orders the rows by key, numerically when both values are numbers.
+// A leading "-" sorts in descending order.
+func (t *Table) SortBy(key string) error {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+	if !t.hasColumn(key) {
+		return t.unknownColumn(key)
+	}
+
+	sort.SliceStable(t.Rows, func(i, j int) bool {
+		a, b := cell(t.Rows[i], key), cell(t.Rows[j], key)
+		if desc {
+			a, b = b, a
+		}
+		return lessValue(a, b)
+	})
+	return nil
+}
+
+func (t *Table) unknownColumn(key string) error {
+	return fmt.Errorf("unknown column %q (available: %s)", key, strings.Join(t.Columns, ", "))
+}
+
+func lessValue(a, b string) bool {
+	fa, errA := strconv.ParseFloat(strings.TrimSuffix(a, "%"), 64)
+	fb, errB := strconv.ParseFloat(strings.TrimSuffix(b, "%"), 64)
+	if errA == nil && errB == nil {
+		return fa < fb
+	}
+	return a < b
+}
+
+func cell(row map[string]interface{}, key string) string {
+	v, ok := row[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Render prints the table as text, boxed with headers, or as plain aligned
+// columns when headers is false so scripts can split the lines
+func (t *Table) Render(headers bool) {
+	widths := make([]int, len(t.Columns))
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = strings.ToUpper(c)
+		if headers {
+			widths[i] = utf8.RuneCountInString(names[i])
+		}
+	}
+	cells := make([][]string, len(t.Rows))
+	marked := make([][]bool, len(t.Rows))
+	for r, row := range t.Rows {
+		cells[r] = make([]string, len(t.Columns))
+		marked[r] = make([]bool, len(t.Columns))
+		for i, c := range t.Columns {
+			cells[r][i] = cell(row, c)
+			marked[r][i] = changed(t.rowKey(row), c, cells[r][i])
+			if n := utf8.RuneCountInString(cells[r][i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	if !headers {
+		for r, row := range cells {
+			for i, v := range row {
+				if i < len(row)-1 {
+					v = fmt.Sprintf("%-*s", widths[i], v)
+				}
+				if marked[r][i] {
+					v = highlightText(v)
+				}
+				if i == len(row)-1 {
+					fmt.Fprintln(stdout, v)
+				} else {
+					fmt.Fprint(stdout, v+"  ")
+				}
+			}
+		}
+		return
+	}
+
+	for i := range widths {
+		widths[i] += 2
+	}
+	printTopBorder(widths)
+	printRow(names, widths)
+	printSeparator(widths)
+	for r, row := range cells {
+		printMarkedRow(row, widths, marked[r])
+	}
+	printBottomBorder(widths)
+}
+
+// rowKey identifies a row across runs of a command by its first column, such
+// as the pid of a process or the name of a service
+func (t *Table) rowKey(row map[string]interface{}) string {
+	if len(t.Columns) == 0 {
+		return ""
+	}
+	return cell(row, t.Columns[0])
+}
+
+// MarshalJSON renders the rows as objects whose keys follow the column order
+func (t *Table) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for r, row := range t.Rows {
+		if r > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for i, c := range t.Columns {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(c)
+			value, err := json.Marshal(row[c])
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func processItems() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"name": "nginx", "status": "running", "pid": 120},
+		{"name": "sshd", "status": "running", "pid": 9},
+		{"name": "cron", "status": "stopped", "pid": 0},
+	}
+}
+
+func TestTableFromItemsColumnOrder(t *testing.T) {
+	table, ok := TableFromItems(processItems(), "name")
+	if !ok {
+		t.Fatal("Expected a table from map items")
+	}
+	if want := []string{"name", "pid", "status"}; !reflect.DeepEqual(table.Columns, want) {
+		t.Errorf("Expected columns %v, got %v", want, table.Columns)
+	}
+
+	type entry struct {
+		Status string `json:"status"`
+		Name   string `json:"name"`
+		Hidden string `json:"-"`
+	}
+	table, ok = TableFromItems([]entry{{Status: "up", Name: "a"}})
+	if !ok {
+		t.Fatal("Expected a table from struct items")
+	}
+	if want := []string{"status", "name"}; !reflect.DeepEqual(table.Columns, want) {
+		t.Errorf("Struct columns should follow field order, got %v", table.Columns)
+	}
+
+	if _, ok := TableFromItems("not a list"); ok {
+		t.Error("Non-slice items should not become a table")
+	}
+}
+
+func TestTableApply(t *testing.T) {
+	table, _ := TableFromItems(processItems(), "name")
+	err := table.Apply(View{
+		Columns: []string{"pid", "name"},
+		SortBy:  "-pid",
+		Filters: []string{"status=Running"},
+	})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `[{"pid":120,"name":"nginx"},{"pid":9,"name":"sshd"}]`
+	if string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+func TestTableApplyUnknownColumn(t *testing.T) {
+	for _, v := range []View{
+		{Columns: []string{"name", "cpu"}},
+		{SortBy: "cpu"},
+		{Filters: []string{"cpu=1"}},
+		{Filters: []string{"status"}},
+	} {
+		table, _ := TableFromItems(processItems())
+		if err := table.Apply(v); err == nil {
+			t.Errorf("Expected an error for %+v", v)
+		}
+	}
+}
+
+func TestPrintAppliesView(t *testing.T) {
+	SetView(View{Filters: []string{"name=cron"}})
+	defer SetView(View{})
+
+	out := NewList(processItems(), 3).WithColumns("name")
+	out.Print()
+
+	table, ok := out.Items.(*Table)
+	if !ok || len(table.Rows) != 1 || out.Total != 1 {
+		t.Errorf("Expected one filtered row, got %+v (total %d)", out.Items, out.Total)
+	}
+}
@@ -0,0 +1,125 @@
+package output
+
+import (
+	"fmt"
+	"time"
+)
+
+// capture receives the outputs of a command instead of printing them, see
+// Capture
+var capture func(*Output)
+
+// previous holds the cells of the last output a Watch rendered, by row key and
+// column, so the values that changed since can be highlighted
+var previous map[string]map[string]string
+
+// Capture hands every output printed until restore is called to fn instead of
+// printing it
+func Capture(fn func(*Output)) (restore func()) {
+	saved := capture
+	capture = fn
+	return func() {
+		capture = saved
+	}
+}
+
+// Watch renders the output of a command run again at every tick of --watch:
+// redrawn in place with the values that changed highlighted, or as one NDJSON
+// record per tick with --json
+type Watch struct {
+	// Command is the command line shown above the output
+	Command  string
+	Interval time.Duration
+	// Redraw clears the screen before every tick, for terminals
+	Redraw bool
+	// Highlight marks the values that changed since the previous tick
+	Highlight bool
+
+	tick int
+	prev map[string]map[string]string
+}
+
+// watchRecord is one tick of --watch in JSON or YAML: the output of the
+// command with the time it was taken
+type watchRecord struct {
+	Type string    `json:"type"`
+	Tick int       `json:"tick"`
+	Time time.Time `json:"time"`
+	*Output
+}
+
+// Render prints the output of one run of the command taken at at. It fails
+// when the view requested with --columns, --filter or --sort-by does not fit
+// the output.
+func (w *Watch) Render(o *Output, at time.Time) error {
+	w.tick++
+	if o.Items != nil {
+		if err := o.applyView(); err != nil {
+			return err
+		}
+	}
+
+	rendered := query != nil || outputTemplate != nil
+	switch {
+	case jsonOutput && !rendered:
+		PrintEvent(watchRecord{Type: "tick", Tick: w.tick, Time: at, Output: o})
+		return nil
+	case yamlOutput && !rendered:
+		// One document per tick
+		fmt.Fprintln(stdout, "---")
+		printYAML(watchRecord{Type: "tick", Tick: w.tick, Time: at, Output: o})
+		return nil
+	case jsonOutput || yamlOutput:
+		o.render()
+		return nil
+	}
+
+	if w.Redraw {
+		fmt.Fprint(stdout, "\033[H\033[2J")
+	}
+	fmt.Fprintf(stdout, "Every %s: %s    %s\n", w.Interval, w.Command, at.Format(time.DateTime))
+	if w.Highlight && w.tick > 1 {
+		previous = w.prev
+	}
+	o.render()
+	previous = nil
+	w.prev = snapshotCells(o)
+	return nil
+}
+
+// snapshotCells returns the cells of a list or of a key-value output, by row
+// key and column
+func snapshotCells(o *Output) map[string]map[string]string {
+	cells := make(map[string]map[string]string)
+	if t, ok := o.Items.(*Table); ok {
+		for _, row := range t.Rows {
+			values := make(map[string]string, len(t.Columns))
+			for _, c := range t.Columns {
+				values[c] = cell(row, c)
+			}
+			cells[t.rowKey(row)] = values
+		}
+	} else if m, ok := o.Data.(map[string]interface{}); ok {
+		for k, v := range m {
+			cells[k] = map[string]string{"value": fmt.Sprintf("%v", v)}
+		}
+	}
+	return cells
+}
+
+// changed reports whether the value in column of the row identified by key
+// differs from the previous tick of --watch. New rows count as changed.
+func changed(key, column, value string) bool {
+	if previous == nil {
+		return false
+	}
+	row, ok := previous[key]
+	if !ok {
+		return true
+	}
+	return row[column] != value
+}
+
+func highlightText(s string) string {
+	return "\033[7m" + s + "\033[0m"
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapture(t *testing.T) {
+	exitStatus = 0
+	var got []*Output
+	restore := Capture(func(o *Output) {
+		got = append(got, o)
+	})
+	NewError("boom", "COMMAND_FAILED").Print()
+	restore()
+
+	if len(got) != 1 || got[0].Error != "boom" {
+		t.Fatalf("Expected the error to be captured, got %v", got)
+	}
+	if ExitStatus() != 0 {
+		t.Error("Captured errors should not set the exit status")
+	}
+	if capture != nil {
+		t.Error("Expected restore to remove the capture")
+	}
+}
+
+func TestWatchChangedCells(t *testing.T) {
+	w := &Watch{Command: "nux process list", Interval: time.Second, Highlight: true}
+	if err := w.Render(NewList(processItems(), 3).WithColumns("name"), time.Now()); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	previous = w.prev
+	defer func() { previous = nil }()
+	if changed("nginx", "pid", "120") {
+		t.Error("Unchanged cell reported as changed")
+	}
+	if !changed("nginx", "pid", "121") {
+		t.Error("Changed cell not reported")
+	}
+	if !changed("apache", "pid", "1") {
+		t.Error("New rows should be reported as changed")
+	}
+}
+
+func TestWatchRejectsInvalidView(t *testing.T) {
+	SetView(View{Columns: []string{"cpu"}})
+	defer SetView(View{})
+
+	w := &Watch{Interval: time.Second}
+	if err := w.Render(NewList(processItems(), 3), time.Now()); err == nil {
+		t.Error("Expected an unknown column to fail")
+	}
+}
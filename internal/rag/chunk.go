@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize bounds a passage, in bytes: a few paragraphs, well within
+// the context of embedding models
+const DefaultChunkSize = 1500
+
+// Split cuts a Markdown document into passages under its headings. Sections
+// longer than size are cut between paragraphs, or between lines for a
+// paragraph longer than size. Lines starting with # inside code fences, such
+// as shell comments, are not taken for headings.
+func Split(source, text string, size int) []Chunk {
+	var chunks []Chunk
+	heading := ""
+	var section []string
+	inFence := false
+
+	flush := func() {
+		body := strings.TrimSpace(strings.Join(section, "\n"))
+		section = nil
+		if body == "" {
+			return
+		}
+		for _, piece := range pack(body, size) {
+			chunks = append(chunks, Chunk{Source: source, Heading: heading, Text: piece})
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(trimmed, "#") {
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+		section = append(section, line)
+	}
+	flush()
+	return chunks
+}
+
+// pack joins the paragraphs of body into pieces of at most size bytes
+func pack(body string, size int) []string {
+	if len(body) <= size {
+		return []string{body}
+	}
+	var pieces []string
+	var cur strings.Builder
+	add := func(part, sep string) {
+		if cur.Len() > 0 && cur.Len()+len(sep)+len(part) > size {
+			pieces = append(pieces, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(sep)
+		}
+		cur.WriteString(part)
+	}
+	for _, para := range strings.Split(body, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if len(para) <= size {
+			add(para, "\n\n")
+			continue
+		}
+		for _, line := range strings.Split(para, "\n") {
+			for len(line) > size {
+				n := size
+				for n > 0 && !utf8.RuneStart(line[n]) {
+					n--
+				}
+				add(line[:n], "\n")
+				line = line[n:]
+			}
+			add(line, "\n")
+		}
+	}
+	if cur.Len() > 0 {
+		pieces = append(pieces, cur.String())
+	}
+	return pieces
+}
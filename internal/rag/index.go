@@ -0,0 +1,324 @@
+package rag
+
+// Package rag keeps a local index of the nux documentation and skill
+// catalog, embedded through Ollama, and retrieves the passages closest to a
+// question so answers can cite them without any cloud provider.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+const indexFile = ".nux/rag/index.json"
+
+// batchSize is how many chunks are embedded per request
+const batchSize = 32
+
+// docDirs are the directories indexed when none is given
+var docDirs = []string{"commands", "skills", "docs"}
+
+// sharePath is where packages install the docs when the binary is not next
+// to a share/nux directory, as with /usr/local/bin/nux
+const sharePath = "/usr/share/nux"
+
+// DefaultDirs returns the directories indexed when none is given: commands/,
+// skills/ and docs/ of the share/nux directory next to the binary
+// (/usr/bin/nux reads /usr/share/nux), else of /usr/share/nux. When neither
+// holds them, as when running from a checkout, they are read from the working
+// directory.
+func DefaultDirs() []string {
+	var shares []string
+	if exe, err := os.Executable(); err == nil {
+		if exe, err := filepath.EvalSymlinks(exe); err == nil {
+			shares = append(shares, filepath.Join(filepath.Dir(exe), "..", "share", "nux"))
+		}
+	}
+	return sharedDirs(append(shares, sharePath))
+}
+
+// sharedDirs returns the doc directories of the first of shares that holds
+// any, else the doc directories of the working directory
+func sharedDirs(shares []string) []string {
+	for _, share := range shares {
+		var dirs []string
+		for _, d := range docDirs {
+			if info, err := os.Stat(filepath.Join(share, d)); err == nil && info.IsDir() {
+				dirs = append(dirs, filepath.Join(share, d))
+			}
+		}
+		if len(dirs) > 0 {
+			return dirs
+		}
+	}
+	return docDirs
+}
+
+// Chunk is a passage of a document with its embedding
+type Chunk struct {
+	Source  string    `json:"source"`
+	Heading string    `json:"heading,omitempty"`
+	Text    string    `json:"text"`
+	Vector  []float32 `json:"vector"`
+}
+
+// Index is the embedded passages of a set of documents
+type Index struct {
+	Model   string    `json:"model"`
+	Updated time.Time `json:"updated"`
+	// Files maps every indexed document to the hash of its content, so
+	// unchanged documents are not embedded again
+	Files  map[string]string `json:"files"`
+	Chunks []Chunk           `json:"chunks"`
+}
+
+// Hit is a passage retrieved for a question
+type Hit struct {
+	Chunk
+	Score float64
+}
+
+// Path returns the location of the index
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to get home directory")
+	}
+	return filepath.Join(home, indexFile), nil
+}
+
+// Load reads the index at path
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, "no documentation index").
+			WithHint("build it with 'nux ask index'")
+	}
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to read documentation index")
+	}
+	var ix Index
+	if err := json.Unmarshal(data, &ix); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "corrupt documentation index").
+			WithHint("rebuild it with 'nux ask index --rebuild'")
+	}
+	return &ix, nil
+}
+
+// Save writes the index to path
+func (ix *Index) Save(path string) error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeInternal, "failed to encode documentation index")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to create index directory")
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to save documentation index")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to save documentation index")
+	}
+	return nil
+}
+
+// Collect reads the Markdown files under dirs, by path. Directories that do
+// not exist are skipped; none existing is an error.
+func Collect(dirs []string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	found := false
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		found = true
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(path)] = data
+			return nil
+		})
+		if err != nil {
+			return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeNotFound, "failed to read "+dir)
+		}
+	}
+	if !found {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, "none of "+strings.Join(dirs, ", ")+" exists").
+			WithHint("install the docs under " + sharePath + ", run it from the nux checkout, or name the directories with --dir")
+	}
+	return files, nil
+}
+
+// Progress is told how many chunks were embedded so far, of total
+type Progress func(done, total int)
+
+// Update brings the index in line with files: documents that are new or
+// changed are chunked and embedded, and those gone are dropped. A different
+// model starts the index over, as its vectors cannot be compared. It
+// returns the number of documents embedded and removed.
+func (ix *Index) Update(ctx context.Context, e ports.Embedder, model string, files map[string][]byte, progress Progress) (embedded, removed int, err error) {
+	if ix.Model != model || ix.Files == nil {
+		*ix = Index{Model: model, Files: map[string]string{}}
+	}
+
+	var changed []string
+	for path, data := range files {
+		if ix.Files[path] != hash(data) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	stale := map[string]bool{}
+	for path := range ix.Files {
+		if _, ok := files[path]; !ok {
+			stale[path] = true
+			removed++
+		}
+	}
+	for _, path := range changed {
+		stale[path] = true
+	}
+
+	var fresh []Chunk
+	for _, path := range changed {
+		fresh = append(fresh, Split(path, string(files[path]), DefaultChunkSize)...)
+	}
+	for start := 0; start < len(fresh); start += batchSize {
+		end := start + batchSize
+		if end > len(fresh) {
+			end = len(fresh)
+		}
+		texts := make([]string, 0, end-start)
+		for _, c := range fresh[start:end] {
+			texts = append(texts, c.embedText())
+		}
+		vectors, err := e.Embed(ctx, model, texts)
+		if err != nil {
+			return 0, 0, err
+		}
+		for i, v := range vectors {
+			fresh[start+i].Vector = normalize(v)
+		}
+		if progress != nil {
+			progress(end, len(fresh))
+		}
+	}
+
+	kept := ix.Chunks[:0]
+	for _, c := range ix.Chunks {
+		if !stale[c.Source] {
+			kept = append(kept, c)
+		}
+	}
+	ix.Chunks = append(kept, fresh...)
+	for path := range stale {
+		delete(ix.Files, path)
+	}
+	for _, path := range changed {
+		ix.Files[path] = hash(files[path])
+	}
+	ix.Updated = time.Now().UTC()
+	return len(changed), removed, nil
+}
+
+// Search returns the k passages closest to question
+func (ix *Index) Search(ctx context.Context, e ports.Embedder, question string, k int) ([]Hit, error) {
+	if len(ix.Chunks) == 0 {
+		return nil, nil
+	}
+	vectors, err := e.Embed(ctx, ix.Model, []string{question})
+	if err != nil {
+		return nil, err
+	}
+	q := normalize(vectors[0])
+	hits := make([]Hit, 0, len(ix.Chunks))
+	for _, c := range ix.Chunks {
+		if len(c.Vector) != len(q) {
+			continue
+		}
+		hits = append(hits, Hit{Chunk: c, Score: dot(q, c.Vector)})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// Cite returns how a passage is referred to: its file and section
+func (h Hit) Cite() string {
+	if h.Heading == "" {
+		return h.Source
+	}
+	return h.Source + " § " + h.Heading
+}
+
+// Preamble renders the passages as a system message asking the model to
+// answer from them and cite them by number
+func Preamble(hits []Hit) string {
+	var b strings.Builder
+	b.WriteString("Passages of the nux documentation and skill catalog. Answer from them when they are relevant, citing them as [n]; say so when they do not answer the question.\n")
+	for i, h := range hits {
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n", i+1, h.Cite(), strings.TrimSpace(h.Text))
+	}
+	return b.String()
+}
+
+// embedText is what is embedded for a chunk: its text under its source and
+// heading, which often carry the words a question uses
+func (c Chunk) embedText() string {
+	return c.Source + "\n" + c.Heading + "\n" + c.Text
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize scales v to unit length, so the dot product of two vectors is
+// their cosine similarity
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// words embeds texts as bags of words, so texts sharing words are close
+type words struct {
+	calls int
+}
+
+func (w *words) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	w.calls++
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, 64)
+		for _, word := range strings.Fields(strings.ToLower(t)) {
+			h := fnv.New32a()
+			h.Write([]byte(strings.Trim(word, ".,:#`")))
+			v[h.Sum32()%64]++
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestSplit(t *testing.T) {
+	doc := "# Disk\nintro\n\n## Usage\nnux disk top /var\n```bash\n# not a heading\nnux disk usage\n```\n## Empty\n"
+	chunks := Split("commands/disk.md", doc, DefaultChunkSize)
+	if len(chunks) != 2 || chunks[0].Heading != "Disk" || chunks[1].Heading != "Usage" {
+		t.Fatalf("Unexpected chunks %+v", chunks)
+	}
+	if !strings.Contains(chunks[1].Text, "# not a heading") {
+		t.Errorf("A comment in a code fence should stay in its section, got %q", chunks[1].Text)
+	}
+
+	long := strings.Repeat("para one words\n\n", 40) + strings.Repeat("é", 200)
+	for _, c := range Split("x.md", long, 100) {
+		if len(c.Text) > 100 || !strings.HasPrefix(c.Text, "para") && !strings.HasPrefix(c.Text, "é") {
+			t.Errorf("Unexpected passage %q", c.Text)
+		}
+	}
+}
+
+func TestUpdateAndSearch(t *testing.T) {
+	files := map[string][]byte{
+		"commands/disk.md":    []byte("# Disk\nShow the largest files with nux disk top."),
+		"commands/service.md": []byte("# Service\nRestart a systemd service with nux service restart."),
+	}
+	e := &words{}
+	ix := &Index{}
+	embedded, removed, err := ix.Update(context.Background(), e, "m", files, nil)
+	if err != nil || embedded != 2 || removed != 0 || len(ix.Chunks) != 2 {
+		t.Fatalf("Unexpected update %d %d %v, %+v", embedded, removed, err, ix)
+	}
+
+	hits, err := ix.Search(context.Background(), e, "how do I restart a service?", 1)
+	if err != nil || len(hits) != 1 || hits[0].Source != "commands/service.md" {
+		t.Fatalf("Expected the service passage, got %+v, %v", hits, err)
+	}
+	if hits[0].Cite() != "commands/service.md § Service" {
+		t.Errorf("Unexpected citation %q", hits[0].Cite())
+	}
+
+	// Unchanged files are not embedded again, and removed ones are dropped
+	delete(files, "commands/disk.md")
+	files["skills/git.md"] = []byte("# Git\nVersion control.")
+	calls := e.calls
+	embedded, removed, err = ix.Update(context.Background(), e, "m", files, nil)
+	if err != nil || embedded != 1 || removed != 1 || e.calls != calls+1 {
+		t.Errorf("Unexpected update %d %d %v after %d calls", embedded, removed, err, e.calls-calls)
+	}
+	for _, c := range ix.Chunks {
+		if c.Source == "commands/disk.md" {
+			t.Error("A removed file should leave the index")
+		}
+	}
+
+	// Another model starts over
+	if embedded, _, _ = ix.Update(context.Background(), e, "other", files, nil); embedded != 2 || ix.Model != "other" {
+		t.Errorf("A new model should embed every file, got %d", embedded)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rag", "index.json")
+	if _, err := Load(path); err == nil {
+		t.Fatal("A missing index should fail to load")
+	}
+	ix := &Index{Model: "m", Files: map[string]string{"a.md": "h"}, Chunks: []Chunk{{Source: "a.md", Text: "t", Vector: []float32{1}}}}
+	if err := ix.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+	loaded, err := Load(path)
+	if err != nil || loaded.Model != "m" || len(loaded.Chunks) != 1 {
+		t.Errorf("Unexpected index %+v, %v", loaded, err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "skills", "ansible"), 0755)
+	os.WriteFile(filepath.Join(dir, "skills", "git.md"), []byte("git"), 0644)
+	os.WriteFile(filepath.Join(dir, "skills", "ansible", "README.MD"), []byte("ansible"), 0644)
+	os.WriteFile(filepath.Join(dir, "skills", "logo.png"), []byte("png"), 0644)
+
+	files, err := Collect([]string{filepath.Join(dir, "skills"), filepath.Join(dir, "missing")})
+	if err != nil || len(files) != 2 {
+		t.Errorf("Expected the 2 Markdown files, got %v, %v", files, err)
+	}
+	if _, err := Collect([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("No existing directory should fail")
+	}
+}
+
+// The installed docs are preferred over the working directory, which is only
+// the fallback
+func TestSharedDirs(t *testing.T) {
+	empty := t.TempDir()
+	share := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(share, "commands"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(share, "skills"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sharedDirs([]string{empty, share})
+	want := []string{filepath.Join(share, "commands"), filepath.Join(share, "skills")}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("sharedDirs() = %v, want %v", got, want)
+	}
+	if got := sharedDirs([]string{empty}); strings.Join(got, ",") != "commands,skills,docs" {
+		t.Errorf("expected the working directory fallback, got %v", got)
+	}
+}
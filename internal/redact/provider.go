@@ -0,0 +1,145 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// maxPlaceholder bounds the length of a placeholder, so a piece of a stream
+// ending with a longer unfinished one is not held back
+const maxPlaceholder = 24
+
+// Provider implements ports.LLMProvider by redacting every message before
+// it reaches the wrapped provider, and restoring the answer and the tool
+// calls it asks for
+type Provider struct {
+	inner ports.LLMProvider
+	r     *Redactor
+}
+
+// NewProvider wraps p so what it is sent goes through r. A redactor that
+// masks nothing returns p itself.
+func NewProvider(p ports.LLMProvider, r *Redactor) ports.LLMProvider {
+	if !r.Enabled() {
+		return p
+	}
+	return &Provider{inner: p, r: r}
+}
+
+func (p *Provider) Name() string { return p.inner.Name() }
+
+func (p *Provider) DefaultModel() string { return p.inner.DefaultModel() }
+
+// Redactor returns the redactor of the provider
+func (p *Provider) Redactor() *Redactor { return p.r }
+
+// Chat implements ports.LLMProvider. Streamed tokens are restored as they
+// arrive, holding back a placeholder split across pieces until it is whole.
+func (p *Provider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	redacted := req
+	redacted.Messages = make([]ports.ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		m.Content = p.r.Redact(m.Content)
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ports.ToolCall, len(m.ToolCalls))
+			for j, c := range m.ToolCalls {
+				c.Arguments = json.RawMessage(p.r.Redact(string(c.Arguments)))
+				calls[j] = c
+			}
+			m.ToolCalls = calls
+		}
+		redacted.Messages[i] = m
+	}
+
+	var flush func()
+	if onToken != nil {
+		onToken, flush = p.r.restoreStream(onToken)
+	}
+	resp, err := p.inner.Chat(ctx, redacted, onToken)
+	if flush != nil {
+		flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Content = p.r.Restore(resp.Content)
+	for i, c := range resp.ToolCalls {
+		resp.ToolCalls[i].Arguments = p.r.restoreJSON(c.Arguments)
+	}
+	return resp, nil
+}
+
+// ListModels lists the models of the wrapped provider
+func (p *Provider) ListModels(ctx context.Context) ([]ports.Model, error) {
+	return ports.ListModels(ctx, p.inner)
+}
+
+// Embed embeds the redacted texts with the wrapped provider
+func (p *Provider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	e, err := ports.AsEmbedder(p.inner)
+	if err != nil {
+		return nil, err
+	}
+	redacted := make([]string, len(texts))
+	for i, t := range texts {
+		redacted[i] = p.r.Redact(t)
+	}
+	return e.Embed(ctx, model, redacted)
+}
+
+// restoreStream returns a handler restoring the placeholders in the pieces
+// it is given before passing them to out. flush passes on what is held back.
+func (r *Redactor) restoreStream(out ports.TokenHandler) (handler ports.TokenHandler, flush func()) {
+	pending := ""
+	return func(token string) {
+			text := pending + token
+			pending = ""
+			if i := strings.LastIndex(text, "<"); i >= 0 && len(text)-i < maxPlaceholder && !strings.Contains(text[i:], ">") && isPlaceholderStart(text[i+1:]) {
+				text, pending = text[:i], text[i:]
+			}
+			if text != "" {
+				out(r.Restore(text))
+			}
+		}, func() {
+			if pending != "" {
+				out(r.Restore(pending))
+				pending = ""
+			}
+		}
+}
+
+// isPlaceholderStart reports whether s may begin a placeholder, after its <
+func isPlaceholderStart(s string) bool {
+	for _, c := range s {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonPlaceholderPattern matches placeholders in JSON, whose encoders may
+// escape their brackets
+var jsonPlaceholderPattern = regexp.MustCompile(`(?:<|\\u003[cC])([A-Z_]+_\d+)(?:>|\\u003[eE])`)
+
+// restoreJSON restores the placeholders in a JSON document, escaping the
+// values so it stays valid
+func (r *Redactor) restoreJSON(doc json.RawMessage) json.RawMessage {
+	if !r.Enabled() {
+		return doc
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return json.RawMessage(jsonPlaceholderPattern.ReplaceAllStringFunc(string(doc), func(m string) string {
+		v, ok := r.restores["<"+jsonPlaceholderPattern.FindStringSubmatch(m)[1]+">"]
+		if !ok {
+			return m
+		}
+		quoted, _ := json.Marshal(v)
+		return string(quoted[1 : len(quoted)-1])
+	}))
+}
@@ -0,0 +1,281 @@
+package redact
+
+// Package redact masks what identifies a host or grants access to it, such
+// as addresses, names and keys, before text is sent to a cloud AI provider.
+// Every value is replaced by a placeholder like <IP_1>, the same one each
+// time it appears, so answers can be mapped back to the real values locally.
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// Categories of values masked
+const (
+	IP         = "ip"
+	Hostname   = "hostname"
+	Username   = "username"
+	Email      = "email"
+	Secret     = "secret"
+	PrivateKey = "private-key"
+)
+
+// Categories lists every category, in the order they are applied: private
+// key blocks first, so the keys inside are not masked piece by piece
+var Categories = []string{PrivateKey, Secret, Email, IP, Hostname, Username}
+
+// Rules settings besides a list of categories
+const (
+	All = "all"
+	Off = "off"
+)
+
+// placeholderLabels name the placeholders of each category
+var placeholderLabels = map[string]string{
+	IP: "IP", Hostname: "HOST", Username: "USER", Email: "EMAIL", Secret: "SECRET", PrivateKey: "PRIVATE_KEY",
+}
+
+var (
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ipv4Pattern       = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern       = regexp.MustCompile(`(?i)\b(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}\b`)
+	// internalHostPattern matches names under the suffixes private networks use
+	internalHostPattern = regexp.MustCompile(`(?i)\b[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)*\.(?:local|lan|internal|intranet|corp|home|localdomain|priv)\b`)
+	homePattern         = regexp.MustCompile(`/home/([A-Za-z_][A-Za-z0-9_.-]*)`)
+	// secretPatterns match the keys and tokens of well-known services, and
+	// the values given to settings named like secrets
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\b(?:sk|rk)-[A-Za-z0-9_-]{16,}`),
+		regexp.MustCompile(`\bnvapi-[A-Za-z0-9_-]{16,}`),
+		regexp.MustCompile(`\bgh[opsur]_[A-Za-z0-9]{30,}`),
+		regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{30,}`),
+		regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`),
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+		regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+		// Long runs of letters and digits, such as hex tokens
+		regexp.MustCompile(`\b[A-Fa-f0-9]{32,}\b`),
+		regexp.MustCompile(`\b[A-Za-z0-9_-]*[0-9][A-Za-z0-9_-]*[A-Za-z][A-Za-z0-9_-]{30,}\b`),
+	}
+	// assignmentPattern matches "password=...", "api_key: ..." and the like;
+	// the value is the last group
+	assignmentPattern = regexp.MustCompile(`(?i)\b((?:api[_-]?key|access[_-]?key|secret(?:[_-]?key)?|token|passw(?:or)?d|passphrase|auth)["']?\s*[:=]\s*["']?)([^\s"',;]{4,})`)
+)
+
+// ParseRules returns the categories of a rules setting: "all", "off", or a
+// comma-separated list of categories
+func ParseRules(rules string) ([]string, error) {
+	rules = strings.TrimSpace(strings.ToLower(rules))
+	switch rules {
+	case All, "":
+		return append([]string(nil), Categories...), nil
+	case Off, "none":
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, c := range Categories {
+		known[c] = true
+	}
+	seen := map[string]bool{}
+	for _, c := range strings.Split(rules, ",") {
+		c = strings.TrimSpace(c)
+		if !known[c] {
+			return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("unknown redaction category %q (categories: %s, or all, off)", c, strings.Join(Categories, ", ")))
+		}
+		seen[c] = true
+	}
+	var out []string
+	for _, c := range Categories {
+		if seen[c] {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// Redactor masks text and maps placeholders back. It keeps its mapping for
+// its lifetime, so a conversation uses the same placeholders throughout.
+// It is safe for concurrent use.
+type Redactor struct {
+	categories map[string]bool
+	// names are hostnames and usernames known to belong to the host, which
+	// no pattern would find
+	hosts []string
+	users []string
+
+	mu       sync.Mutex
+	values   map[string]string // value -> placeholder
+	restores map[string]string // placeholder -> value
+	counts   map[string]int
+}
+
+// New returns a redactor masking the given categories. hosts and users are
+// names of the host masked wherever they appear, on top of the patterns.
+func New(categories []string, hosts, users []string) *Redactor {
+	r := &Redactor{
+		categories: map[string]bool{},
+		values:     map[string]string{},
+		restores:   map[string]string{},
+		counts:     map[string]int{},
+	}
+	for _, c := range categories {
+		r.categories[c] = true
+	}
+	r.hosts = longestFirst(hosts, 2)
+	r.users = longestFirst(users, 3)
+	return r
+}
+
+// Enabled reports whether r masks anything
+func (r *Redactor) Enabled() bool {
+	return r != nil && len(r.categories) > 0
+}
+
+// Redact returns text with the values of the enabled categories replaced by
+// placeholders
+func (r *Redactor) Redact(text string) string {
+	if !r.Enabled() || text == "" {
+		return text
+	}
+	for _, c := range Categories {
+		if !r.categories[c] {
+			continue
+		}
+		switch c {
+		case PrivateKey:
+			text = r.replace(text, privateKeyPattern, c)
+		case Secret:
+			text = assignmentPattern.ReplaceAllStringFunc(text, func(m string) string {
+				parts := assignmentPattern.FindStringSubmatch(m)
+				if isPlaceholder(parts[2]) {
+					return m
+				}
+				return parts[1] + r.placeholder(c, parts[2])
+			})
+			for _, p := range secretPatterns {
+				text = r.replace(text, p, c)
+			}
+		case Email:
+			text = r.replace(text, emailPattern, c)
+		case IP:
+			text = ipv4Pattern.ReplaceAllStringFunc(text, func(m string) string {
+				if ip := net.ParseIP(m); ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+					return m
+				}
+				return r.placeholder(c, m)
+			})
+			text = ipv6Pattern.ReplaceAllStringFunc(text, func(m string) string {
+				// Times like 12:30:45 and MAC addresses are not addresses
+				ip := net.ParseIP(m)
+				if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || (!strings.Contains(m, "::") && strings.Count(m, ":") < 7) {
+					return m
+				}
+				return r.placeholder(c, m)
+			})
+		case Hostname:
+			text = r.replaceNames(text, r.hosts, c)
+			text = r.replace(text, internalHostPattern, c)
+		case Username:
+			text = homePattern.ReplaceAllStringFunc(text, func(m string) string {
+				name := strings.TrimPrefix(m, "/home/")
+				if isPlaceholder(name) {
+					return m
+				}
+				return "/home/" + r.placeholder(c, name)
+			})
+			text = r.replaceNames(text, r.users, c)
+		}
+	}
+	return text
+}
+
+// Restore replaces the placeholders in text by the values they mask
+func (r *Redactor) Restore(text string) string {
+	if !r.Enabled() || !strings.Contains(text, "<") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		if v, ok := r.restores[m]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// Count returns how many distinct values were masked so far
+func (r *Redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.values)
+}
+
+var placeholderPattern = regexp.MustCompile(`<[A-Z_]+_\d+>`)
+
+func isPlaceholder(s string) bool {
+	return placeholderPattern.MatchString(s)
+}
+
+func (r *Redactor) replace(text string, p *regexp.Regexp, category string) string {
+	return p.ReplaceAllStringFunc(text, func(m string) string {
+		return r.placeholder(category, m)
+	})
+}
+
+// replaceNames masks whole-word occurrences of names
+func (r *Redactor) replaceNames(text string, names []string, category string) string {
+	for _, name := range names {
+		p := regexp.MustCompile(`(^|[^A-Za-z0-9_.-])(` + regexp.QuoteMeta(name) + `)($|[^A-Za-z0-9_-])`)
+		// Matches share their delimiters, so replace until nothing is left
+		for p.MatchString(text) {
+			text = p.ReplaceAllStringFunc(text, func(m string) string {
+				parts := p.FindStringSubmatch(m)
+				return parts[1] + r.placeholder(category, parts[2]) + parts[3]
+			})
+		}
+	}
+	return text
+}
+
+// placeholder returns the placeholder of value, creating it on first use
+func (r *Redactor) placeholder(category, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.values[value]; ok {
+		return p
+	}
+	r.counts[category]++
+	p := fmt.Sprintf("<%s_%d>", placeholderLabels[category], r.counts[category])
+	r.values[value] = p
+	r.restores[p] = value
+	return p
+}
+
+// longestFirst returns the distinct names of at least min characters,
+// longest first so a name is not masked inside a longer one
+func longestFirst(names []string, min int) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if len(n) < min || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i]) > len(out[j]) })
+	return out
+}
@@ -5,16 +5,12 @@
 //
 // This package includes:
 //   - Skill: Represents an external CLI tool integration
-//   - Vault: Secure storage for skill configuration and API keys
 //   - LoadSkillFromMD: Load skill from markdown file
 //   - ListSkills: List all available skills
 //   - SearchSkills: Search skills by name or type
 //
-// Vault features:
-//   - Encrypted storage with AES-256-GCM
-//   - Argon2id key derivation
-//   - Optional passphrase protection
-//   - Backward compatible with plaintext
+// Installed and enabled skills are recorded in the encrypted vault, see
+// package vault.
 //
 // Example usage:
 //
@@ -24,19 +20,8 @@
 //	    log.Fatal(err)
 //	}
 //
-//	// Install the skill
-//	if err := skill.Install(); err != nil {
-//	    log.Fatal(err)
-//	}
-//
-//	// Load vault with optional encryption
-//	v, err := skill.LoadVault("my-passphrase")
-//	if err != nil {
-//	    log.Fatal(err)
-//	}
-//
-//	// Save vault with encryption
-//	if err := skill.SaveVault(v, "my-passphrase"); err != nil {
+//	// Install the skill with the host's package manager
+//	if err := skill.Install(ctx, executor, "dnf", onEvent); err != nil {
 //	    log.Fatal(err)
 //	}
 package skill
\ No newline at end of file
@@ -1,12 +1,15 @@
 package skill
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/rsdenck/nux/internal/core/adapter"
 )
 
 type Skill struct {
@@ -59,15 +62,39 @@ func LoadSkillFromMD(name string) (*Skill, error) {
 	return skill, nil
 }
 
-func (s *Skill) Install() error {
+// installCmdRe matches the `...` commands in a skill's Install line
+var installCmdRe = regexp.MustCompile("`([^`]+)`")
+
+// InstallCommand returns the install command for the package manager pm. An
+// Install line lists one command per package manager, such as
+// "`dnf install lnav -y` or `apt install lnav -y`".
+func (s *Skill) InstallCommand(pm string) (string, bool) {
+	matches := installCmdRe.FindAllStringSubmatch(s.InstallCmd, -1)
+	if len(matches) == 0 {
+		return s.InstallCmd, s.InstallCmd != ""
+	}
+	for _, m := range matches {
+		fields := strings.Fields(m[1])
+		if len(fields) > 0 && fields[0] == pm {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// Install runs the install command for pm through executor, marked as
+// needing root, streaming its output to onEvent
+func (s *Skill) Install(ctx context.Context, executor adapter.Executor, pm string, onEvent adapter.EventHandler) error {
 	if s.InstallCmd == "" {
 		return fmt.Errorf("no install command specified")
 	}
+	command, ok := s.InstallCommand(pm)
+	if !ok {
+		return fmt.Errorf("no install command for %s", pm)
+	}
 
-	cmd := exec.Command("bash", "-c", s.InstallCmd)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := adapter.RunStream(adapter.WithPrivileged(ctx), executor, "", onEvent, "bash", "-c", command)
+	return err
 }
 
 func (s *Skill) Info() string {
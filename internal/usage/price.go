@@ -0,0 +1,86 @@
+package usage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// Price is what a model charges, in US dollars per million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Cost returns the price of a request, in US dollars
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+func (p Price) String() string {
+	return strconv.FormatFloat(p.Input, 'f', -1, 64) + "/" + strconv.FormatFloat(p.Output, 'f', -1, 64)
+}
+
+// ParsePrice parses a price given as "input/output" in US dollars per
+// million tokens, such as "2.5/10"
+func ParsePrice(s string) (Price, error) {
+	in, out, ok := strings.Cut(strings.TrimSpace(s), "/")
+	input, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+	output, err2 := strconv.ParseFloat(strings.TrimSpace(out), 64)
+	if !ok || err1 != nil || err2 != nil || input < 0 || output < 0 {
+		return Price{}, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid price %q, expected input/output in USD per million tokens, such as 2.5/10", s))
+	}
+	return Price{Input: input, Output: output}, nil
+}
+
+// freeProviders run on hardware of the user
+var freeProviders = map[string]bool{"ollama": true}
+
+// listPrices are the prices of common models, matched in order against the
+// model name so the longer names of a family come first
+var listPrices = []struct {
+	family string
+	price  Price
+}{
+	{"gpt-4o-mini", Price{0.15, 0.60}},
+	{"gpt-4o", Price{2.50, 10}},
+	{"gpt-4.1-nano", Price{0.10, 0.40}},
+	{"gpt-4.1-mini", Price{0.40, 1.60}},
+	{"gpt-4.1", Price{2, 8}},
+	{"gpt-4-turbo", Price{10, 30}},
+	{"gpt-3.5-turbo", Price{0.50, 1.50}},
+	{"o3-mini", Price{1.10, 4.40}},
+	{"o4-mini", Price{1.10, 4.40}},
+	{"claude-3-5-haiku", Price{0.80, 4}},
+	{"claude-3-haiku", Price{0.25, 1.25}},
+	{"claude-3-5-sonnet", Price{3, 15}},
+	{"claude-3-7-sonnet", Price{3, 15}},
+	{"claude-sonnet-4", Price{3, 15}},
+	{"claude-3-opus", Price{15, 75}},
+	{"claude-opus-4", Price{15, 75}},
+}
+
+// Lookup returns the price of a model: the one configured for the model or
+// its provider, the list price of the model, or nothing for providers that
+// run locally. ok is false when the price is unknown.
+func Lookup(configured map[string]string, provider, model string) (p Price, ok bool) {
+	for _, key := range []string{model, provider} {
+		if s, found := configured[key]; found {
+			if p, err := ParsePrice(s); err == nil {
+				return p, true
+			}
+		}
+	}
+	if freeProviders[provider] {
+		return Price{}, true
+	}
+	name := strings.ToLower(model)
+	for _, lp := range listPrices {
+		if strings.HasPrefix(name, lp.family) {
+			return lp.price, true
+		}
+	}
+	return Price{}, false
+}
@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/rsdenck/nux/internal/chat"
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+// Provider implements ports.LLMProvider by recording every request to the
+// wrapped provider in a Log
+type Provider struct {
+	inner ports.LLMProvider
+	log   *Log
+	now   func() time.Time
+}
+
+// NewProvider wraps p so its requests are recorded in log. A nil log
+// returns p itself.
+func NewProvider(p ports.LLMProvider, log *Log) ports.LLMProvider {
+	if log == nil {
+		return p
+	}
+	return &Provider{inner: p, log: log, now: time.Now}
+}
+
+func (p *Provider) Name() string { return p.inner.Name() }
+
+func (p *Provider) DefaultModel() string { return p.inner.DefaultModel() }
+
+// Chat implements ports.LLMProvider. Tokens the provider did not count are
+// estimated from the length of the conversation and of the answer.
+func (p *Provider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	start := p.now()
+	var first time.Duration
+	handler := onToken
+	if onToken != nil {
+		handler = func(token string) {
+			if first == 0 {
+				first = p.now().Sub(start)
+			}
+			onToken(token)
+		}
+	}
+
+	resp, err := p.inner.Chat(ctx, req, handler)
+	r := Record{
+		Time:       start.UTC(),
+		Provider:   p.inner.Name(),
+		Model:      req.Model,
+		Latency:    p.now().Sub(start).Milliseconds(),
+		FirstToken: first.Milliseconds(),
+	}
+	if r.Model == "" {
+		r.Model = p.inner.DefaultModel()
+	}
+	if err != nil {
+		r.Error = string(nuxerrors.CodeOf(err))
+		if r.Error == "" {
+			r.Error = string(nuxerrors.ErrCodeProvider)
+		}
+		p.log.Add(r)
+		return nil, err
+	}
+
+	if resp.Model != "" {
+		r.Model = resp.Model
+	}
+	r.PromptTokens, r.CompletionTokens = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+	if r.PromptTokens == 0 && r.CompletionTokens == 0 {
+		r.Estimated = true
+		for _, m := range req.Messages {
+			r.PromptTokens += chat.EstimateTokens(m)
+		}
+		r.CompletionTokens = chat.EstimateTokens(ports.ChatMessage{Content: resp.Content})
+	}
+	p.log.Add(r)
+	return resp, nil
+}
+
+// ListModels implements ports.ModelLister when the wrapped provider does
+func (p *Provider) ListModels(ctx context.Context) ([]ports.Model, error) {
+	return ports.ListModels(ctx, p.inner)
+}
+
+// Embed implements ports.Embedder when the wrapped provider does
+func (p *Provider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	e, err := ports.AsEmbedder(p.inner)
+	if err != nil {
+		return nil, err
+	}
+	return e.Embed(ctx, model, texts)
+}
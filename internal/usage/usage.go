@@ -0,0 +1,181 @@
+package usage
+
+// Package usage records what every request to an AI provider took, in
+// tokens and time, to ~/.nux/usage.jsonl, and sums it up by provider and
+// model with what it cost.
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/logger"
+)
+
+const logFile = ".nux/usage.jsonl"
+
+// Record is one request to a provider, answered or failed
+type Record struct {
+	Time             time.Time `json:"time"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	// Estimated is set when the provider did not count the tokens
+	Estimated bool `json:"estimated,omitempty"`
+	// Latency is the time to the whole answer, FirstToken to its first piece
+	Latency    int64 `json:"latency_ms"`
+	FirstToken int64 `json:"first_token_ms,omitempty"`
+	// Error is the code of a failed request
+	Error string `json:"error,omitempty"`
+}
+
+// Path returns the location of the usage log
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to get home directory")
+	}
+	return filepath.Join(home, logFile), nil
+}
+
+// Log appends records to the usage log. A nil *Log records nothing.
+type Log struct {
+	path string
+}
+
+// Open returns the usage log of the user
+func Open() (*Log, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return NewLogAt(path), nil
+}
+
+// NewLogAt returns the usage log stored at path
+func NewLogAt(path string) *Log {
+	return &Log{path: path}
+}
+
+// Add appends a record. Failing to write the log must not fail the request,
+// so errors are only logged.
+func (l *Log) Add(r Record) {
+	if l == nil {
+		return
+	}
+	if err := l.append(r); err != nil {
+		logger.Warn("failed to record the usage of %s: %v", r.Provider, err)
+	}
+}
+
+func (l *Log) append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Read returns the records at path made since the given time, all of them
+// for a zero time. Lines that cannot be parsed, such as one cut short by a
+// crash, are skipped.
+func Read(path string, since time.Time) ([]Record, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to read usage log")
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if json.Unmarshal(scanner.Bytes(), &r) != nil || r.Time.Before(since) {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeConfig, "failed to read usage log")
+	}
+	return records, nil
+}
+
+// Total sums up the records of a provider and model
+type Total struct {
+	Provider         string
+	Model            string
+	Requests         int
+	Failed           int
+	PromptTokens     int
+	CompletionTokens int
+	// Estimated is set when some of the tokens were estimated
+	Estimated bool
+	// Latency is the average time to a whole answer, of the answered requests
+	Latency time.Duration
+	// Cost is in US dollars; Priced is false when the model has no price
+	Cost   float64
+	Priced bool
+}
+
+// Summarize sums up records by provider and model, the most expensive
+// first, then the most used. price returns the price of a model.
+func Summarize(records []Record, price func(provider, model string) (Price, bool)) []Total {
+	byKey := map[[2]string]*Total{}
+	latency := map[[2]string]time.Duration{}
+	var totals []*Total
+	for _, r := range records {
+		key := [2]string{r.Provider, r.Model}
+		t, ok := byKey[key]
+		if !ok {
+			t = &Total{Provider: r.Provider, Model: r.Model}
+			byKey[key] = t
+			totals = append(totals, t)
+		}
+		t.Requests++
+		if r.Error != "" {
+			t.Failed++
+			continue
+		}
+		t.PromptTokens += r.PromptTokens
+		t.CompletionTokens += r.CompletionTokens
+		t.Estimated = t.Estimated || r.Estimated
+		latency[key] += time.Duration(r.Latency) * time.Millisecond
+	}
+
+	out := make([]Total, 0, len(totals))
+	for _, t := range totals {
+		if answered := t.Requests - t.Failed; answered > 0 {
+			t.Latency = latency[[2]string{t.Provider, t.Model}] / time.Duration(answered)
+		}
+		p, ok := price(t.Provider, t.Model)
+		t.Priced = ok
+		t.Cost = p.Cost(t.PromptTokens, t.CompletionTokens)
+		out = append(out, *t)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Cost != out[j].Cost {
+			return out[i].Cost > out[j].Cost
+		}
+		return out[i].Requests > out[j].Requests
+	})
+	return out
+}
@@ -0,0 +1,147 @@
+package usage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"github.com/rsdenck/nux/internal/core/ports"
+)
+
+type fakeProvider struct {
+	resp *ports.ChatResponse
+	err  error
+}
+
+func (f *fakeProvider) Name() string         { return "openai" }
+func (f *fakeProvider) DefaultModel() string { return "gpt-4o-mini" }
+
+func (f *fakeProvider) Chat(ctx context.Context, req ports.ChatRequest, onToken ports.TokenHandler) (*ports.ChatResponse, error) {
+	if f.resp != nil && onToken != nil {
+		onToken(f.resp.Content)
+	}
+	return f.resp, f.err
+}
+
+// clock advances by step every time it is read
+func clock(step time.Duration) func() time.Time {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestProviderRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	log := NewLogAt(path)
+	req := ports.ChatRequest{Messages: []ports.ChatMessage{{Role: ports.RoleUser, Content: "how much disk is left?"}}}
+
+	counted := NewProvider(&fakeProvider{resp: &ports.ChatResponse{Model: "gpt-4o-mini", Content: "plenty", Usage: ports.Usage{PromptTokens: 12, CompletionTokens: 3}}}, log).(*Provider)
+	counted.now = clock(100 * time.Millisecond)
+	if _, err := counted.Chat(context.Background(), req, func(string) {}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	uncounted := NewProvider(&fakeProvider{resp: &ports.ChatResponse{Content: "a few gigabytes, mostly logs"}}, log)
+	if _, err := uncounted.Chat(context.Background(), req, nil); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	failing := NewProvider(&fakeProvider{err: nuxerrors.New(nuxerrors.ErrCodeUnauthorized, "bad key")}, log)
+	if _, err := failing.Chat(context.Background(), req, nil); err == nil {
+		t.Fatal("Chat should fail")
+	}
+
+	records, err := Read(path, time.Time{})
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d (%v)", len(records), err)
+	}
+	if r := records[0]; r.PromptTokens != 12 || r.CompletionTokens != 3 || r.Estimated || r.Latency != 200 || r.FirstToken != 100 {
+		t.Errorf("Unexpected record %+v", r)
+	}
+	if r := records[1]; !r.Estimated || r.PromptTokens == 0 || r.CompletionTokens == 0 || r.Model != "gpt-4o-mini" {
+		t.Errorf("Tokens not counted should be estimated, got %+v", r)
+	}
+	if r := records[2]; r.Error != string(nuxerrors.ErrCodeUnauthorized) {
+		t.Errorf("Failure should be recorded, got %+v", r)
+	}
+}
+
+func TestReadSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	data := `{"time":"2026-09-01T00:00:00Z","provider":"openai","model":"gpt-4o"}
+{"time":"2026-10-02T00:00:00Z","provider":"ollama","model":"qwen3"}
+{"time":"2026-10-03T00:00:00Z","provi`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	records, err := Read(path, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(records) != 1 || records[0].Provider != "ollama" {
+		t.Errorf("Expected the one complete record since October, got %+v (%v)", records, err)
+	}
+	if records, err := Read(filepath.Join(t.TempDir(), "none"), time.Time{}); err != nil || records != nil {
+		t.Errorf("A missing log should be empty, got %v (%v)", records, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	records := []Record{
+		{Provider: "ollama", Model: "qwen3", PromptTokens: 5000, CompletionTokens: 500, Latency: 3000},
+		{Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, CompletionTokens: 100000, Latency: 1000},
+		{Provider: "openai", Model: "gpt-4o", PromptTokens: 1000000, CompletionTokens: 100000, Latency: 3000, Estimated: true},
+		{Provider: "openai", Model: "gpt-4o", Error: "PROVIDER_ERROR", Latency: 9000},
+		{Provider: "vllm", Model: "mystery", PromptTokens: 10, CompletionTokens: 10, Latency: 500},
+	}
+	totals := Summarize(records, func(provider, model string) (Price, bool) {
+		return Lookup(nil, provider, model)
+	})
+	if len(totals) != 3 {
+		t.Fatalf("Expected 3 totals, got %+v", totals)
+	}
+	gpt := totals[0]
+	if gpt.Model != "gpt-4o" || gpt.Requests != 3 || gpt.Failed != 1 || !gpt.Estimated || gpt.Latency != 2*time.Second {
+		t.Errorf("Unexpected total %+v", gpt)
+	}
+	// 2M input tokens at 2.50 and 200k output tokens at 10
+	if gpt.Cost < 6.99 || gpt.Cost > 7.01 || !gpt.Priced {
+		t.Errorf("Expected $7, got %f", gpt.Cost)
+	}
+	for _, total := range totals[1:] {
+		if total.Cost != 0 || total.Priced != (total.Provider == "ollama") {
+			t.Errorf("Unexpected cost of %s: %+v", total.Provider, total)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	configured := map[string]string{"vllm": "0.2/0.2", "gpt-4o": "1/1"}
+	tests := []struct {
+		provider, model string
+		want            Price
+		ok              bool
+	}{
+		{"openai", "gpt-4o", Price{1, 1}, true},
+		{"openai", "gpt-4o-mini-2024-07-18", Price{0.15, 0.60}, true},
+		{"claude", "claude-3-5-haiku-20241022", Price{0.80, 4}, true},
+		{"vllm", "qwen2.5-72b", Price{0.2, 0.2}, true},
+		{"ollama", "qwen3-coder", Price{}, true},
+		{"nvidia", "minimaxai/minimax-m2.7", Price{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Lookup(configured, tt.provider, tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%s, %s) = %v, %v; want %v, %v", tt.provider, tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if p, err := ParsePrice(" 2.5 / 10 "); err != nil || p != (Price{2.5, 10}) || p.String() != "2.5/10" {
+		t.Errorf("Unexpected price %v (%v)", p, err)
+	}
+	for _, bad := range []string{"2.5", "a/b", "-1/2", ""} {
+		if _, err := ParsePrice(bad); err == nil {
+			t.Errorf("ParsePrice(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"golang.org/x/sys/unix"
+)
+
+const agentSocket = "agent.sock"
+
+// agentTimeout bounds a request to the unlock agent, so a wedged agent falls
+// back to the passphrase prompt instead of hanging every command
+const agentTimeout = 2 * time.Second
+
+// Key is the key derived from the vault passphrase, with the salt it was
+// derived with. The unlock agent holds it so the passphrase is typed once.
+type Key struct {
+	key  []byte
+	salt []byte
+}
+
+// UnlockKey derives the key of the vault from its passphrase and checks it
+// opens the vault
+func UnlockKey() (*Key, error) {
+	path, err := getVaultPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no vault at %s", path)).
+				WithHint("store a key first with 'nux vault set-key'")
+		}
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+	encrypted, ok := parseEncrypted(data)
+	if !ok {
+		return nil, plaintextError(path)
+	}
+	salt, err := encrypted.DecodeSalt()
+	if err != nil {
+		return nil, err
+	}
+	pass, err := passphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	v, err := open(encrypted, DeriveKey(pass, salt), salt)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{key: v.key, salt: v.salt}, nil
+}
+
+// WriteTo sends the key to an agent started with ReadKey
+func (k *Key) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(append(append([]byte{}, k.key...), k.salt...))
+	return int64(n), err
+}
+
+// ReadKey reads a key sent with WriteTo
+func ReadKey(r io.Reader) (*Key, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault key: %w", err)
+	}
+	if len(data) != keyLen+saltLen {
+		return nil, fmt.Errorf("invalid vault key")
+	}
+	return &Key{key: data[:keyLen], salt: data[keyLen:]}, nil
+}
+
+// Wipe overwrites the key in memory
+func (k *Key) Wipe() {
+	clear(k.key)
+}
+
+// AgentSocket returns the path of the unlock agent socket
+func AgentSocket() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, vaultDir, agentSocket), nil
+}
+
+type agentRequest struct {
+	Op string `json:"op"`
+}
+
+type agentResponse struct {
+	Key     []byte    `json:"key,omitempty"`
+	Salt    []byte    `json:"salt,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ServeAgent holds k in locked memory and hands it to the commands of the
+// same user over a 0600 Unix socket until ctx is done, the ttl expires or
+// the agent is locked. A zero ttl keeps the key until the agent is locked.
+func ServeAgent(ctx context.Context, k *Key, ttl time.Duration) error {
+	defer k.Wipe()
+	path, err := AgentSocket()
+	if err != nil {
+		return err
+	}
+
+	// Keep the key out of swap and core dumps
+	key, err := lockedCopy(k.key)
+	if err != nil {
+		return err
+	}
+	defer wipeLocked(key)
+	k.Wipe()
+	unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0)
+
+	l, err := listenAgent(path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+		timer := time.AfterFunc(ttl, cancel)
+		defer timer.Stop()
+	}
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("vault agent: %w", err)
+		}
+		serveAgentConn(conn, agentResponse{Key: key, Salt: k.salt, Expires: expires}, func() {
+			cancel()
+			l.Close()
+		})
+	}
+}
+
+// listenAgent listens on the agent socket, replacing a stale one. The socket
+// is created 0600 in a 0700 directory.
+func listenAgent(path string) (*net.UnixListener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	if _, ok := agentCall("status"); ok {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeAlreadyExists, "a vault agent is already running").
+			WithHint("run 'nux vault lock' first")
+	}
+	os.Remove(path)
+
+	mask := unix.Umask(0177)
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	unix.Umask(mask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// serveAgentConn answers one request. lock stops the agent, and removes its
+// socket before the answer so a new agent can start right away.
+func serveAgentConn(conn *net.UnixConn, state agentResponse, lock func()) {
+	defer conn.Close()
+	if !sameUser(conn) {
+		return
+	}
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+
+	var req agentRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	var resp agentResponse
+	switch req.Op {
+	case "key":
+		resp = state
+	case "status":
+		resp.Expires = state.Expires
+	case "lock":
+		lock()
+	default:
+		resp.Error = fmt.Sprintf("unknown request %q", req.Op)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// sameUser reports whether the peer of conn runs as the agent's user. The
+// socket mode already keeps other users out; root is not.
+func sameUser(conn *net.UnixConn) bool {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var cred *unix.Ucred
+	raw.Control(func(fd uintptr) {
+		cred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	return err == nil && cred != nil && int(cred.Uid) == os.Getuid()
+}
+
+// agentCall sends a request to the unlock agent, reporting false when none
+// is running
+func agentCall(op string) (*agentResponse, bool) {
+	path, err := AgentSocket()
+	if err != nil {
+		return nil, false
+	}
+	conn, err := net.DialTimeout("unix", path, agentTimeout)
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+
+	if err := json.NewEncoder(conn).Encode(agentRequest{Op: op}); err != nil {
+		return nil, false
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil || resp.Error != "" {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// agentKey returns the key cached by the unlock agent, if it was derived with
+// salt
+func agentKey(salt []byte) ([]byte, bool) {
+	resp, ok := agentCall("key")
+	if !ok || len(resp.Key) != keyLen || !bytes.Equal(resp.Salt, salt) {
+		return nil, false
+	}
+	return resp.Key, true
+}
+
+// AgentStatus reports whether the unlock agent is running, and when it will
+// forget the key; the time is zero when it has no ttl
+func AgentStatus() (time.Time, bool) {
+	resp, ok := agentCall("status")
+	if !ok {
+		return time.Time{}, false
+	}
+	return resp.Expires, true
+}
+
+// LockAgent makes the unlock agent forget the key and exit. It reports
+// whether an agent was running.
+func LockAgent() bool {
+	_, ok := agentCall("lock")
+	return ok
+}
+
+// lockedCopy copies b to memory that is locked into RAM
+func lockedCopy(b []byte) ([]byte, error) {
+	mem, err := unix.Mmap(-1, 0, len(b), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate key memory: %w", err)
+	}
+	if err := unix.Mlock(mem); err != nil {
+		unix.Munmap(mem)
+		return nil, fmt.Errorf("failed to lock key memory: %w", err)
+	}
+	copy(mem, b)
+	return mem, nil
+}
+
+func wipeLocked(mem []byte) {
+	clear(mem)
+	unix.Munlock(mem)
+	unix.Munmap(mem)
+}
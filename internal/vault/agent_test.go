@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUnlockAgent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	v := defaultVault()
+	v.SetAPIKey("openai", "sk-test123")
+	if err := Save(v); err != nil {
+		t.Fatalf("Failed to save vault: %v", err)
+	}
+	key, err := UnlockKey()
+	if err != nil {
+		t.Fatalf("UnlockKey failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- ServeAgent(context.Background(), key, time.Minute) }()
+	for i := 0; ; i++ {
+		if expires, ok := AgentStatus(); ok {
+			if time.Until(expires) <= 0 {
+				t.Errorf("Expected the key to expire in the future, got %v", expires)
+			}
+			break
+		}
+		if i == 100 {
+			t.Fatal("Agent did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// With the agent running, no passphrase is needed
+	t.Setenv(PassphraseEnv, "")
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load through the agent failed: %v", err)
+	}
+	loaded.SetAPIKey("nvidia", "nv-key")
+	if err := Save(loaded); err != nil {
+		t.Fatalf("Save through the agent failed: %v", err)
+	}
+
+	if !LockAgent() {
+		t.Fatal("LockAgent should find the agent")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("ServeAgent failed: %v", err)
+	}
+	if _, ok := AgentStatus(); ok {
+		t.Error("Agent should be stopped")
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Load should need the passphrase once the agent is locked")
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	loaded, err = Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if key, _ := loaded.GetAPIKey("nvidia"); key != "nv-key" {
+		t.Errorf("Expected nv-key, got %q", key)
+	}
+}
+
+func TestUnlockAgentExpires(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	k := &Key{key: make([]byte, keyLen), salt: make([]byte, saltLen)}
+
+	done := make(chan error, 1)
+	go func() { done <- ServeAgent(context.Background(), k, 50*time.Millisecond) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeAgent failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Agent should stop once its ttl expires")
+	}
+	if _, ok := AgentStatus(); ok {
+		t.Error("Agent should be stopped")
+	}
+}
@@ -0,0 +1,98 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// bundleVersion is the version of the export format
+const bundleVersion = "nux-secrets/1"
+
+// bundle is the content of an export, sealed with its own passphrase so it
+// can travel between workstations whose vaults use different ones
+type bundle struct {
+	Version  string                  `json:"version"`
+	Exported string                  `json:"exported"`
+	Secrets  map[string]bundleSecret `json:"secrets"`
+}
+
+type bundleSecret struct {
+	Value string     `json:"value"`
+	Meta  SecretMeta `json:"meta"`
+}
+
+// Export seals the named secrets, all of them when names is empty, into an
+// encrypted bundle for Import
+func (v *Vault) Export(names []string, pass []byte) ([]byte, error) {
+	v.mu.RLock()
+	b := bundle{
+		Version:  bundleVersion,
+		Exported: time.Now().UTC().Format(time.RFC3339),
+		Secrets:  make(map[string]bundleSecret),
+	}
+	if len(names) == 0 {
+		for name := range v.APIKeys {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		value, ok := v.APIKeys[name]
+		if !ok {
+			v.mu.RUnlock()
+			return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no secret named %s", name))
+		}
+		b.Secrets[name] = bundleSecret{Value: value, Meta: v.Metadata[name]}
+	}
+	v.mu.RUnlock()
+
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	encrypted, err := Encrypt(plaintext, pass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	return json.MarshalIndent(encrypted, "", "  ")
+}
+
+// Import adds the secrets of a bundle written by Export, with their
+// metadata. Secrets the vault already has are skipped unless overwrite is
+// set. It returns the names imported and skipped.
+func (v *Vault) Import(data, pass []byte, overwrite bool) (imported, skipped []string, err error) {
+	encrypted, ok := parseEncrypted(data)
+	if !ok {
+		return nil, nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "not a nux secrets bundle")
+	}
+	plaintext, err := Decrypt(encrypted, pass)
+	if err != nil {
+		return nil, nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeVault, "failed to decrypt bundle").
+			WithHint("check the passphrase it was exported with")
+	}
+	var b bundle
+	if err := json.Unmarshal(plaintext, &b); err != nil || b.Version != bundleVersion {
+		return nil, nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, "not a nux secrets bundle")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.Metadata == nil {
+		v.Metadata = make(map[string]SecretMeta)
+	}
+	for name, s := range b.Secrets {
+		if _, exists := v.APIKeys[name]; exists && !overwrite {
+			skipped = append(skipped, name)
+			continue
+		}
+		v.APIKeys[name] = s.Value
+		v.Metadata[name] = s.Meta
+		imported = append(imported, name)
+	}
+	sort.Strings(imported)
+	sort.Strings(skipped)
+	return imported, skipped, nil
+}
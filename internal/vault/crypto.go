@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltLen  = 32
+	nonceLen = 12
+	keyLen   = 32 // 256 bits for AES-256
+)
+
+// EncryptedVault is the on-disk form of the vault: the JSON vault sealed with
+// AES-256-GCM under a key derived from the passphrase with Argon2id
+type EncryptedVault struct {
+	Version string `json:"version"`
+	Salt    string `json:"salt"`
+	Nonce   string `json:"nonce"`
+	Data    string `json:"data"`
+}
+
+// DeriveKey derives a key from password using Argon2id
+func DeriveKey(password, salt []byte) []byte {
+	return argon2.IDKey(password, salt, 1, 64*1024, 4, keyLen)
+}
+
+// NewSalt returns a random salt for DeriveKey
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return salt, nil
+}
+
+// Encrypt encrypts data with AES-256-GCM
+func Encrypt(data []byte, password []byte) (*EncryptedVault, error) {
+	salt, err := NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	return Seal(data, DeriveKey(password, salt), salt)
+}
+
+// Decrypt decrypts data with AES-256-GCM
+func Decrypt(encrypted *EncryptedVault, password []byte) ([]byte, error) {
+	salt, err := encrypted.DecodeSalt()
+	if err != nil {
+		return nil, err
+	}
+	return Open(encrypted, DeriveKey(password, salt))
+}
+
+// Seal encrypts data with AES-256-GCM under a key DeriveKey derived with
+// salt. Sealing with a known key skips the slow key derivation.
+func Seal(data, key, salt []byte) (*EncryptedVault, error) {
+	// Generate random nonce
+	nonce := make([]byte, nonceLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encrypt data
+	ciphertext := gcm.Seal(nil, nonce, data, nil)
+
+	return &EncryptedVault{
+		Version: "1.0",
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// Open decrypts data sealed with key
+func Open(encrypted *EncryptedVault, key []byte) ([]byte, error) {
+	// Decode nonce
+	nonce, err := base64.StdEncoding.DecodeString(encrypted.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode nonce: %w", err)
+	}
+
+	// Decode ciphertext
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt data
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// DecodeSalt returns the salt the key of the vault was derived with
+func (e *EncryptedVault) DecodeSalt() ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	return salt, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	// Create AES cipher
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	// Create GCM mode
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// EndpointOpenAICompatible is the type of servers speaking the OpenAI chat
+// completions API, such as llama.cpp and vLLM
+const EndpointOpenAICompatible = "openai-compatible"
+
+// Endpoint is an AI server configured by name, selected with --provider
+type Endpoint struct {
+	Type    string `json:"type"`
+	BaseURL string `json:"base_url"`
+	// APIKeyRef names the secret holding the API key, as vault://name
+	APIKeyRef string `json:"api_key_ref,omitempty"`
+	// Model is used when a request names none
+	Model string `json:"model,omitempty"`
+}
+
+// SetEndpoint stores or replaces a named endpoint
+func (v *Vault) SetEndpoint(name string, e Endpoint) error {
+	if e.APIKeyRef != "" && !strings.HasPrefix(e.APIKeyRef, RefScheme) {
+		return nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid API key reference %q, expected %sSECRET", e.APIKeyRef, RefScheme))
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.Endpoints == nil {
+		v.Endpoints = make(map[string]Endpoint)
+	}
+	v.Endpoints[name] = e
+	return nil
+}
+
+// GetEndpoint returns a named endpoint
+func (v *Vault) GetEndpoint(name string) (Endpoint, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	e, ok := v.Endpoints[name]
+	return e, ok
+}
+
+// RemoveEndpoint forgets a named endpoint, reporting whether it existed
+func (v *Vault) RemoveEndpoint(name string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, ok := v.Endpoints[name]
+	delete(v.Endpoints, name)
+	return ok
+}
+
+// EndpointNames lists the named endpoints in order
+func (v *Vault) EndpointNames() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	names := make([]string, 0, len(v.Endpoints))
+	for name := range v.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EndpointKey returns the API key of an endpoint, recording its use. Endpoints
+// without a key reference return "".
+func (v *Vault) EndpointKey(e Endpoint) (string, error) {
+	ref, ok := strings.CutPrefix(e.APIKeyRef, RefScheme)
+	if !ok {
+		return "", nil
+	}
+	key, found := v.UseSecret(ref)
+	if !found {
+		return "", nuxerrors.New(nuxerrors.ErrCodeConfig, fmt.Sprintf("no secret named %s in the vault", ref)).
+			WithHint("store it with 'nux vault set-key " + ref + "'")
+	}
+	return key, nil
+}
@@ -0,0 +1,153 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// legacySkillVault is the skill vault earlier versions kept in
+// ~/.skills/.nux.json, in plaintext unless a passphrase was given
+type legacySkillVault struct {
+	InstalledSkills []string          `json:"installed_skills"`
+	EnabledSkills   []string          `json:"enabled_skills"`
+	APIKeys         map[string]string `json:"api_keys"`
+	Ollama          struct {
+		Host    string `json:"host"`
+		Model   string `json:"model"`
+		Enabled bool   `json:"enabled"`
+	} `json:"ollama"`
+}
+
+func legacySkillVaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".skills", ".nux.json"), nil
+}
+
+// Migrate encrypts the vaults earlier versions stored in plaintext: the vault
+// at ~/.nux/vault.json and the skill vault at ~/.skills/.nux.json are merged
+// into one encrypted vault, and the plaintext files are removed. It returns
+// the files it migrated, none when there was nothing to do.
+func Migrate() ([]string, error) {
+	path, err := getVaultPath()
+	if err != nil {
+		return nil, err
+	}
+	skillPath, err := legacySkillVaultPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []string
+	var pass []byte
+	skillMigrated := false
+	v := defaultVault()
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	default:
+		if encrypted, ok := parseEncrypted(data); ok {
+			if v, err = unlock(encrypted); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := json.Unmarshal(data, v); err != nil {
+				return nil, fmt.Errorf("failed to parse vault %s: %w", path, err)
+			}
+			migrated = append(migrated, path)
+		}
+	}
+
+	skillData, err := os.ReadFile(skillPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read skill vault: %w", err)
+	default:
+		legacy, err := readLegacySkillVault(skillData, &pass)
+		if err != nil {
+			return nil, fmt.Errorf("skill vault %s: %w", skillPath, err)
+		}
+		v.mergeSkillVault(legacy)
+		migrated = append(migrated, skillPath)
+		skillMigrated = true
+	}
+
+	if len(migrated) == 0 {
+		return nil, nil
+	}
+	if v.key == nil {
+		if pass == nil {
+			if pass, err = passphrase(true); err != nil {
+				return nil, err
+			}
+		}
+		if err := v.setPassphrase(pass); err != nil {
+			return nil, err
+		}
+	}
+	if err := write(path, v); err != nil {
+		return nil, err
+	}
+	if skillMigrated {
+		if err := os.Remove(skillPath); err != nil {
+			return migrated, fmt.Errorf("vault encrypted, but failed to remove %s: %w", skillPath, err)
+		}
+	}
+	return migrated, nil
+}
+
+// readLegacySkillVault parses the skill vault, asking for its passphrase when
+// it was encrypted. The passphrase is kept in pass, to encrypt a new vault.
+func readLegacySkillVault(data []byte, pass *[]byte) (*legacySkillVault, error) {
+	if encrypted, ok := parseEncrypted(data); ok {
+		p, err := passphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := Decrypt(encrypted, p)
+		if err != nil {
+			return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeVault, "failed to unlock the skill vault").
+				WithHint("check the passphrase, or " + PassphraseEnv)
+		}
+		data = plaintext
+		*pass = p
+	}
+	var legacy legacySkillVault
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	return &legacy, nil
+}
+
+// mergeSkillVault adds what the skill vault knows to v, keeping the values v
+// already has
+func (v *Vault) mergeSkillVault(legacy *legacySkillVault) {
+	for k, key := range legacy.APIKeys {
+		if _, ok := v.APIKeys[k]; !ok {
+			v.APIKeys[k] = key
+		}
+	}
+	for _, name := range legacy.InstalledSkills {
+		if !v.IsInstalled(name) {
+			v.InstallSkill(name, "")
+		}
+	}
+	for _, name := range legacy.EnabledSkills {
+		v.EnableSkill(name)
+	}
+	if _, ok := v.Config["ollama_host"]; !ok && legacy.Ollama.Host != "" {
+		v.SetConfig("ollama_host", legacy.Ollama.Host)
+	}
+	if _, ok := v.Config["agent_model"]; !ok && legacy.Ollama.Model != "" {
+		v.SetConfig("agent_model", legacy.Ollama.Model)
+	}
+}
@@ -0,0 +1,23 @@
+package vault
+
+// SetPrice stores what a model or a whole provider charges, as "input/output"
+// in US dollars per million tokens, as understood by the usage package
+func (v *Vault) SetPrice(name, price string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.Prices == nil {
+		v.Prices = make(map[string]string)
+	}
+	v.Prices[name] = price
+}
+
+// GetPrices returns a copy of the configured prices, by model or provider
+func (v *Vault) GetPrices() map[string]string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	prices := make(map[string]string, len(v.Prices))
+	for k, p := range v.Prices {
+		prices[k] = p
+	}
+	return prices
+}
@@ -0,0 +1,20 @@
+package vault
+
+// SetRedaction stores the redaction rules of a provider or endpoint: "all",
+// "off" or a list of categories, as understood by the redact package
+func (v *Vault) SetRedaction(provider, rules string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.Redaction == nil {
+		v.Redaction = make(map[string]string)
+	}
+	v.Redaction[provider] = rules
+}
+
+// GetRedaction returns the redaction rules configured for a provider
+func (v *Vault) GetRedaction(provider string) (string, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	rules, ok := v.Redaction[provider]
+	return rules, ok
+}
@@ -0,0 +1,220 @@
+package vault
+
+import (
+	"fmt"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+)
+
+// RefScheme prefixes a reference to a vault secret, such as vault://openai,
+// that is resolved when a command runs so the secret never appears on the
+// command line
+const RefScheme = "vault://"
+
+// ExpiryWarning is how long before it expires a secret starts being reported
+const ExpiryWarning = 14 * 24 * time.Hour
+
+// SecretMeta tracks the lifecycle of a secret in APIKeys. Times are RFC 3339.
+type SecretMeta struct {
+	Created  string `json:"created,omitempty"`
+	Rotated  string `json:"rotated,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	LastUsed string `json:"last_used,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+}
+
+// Secret is a secret of the vault with its metadata, as listed by ListSecrets
+type Secret struct {
+	Name string
+	SecretMeta
+	// Token is set for secrets stored in Tokens rather than APIKeys
+	Token bool
+}
+
+// ExpiresAt returns when the secret expires, zero when it does not
+func (m SecretMeta) ExpiresAt() time.Time {
+	t, _ := time.Parse(time.RFC3339, m.Expires)
+	return t
+}
+
+// ExpiresWithin reports whether the secret expires before now+d, or has
+// already expired
+func (m SecretMeta) ExpiresWithin(now time.Time, d time.Duration) bool {
+	t := m.ExpiresAt()
+	return !t.IsZero() && t.Before(now.Add(d))
+}
+
+// SetSecret stores a secret, recording when it was created or rotated. A
+// zero expires and an empty owner keep the previous ones.
+func (v *Vault) SetSecret(name, value string, expires time.Time, owner string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.setSecret(name, value, time.Now(), expires, owner)
+}
+
+// Rotate replaces the value of an existing secret. Without a new expiry, a
+// secret that had one is given the same lifetime again.
+func (v *Vault) Rotate(name, value string, expires time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.APIKeys[name]; !ok {
+		return nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("no secret named %s", name)).
+			WithHint("list secrets with 'nux vault list'")
+	}
+	now := time.Now()
+	if meta := v.Metadata[name]; expires.IsZero() && meta.Expires != "" {
+		since := meta.Rotated
+		if since == "" {
+			since = meta.Created
+		}
+		start, err1 := time.Parse(time.RFC3339, since)
+		end, err2 := time.Parse(time.RFC3339, meta.Expires)
+		if err1 == nil && err2 == nil && end.After(start) {
+			expires = now.Add(end.Sub(start))
+		}
+	}
+	v.setSecret(name, value, now, expires, "")
+	return nil
+}
+
+func (v *Vault) setSecret(name, value string, now, expires time.Time, owner string) {
+	if v.Metadata == nil {
+		v.Metadata = make(map[string]SecretMeta)
+	}
+	meta := v.Metadata[name]
+	stamp := now.UTC().Format(time.RFC3339)
+	if _, ok := v.APIKeys[name]; ok && meta.Created != "" {
+		meta.Rotated = stamp
+	} else {
+		meta.Created = stamp
+	}
+	if !expires.IsZero() {
+		meta.Expires = expires.UTC().Format(time.RFC3339)
+	}
+	if owner != "" {
+		meta.Owner = owner
+	} else if meta.Owner == "" {
+		meta.Owner = currentUser()
+	}
+	v.APIKeys[name] = value
+	v.Metadata[name] = meta
+}
+
+// UseSecret returns a secret, from APIKeys or else Tokens, and records that
+// it was used
+func (v *Vault) UseSecret(name string) (string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now().UTC().Format(time.RFC3339)
+	if value, ok := v.APIKeys[name]; ok {
+		if v.Metadata == nil {
+			v.Metadata = make(map[string]SecretMeta)
+		}
+		meta := v.Metadata[name]
+		meta.LastUsed = now
+		v.Metadata[name] = meta
+		if v.used == nil {
+			v.used = make(map[string]string)
+		}
+		v.used[name] = now
+		return value, true
+	}
+	if token, ok := v.Tokens[name]; ok {
+		return token.Value, true
+	}
+	return "", false
+}
+
+// ListSecrets lists the secrets of the vault by name, without their values
+func (v *Vault) ListSecrets() []Secret {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	var list []Secret
+	for name := range v.APIKeys {
+		list = append(list, Secret{Name: name, SecretMeta: v.Metadata[name]})
+	}
+	for name, token := range v.Tokens {
+		if _, ok := v.APIKeys[name]; !ok {
+			list = append(list, Secret{Name: name, SecretMeta: SecretMeta{Expires: token.Expires}, Token: true})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Expiring lists the secrets that expire within ExpiryWarning of now
+func (v *Vault) Expiring(now time.Time) []Secret {
+	var list []Secret
+	for _, s := range v.ListSecrets() {
+		if s.ExpiresWithin(now, ExpiryWarning) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// ResolveEnv turns NAME=value pairs into environment variables, resolving
+// values such as vault://openai to the secret they name
+func (v *Vault) ResolveEnv(pairs []string) ([]string, error) {
+	env := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("invalid variable %q, expected NAME=value or NAME=%sSECRET", pair, RefScheme))
+		}
+		if ref, isRef := strings.CutPrefix(value, RefScheme); isRef {
+			secret, found := v.UseSecret(ref)
+			if !found {
+				return nil, nuxerrors.New(nuxerrors.ErrCodeNotFound, fmt.Sprintf("%s: no secret named %s in the vault", name, ref)).
+					WithHint("store it with 'nux vault set-key " + ref + "'")
+			}
+			value = secret
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// Refs returns the secrets that NAME=value pairs refer to
+func Refs(pairs []string) []string {
+	var refs []string
+	for _, pair := range pairs {
+		_, value, _ := strings.Cut(pair, "=")
+		if ref, ok := strings.CutPrefix(value, RefScheme); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ParseExpiry reads an expiry given as a number of days ("90d"), a duration
+// ("720h") or a date ("2027-01-31", or RFC 3339)
+func ParseExpiry(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q, expected days (90d), a duration (720h) or a date (2027-01-31)", s)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
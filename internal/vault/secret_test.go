@@ -0,0 +1,238 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetSecretAndRotate(t *testing.T) {
+	v := defaultVault()
+	expires := time.Now().Add(30 * 24 * time.Hour)
+
+	v.SetSecret("github", "ghp_old", expires, "ops")
+	meta := v.Metadata["github"]
+	if meta.Created == "" || meta.Rotated != "" {
+		t.Errorf("Expected only created to be set, got %+v", meta)
+	}
+	if meta.Owner != "ops" {
+		t.Errorf("Expected owner ops, got %q", meta.Owner)
+	}
+
+	// Pretend the secret was created 60 days ago so rotating keeps a 30 day lifetime
+	meta.Created = time.Now().Add(-60 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	meta.Expires = time.Now().Add(-30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	v.Metadata["github"] = meta
+
+	if err := v.Rotate("github", "ghp_new", time.Time{}); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if key, _ := v.GetAPIKey("github"); key != "ghp_new" {
+		t.Errorf("Expected rotated value, got %s", key)
+	}
+	meta = v.Metadata["github"]
+	if meta.Rotated == "" || meta.Owner != "ops" {
+		t.Errorf("Expected rotated stamp and owner kept, got %+v", meta)
+	}
+	if left := time.Until(meta.ExpiresAt()); left < 29*24*time.Hour || left > 31*24*time.Hour {
+		t.Errorf("Expected the 30 day lifetime to be renewed, expires in %s", left)
+	}
+
+	if err := v.Rotate("missing", "x", time.Time{}); err == nil {
+		t.Error("Rotating a missing secret should fail")
+	}
+}
+
+func TestExpiring(t *testing.T) {
+	v := defaultVault()
+	now := time.Now()
+	v.SetSecret("soon", "a", now.Add(24*time.Hour), "")
+	v.SetSecret("later", "b", now.Add(90*24*time.Hour), "")
+	v.SetSecret("never", "c", time.Time{}, "")
+
+	expiring := v.Expiring(now)
+	if len(expiring) != 1 || expiring[0].Name != "soon" {
+		t.Errorf("Expected only soon to be expiring, got %+v", expiring)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	v := defaultVault()
+	v.SetAPIKey("github", "ghp_secret")
+
+	env, err := v.ResolveEnv([]string{"GH_TOKEN=vault://github", "DEBUG=1"})
+	if err != nil {
+		t.Fatalf("ResolveEnv failed: %v", err)
+	}
+	if strings.Join(env, " ") != "GH_TOKEN=ghp_secret DEBUG=1" {
+		t.Errorf("Unexpected env %v", env)
+	}
+	if v.Metadata["github"].LastUsed == "" {
+		t.Error("Resolving a reference should record its use")
+	}
+
+	if _, err := v.ResolveEnv([]string{"TOKEN=vault://missing"}); err == nil {
+		t.Error("A reference to a missing secret should fail")
+	}
+	if _, err := v.ResolveEnv([]string{"NOVALUE"}); err == nil {
+		t.Error("A pair without = should fail")
+	}
+
+	if refs := Refs([]string{"A=vault://x", "B=plain", "C=vault://y"}); strings.Join(refs, ",") != "x,y" {
+		t.Errorf("Unexpected refs %v", refs)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"90d", now.AddDate(0, 0, 90)},
+		{"720h", now.Add(720 * time.Hour)},
+		{"2027-01-31", time.Date(2027, 1, 31, 0, 0, 0, 0, time.Local)},
+		{"2027-01-31T10:00:00Z", time.Date(2027, 1, 31, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseExpiry(tt.in, now)
+		if err != nil {
+			t.Errorf("ParseExpiry(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseExpiry(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "-5d", "0d"} {
+		if _, err := ParseExpiry(bad, now); err == nil {
+			t.Errorf("ParseExpiry(%q) should fail", bad)
+		}
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	src := defaultVault()
+	src.SetSecret("openai", "sk-1", time.Time{}, "alice")
+	src.SetSecret("github", "ghp-1", time.Time{}, "")
+
+	data, err := src.Export([]string{"openai"}, []byte("bundle-pass"))
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if strings.Contains(string(data), "sk-1") {
+		t.Error("Bundle should not contain the secret in clear text")
+	}
+	if _, err := src.Export([]string{"missing"}, []byte("bundle-pass")); err == nil {
+		t.Error("Exporting a missing secret should fail")
+	}
+
+	dst := defaultVault()
+	if _, _, err := dst.Import(data, []byte("wrong"), false); err == nil {
+		t.Fatal("Import with the wrong passphrase should fail")
+	}
+
+	dst.SetAPIKey("openai", "sk-local")
+	imported, skipped, err := dst.Import(data, []byte("bundle-pass"), false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(imported) != 0 || len(skipped) != 1 {
+		t.Errorf("Expected openai to be skipped, got imported %v skipped %v", imported, skipped)
+	}
+	if key, _ := dst.GetAPIKey("openai"); key != "sk-local" {
+		t.Errorf("Existing secret should be kept, got %s", key)
+	}
+
+	imported, _, err = dst.Import(data, []byte("bundle-pass"), true)
+	if err != nil || len(imported) != 1 {
+		t.Fatalf("Import with overwrite failed: %v %v", imported, err)
+	}
+	if key, _ := dst.GetAPIKey("openai"); key != "sk-1" {
+		t.Errorf("Expected imported secret, got %s", key)
+	}
+	if dst.Metadata["openai"].Owner != "alice" {
+		t.Errorf("Expected metadata to be imported, got %+v", dst.Metadata["openai"])
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	v := defaultVault()
+	if err := v.SetEndpoint("vllm", Endpoint{BaseURL: "http://gpu01:8000", APIKeyRef: "plain-key"}); err == nil {
+		t.Error("A key reference without vault:// should be refused")
+	}
+
+	e := Endpoint{Type: EndpointOpenAICompatible, BaseURL: "http://gpu01:8000", APIKeyRef: "vault://vllm"}
+	if err := v.SetEndpoint("vllm", e); err != nil {
+		t.Fatalf("SetEndpoint failed: %v", err)
+	}
+	v.SetEndpoint("llamacpp", Endpoint{Type: EndpointOpenAICompatible, BaseURL: "http://localhost:8080"})
+	if names := v.EndpointNames(); strings.Join(names, ",") != "llamacpp,vllm" {
+		t.Errorf("Unexpected endpoints %v", names)
+	}
+
+	if _, err := v.EndpointKey(e); err == nil {
+		t.Error("A reference to a missing secret should fail")
+	}
+	v.SetAPIKey("vllm", "tok")
+	if key, err := v.EndpointKey(e); err != nil || key != "tok" {
+		t.Errorf("Expected the referenced key, got %q, %v", key, err)
+	}
+	if key, err := v.EndpointKey(v.Endpoints["llamacpp"]); err != nil || key != "" {
+		t.Errorf("An endpoint without a reference has no key, got %q, %v", key, err)
+	}
+
+	if !v.RemoveEndpoint("llamacpp") || v.RemoveEndpoint("llamacpp") {
+		t.Error("RemoveEndpoint should report whether the endpoint existed")
+	}
+}
+
+// Recording the use of a key must not write over what another command saved
+// since the vault was loaded
+func TestSaveUsageKeepsConcurrentChanges(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	v := NewVault()
+	v.SetAPIKey("openai", "sk-old")
+	if err := Save(v); err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := running.UseSecret("openai"); !ok {
+		t.Fatal("expected the openai key")
+	}
+
+	other, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Rotate("openai", "sk-new", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	other.SetAPIKey("anthropic", "sk-ant")
+	if err := Save(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveUsage(running); err != nil {
+		t.Fatalf("SaveUsage failed: %v", err)
+	}
+	saved, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := saved.GetAPIKey("openai"); key != "sk-new" {
+		t.Errorf("the rotated key was written over: %q", key)
+	}
+	if _, ok := saved.GetAPIKey("anthropic"); !ok {
+		t.Error("the key added meanwhile was lost")
+	}
+	if saved.Metadata["openai"].LastUsed == "" || saved.Metadata["openai"].Rotated == "" {
+		t.Errorf("expected the use and the rotation to be kept, got %+v", saved.Metadata["openai"])
+	}
+}
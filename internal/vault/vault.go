@@ -6,19 +6,52 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
+
+	nuxerrors "github.com/rsdenck/nux/internal/core/errors"
+	"golang.org/x/sys/unix"
 )
 
 const vaultDir = ".nux"
 const vaultFile = "vault.json"
 
+// PassphraseEnv is read for the vault passphrase before asking for it, for
+// scripts and CI
+const PassphraseEnv = "NUX_VAULT_PASSWORD"
+
+// BundlePassphraseEnv is read for the passphrase of export bundles
+const BundlePassphraseEnv = "NUX_BUNDLE_PASSWORD"
+
+// minPassphraseLen is the shortest passphrase accepted for a new vault
+const minPassphraseLen = 8
+
 type Vault struct {
 	Version   string                 `json:"version"`
 	Installed map[string]SkillStatus `json:"installed"`
-	Enabled   map[string]bool       `json:"enabled"`
-	APIKeys   map[string]string     `json:"api_keys"`
-	Tokens    map[string]TokenInfo  `json:"tokens"`
+	Enabled   map[string]bool        `json:"enabled"`
+	APIKeys   map[string]string      `json:"api_keys"`
+	Tokens    map[string]TokenInfo   `json:"tokens"`
 	Config    map[string]interface{} `json:"config"`
-	mu        sync.RWMutex
+	// Metadata tracks the lifecycle of the secrets in APIKeys
+	Metadata map[string]SecretMeta `json:"metadata,omitempty"`
+	// Endpoints are the AI servers configured by name, see SetEndpoint
+	Endpoints map[string]Endpoint `json:"endpoints,omitempty"`
+	// Redaction holds the redaction rules of AI providers, see SetRedaction
+	Redaction map[string]string `json:"redaction,omitempty"`
+	// Prices holds what AI models and providers charge, see SetPrice
+	Prices map[string]string `json:"prices,omitempty"`
+	mu     sync.RWMutex
+
+	// key unlocked the vault file and seals it again on Save; it was derived
+	// from the passphrase with salt
+	key  []byte
+	salt []byte
+	// loaded is set for vaults returned by Load, the only ones Save may
+	// write over an existing file
+	loaded bool
+	// used holds when the secrets read with UseSecret were used, for
+	// SaveUsage
+	used map[string]string
 }
 
 type SkillStatus struct {
@@ -33,6 +66,19 @@ type TokenInfo struct {
 	Provider string `json:"provider"`
 }
 
+// PassphraseFunc asks the user for the passphrase of what, "vault" or
+// "bundle". confirm is set when the passphrase is new and should be typed
+// twice.
+type PassphraseFunc func(what string, confirm bool) ([]byte, error)
+
+var passphraseFunc PassphraseFunc
+
+// SetPassphraseFunc installs how passphrases are asked for when they are not
+// set in the environment
+func SetPassphraseFunc(fn PassphraseFunc) {
+	passphraseFunc = fn
+}
+
 func getVaultPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -41,6 +87,15 @@ func getVaultPath() (string, error) {
 	return filepath.Join(home, vaultDir, vaultFile), nil
 }
 
+// Path returns the location of the vault file
+func Path() (string, error) {
+	return getVaultPath()
+}
+
+// Load reads and decrypts the vault, with the key cached by the unlock agent
+// or else the passphrase. A missing vault file yields an empty vault; a
+// plaintext one written by an earlier version is refused until it is
+// migrated.
 func Load() (*Vault, error) {
 	path, err := getVaultPath()
 	if err != nil {
@@ -50,40 +105,284 @@ func Load() (*Vault, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return defaultVault(), nil
+			v := defaultVault()
+			v.loaded = true
+			return v, nil
 		}
 		return nil, fmt.Errorf("failed to read vault: %w", err)
 	}
 
-	var v Vault
-	if err := json.Unmarshal(data, &v); err != nil {
-		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	encrypted, ok := parseEncrypted(data)
+	if !ok {
+		return nil, plaintextError(path)
+	}
+	v, err := unlock(encrypted)
+	if err != nil {
+		return nil, err
 	}
-	return &v, nil
+	v.loaded = true
+	return v, nil
+}
+
+func plaintextError(path string) error {
+	return nuxerrors.New(nuxerrors.ErrCodeVault, fmt.Sprintf("vault %s is stored in plaintext", path)).
+		WithHint("run 'nux vault migrate' to encrypt it")
 }
 
+// Save encrypts and writes the vault. The key that unlocked it is used again;
+// a new vault asks for a passphrase.
 func Save(v *Vault) error {
 	path, err := getVaultPath()
 	if err != nil {
 		return err
 	}
+	if !v.loaded {
+		if _, err := os.Stat(path); err == nil {
+			return nuxerrors.New(nuxerrors.ErrCodeVault, fmt.Sprintf("refusing to overwrite %s with a vault that was not loaded from it", path))
+		}
+	}
+	if v.key == nil {
+		pass, err := passphrase(true)
+		if err != nil {
+			return err
+		}
+		if err := v.setPassphrase(pass); err != nil {
+			return err
+		}
+	}
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return write(path, v)
+}
 
+// SaveUsage records when the secrets of v were last used, see UseSecret. The
+// vault file is read again and only the last use of those secrets is changed,
+// so a command that ran meanwhile, such as a rotate, is not written over. A
+// vault that used no secret, or whose file was sealed again with another
+// passphrase, is not saved.
+func SaveUsage(v *Vault) error {
+	v.mu.RLock()
+	used := make(map[string]string, len(v.used))
+	for name, stamp := range v.used {
+		used[name] = stamp
+	}
+	key, salt := v.key, v.salt
+	v.mu.RUnlock()
+	if len(used) == 0 || key == nil {
+		return nil
+	}
+
+	path, err := getVaultPath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read vault: %w", err)
+	}
+	encrypted, ok := parseEncrypted(data)
+	if !ok {
+		return plaintextError(path)
+	}
+	current, err := open(encrypted, key, salt)
+	if err != nil {
+		return nil
+	}
+	changed := false
+	for name, stamp := range used {
+		meta, ok := current.Metadata[name]
+		if _, exists := current.APIKeys[name]; !exists || (ok && meta.LastUsed >= stamp) {
+			continue
+		}
+		meta.LastUsed = stamp
+		current.Metadata[name] = meta
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return write(path, current)
+}
+
+// lockFile takes an exclusive lock next to the vault file at path, held until
+// unlock is called, so the vault is rewritten by one command at a time
+func lockFile(path string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock vault: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock vault: %w", err)
+	}
+	return func() {
+		unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// setPassphrase derives a new key from pass, with a new salt
+func (v *Vault) setPassphrase(pass []byte) error {
+	salt, err := NewSalt()
+	if err != nil {
+		return err
+	}
+	v.key = DeriveKey(pass, salt)
+	v.salt = salt
+	return nil
+}
+
+// IsEncrypted reports whether the vault file exists and is encrypted
+func IsEncrypted() (bool, error) {
+	path, err := getVaultPath()
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read vault: %w", err)
+	}
+	_, ok := parseEncrypted(data)
+	return ok, nil
+}
+
+func parseEncrypted(data []byte) (*EncryptedVault, bool) {
+	var encrypted EncryptedVault
+	if err := json.Unmarshal(data, &encrypted); err != nil || encrypted.Salt == "" || encrypted.Data == "" {
+		return nil, false
+	}
+	return &encrypted, true
+}
+
+// unlock decrypts an encrypted vault with the key cached by the unlock agent,
+// falling back to the passphrase when no agent holds the key
+func unlock(encrypted *EncryptedVault) (*Vault, error) {
+	salt, err := encrypted.DecodeSalt()
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv(PassphraseEnv) == "" {
+		if key, ok := agentKey(salt); ok {
+			if v, err := open(encrypted, key, salt); err == nil {
+				return v, nil
+			}
+		}
+	}
+	pass, err := passphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	return open(encrypted, DeriveKey(pass, salt), salt)
+}
+
+// open decrypts an encrypted vault with key
+func open(encrypted *EncryptedVault, key, salt []byte) (*Vault, error) {
+	plaintext, err := Open(encrypted, key)
+	if err != nil {
+		return nil, nuxerrors.Wrap(err, nuxerrors.ErrCodeVault, "failed to unlock vault").
+			WithHint("check the passphrase, or " + PassphraseEnv)
+	}
+	v := defaultVault()
+	if err := json.Unmarshal(plaintext, v); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	}
+	v.key = key
+	v.salt = salt
+	return v, nil
+}
+
+// write seals v with its key and replaces the file at path, through a
+// temporary file so an interrupted write never leaves a truncated vault
+func write(path string, v *Vault) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create vault directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(v, "", "  ")
+	v.mu.RLock()
+	plaintext, err := json.Marshal(v)
+	v.mu.RUnlock()
 	if err != nil {
 		return fmt.Errorf("failed to marshal vault: %w", err)
 	}
+	encrypted, err := Seal(plaintext, v.key, v.salt)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt vault: %w", err)
+	}
+	data, err := json.MarshalIndent(encrypted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted vault: %w", err)
+	}
 
-	if err := os.WriteFile(path, data, 0600); err != nil {
+	tmp, err := os.CreateTemp(dir, ".vault-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
 		return fmt.Errorf("failed to write vault: %w", err)
 	}
 	return nil
 }
 
+// passphrase returns the passphrase from NUX_VAULT_PASSWORD or asks for it
+func passphrase(confirm bool) ([]byte, error) {
+	return askPassphrase(PassphraseEnv, "vault", confirm)
+}
+
+// BundlePassphrase returns the passphrase of an export bundle from
+// NUX_BUNDLE_PASSWORD or asks for it
+func BundlePassphrase(confirm bool) ([]byte, error) {
+	return askPassphrase(BundlePassphraseEnv, "bundle", confirm)
+}
+
+// askPassphrase reads the passphrase of what from env, or asks for it
+func askPassphrase(env, what string, confirm bool) ([]byte, error) {
+	if p := os.Getenv(env); p != "" {
+		return []byte(p), nil
+	}
+	if passphraseFunc == nil {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeVault, fmt.Sprintf("no %s passphrase", what)).
+			WithHint("set " + env + " or run nux in a terminal")
+	}
+	p, err := passphraseFunc(what, confirm)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeVault, fmt.Sprintf("empty %s passphrase", what))
+	}
+	if confirm && len(p) < minPassphraseLen {
+		return nil, nuxerrors.New(nuxerrors.ErrCodeInvalidInput, fmt.Sprintf("%s passphrase must have at least %d characters", what, minPassphraseLen))
+	}
+	return p, nil
+}
+
 func NewVault() *Vault {
 	return &Vault{
 		Version:   "1.0.0",
@@ -92,6 +391,8 @@ func NewVault() *Vault {
 		APIKeys:   make(map[string]string),
 		Tokens:    make(map[string]TokenInfo),
 		Config:    make(map[string]interface{}),
+		Metadata:  make(map[string]SecretMeta),
+		Endpoints: make(map[string]Endpoint),
 	}
 }
 
@@ -102,7 +403,7 @@ func defaultVault() *Vault {
 func (v *Vault) SetAPIKey(service, key string) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
-	v.APIKeys[service] = key
+	v.setSecret(service, key, time.Now(), time.Time{}, "")
 }
 
 func (v *Vault) GetAPIKey(service string) (string, bool) {
@@ -116,10 +417,12 @@ func (v *Vault) EnableSkill(name string) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	v.Enabled[name] = true
-	v.Installed[name] = SkillStatus{
-		InstalledAt: "now",
-		Status:      "active",
+	s := v.Installed[name]
+	if s.InstalledAt == "" {
+		s.InstalledAt = "now"
 	}
+	s.Status = "active"
+	v.Installed[name] = s
 }
 
 func (v *Vault) DisableSkill(name string) {
@@ -131,3 +434,31 @@ func (v *Vault) DisableSkill(name string) {
 		v.Installed[name] = s
 	}
 }
+
+// InstallSkill records a skill as installed but not yet enabled
+func (v *Vault) InstallSkill(name, installedAt string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.Installed[name] = SkillStatus{
+		InstalledAt: installedAt,
+		Status:      "installed",
+	}
+}
+
+// IsInstalled reports whether a skill was installed
+func (v *Vault) IsInstalled(name string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	_, ok := v.Installed[name]
+	return ok
+}
+
+// SetConfig sets a configuration value
+func (v *Vault) SetConfig(key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.Config == nil {
+		v.Config = make(map[string]interface{})
+	}
+	v.Config[key] = value
+}
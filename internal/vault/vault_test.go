@@ -3,6 +3,7 @@ package vault
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -69,8 +70,8 @@ func TestSaveAndLoad(t *testing.T) {
 	tmpDir := t.TempDir()
 	
 	// Override home dir for test
-	os.Setenv("HOME", tmpDir)
-	defer os.Unsetenv("HOME")
+	t.Setenv("HOME", tmpDir)
+	t.Setenv(PassphraseEnv, "correct horse")
 	
 	// Create and save vault
 	v := defaultVault()
@@ -83,8 +84,12 @@ func TestSaveAndLoad(t *testing.T) {
 	
 	// Check file exists
 	vaultPath := filepath.Join(tmpDir, ".nux", "vault.json")
-	if _, err := os.Stat(vaultPath); os.IsNotExist(err) {
-		t.Error("Vault file should exist")
+	data, err := os.ReadFile(vaultPath)
+	if err != nil {
+		t.Fatalf("Vault file should exist: %v", err)
+	}
+	if strings.Contains(string(data), "key123") {
+		t.Error("Vault file should not contain the key in plaintext")
 	}
 	
 	// Load vault
@@ -96,4 +101,94 @@ func TestSaveAndLoad(t *testing.T) {
 	if loaded.Version != v.Version {
 		t.Errorf("Version mismatch: expected %s, got %s", v.Version, loaded.Version)
 	}
+
+	if key, _ := loaded.GetAPIKey("test"); key != "key123" {
+		t.Errorf("Expected key123, got %q", key)
+	}
+}
+
+func TestLoadWrongPassphrase(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	v := defaultVault()
+	v.SetAPIKey("openai", "sk-test123")
+	if err := Save(v); err != nil {
+		t.Fatalf("Failed to save vault: %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong horse")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load should fail with the wrong passphrase")
+	}
+
+	// A vault that failed to load must not replace the one on disk
+	if err := Save(NewVault()); err == nil {
+		t.Error("Save should refuse to overwrite a vault it did not load")
+	}
+}
+
+func TestLoadRefusesPlaintext(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	writeFile(t, filepath.Join(home, ".nux", "vault.json"), `{"version":"1.0.0","api_keys":{"openai":"sk-plain"}}`)
+	if _, err := Load(); err == nil {
+		t.Fatal("Load should refuse a plaintext vault")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	vaultPath := filepath.Join(home, ".nux", "vault.json")
+	skillPath := filepath.Join(home, ".skills", ".nux.json")
+	writeFile(t, vaultPath, `{"version":"1.0.0","api_keys":{"openai":"sk-plain"}}`)
+	writeFile(t, skillPath, `{"installed_skills":["docker"],"enabled_skills":["docker"],"api_keys":{"openai":"sk-old","nvidia":"nv-key"},"ollama":{"host":"http://gpu:11434"}}`)
+
+	migrated, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(migrated) != 2 {
+		t.Errorf("Expected 2 migrated files, got %v", migrated)
+	}
+	if _, err := os.Stat(skillPath); !os.IsNotExist(err) {
+		t.Error("Skill vault should be removed")
+	}
+
+	v, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load migrated vault: %v", err)
+	}
+	if key, _ := v.GetAPIKey("openai"); key != "sk-plain" {
+		t.Errorf("Expected the vault key to win, got %q", key)
+	}
+	if key, _ := v.GetAPIKey("nvidia"); key != "nv-key" {
+		t.Errorf("Expected nv-key, got %q", key)
+	}
+	if !v.Enabled["docker"] || !v.IsInstalled("docker") {
+		t.Error("Docker should be installed and enabled")
+	}
+	if v.Config["ollama_host"] != "http://gpu:11434" {
+		t.Errorf("Expected the ollama host, got %v", v.Config["ollama_host"])
+	}
+
+	migrated, err = Migrate()
+	if err != nil || len(migrated) != 0 {
+		t.Errorf("Second migration should do nothing, got %v, %v", migrated, err)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
 }